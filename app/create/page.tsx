@@ -181,7 +181,7 @@ function CreatePageContent() {
             modelName: item.modelName,
             prompt: item.prompt,
             type: item.type as "image" | "video",
-            createdAt: item.createdAt,
+            createdAt: Date.parse(item.createdAt),
             generations: item.mediaUrls?.map((url, idx) => ({
               id: `${item.jobId}-${idx}`,
               seed: item.params?.seed || '',
@@ -318,8 +318,20 @@ function CreatePageContent() {
           return;
         }
 
+        if (status.status === "completed_empty") {
+          setError(status.message || "The generation finished without producing any output");
+          setIsGenerating(false);
+          setCurrentJob(null);
+          return;
+        }
+
         if (status.status === "faulted") {
-          setError("Generation failed");
+          const reason = status.faultReason;
+          setError(
+            reason
+              ? `Generation failed: ${reason.message}${reason.class === "retryable" ? " (try again)" : ""}`
+              : "Generation failed"
+          );
           setIsGenerating(false);
           return;
         }
@@ -1,4 +1,5 @@
 import { CreateJobRequest, GalleryModel, JobStatus, ModelsResponse } from "@/types/models";
+import { walletAuthHeaders } from "@/lib/wallet-auth";
 
 const getApiBase = () =>
   process.env.NEXT_PUBLIC_GALLERY_API ?? "http://localhost:4000/api";
@@ -30,18 +31,73 @@ export function fetchModels(): Promise<ModelsResponse> {
   return jsonFetch("/models", undefined, 30);
 }
 
-export function createJob(payload: CreateJobRequest) {
-  return jsonFetch<{ jobId: string; status: string }>("/jobs", {
+type CreateJobResponse = {
+  jobId: string;
+  status: string;
+  fallbackModel?: string;
+  anonymous?: boolean;
+  job?: JobStatus;
+  /** Set instead of jobId while the job waits in the server's submit queue */
+  queueId?: string;
+  queuePosition?: number;
+};
+
+export interface QueuedSubmission {
+  queueId: string;
+  status: "pending" | "submitted" | "failed";
+  queuePosition: number;
+  jobId?: string;
+  error?: string;
+}
+
+/**
+ * Submit a job. With `track`, the response also carries the job's first
+ * status so the caller can skip its immediate poll. A job the server had to
+ * queue is waited on until the Grid has it, so the result always has a jobId.
+ */
+export async function createJob(payload: CreateJobRequest, options: { track?: boolean } = {}): Promise<CreateJobResponse> {
+  const resp = await jsonFetch<CreateJobResponse>(options.track ? "/jobs?track=true" : "/jobs", {
     method: "POST",
     headers: { "Content-Type": "application/json" },
     body: JSON.stringify(payload),
   });
+  if (resp.jobId || !resp.queueId) return resp;
+
+  for (;;) {
+    await new Promise((resolve) => setTimeout(resolve, 1000));
+    const queued = await fetchQueuedJob(resp.queueId);
+    if (queued.status === "failed") throw new Error(queued.error || "Job submission failed");
+    if (queued.status === "submitted" && queued.jobId) {
+      return { ...resp, jobId: queued.jobId, status: "queued" };
+    }
+  }
+}
+
+export function fetchQueuedJob(queueId: string) {
+  return jsonFetch<QueuedSubmission>(`/jobs/queued/${queueId}`);
 }
 
 export function fetchJobStatus(jobId: string) {
   return jsonFetch<JobStatus>(`/jobs/${jobId}`);
 }
 
+export interface BatchJobStatusResult {
+  jobId: string;
+  /** HTTP status a single-job lookup would have returned */
+  code: number;
+  job?: JobStatus;
+  error?: string;
+}
+
+/** Status of up to 50 jobs in one request; failures are reported per job */
+export function fetchJobStatuses(jobIds: string[]) {
+  return jsonFetch<{ results: BatchJobStatusResult[] }>("/jobs/status/batch", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ jobIds }),
+  });
+}
+
 // Gallery API
 
 export interface JobParams {
@@ -69,9 +125,15 @@ export interface GalleryItem {
   isNsfw: boolean;
   isPublic?: boolean;
   walletAddress?: string;
-  createdAt: number;
+  /** RFC3339, like every timestamp the API returns */
+  createdAt: string;
   params?: JobParams;
+  /** Complete params map the job was submitted to the Grid with */
+  gridParams?: Record<string, unknown>;
   mediaUrls?: string[];
+  /** Hand-picked by an admin for the homepage showcase */
+  featured?: boolean;
+  featuredAt?: string;
 }
 
 export interface GalleryResponse {
@@ -79,18 +141,55 @@ export interface GalleryResponse {
   total: number;
   hasMore: boolean;
   nextOffset: number;
+  /** Shuffle seed the page was ordered by; send it back for the next page */
+  seed?: string;
 }
 
-export function fetchGallery(typeFilter?: string, limit?: number, offset?: number, searchQuery?: string): Promise<GalleryResponse> {
+/** Fields a gallery search can look in; the server searches the prompt alone by default */
+export type SearchField = "prompt" | "negativePrompt" | "model";
+
+export function fetchGallery(typeFilter?: string, limit?: number, offset?: number, searchQuery?: string, searchFields?: SearchField[]): Promise<GalleryResponse> {
   const params = new URLSearchParams();
   if (typeFilter && typeFilter !== "all") params.append("type", typeFilter);
   if (limit) params.append("limit", String(limit));
   if (offset !== undefined) params.append("offset", String(offset));
   if (searchQuery) params.append("q", searchQuery);
+  if (searchQuery && searchFields?.length) params.append("searchFields", searchFields.join(","));
   const query = params.toString();
   return jsonFetch(`/gallery${query ? `?${query}` : ""}`);
 }
 
+/** One item type's gallery feed; it pages independently of the mixed feed */
+export function fetchGalleryFeed(feed: "images" | "videos", limit?: number, offset?: number, searchQuery?: string, searchFields?: SearchField[]): Promise<GalleryResponse> {
+  const params = new URLSearchParams();
+  if (limit) params.append("limit", String(limit));
+  if (offset !== undefined) params.append("offset", String(offset));
+  if (searchQuery) params.append("q", searchQuery);
+  if (searchQuery && searchFields?.length) params.append("searchFields", searchFields.join(","));
+  const query = params.toString();
+  return jsonFetch(`/gallery/${feed}${query ? `?${query}` : ""}`);
+}
+
+/** Featured public items, most recently featured first */
+export function fetchFeaturedGallery(typeFilter?: string, limit?: number, offset?: number): Promise<GalleryResponse> {
+  const params = new URLSearchParams();
+  if (typeFilter && typeFilter !== "all") params.append("type", typeFilter);
+  if (limit) params.append("limit", String(limit));
+  if (offset !== undefined) params.append("offset", String(offset));
+  const query = params.toString();
+  return jsonFetch(`/gallery/featured${query ? `?${query}` : ""}`);
+}
+
+/** The public gallery in a random order that stays put while paging with the same seed */
+export function fetchExplore(seed?: string, limit?: number, offset?: number): Promise<GalleryResponse> {
+  const params = new URLSearchParams();
+  if (seed) params.append("seed", seed);
+  if (limit) params.append("limit", String(limit));
+  if (offset !== undefined) params.append("offset", String(offset));
+  const query = params.toString();
+  return jsonFetch(`/gallery/explore${query ? `?${query}` : ""}`);
+}
+
 export interface AddToGalleryRequest {
   jobId: string;
   modelId: string;
@@ -105,10 +204,12 @@ export interface AddToGalleryRequest {
   mediaUrls?: string[];
 }
 
-export function addToGallery(item: AddToGalleryRequest): Promise<{ success: boolean }> {
+export async function addToGallery(item: AddToGalleryRequest): Promise<{ success: boolean }> {
+  // Saving under a wallet has to be signed by it
+  const auth = item.walletAddress ? await walletAuthHeaders(item.walletAddress) : {};
   return jsonFetch("/gallery", {
     method: "POST",
-    headers: { "Content-Type": "application/json" },
+    headers: { "Content-Type": "application/json", ...auth },
     body: JSON.stringify(item),
   });
 }
@@ -138,36 +239,44 @@ export function fetchGalleryMedia(jobId: string): Promise<GalleryMediaResponse>
   return jsonFetch(`/gallery/${jobId}/media`);
 }
 
-export function deleteGalleryItem(jobId: string, walletAddress?: string): Promise<{ success: boolean; message: string }> {
-  const headers: Record<string, string> = {};
-  if (walletAddress) {
-    headers["X-Wallet-Address"] = walletAddress;
-  }
+/**
+ * Link that downloads one of an item's files. "embed" writes the prompt and
+ * params into PNGs and zips other formats with a JSON sidecar; "sidecar"
+ * always zips. Private items need a request signed by the owner's wallet, so
+ * link only public ones directly.
+ */
+export function galleryDownloadUrl(jobId: string, index = 0, metadata: "none" | "embed" | "sidecar" = "none"): string {
+  const params = new URLSearchParams({ index: String(index), metadata });
+  return `${getApiBase()}/gallery/${jobId}/download?${params}`;
+}
+
+export async function deleteGalleryItem(jobId: string, walletAddress?: string): Promise<{ success: boolean; message: string }> {
+  const headers = walletAddress ? await walletAuthHeaders(walletAddress) : {};
   return jsonFetch(`/gallery/${jobId}`, {
     method: "DELETE",
     headers,
   });
 }
 
-export function publishGalleryItem(jobId: string, walletAddress: string): Promise<{ success: boolean; isPublic: boolean }> {
+export async function publishGalleryItem(jobId: string, walletAddress: string): Promise<{ success: boolean; isPublic: boolean }> {
   return jsonFetch(`/gallery/${jobId}/publish`, {
     method: "POST",
-    headers: { "X-Wallet-Address": walletAddress },
+    headers: await walletAuthHeaders(walletAddress),
   });
 }
 
 // Favorites API
-export function addFavorite(jobId: string, walletAddress: string): Promise<{ success: boolean }> {
+export async function addFavorite(jobId: string, walletAddress: string): Promise<{ success: boolean }> {
   return jsonFetch(`/favorites/${jobId}`, {
     method: "POST",
-    headers: { "X-Wallet-Address": walletAddress },
+    headers: await walletAuthHeaders(walletAddress),
   });
 }
 
-export function removeFavorite(jobId: string, walletAddress: string): Promise<{ success: boolean }> {
+export async function removeFavorite(jobId: string, walletAddress: string): Promise<{ success: boolean }> {
   return jsonFetch(`/favorites/${jobId}`, {
     method: "DELETE",
-    headers: { "X-Wallet-Address": walletAddress },
+    headers: await walletAuthHeaders(walletAddress),
   });
 }
 
@@ -290,7 +290,7 @@ export function generateTagsFromPrompt(prompt: string): string[] {
 export interface ActiveJob {
   jobId: string;
   submittedAt: number;
-  status: 'queued' | 'processing' | 'completed' | 'faulted' | null;
+  status: 'queued' | 'processing' | 'completed' | 'completed_empty' | 'faulted' | null;
   error?: string;
 }
 
@@ -329,7 +329,7 @@ export function saveActiveJob(job: ActiveJob): void {
     // Remove completed/faulted jobs older than 1 hour
     const now = Date.now();
     const filtered = jobs.filter(j => {
-      if (j.status === 'completed' || j.status === 'faulted') {
+      if (j.status === 'completed' || j.status === 'completed_empty' || j.status === 'faulted') {
         return (now - j.submittedAt) < 3600000; // Keep for 1 hour
       }
       return true; // Keep all queued/processing jobs
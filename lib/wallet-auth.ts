@@ -0,0 +1,41 @@
+import { stringToHex } from "viem";
+
+/**
+ * Signed wallet headers for endpoints that reveal or change a wallet's data.
+ * The server accepts a signature for 5 minutes, so one is reused for a bit
+ * less than that to avoid a wallet prompt on every request.
+ */
+const SIGNATURE_REUSE_MS = 4 * 60 * 1000;
+
+const signed = new Map<string, { headers: Record<string, string>; signedAt: number }>();
+
+/** The text the server expects a wallet to sign; see server/internal/app/wallet_auth.go */
+function walletAuthMessage(wallet: string, timestamp: number): string {
+  return `AIPG Art Gallery\nWallet: ${wallet}\nTimestamp: ${timestamp}`;
+}
+
+export async function walletAuthHeaders(walletAddress: string): Promise<Record<string, string>> {
+  const wallet = walletAddress.toLowerCase();
+  const cached = signed.get(wallet);
+  if (cached && Date.now() - cached.signedAt < SIGNATURE_REUSE_MS) {
+    return cached.headers;
+  }
+
+  const ethereum = typeof window !== "undefined" ? (window as any).ethereum : undefined;
+  if (!ethereum) {
+    throw new Error("Connect a wallet to sign this request");
+  }
+  const timestamp = Math.floor(Date.now() / 1000);
+  const signature: string = await ethereum.request({
+    method: "personal_sign",
+    params: [stringToHex(walletAuthMessage(wallet, timestamp)), walletAddress],
+  });
+
+  const headers = {
+    "X-Wallet-Address": wallet,
+    "X-Wallet-Timestamp": String(timestamp),
+    "X-Wallet-Signature": signature,
+  };
+  signed.set(wallet, { headers, signedAt: Date.now() });
+  return headers;
+}
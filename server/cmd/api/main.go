@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
@@ -43,6 +44,7 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to initialise app: %v", err)
 	}
+	appInstance.Start(context.Background())
 
 	log.Printf("AIPG gallery API listening on %s", cfg.Address)
 	if err := http.ListenAndServe(cfg.Address, appInstance.Router()); err != nil {
@@ -4,15 +4,120 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 )
 
+// ErrJobNotFound is returned by JobStatus when the Grid has no record of the job.
+// Right after submission this is usually transient; later it means the job
+// expired or never existed.
+var ErrJobNotFound = errors.New("job not found")
+
+// ErrInvalidAPIKey is returned by FindUser when the Grid doesn't recognise the API key
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// InsufficientKudosError is returned by CreateJob when the API key doesn't have
+// enough kudos to pay for the job up front
+type InsufficientKudosError struct {
+	Message string
+}
+
+func (e *InsufficientKudosError) Error() string {
+	return "insufficient kudos: " + e.Message
+}
+
+// kudosErrorCodes are the Grid "rc" values that mean the key can't pay for the job
+var kudosErrorCodes = map[string]bool{
+	"KudosUpfront":      true,
+	"InsufficientKudos": true,
+}
+
+// parseKudosError reports whether a failed generate response is the Grid's
+// insufficient-kudos rejection
+func parseKudosError(status int, body []byte) (*InsufficientKudosError, bool) {
+	if status != http.StatusForbidden && status != http.StatusPaymentRequired {
+		return nil, false
+	}
+	var parsed struct {
+		Message string `json:"message"`
+		RC      string `json:"rc"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, false
+	}
+	if kudosErrorCodes[parsed.RC] || strings.Contains(strings.ToLower(parsed.Message), "kudos") {
+		return &InsufficientKudosError{Message: parsed.Message}, true
+	}
+	return nil, false
+}
+
+// Paths are the Grid API routes, relative to the base URL (which normally
+// already includes the API version, e.g. https://api.aipowergrid.io/api/v2).
+// Empty fields fall back to the defaults. JobStatus must contain "{id}".
+type Paths struct {
+	Models    string
+	Generate  string
+	JobStatus string
+	FindUser  string
+}
+
+// DefaultPaths are the routes of the public AI Power Grid v2 API
+func DefaultPaths() Paths {
+	return Paths{
+		Models:    "/status/models",
+		Generate:  "/generate/async",
+		JobStatus: "/generate/status/{id}",
+		FindUser:  "/find_user",
+	}
+}
+
+func (p Paths) withDefaults() Paths {
+	d := DefaultPaths()
+	if p.Models == "" {
+		p.Models = d.Models
+	}
+	if p.Generate == "" {
+		p.Generate = d.Generate
+	}
+	if p.JobStatus == "" {
+		p.JobStatus = d.JobStatus
+	}
+	if p.FindUser == "" {
+		p.FindUser = d.FindUser
+	}
+	return p
+}
+
+// Timeouts bound each Grid operation when the caller's context has no deadline
+// of its own. A caller deadline always wins, so a long-poll can run past these.
+type Timeouts struct {
+	Models    time.Duration
+	Generate  time.Duration
+	JobStatus time.Duration
+	FindUser  time.Duration
+}
+
+// DefaultTimeouts fail model stats fast and give job submission the longest
+func DefaultTimeouts() Timeouts {
+	return Timeouts{
+		Models:    10 * time.Second,
+		Generate:  30 * time.Second,
+		JobStatus: 20 * time.Second,
+		FindUser:  10 * time.Second,
+	}
+}
+
 type Client struct {
 	baseURL     string
+	paths       Paths
+	timeouts    Timeouts
+	retry       RetryPolicy
 	httpClient  *http.Client
 	clientAgent string
 }
@@ -20,21 +125,82 @@ type Client struct {
 func NewClient(baseURL, clientAgent string) *Client {
 	return &Client{
 		baseURL:     baseURL,
+		paths:       DefaultPaths(),
+		timeouts:    DefaultTimeouts(),
+		retry:       DefaultRetryPolicy(),
 		clientAgent: clientAgent,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		// No client-wide timeout: every request is bounded by its context (see withTimeout)
+		httpClient: &http.Client{},
+	}
+}
+
+// SetTimeouts replaces the per-operation defaults; zero fields keep theirs
+func (c *Client) SetTimeouts(t Timeouts) {
+	d := DefaultTimeouts()
+	if t.Models <= 0 {
+		t.Models = d.Models
+	}
+	if t.Generate <= 0 {
+		t.Generate = d.Generate
+	}
+	if t.JobStatus <= 0 {
+		t.JobStatus = d.JobStatus
+	}
+	if t.FindUser <= 0 {
+		t.FindUser = d.FindUser
+	}
+	c.timeouts = t
+}
+
+// withTimeout applies an operation's default timeout unless ctx already has a deadline
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// NewClientWithPaths creates a client for a Grid deployment with non-standard
+// routing, validating that every assembled URL is well formed
+func NewClientWithPaths(baseURL, clientAgent string, paths Paths) (*Client, error) {
+	c := NewClient(baseURL, clientAgent)
+	c.paths = paths.withDefaults()
+
+	base, err := url.Parse(baseURL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("invalid Grid API base URL %q", baseURL)
 	}
+	if !strings.Contains(c.paths.JobStatus, "{id}") {
+		return nil, fmt.Errorf("job status path %q must contain {id}", c.paths.JobStatus)
+	}
+	for _, p := range []string{c.paths.Models, c.paths.Generate, c.paths.JobStatus, c.paths.FindUser} {
+		if !strings.HasPrefix(p, "/") {
+			return nil, fmt.Errorf("Grid API path %q must start with /", p)
+		}
+		if _, err := url.Parse(c.endpoint(p)); err != nil {
+			return nil, fmt.Errorf("invalid Grid API URL for path %q: %w", p, err)
+		}
+	}
+	return c, nil
+}
+
+// endpoint joins the base URL and a route
+func (c *Client) endpoint(path string) string {
+	return strings.TrimRight(c.baseURL, "/") + path
 }
 
 func (c *Client) FetchModelStats(ctx context.Context) ([]ModelStatus, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/status/models", c.baseURL), nil)
+	ctx, cancel := withTimeout(ctx, c.timeouts.Models)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint(c.paths.Models), nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Client-Agent", c.clientAgent)
+	setRequestID(req)
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req, true)
 	if err != nil {
 		return nil, err
 	}
@@ -45,14 +211,38 @@ func (c *Client) FetchModelStats(ctx context.Context) ([]ModelStatus, error) {
 		return nil, fmt.Errorf("models request failed: %s", body)
 	}
 
-	var raw []ModelStatus
-	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
-		return nil, err
+	return decodeModelStats(resp.Body)
+}
+
+// decodeModelStats parses each entry of the stats array on its own, so one
+// malformed model is logged and skipped instead of failing the whole list.
+// Unknown fields are ignored; only a body that isn't an array is an error.
+func decodeModelStats(body io.Reader) ([]ModelStatus, error) {
+	var entries []json.RawMessage
+	if err := json.NewDecoder(body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("decode model stats: %w", err)
+	}
+
+	stats := make([]ModelStatus, 0, len(entries))
+	for i, entry := range entries {
+		var stat ModelStatus
+		if err := json.Unmarshal(entry, &stat); err != nil {
+			log.Printf("Warning: skipping model stats entry %d: %v", i, err)
+			continue
+		}
+		if stat.Name == "" {
+			log.Printf("Warning: skipping model stats entry %d: no name", i)
+			continue
+		}
+		stats = append(stats, stat)
 	}
-	return raw, nil
+	return stats, nil
 }
 
 func (c *Client) CreateJob(ctx context.Context, request CreateJobPayload, apiKey, clientHeader string) (*CreateJobResponse, error) {
+	ctx, cancel := withTimeout(ctx, c.timeouts.Generate)
+	defer cancel()
+
 	payload, err := json.Marshal(request)
 	if err != nil {
 		return nil, err
@@ -63,17 +253,18 @@ func (c *Client) CreateJob(ctx context.Context, request CreateJobPayload, apiKey
 		request.Models, request.MediaType, len(request.Prompt))
 	log.Printf("🌐 Grid API full payload: %s", string(payload))
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/generate/async", c.baseURL), bytes.NewReader(payload))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint(c.paths.Generate), bytes.NewReader(payload))
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Client-Agent", clientHeader)
+	setRequestID(req)
 	if apiKey != "" {
 		req.Header.Set("apikey", apiKey)
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req, c.retry.RetryCreateJob)
 	if err != nil {
 		return nil, err
 	}
@@ -82,6 +273,9 @@ func (c *Client) CreateJob(ctx context.Context, request CreateJobPayload, apiKey
 	body, _ := io.ReadAll(resp.Body)
 	log.Printf("🌐 Grid API response: status=%d, body=%s", resp.StatusCode, string(body))
 	
+	if kudosErr, ok := parseKudosError(resp.StatusCode, body); ok {
+		return nil, kudosErr
+	}
 	if resp.StatusCode != http.StatusAccepted {
 		return nil, fmt.Errorf("create job failed (%d): %s", resp.StatusCode, body)
 	}
@@ -94,19 +288,26 @@ func (c *Client) CreateJob(ctx context.Context, request CreateJobPayload, apiKey
 }
 
 func (c *Client) JobStatus(ctx context.Context, jobID string) (*JobStatusResponse, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/generate/status/%s", c.baseURL, jobID), nil)
+	ctx, cancel := withTimeout(ctx, c.timeouts.JobStatus)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint(strings.ReplaceAll(c.paths.JobStatus, "{id}", url.PathEscape(jobID))), nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Client-Agent", c.clientAgent)
+	setRequestID(req)
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req, true)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, jobID)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("job status failed (%d): %s", resp.StatusCode, body)
 	}
@@ -117,3 +318,38 @@ func (c *Client) JobStatus(ctx context.Context, jobID string) (*JobStatusRespons
 	}
 	return &parsed, nil
 }
+
+// FindUser returns the account details for an API key
+func (c *Client) FindUser(ctx context.Context, apiKey string) (*UserDetails, error) {
+	ctx, cancel := withTimeout(ctx, c.timeouts.FindUser)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint(c.paths.FindUser), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Client-Agent", c.clientAgent)
+	setRequestID(req)
+	req.Header.Set("apikey", apiKey)
+
+	resp, err := c.do(req, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		return nil, fmt.Errorf("%w (%d): %s", ErrInvalidAPIKey, resp.StatusCode, body)
+	default:
+		return nil, fmt.Errorf("find user failed (%d): %s", resp.StatusCode, body)
+	}
+
+	var parsed UserDetails
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
@@ -0,0 +1,252 @@
+package aipg
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestJobStatusNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "test")
+	_, err := c.JobStatus(context.Background(), "missing")
+	if !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("JobStatus error = %v, want ErrJobNotFound", err)
+	}
+}
+
+func TestJobStatusServerError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "test")
+	_, err := c.JobStatus(context.Background(), "job")
+	if err == nil || errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("JobStatus error = %v, want a non-not-found error", err)
+	}
+}
+
+func TestCreateJobInsufficientKudos(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   bool
+	}{
+		{name: "upfront kudos rc", status: http.StatusForbidden, body: `{"message":"Need 12 kudos upfront","rc":"KudosUpfront"}`, want: true},
+		{name: "message mentions kudos", status: http.StatusForbidden, body: `{"message":"Not enough kudos"}`, want: true},
+		{name: "other forbidden", status: http.StatusForbidden, body: `{"message":"Banned","rc":"Banned"}`, want: false},
+		{name: "server error", status: http.StatusInternalServerError, body: `{"message":"kudos service down"}`, want: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			}))
+			defer srv.Close()
+
+			c := NewClient(srv.URL, "test")
+			_, err := c.CreateJob(context.Background(), CreateJobPayload{Prompt: "x"}, "key", "test")
+			var kudosErr *InsufficientKudosError
+			if got := errors.As(err, &kudosErr); got != tc.want {
+				t.Errorf("errors.As(InsufficientKudosError) = %v, want %v (err %v)", got, tc.want, err)
+			}
+		})
+	}
+}
+
+func TestCustomPaths(t *testing.T) {
+	var mu sync.Mutex
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/generate"):
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"id":"job-1"}`))
+		case strings.HasSuffix(r.URL.Path, "/models"):
+			w.Write([]byte(`[]`))
+		case strings.HasSuffix(r.URL.Path, "/find_user"):
+			w.Write([]byte(`{"id":1,"username":"tester#1"}`))
+		default:
+			w.Write([]byte(`{"id":"job-1"}`))
+		}
+	}))
+	defer srv.Close()
+
+	c, err := NewClientWithPaths(srv.URL+"/horde/api/v3/", "test", Paths{
+		Models:    "/models",
+		Generate:  "/jobs/generate",
+		JobStatus: "/jobs/{id}/status",
+	})
+	if err != nil {
+		t.Fatalf("NewClientWithPaths: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := c.FetchModelStats(ctx); err != nil {
+		t.Fatalf("FetchModelStats: %v", err)
+	}
+	if _, err := c.CreateJob(ctx, CreateJobPayload{Prompt: "x"}, "key", "test"); err != nil {
+		t.Fatalf("CreateJob: %v", err)
+	}
+	if _, err := c.JobStatus(ctx, "job-1"); err != nil {
+		t.Fatalf("JobStatus: %v", err)
+	}
+	if _, err := c.FindUser(ctx, "key"); err != nil {
+		t.Fatalf("FindUser: %v", err)
+	}
+
+	want := []string{
+		"GET /horde/api/v3/models",
+		"POST /horde/api/v3/jobs/generate",
+		"GET /horde/api/v3/jobs/job-1/status",
+		"GET /horde/api/v3/find_user", // unset paths keep their defaults
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(seen, "\n") != strings.Join(want, "\n") {
+		t.Errorf("requests:\n%s\nwant:\n%s", strings.Join(seen, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestNewClientWithPathsValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		base  string
+		paths Paths
+	}{
+		{name: "relative base", base: "api/v2", paths: Paths{}},
+		{name: "status without id", base: "https://grid.example/api/v2", paths: Paths{JobStatus: "/status"}},
+		{name: "path without slash", base: "https://grid.example/api/v2", paths: Paths{Models: "models"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewClientWithPaths(tc.base, "test", tc.paths); err == nil {
+				t.Error("expected validation error")
+			}
+		})
+	}
+}
+
+func TestRequestIDHeader(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen[r.URL.Path] = r.Header.Get(RequestIDHeader)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/generate/async":
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"id":"job-1"}`))
+		case "/status/models":
+			w.Write([]byte(`[]`))
+		default:
+			w.Write([]byte(`{"done":true}`))
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "test")
+	ctx := WithRequestID(context.Background(), "trace-abc")
+	if _, err := c.FetchModelStats(ctx); err != nil {
+		t.Fatalf("FetchModelStats: %v", err)
+	}
+	if _, err := c.CreateJob(ctx, CreateJobPayload{Models: []string{"m"}}, "key", "test"); err != nil {
+		t.Fatalf("CreateJob: %v", err)
+	}
+	if _, err := c.JobStatus(ctx, "job-1"); err != nil {
+		t.Fatalf("JobStatus: %v", err)
+	}
+	for _, path := range []string{"/status/models", "/generate/async", "/generate/status/job-1"} {
+		if seen[path] != "trace-abc" {
+			t.Errorf("%s %s = %q, want the context's request ID", path, RequestIDHeader, seen[path])
+		}
+	}
+
+	if _, err := c.FetchModelStats(context.Background()); err != nil {
+		t.Fatalf("FetchModelStats: %v", err)
+	}
+	if id := seen["/status/models"]; len(id) != 32 {
+		t.Errorf("request without an ID sent %q, want a generated one", id)
+	}
+}
+
+func TestCallerDeadlineOverridesOperationTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(150 * time.Millisecond):
+		case <-r.Context().Done():
+			return
+		}
+		w.Write([]byte(`{"id":"job","done":true}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "test")
+	c.SetTimeouts(Timeouts{JobStatus: 50 * time.Millisecond})
+
+	if _, err := c.JobStatus(context.Background(), "job"); err == nil {
+		t.Fatal("JobStatus without a deadline succeeded, want the 50ms operation timeout")
+	}
+
+	// A long-poll sets its own, longer deadline and must not be cut short
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := c.JobStatus(ctx, "job")
+	if err != nil {
+		t.Fatalf("JobStatus with a long deadline: %v", err)
+	}
+	if !resp.Done {
+		t.Errorf("Done = false, want true")
+	}
+}
+
+func TestFetchModelStatsSkipsBadEntries(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"name": "flux", "count": 3, "queued": "12", "renamed_field": {"x": 1}},
+			{"name": 42, "count": 1},
+			"not an object",
+			null,
+			{"count": 2},
+			{"name": "sdxl", "count": 1, "type": "image"}
+		]`))
+	}))
+	defer srv.Close()
+
+	stats, err := NewClient(srv.URL, "test").FetchModelStats(context.Background())
+	if err != nil {
+		t.Fatalf("FetchModelStats: %v", err)
+	}
+	if len(stats) != 2 || stats[0].Name != "flux" || stats[1].Name != "sdxl" {
+		t.Fatalf("stats = %+v, want just flux and sdxl", stats)
+	}
+	if stats[0].ParseCount() != 3 || stats[0].ParseQueued() != 12 {
+		t.Errorf("flux count/queued = %d/%d, want 3/12", stats[0].ParseCount(), stats[0].ParseQueued())
+	}
+
+	notArray := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message": "maintenance"}`))
+	}))
+	defer notArray.Close()
+	if _, err := NewClient(notArray.URL, "test").FetchModelStats(context.Background()); err == nil {
+		t.Error("a non-array body should still fail")
+	}
+}
@@ -0,0 +1,68 @@
+package aipg
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Number is a Grid field that may arrive as a JSON number, a numeric string or
+// null. It holds the value in plain decimal form: integers never pick up an
+// exponent (1.2e+07 becomes "12000000") and large seeds keep every digit.
+// Strings that aren't numbers are kept as sent.
+type Number string
+
+// UnmarshalJSON accepts any JSON scalar and never fails, so one odd field
+// doesn't sink the whole response
+func (n *Number) UnmarshalJSON(data []byte) error {
+	*n = normalizeNumber(data)
+	return nil
+}
+
+func (n Number) String() string { return string(n) }
+
+// Float64 returns the value, or 0 when it isn't numeric
+func (n Number) Float64() float64 {
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0
+	}
+	return f
+}
+
+// normalizeNumber decodes a raw JSON number, string, bool or null into a Number
+func normalizeNumber(raw []byte) Number {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return ""
+	}
+
+	text := string(raw)
+	if raw[0] == '"' {
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return ""
+		}
+		text = strings.TrimSpace(text)
+	} else if raw[0] == 't' || raw[0] == 'f' || raw[0] == '{' || raw[0] == '[' {
+		return ""
+	}
+	return Number(canonicalNumber(text))
+}
+
+// canonicalNumber writes a numeric string in plain decimal, leaving anything
+// else untouched
+func canonicalNumber(s string) string {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return strconv.FormatInt(i, 10)
+	}
+	if u, err := strconv.ParseUint(strings.TrimPrefix(s, "+"), 10, 64); err == nil {
+		return strconv.FormatUint(u, 10)
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return s
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
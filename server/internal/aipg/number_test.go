@@ -0,0 +1,56 @@
+package aipg
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNumberDecodesGridShapes(t *testing.T) {
+	tests := []struct {
+		raw       string
+		want      string
+		wantFloat float64
+	}{
+		{raw: `12345`, want: "12345", wantFloat: 12345},
+		{raw: `"12345"`, want: "12345", wantFloat: 12345},
+		{raw: `" 42 "`, want: "42", wantFloat: 42},
+		{raw: `1.2e+07`, want: "12000000", wantFloat: 12000000},
+		{raw: `"1.2e+07"`, want: "12000000", wantFloat: 12000000},
+		{raw: `3947195883.0`, want: "3947195883", wantFloat: 3947195883},
+		{raw: `18446744073709551615`, want: "18446744073709551615", wantFloat: 18446744073709551615},
+		{raw: `0.75`, want: "0.75", wantFloat: 0.75},
+		{raw: `"-3"`, want: "-3", wantFloat: -3},
+		{raw: `null`, want: "", wantFloat: 0},
+		{raw: `""`, want: "", wantFloat: 0},
+		{raw: `true`, want: "", wantFloat: 0},
+		{raw: `"random"`, want: "random", wantFloat: 0},
+		{raw: `"NaN"`, want: "NaN", wantFloat: 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.raw, func(t *testing.T) {
+			var gen Generation
+			if err := json.Unmarshal([]byte(`{"id":"g","seed":`+tc.raw+`}`), &gen); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if gen.Seed.String() != tc.want {
+				t.Errorf("seed = %q, want %q", gen.Seed, tc.want)
+			}
+			if got := gen.Seed.Float64(); got != tc.wantFloat {
+				t.Errorf("Float64() = %v, want %v", got, tc.wantFloat)
+			}
+		})
+	}
+}
+
+func TestModelStatusParsesMixedNumbers(t *testing.T) {
+	var stats []ModelStatus
+	body := `[{"name":"flux","performance":"1.5e3","queued":"12","jobs":3.0,"eta":null,"count":"2"}]`
+	if err := json.Unmarshal([]byte(body), &stats); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	s := stats[0]
+	if s.ParsePerformance() != 1500 || s.ParseQueued() != 12 || s.ParseJobs() != 3 || s.ParseETA() != 0 || s.ParseCount() != 2 {
+		t.Errorf("parsed = perf %v queued %d jobs %d eta %v count %d", s.ParsePerformance(), s.ParseQueued(), s.ParseJobs(), s.ParseETA(), s.ParseCount())
+	}
+}
@@ -0,0 +1,42 @@
+package aipg
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDHeader carries our request ID to the Grid so its worker logs can
+// be correlated with ours
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context whose Grid calls are sent with id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, or "" if there is none
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID returns a random 32-character hex ID
+func NewRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// setRequestID tags an outbound request with the context's request ID,
+// generating one for calls made outside a request (e.g. background pollers)
+func setRequestID(req *http.Request) {
+	id := RequestID(req.Context())
+	if id == "" {
+		id = NewRequestID()
+	}
+	req.Header.Set(RequestIDHeader, id)
+}
@@ -0,0 +1,95 @@
+package aipg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy retries Grid requests that fail with a 5xx or a network error,
+// backing off exponentially with jitter. 4xx responses are never retried.
+type RetryPolicy struct {
+	// Tries per request, the first included; 1 or less turns retries off
+	MaxAttempts int
+	// Wait before the first retry; it doubles for each retry after
+	BaseDelay time.Duration
+	// Job submission isn't idempotent: a retry after a submission the Grid
+	// took but didn't answer starts a second job. It's only retried when set.
+	RetryCreateJob bool
+}
+
+// DefaultRetryPolicy tries each read up to three times and submits jobs once
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 3, BaseDelay: 500 * time.Millisecond}
+}
+
+// SetRetryPolicy replaces the retry defaults; a zero BaseDelay keeps the default
+func (c *Client) SetRetryPolicy(p RetryPolicy) {
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = DefaultRetryPolicy().BaseDelay
+	}
+	c.retry = p
+}
+
+// do sends req, retrying under the client's policy when retry is set. The
+// final response is returned as is, 5xx included, so callers report it the
+// way they would a single failed try. req's body must be replayable, which
+// it is for requests built from a bytes.Reader or without a body.
+func (c *Client) do(req *http.Request, retry bool) (*http.Response, error) {
+	attempts := c.retry.MaxAttempts
+	if !retry || attempts < 1 {
+		attempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.httpClient.Do(req)
+		if attempt >= attempts || !retryable(req.Context(), resp, err) {
+			return resp, err
+		}
+
+		reason := fmt.Sprint(err)
+		if err == nil {
+			reason = resp.Status
+			// Drain so the connection can be reused for the next try
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		delay := backoff(c.retry.BaseDelay, attempt)
+		log.Printf("Grid %s %s failed (%s), retrying in %v (attempt %d of %d)", req.Method, req.URL.Path, reason, delay, attempt+1, attempts)
+
+		select {
+		case <-req.Context().Done():
+			return nil, fmt.Errorf("%s %s: %w after %d attempts, last: %s", req.Method, req.URL.Path, req.Context().Err(), attempt, reason)
+		case <-time.After(delay):
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// retryable reports whether a try failed in a way another might not: a
+// network error, while the caller is still waiting, or a 5xx
+func retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil
+	}
+	return resp.StatusCode >= 500
+}
+
+// backoff is the wait before retry n (from 1): base doubled n-1 times, then
+// spread over half to one and a half times that so clients don't retry in step
+func backoff(base time.Duration, n int) time.Duration {
+	d := base << (n - 1)
+	return d/2 + rand.N(d)
+}
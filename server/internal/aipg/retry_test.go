@@ -0,0 +1,133 @@
+package aipg
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyGrid fails the first failures requests with status, then answers ok
+func flakyGrid(t *testing.T, failures int32, status int, ok string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			http.Error(w, "try again", status)
+			return
+		}
+		w.Write([]byte(ok))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestRetryTransientFailures(t *testing.T) {
+	srv, calls := flakyGrid(t, 2, http.StatusServiceUnavailable, `{"id":"job","done":true}`)
+	c := NewClient(srv.URL, "test")
+	c.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+
+	resp, err := c.JobStatus(context.Background(), "job")
+	if err != nil {
+		t.Fatalf("JobStatus after two 503s: %v", err)
+	}
+	if !resp.Done || calls.Load() != 3 {
+		t.Errorf("done = %t after %d calls, want done after 3", resp.Done, calls.Load())
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	srv, calls := flakyGrid(t, 5, http.StatusBadGateway, `[]`)
+	c := NewClient(srv.URL, "test")
+	c.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+
+	if _, err := c.FetchModelStats(context.Background()); err == nil {
+		t.Fatal("FetchModelStats succeeded, want the last 502")
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("calls = %d, want 3", n)
+	}
+}
+
+func TestRetrySkipsClientErrors(t *testing.T) {
+	srv, calls := flakyGrid(t, 1, http.StatusTooManyRequests, `{"id":"job","done":true}`)
+	c := NewClient(srv.URL, "test")
+	c.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+
+	if _, err := c.JobStatus(context.Background(), "job"); err == nil {
+		t.Fatal("JobStatus succeeded, want the 429")
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("calls = %d, want 1", n)
+	}
+}
+
+func TestRetryCreateJobOnlyWhenEnabled(t *testing.T) {
+	payload := CreateJobPayload{Prompt: "a fox"}
+
+	srv, calls := flakyGrid(t, 1, http.StatusServiceUnavailable, `{"id":"job-1"}`)
+	c := NewClient(srv.URL, "test")
+	c.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	if _, err := c.CreateJob(context.Background(), payload, "key", "test"); err == nil {
+		t.Error("CreateJob succeeded, want the 503 without a retry")
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("default policy: calls = %d, want 1", n)
+	}
+
+	var bodies []string
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"id":"job-1"}`))
+	}))
+	defer srv.Close()
+	c = NewClient(srv.URL, "test")
+	c.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, RetryCreateJob: true})
+	resp, err := c.CreateJob(context.Background(), payload, "key", "test")
+	if err != nil {
+		t.Fatalf("CreateJob with retries on: %v", err)
+	}
+	if resp.ID != "job-1" || len(bodies) != 2 {
+		t.Fatalf("id = %q after %d calls, want job-1 after 2", resp.ID, len(bodies))
+	}
+	if bodies[0] == "" || bodies[1] != bodies[0] {
+		t.Errorf("retried body = %q, want the original %q", bodies[1], bodies[0])
+	}
+}
+
+func TestRetryStopsWhenCallerGivesUp(t *testing.T) {
+	srv, calls := flakyGrid(t, 5, http.StatusServiceUnavailable, `{}`)
+	c := NewClient(srv.URL, "test")
+	c.SetRetryPolicy(RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := c.JobStatus(ctx, "job"); err == nil {
+		t.Fatal("JobStatus succeeded, want the deadline")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second || calls.Load() != 1 {
+		t.Errorf("returned after %v and %d calls, want the deadline to cut the backoff short", elapsed, calls.Load())
+	}
+}
+
+func TestBackoffGrows(t *testing.T) {
+	base := 100 * time.Millisecond
+	for n := 1; n <= 4; n++ {
+		d := base << (n - 1)
+		for range 20 {
+			if got := backoff(base, n); got < d/2 || got >= d/2+d {
+				t.Fatalf("backoff(%v, %d) = %v, want within [%v, %v)", base, n, got, d/2, d/2+d)
+			}
+		}
+	}
+}
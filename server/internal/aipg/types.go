@@ -2,7 +2,6 @@ package aipg
 
 import (
 	"encoding/json"
-	"strconv"
 )
 
 type ModelStatus struct {
@@ -22,20 +21,7 @@ func (m ModelStatus) ParseETA() float64         { return parseFloat(m.Eta) }
 func (m ModelStatus) ParseCount() int           { return int(parseFloat(m.Count)) }
 
 func parseFloat(raw json.RawMessage) float64 {
-	if len(raw) == 0 {
-		return 0
-	}
-	var f float64
-	if err := json.Unmarshal(raw, &f); err == nil {
-		return f
-	}
-	var s string
-	if err := json.Unmarshal(raw, &s); err == nil {
-		if v, err := strconv.ParseFloat(s, 64); err == nil {
-			return v
-		}
-	}
-	return 0
+	return normalizeNumber(raw).Float64()
 }
 
 type CreateJobPayload struct {
@@ -76,14 +62,34 @@ type JobStatusResponse struct {
 }
 
 type Generation struct {
-	ID       string      `json:"id"`
-	Img      string      `json:"img"`
-	ImgURL   string      `json:"img_url"`
-	Image    string      `json:"image"`
-	Mime     string      `json:"mime"`
-	Seed     interface{} `json:"seed"`
-	WorkerID string      `json:"worker_id"`
-	Worker   string      `json:"worker_name"`
-	State    string      `json:"state"`
-	Video    string      `json:"video"`
+	ID       string `json:"id"`
+	Img      string `json:"img"`
+	ImgURL   string `json:"img_url"`
+	Image    string `json:"image"`
+	Mime     string `json:"mime"`
+	Seed     Number `json:"seed"`
+	WorkerID string `json:"worker_id"`
+	Worker   string `json:"worker_name"`
+	State    string `json:"state"`
+	Video    string `json:"video"`
+	Model    string `json:"model"`
+}
+
+// UserDetails is the subset of the Grid's find_user response we use
+type UserDetails struct {
+	ID       int         `json:"id"`
+	Username string      `json:"username"`
+	Kudos    float64     `json:"kudos"`
+	Records  UserRecords `json:"records"`
+}
+
+// UserRecords are an account's lifetime totals
+type UserRecords struct {
+	Usage UserUsage `json:"usage"`
+}
+
+// UserUsage is what an account has spent kudos on
+type UserUsage struct {
+	Megapixelsteps float64 `json:"megapixelsteps"`
+	Requests       int     `json:"requests"`
 }
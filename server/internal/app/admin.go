@@ -0,0 +1,214 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/aipg"
+)
+
+// requireAdmin guards operator endpoints with the GALLERY_ADMIN_TOKEN bearer token.
+// Without a configured token the admin API is unavailable.
+func (a *App) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.cfg.AdminToken == "" {
+			writeError(w, http.StatusServiceUnavailable, errors.New("admin API not available"))
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(a.cfg.AdminToken)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("admin token required"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleSchedulerStats reports the background poll scheduler's load and per-poller runs
+func (a *App) handleSchedulerStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.scheduler.Stats())
+}
+
+// GridModelStatsView is one entry of the Grid's /status/models exactly as sent,
+// alongside the values we parse out of it
+type GridModelStatsView struct {
+	aipg.ModelStatus
+	Parsed ParsedModelStats `json:"parsed"`
+}
+
+// ParsedModelStats are the numbers the model endpoints use for a Grid entry
+type ParsedModelStats struct {
+	Count       int     `json:"count"`
+	Queued      int     `json:"queued"`
+	Jobs        int     `json:"jobs"`
+	ETA         float64 `json:"eta"`
+	Performance float64 `json:"performance"`
+}
+
+// handleGridModelStats returns the Grid's model stats uncached, so operators can
+// compare the names workers report with preset IDs and the alias table
+func (a *App) handleGridModelStats(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	stats, err := a.client.FetchModelStats(ctx)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	views := make([]GridModelStatsView, 0, len(stats))
+	for _, s := range stats {
+		views = append(views, GridModelStatsView{
+			ModelStatus: s,
+			Parsed: ParsedModelStats{
+				Count:       s.ParseCount(),
+				Queued:      s.ParseQueued(),
+				Jobs:        s.ParseJobs(),
+				ETA:         s.ParseETA(),
+				Performance: s.ParsePerformance(),
+			},
+		})
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Name < views[j].Name })
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"models": views,
+		"count":  len(views),
+	})
+}
+
+// handleUnmatchedModels lists presets that don't resolve to a Grid model with
+// workers, with the nearest names the Grid reports, to show which need aliases
+func (a *App) handleUnmatchedModels(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	stats, err := a.client.FetchModelStats(ctx)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	unmatched := unmatchedModels(a.catalog.List(), stats)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"models":     unmatched,
+		"count":      len(unmatched),
+		"gridModels": len(stats),
+	})
+}
+
+// handleConfirmAlias saves an operator-confirmed Grid name for a preset, typically
+// one of the unmatched endpoint's suggestions, and starts using it immediately.
+// The alias is only saved if the Grid reports that name and the preset would
+// then resolve to it.
+func (a *App) handleConfirmAlias(w http.ResponseWriter, r *http.Request) {
+	if a.aliases == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("alias store not available"))
+		return
+	}
+	if !requireJSONBody(w, r) {
+		return
+	}
+
+	var req ConfirmAliasRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %w", err))
+		return
+	}
+	if err := req.normalize(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, ok := a.catalog.Get(req.PresetID); !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("preset %s not found", req.PresetID))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	stats, err := a.client.FetchModelStats(ctx)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	index := indexModelStats(stats)
+	if _, ok := index.byName[strings.ToLower(req.GridName)]; !ok {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("the Grid reports no model named %q", req.GridName))
+		return
+	}
+	if stat := lookupModelStatsWith(req.PresetID, index, withAlias(req.PresetID, req.GridName)); !strings.EqualFold(stat.Name, req.GridName) {
+		writeError(w, http.StatusConflict, fmt.Errorf("preset %s already resolves to %q", req.PresetID, stat.Name))
+		return
+	}
+
+	alias := ConfirmedAlias{
+		PresetID:    req.PresetID,
+		GridName:    req.GridName,
+		ConfirmedBy: req.ConfirmedBy,
+		ConfirmedAt: time.Now().UTC(),
+	}
+	if err := a.aliases.Add(alias); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to save alias: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusCreated, alias)
+}
+
+// accountTTL is how long the default key's account details are reused; a
+// balance alert doesn't need them fresher than this
+const accountTTL = time.Minute
+
+// AccountView is the default API key's Grid account, for balance monitoring
+type AccountView struct {
+	Username string         `json:"username"`
+	Kudos    float64        `json:"kudos"`
+	Usage    aipg.UserUsage `json:"usage"`
+	// Set when the Grid couldn't be reached and these are the last known details
+	Stale bool `json:"stale,omitempty"`
+}
+
+// handleAdminAccount reports the username, kudos balance and usage of the
+// account behind AIPG_API_KEY, so operators can alert before it runs dry
+func (a *App) handleAdminAccount(w http.ResponseWriter, r *http.Request) {
+	if a.cfg.DefaultAPIKey == "" {
+		writeError(w, http.StatusServiceUnavailable, errors.New("no default API key configured"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	load := func(ctx context.Context) (*aipg.UserDetails, error) {
+		return a.client.FindUser(ctx, a.cfg.DefaultAPIKey)
+	}
+	var (
+		user  *aipg.UserDetails
+		stale bool
+		err   error
+	)
+	if a.account == nil {
+		user, err = load(ctx)
+	} else {
+		user, stale, err = a.account.Get(ctx, load)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, AccountView{
+		Username: user.Username,
+		Kudos:    user.Kudos,
+		Usage:    user.Records.Usage,
+		Stale:    stale,
+	})
+}
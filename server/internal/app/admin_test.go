@@ -0,0 +1,283 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/aipg"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/cache"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/models"
+)
+
+func TestAdminEndpointsRequireToken(t *testing.T) {
+	a := newTestApp(t, "")
+	a.scheduler.Register("modelNotify", time.Minute, nil)
+
+	get := func(auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/scheduler", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		return serve(a, req)
+	}
+
+	if rec := get("Bearer anything"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("without a configured token status = %d, want 503", rec.Code)
+	}
+
+	a.cfg.AdminToken = "s3cret"
+	for _, auth := range []string{"", "s3cret", "Bearer wrong"} {
+		if rec := get(auth); rec.Code != http.StatusUnauthorized {
+			t.Errorf("Authorization %q status = %d, want 401", auth, rec.Code)
+		}
+	}
+
+	rec := get("Bearer s3cret")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var stats SchedulerStats
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(stats.Pollers) != 1 || stats.Pollers[0].Name != "modelNotify" || stats.Pollers[0].Interval != "1m0s" {
+		t.Errorf("stats = %+v, want the registered poller", stats)
+	}
+}
+
+func TestAdminGridModelStats(t *testing.T) {
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"name":"wan2_2_t2v_14b","count":"2","queued":7,"eta":"30.5","jobs":1},{"name":"flux1-dev","count":1}]`))
+	}))
+	defer grid.Close()
+
+	a := newTestApp(t, grid.URL)
+	a.cfg.AdminToken = "s3cret"
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/grid/models", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec := serve(a, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var body struct {
+		Models []struct {
+			Name   string           `json:"name"`
+			Count  json.RawMessage  `json:"count"`
+			Parsed ParsedModelStats `json:"parsed"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(body.Models) != 2 || body.Models[0].Name != "flux1-dev" || body.Models[1].Name != "wan2_2_t2v_14b" {
+		t.Fatalf("models = %+v, want both Grid entries sorted by name", body.Models)
+	}
+	wan := body.Models[1]
+	if string(wan.Count) != `"2"` {
+		t.Errorf("raw count = %s, want the Grid's \"2\"", wan.Count)
+	}
+	if wan.Parsed != (ParsedModelStats{Count: 2, Queued: 7, Jobs: 1, ETA: 30.5}) {
+		t.Errorf("parsed = %+v", wan.Parsed)
+	}
+}
+
+func TestAdminUnmatchedModels(t *testing.T) {
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"name":"flux1-dev","count":1},{"name":"ltxv","count":0},{"name":"moonbeam_xl_v3","count":2},{"name":"SDXL 1.0","count":1}]`))
+	}))
+	defer grid.Close()
+
+	a := newTestApp(t, grid.URL)
+	a.cfg.AdminToken = "s3cret"
+	a.catalog = models.NewCatalog([]models.ModelPreset{
+		{ID: "FLUX.1-dev", DisplayName: "FLUX.1 Dev"},
+		{ID: "ltxv", DisplayName: "LTX Video"},
+		{ID: "Moonbeam-XL-v2", DisplayName: "Moonbeam XL"},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/models/unmatched", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec := serve(a, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var body struct {
+		Models []UnmatchedModel `json:"models"`
+		Count  int              `json:"count"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	got := map[string]UnmatchedModel{}
+	for _, m := range body.Models {
+		got[m.PresetID] = m
+	}
+	if body.Count != 2 || len(got) != 2 {
+		t.Fatalf("unmatched = %+v, want ltxv and Moonbeam-XL-v2", body.Models)
+	}
+	if _, ok := got["FLUX.1-dev"]; ok {
+		t.Error("FLUX.1-dev matched through its alias but was reported")
+	}
+	if m := got["ltxv"]; m.MatchedName != "ltxv" || m.Workers != 0 {
+		t.Errorf("ltxv = %+v, want matched with no workers", m)
+	}
+	moon := got["Moonbeam-XL-v2"]
+	if moon.MatchedName != "" || len(moon.Suggestions) == 0 || moon.Suggestions[0].Name != "moonbeam_xl_v3" {
+		t.Errorf("Moonbeam-XL-v2 = %+v, want no match and moonbeam_xl_v3 suggested first", moon)
+	}
+}
+
+func TestAdminConfirmAliasPersists(t *testing.T) {
+	t.Cleanup(func() { activeAliases.Store(nil); confirmedGridNames.Store(nil) })
+
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"name":"ltxv","count":1},{"name":"moonbeam_xl_v3","count":2}]`))
+	}))
+	defer grid.Close()
+
+	path := filepath.Join(t.TempDir(), "model_aliases.json")
+	store, err := newAliasStore(path)
+	if err != nil {
+		t.Fatalf("newAliasStore: %v", err)
+	}
+	a := newTestApp(t, grid.URL)
+	a.cfg.AdminToken = "s3cret"
+	a.aliases = store
+	a.catalog = models.NewCatalog([]models.ModelPreset{
+		{ID: "ltxv", DisplayName: "LTX Video"},
+		{ID: "Moonbeam-XL-v2", DisplayName: "Moonbeam XL"},
+	})
+
+	confirm := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/models/aliases", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer s3cret")
+		req.Header.Set("Content-Type", "application/json")
+		return serve(a, req)
+	}
+
+	rejected := map[string]int{
+		`{"presetId":"Moonbeam-XL-v2","gridName":"moonbeam_xl_v3"}`:                     http.StatusBadRequest, // no confirmedBy
+		`{"presetId":"Nope","gridName":"moonbeam_xl_v3","confirmedBy":"ops"}`:           http.StatusNotFound,
+		`{"presetId":"Moonbeam-XL-v2","gridName":"moonbeam_xl_v9","confirmedBy":"ops"}`: http.StatusBadRequest,
+		`{"presetId":"ltxv","gridName":"moonbeam_xl_v3","confirmedBy":"ops"}`:           http.StatusConflict,
+	}
+	for body, want := range rejected {
+		if rec := confirm(body); rec.Code != want {
+			t.Errorf("%s: status = %d, want %d (%s)", body, rec.Code, want, rec.Body.String())
+		}
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("rejected aliases wrote the alias file (stat err %v)", err)
+	}
+
+	rec := confirm(`{"presetId":"Moonbeam-XL-v2","gridName":"moonbeam_xl_v3","confirmedBy":"ops@example.com"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	stats := indexModelStats([]aipg.ModelStatus{{Name: "moonbeam_xl_v3", Count: json.RawMessage("2")}})
+	if got := lookupModelStats("Moonbeam-XL-v2", stats).Name; got != "moonbeam_xl_v3" {
+		t.Errorf("lookup after confirming = %q, want moonbeam_xl_v3", got)
+	}
+
+	// A fresh store (as after a restart) picks the alias up from disk
+	activeAliases.Store(nil)
+	if _, err := newAliasStore(path); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if got := lookupModelStats("Moonbeam-XL-v2", stats).Name; got != "moonbeam_xl_v3" {
+		t.Errorf("lookup after reload = %q, want moonbeam_xl_v3", got)
+	}
+	data, _ := os.ReadFile(path)
+	var saved []ConfirmedAlias
+	if err := json.Unmarshal(data, &saved); err != nil || len(saved) != 1 {
+		t.Fatalf("alias file = %s (err %v), want one entry", data, err)
+	}
+	if saved[0].ConfirmedBy != "ops@example.com" || saved[0].ConfirmedAt.IsZero() {
+		t.Errorf("saved alias = %+v, want the confirming admin and time", saved[0])
+	}
+}
+
+func TestConfirmedAliasUsedForJobs(t *testing.T) {
+	t.Cleanup(func() { activeAliases.Store(nil); confirmedGridNames.Store(nil) })
+
+	var sentModels []string
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload aipg.CreateJobPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		sentModels = payload.Models
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"id":"job-1"}`))
+	}))
+	defer grid.Close()
+	a := newTestApp(t, grid.URL)
+	a.catalog = models.NewCatalog(testPresets)
+
+	store, err := newAliasStore(filepath.Join(t.TempDir(), "model_aliases.json"))
+	if err != nil {
+		t.Fatalf("newAliasStore: %v", err)
+	}
+	if err := store.Add(ConfirmedAlias{PresetID: "FLUX.1-dev", GridName: "flux1_dev_fp8", ConfirmedAt: time.Now()}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	body := `{"modelId":"FLUX.1-dev","prompt":"p"}`
+	if rec := serve(a, httptest.NewRequest(http.MethodPost, "/api/jobs", strings.NewReader(body))); rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(sentModels) != 1 || sentModels[0] != "flux1_dev_fp8" {
+		t.Errorf("models sent to grid = %v, want the confirmed alias flux1_dev_fp8", sentModels)
+	}
+}
+
+func TestAdminAccount(t *testing.T) {
+	calls := 0
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/find_user" || r.Header.Get("apikey") != "test-key" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		calls++
+		w.Write([]byte(`{"id":7,"username":"gallery#7","kudos":1234.5,"records":{"usage":{"megapixelsteps":88.25,"requests":42}}}`))
+	}))
+	defer grid.Close()
+
+	a := newTestApp(t, grid.URL)
+	a.cfg.AdminToken = "s3cret"
+	a.account = cache.New[*aipg.UserDetails](time.Minute)
+
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/account", nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		return serve(a, req)
+	}
+
+	for i := 0; i < 2; i++ {
+		rec := get()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+		}
+		var view AccountView
+		json.NewDecoder(rec.Body).Decode(&view)
+		want := AccountView{Username: "gallery#7", Kudos: 1234.5, Usage: aipg.UserUsage{Megapixelsteps: 88.25, Requests: 42}}
+		if view != want {
+			t.Errorf("account = %+v, want %+v", view, want)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Grid called %d times, want the second request served from cache", calls)
+	}
+
+	a.cfg.DefaultAPIKey = ""
+	if rec := get(); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("without a default key status = %d, want 503", rec.Code)
+	}
+}
@@ -6,7 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math"
+	"mime"
 	"net/http"
+	"net/url"
 	"os"
 	"sort"
 	"strconv"
@@ -17,6 +20,7 @@ import (
 	"github.com/go-chi/cors"
 
 	"github.com/aipowergrid/aipg-art-gallery/server/internal/aipg"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/cache"
 	"github.com/aipowergrid/aipg-art-gallery/server/internal/config"
 	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
 	"github.com/aipowergrid/aipg-art-gallery/server/internal/models"
@@ -30,13 +34,34 @@ type App struct {
 	cfg               config.Config
 	catalog           models.Catalog
 	client            *aipg.Client
+	modelStats        *cache.TTLCache[[]aipg.ModelStatus]
+	statusThresholds  statusThresholds
+	galleryModels     *cache.TTLCache[[]gallery.ModelCount]
+	galleryModelsNSFW *cache.TTLCache[[]gallery.ModelCount]
+	account           *cache.TTLCache[*aipg.UserDetails]
 	vaultClient       *modelvault.Client
 	recipeVaultClient *recipevault.Client
+	recipes           recipeReader
 	galleryStore      gallery.GalleryStore
 	userStore         *gallery.UserStore
+	settingsStore     gallery.SettingsStore
 	jobStore          *gallery.JobStore
+	jobRequests       gallery.JobRequestStore
+	jobResults        gallery.JobResultStore
 	favoritesStore    *gallery.FavoritesStore
+	collectionStore   gallery.CollectionStore
 	r2Client          *r2.Client
+	jobs              *jobTracker
+	notifier          *modelNotifier
+	scheduler         *pollScheduler
+	health            *healthChecks
+	modelSync         modelSyncState
+	aliases           *aliasStore
+	exploreLimiter    *rateLimiter
+	anonLimiter       *rateLimiter
+	submissions       *submitQueue
+	workers           workerFilter
+	filterRetries     filterRetries
 }
 
 func New(cfg config.Config) (*App, error) {
@@ -44,36 +69,73 @@ func New(cfg config.Config) (*App, error) {
 	if err != nil {
 		return nil, err
 	}
+	applied, rejected := catalog.ApplyOverrides(cfg.ModelOverrides)
+	for _, line := range applied {
+		log.Printf("Model preset override: %s", line)
+	}
+	for _, err := range rejected {
+		log.Printf("Warning: ignoring model preset override %v", err)
+	}
+
+	gridClient, err := aipg.NewClientWithPaths(cfg.APIBaseURL, cfg.ClientAgent, aipg.Paths{
+		Models:    cfg.APIModelsPath,
+		Generate:  cfg.APIGeneratePath,
+		JobStatus: cfg.APIStatusPath,
+		FindUser:  cfg.APIFindUserPath,
+	})
+	if err != nil {
+		return nil, err
+	}
+	gridClient.SetRetryPolicy(aipg.RetryPolicy{
+		MaxAttempts:    cfg.APIRetries,
+		BaseDelay:      cfg.APIRetryDelay,
+		RetryCreateJob: cfg.APIRetryCreateJob,
+	})
 
 	// Initialize ModelVault client for blockchain model registry
 	vaultClient, err := modelvault.NewClient(
-		cfg.ModelVaultRPCURL,
+		cfg.ModelVaultRPCURLs,
 		cfg.ModelVaultContractAddress,
 		cfg.ModelVaultEnabled,
 	)
 	if err != nil {
 		log.Printf("Warning: ModelVault client initialization failed: %v", err)
 		// Continue without blockchain - use presets only
-		vaultClient, _ = modelvault.NewClient("", "", false)
+		vaultClient, _ = modelvault.NewClient(nil, "", false)
+	} else if cfg.ModelVaultMulticall != "off" {
+		if err := vaultClient.EnableMulticall(cfg.ModelVaultMulticall); err != nil {
+			log.Printf("Warning: ModelVault multicall unavailable, loading models one call at a time: %v", err)
+		}
 	}
+	vaultClient.SetParamNames(knownParamNames(catalog.List()))
 
 	// Initialize RecipeVault client for blockchain recipe/workflow registry
 	recipeVaultClient, err := recipevault.NewClient(
-		cfg.RecipeVaultRPCURL,
+		cfg.RecipeVaultRPCURLs,
 		cfg.RecipeVaultContractAddress,
 		cfg.RecipeVaultEnabled,
 	)
 	if err != nil {
 		log.Printf("Warning: RecipeVault client initialization failed: %v", err)
 		// Continue without RecipeVault
-		recipeVaultClient, _ = recipevault.NewClient("", "", false)
+		recipeVaultClient, _ = recipevault.NewClient(nil, "", false)
+	}
+
+	// Nil unless the vault is enabled, so recipe endpoints report unavailable
+	var recipes recipeReader
+	if recipeVaultClient.IsEnabled() {
+		recipes = recipeVaultClient
 	}
 
 	// Initialize gallery store
 	var galleryStore gallery.GalleryStore
 	var userStore *gallery.UserStore
+	var settingsStore gallery.SettingsStore
 	var jobStore *gallery.JobStore
+	var jobRequests gallery.JobRequestStore
+	var jobResults gallery.JobResultStore
 	var favoritesStore *gallery.FavoritesStore
+	var collectionStore gallery.CollectionStore
 
 	if cfg.PostgresEnabled {
 		// Use PostgreSQL
@@ -83,17 +145,22 @@ func New(cfg config.Config) (*App, error) {
 			fileStore := gallery.NewStore(cfg.GalleryStorePath, 5000)
 			galleryStore = &gallery.FileStoreAdapter{Store: fileStore}
 		} else {
+			pgStore.SetQueryLimits(cfg.PostgresQueryTimeout, cfg.PostgresSlowQuery)
 			galleryStore = pgStore
 			userStore = pgStore.UserStore
+			settingsStore = pgStore.UserStore
 			jobStore = pgStore.JobStore
+			jobRequests = pgStore.JobStore
+			jobResults = pgStore.JobStore
 			favoritesStore = gallery.NewFavoritesStore(pgStore.DB())
-			log.Printf("PostgreSQL gallery store connected, %d items", pgStore.Count())
+			collectionStore = gallery.NewPostgresCollectionStore(pgStore)
+			log.Printf("PostgreSQL gallery store connected, %d items", pgStore.Count(context.Background()))
 		}
 	} else {
 		// Use file-based store
 		fileStore := gallery.NewStore(cfg.GalleryStorePath, 5000)
 		galleryStore = &gallery.FileStoreAdapter{Store: fileStore}
-		log.Printf("File-based gallery store initialized with %d items", fileStore.List("", 1000, 0, "").Total)
+		log.Printf("File-based gallery store initialized with %d items", fileStore.List(gallery.ListOptions{Limit: 1000, IncludeNSFW: true}).Total)
 	}
 
 	// Initialize R2 client for direct media access
@@ -108,6 +175,7 @@ func New(cfg config.Config) (*App, error) {
 			cfg.R2AccessKeySecret,
 			cfg.R2SharedAccessKeyID,
 			cfg.R2SharedAccessKey,
+			cfg.R2KeyPrefix,
 		)
 		if r2Err != nil {
 			log.Printf("Warning: R2 client initialization failed: %v", r2Err)
@@ -118,48 +186,130 @@ func New(cfg config.Config) (*App, error) {
 		log.Printf("R2 direct access disabled (set AWS_ACCESS_KEY_ID or SHARED_AWS_ACCESS_ID to enable)")
 	}
 
+	// Operator-confirmed aliases extend modelNameAliases without a redeploy
+	var aliases *aliasStore
+	if cfg.ModelAliasesPath != "" {
+		var aliasErr error
+		aliases, aliasErr = newAliasStore(cfg.ModelAliasesPath)
+		if aliasErr != nil {
+			log.Printf("Warning: model alias file not loaded, using built-in aliases: %v", aliasErr)
+		}
+	}
+
+	// Grid stats are shared by the model endpoints and the notify watcher
+	var modelStats *cache.TTLCache[[]aipg.ModelStatus]
+	if cfg.ModelStatsCacheTTL > 0 {
+		modelStats = cache.New[[]aipg.ModelStatus](cfg.ModelStatsCacheTTL)
+	}
+
 	return &App{
 		cfg:               cfg,
 		catalog:           catalog,
-		client:            aipg.NewClient(cfg.APIBaseURL, cfg.ClientAgent),
+		client:            gridClient,
+		modelStats:        modelStats,
+		statusThresholds:  newStatusThresholds(cfg),
+		galleryModels:     cache.New[[]gallery.ModelCount](galleryModelsTTL),
+		galleryModelsNSFW: cache.New[[]gallery.ModelCount](galleryModelsTTL),
+		account:           cache.New[*aipg.UserDetails](accountTTL),
 		vaultClient:       vaultClient,
 		recipeVaultClient: recipeVaultClient,
+		recipes:           recipes,
 		r2Client:          r2Client,
 		galleryStore:      galleryStore,
 		userStore:         userStore,
+		settingsStore:     settingsStore,
 		jobStore:          jobStore,
+		jobRequests:       jobRequests,
+		jobResults:        jobResults,
 		favoritesStore:    favoritesStore,
+		collectionStore:   collectionStore,
+		jobs:              newJobTracker(),
+		notifier:          newModelNotifier(),
+		scheduler:         newPollScheduler(cfg.PollMaxConcurrency, cfg.PollJitter, cfg.PollTimeout),
+		health:            newHealthChecks(),
+		aliases:           aliases,
+		exploreLimiter:    newRateLimiter(cfg.ExploreRateLimit, time.Minute),
+		anonLimiter:       newRateLimiter(cfg.AnonRateLimit, time.Hour),
+		submissions:       newSubmitQueue(cfg.SubmitRate, cfg.SubmitQueueSize),
+		workers:           newWorkerFilter(cfg),
 	}, nil
 }
 
+// Start launches background workers; they stop when ctx is cancelled
+func (a *App) Start(ctx context.Context) {
+	if a.cfg.ValidateAPIKey {
+		go a.checkDefaultAPIKey(ctx)
+	}
+	go a.backfillGalleryModelIDs()
+	if a.submissions != nil {
+		go a.submissions.run(ctx)
+	}
+	
+	// Everything that polls the Grid goes through the scheduler's shared concurrency cap
+	if a.cfg.ModelNotifyInterval > 0 {
+		a.scheduler.Register("modelNotify", a.cfg.ModelNotifyInterval, a.checkAwaitedModels)
+	}
+	if a.cfg.ModelSyncInterval > 0 {
+		a.scheduler.Register("modelSync", a.cfg.ModelSyncInterval, a.checkModelSync)
+	}
+	if a.cfg.RetentionMaxAge > 0 && a.cfg.RetentionInterval > 0 {
+		a.scheduler.Register("retention", a.cfg.RetentionInterval, a.pruneExpiredItems)
+	}
+	a.scheduler.Start(ctx)
+}
+
 func (a *App) Router() http.Handler {
 	r := chi.NewRouter()
+	r.Use(requestID)
+	r.Use(a.withClientIP)
+	r.Use(accessLog)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   a.allowedOrigins(),
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Content-Type", "apikey", "X-Wallet-Address"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Content-Type", "apikey", "X-Wallet-Address", walletSignatureHeader, walletTimestampHeader, aipg.RequestIDHeader},
+		ExposedHeaders:   corsExposedHeaders,
 		AllowCredentials: true,
+		MaxAge:           int(a.cfg.CORSMaxAge.Seconds()),
 	}))
 
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 	})
+	r.Get("/health/ready", a.handleReady)
 
 	r.Route("/api", func(api chi.Router) {
-		api.Get("/models", a.handleListModels)
-		api.Get("/models/{id}", a.handleGetModel)
-		api.Get("/styles", a.handleGetStyles)
+		api.Use(withCacheControl(cacheNoStore))
+
+		api.With(withCacheControl(cacheShort)).Get("/models", a.handleListModels)
+		api.With(withCacheControl(cacheShort)).Get("/models/{id}", a.handleGetModel)
+		api.Post("/models/{id}/notify", a.handleNotifyModel)
+		api.With(withCacheControl(cacheMedium)).Get("/styles", a.handleGetStyles)
+		api.Get("/recipes/creator/{address}", a.handleListCreatorRecipes)
+		api.With(withCacheControl(cacheImmutable)).Get("/recipes/{id}", a.handleGetRecipe)
+		api.With(withCacheControl(cacheImmutable)).Get("/recipes/{id}/raw", a.handleRawRecipe)
+		api.Post("/workflows/validate", a.handleValidateWorkflow)
 
 		api.Post("/jobs", a.handleCreateJob)
+		api.Get("/jobs/queued/{id}", a.handleQueuedSubmission)
+		api.Post("/jobs/status/batch", a.handleBatchJobStatus)
 		api.Get("/jobs/{id}", a.handleJobStatus)
+		api.Post("/jobs/{id}/retry", a.handleRetryJob)
 
 		// Public gallery endpoints
 		api.Get("/gallery", a.handleListGallery)
+		api.Get("/gallery/images", a.handleListGalleryFeed(gallery.TypeImage))
+		api.Get("/gallery/videos", a.handleListGalleryFeed(gallery.TypeVideo))
+		api.With(a.exploreLimiter.middleware).Get("/gallery/explore", a.handleExploreGallery)
+		api.Get("/gallery/featured", a.handleListFeaturedGallery)
 		api.Post("/gallery", a.handleAddToGallery)
 		api.Get("/gallery/wallet/{wallet}", a.handleListByWallet)
+		api.With(withCacheControl(cacheShort)).Get("/gallery/models", a.handleListGalleryModels)
+		api.Post("/gallery/compare", a.handleCompareGallery)
 		api.Get("/gallery/{id}", a.handleGetGalleryItem)
 		api.Get("/gallery/{id}/media", a.handleGetGalleryMedia)
+		api.Get("/gallery/{id}/download", a.handleDownloadGalleryMedia)
 		api.Delete("/gallery/{id}", a.handleDeleteGalleryItem)
+		api.Patch("/gallery/{id}", a.handleUpdateGalleryItem)
 		api.Post("/gallery/{id}/publish", a.handlePublishGalleryItem)
 		
 		// Favorites
@@ -167,11 +317,83 @@ func (a *App) Router() http.Handler {
 		api.Delete("/favorites/{jobId}", a.handleRemoveFavorite)
 		api.Get("/favorites/wallet/{wallet}", a.handleGetFavorites)
 		api.Get("/favorites/check/{wallet}/{jobId}", a.handleCheckFavorite)
+		
+		// Collections
+		api.Post("/collections", a.handleCreateCollection)
+		api.Get("/collections/wallet/{wallet}", a.handleListCollections)
+		api.Get("/collections/{id}/items", a.handleGetCollectionItems)
+		api.Post("/collections/{id}/items", a.handleAddCollectionItem)
+		api.Delete("/collections/{id}/items/{jobId}", a.handleRemoveCollectionItem)
+		api.Post("/collections/{id}/publish", a.handlePublishCollection)
+		api.Post("/collections/{id}/unpublish", a.handleUnpublishCollection)
+		api.Get("/collections/{slug}", a.handleGetPublicCollection)
+
+		// Per-wallet preferences
+		api.Get("/profile/{wallet}/settings", a.handleGetSettings)
+		api.Put("/profile/{wallet}/settings", a.handleUpdateSettings)
+		api.Get("/profile/{wallet}/notifications", a.handleGetModelNotifications)
+		api.Get("/profile/{wallet}/export", a.handleExportGenerations)
+
+		// Operator endpoints, guarded by GALLERY_ADMIN_TOKEN
+		api.Route("/admin", func(admin chi.Router) {
+			admin.Use(a.requireAdmin)
+			admin.Get("/scheduler", a.handleSchedulerStats)
+			admin.Get("/account", a.handleAdminAccount)
+			admin.Get("/grid/models", a.handleGridModelStats)
+			admin.Get("/models/unmatched", a.handleUnmatchedModels)
+			admin.Get("/models/{id}/debug", a.handleModelDebug)
+			admin.Post("/models/aliases", a.handleConfirmAlias)
+			admin.Post("/gallery/{id}/featured", a.handleSetFeatured(true))
+			admin.Delete("/gallery/{id}/featured", a.handleSetFeatured(false))
+		})
 	})
 
 	return r
 }
 
+// corsExposedHeaders are the custom response headers browser clients may read
+var corsExposedHeaders = []string{
+	"X-Total-Count",
+	"X-RateLimit-Limit",
+	"X-RateLimit-Remaining",
+	"X-RateLimit-Reset",
+	"Retry-After",
+	"X-Recipe-Root",
+	"X-Recipe-Compression",
+	"X-Recipe-Verified",
+	aipg.RequestIDHeader,
+}
+
+// requestID tags each request with the caller's X-Request-ID, or a fresh one,
+// echoes it back and passes it on to any Grid calls made while serving it
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(aipg.RequestIDHeader)
+		if !validRequestID(id) {
+			id = aipg.NewRequestID()
+		}
+		w.Header().Set(aipg.RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(aipg.WithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID accepts short IDs of letters, digits and - _ . : so a
+// caller's ID can't smuggle anything odd into our logs or the Grid's
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 func (a *App) allowedOrigins() []string {
 	if len(a.cfg.AllowedOrigins) == 0 {
 		return []string{"*"}
@@ -242,8 +464,14 @@ var presetToGridName = map[string]string{
 	"Movie Diffusion":      "Movie Diffusion",
 }
 
-// getGridModelName converts a preset ID to the Grid API model name
+// getGridModelName converts a preset ID to the Grid API model name. An
+// operator-confirmed alias takes precedence over the built-in mapping.
 func getGridModelName(presetID string) string {
+	if names := confirmedGridNames.Load(); names != nil {
+		if gridName, ok := (*names)[presetID]; ok {
+			return gridName
+		}
+	}
 	if gridName, ok := presetToGridName[presetID]; ok {
 		return gridName
 	}
@@ -251,11 +479,29 @@ func getGridModelName(presetID string) string {
 	return presetID
 }
 
+// fetchModelStats returns Grid model stats, through the shared cache when enabled
+func (a *App) fetchModelStats(ctx context.Context) ([]aipg.ModelStatus, error) {
+	if a.modelStats == nil {
+		return a.client.FetchModelStats(ctx)
+	}
+	stats, stale, err := a.modelStats.Get(ctx, a.client.FetchModelStats)
+	if stale {
+		log.Printf("Warning: Grid model stats unavailable, serving cached stats")
+	}
+	return stats, err
+}
+
 func (a *App) handleListModels(w http.ResponseWriter, r *http.Request) {
+	view := r.URL.Query().Get("view")
+	if view != "" && view != "full" && view != "lite" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown view %q (expected full or lite)", view))
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
 	defer cancel()
 
-	stats, err := a.client.FetchModelStats(ctx)
+	stats, err := a.fetchModelStats(ctx)
 	if err != nil {
 		writeError(w, http.StatusBadGateway, err)
 		return
@@ -268,13 +514,7 @@ func (a *App) handleListModels(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	byName := make(map[string]aipg.ModelStatus, len(stats))
-	for _, s := range stats {
-		// Index by lowercase name
-		byName[strings.ToLower(s.Name)] = s
-		// Also index by exact name for case-sensitive matches
-		byName[s.Name] = s
-	}
+	byName := indexModelStats(stats)
 
 	// Fetch on-chain models if available
 	var chainModels map[string]*modelvault.OnChainModel
@@ -328,7 +568,7 @@ func (a *App) handleListModels(w http.ResponseWriter, r *http.Request) {
 
 	presets := a.catalog.List()
 	log.Printf("RecipeVault: total presets in catalog: %d", len(presets))
-	response := make([]ModelView, 0, len(presets))
+	included := make([]models.ModelPreset, 0, len(presets))
 	
 	// If RecipeVault is enabled, filter presets to only include models found in recipes
 	// Otherwise, show all presets
@@ -365,7 +605,7 @@ func (a *App) handleListModels(w http.ResponseWriter, r *http.Request) {
 			
 			// Check aliases
 			if !found {
-				if aliases, ok := modelNameAliases[preset.ID]; ok {
+				if aliases, ok := modelAliases()[preset.ID]; ok {
 					for _, alias := range aliases {
 						if recipeVaultModelSet[strings.ToLower(alias)] || recipeVaultModelSet[alias] {
 							found = true
@@ -446,21 +686,11 @@ func (a *App) handleListModels(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 		
-		// Look up stats using preset ID and all known aliases
-		stat := lookupModelStats(preset.ID, byName)
-		
-		// Merge chain data if available
-		var chainModel *modelvault.OnChainModel
-		if chainModels != nil {
-			chainModel = chainModels[preset.ID]
-			if chainModel == nil {
-				chainModel = chainModels[strings.ToLower(preset.ID)]
-			}
-		}
-		
-		response = append(response, buildModelView(preset, stat, chainModel))
+		included = append(included, preset)
 	}
-
+	
+	response := buildModelViews(included, byName, chainModels, a.statusThresholds)
+	
 	// Sort models by display name for stable ordering
 	sort.Slice(response, func(i, j int) bool {
 		return response[i].DisplayName < response[j].DisplayName
@@ -468,6 +698,13 @@ func (a *App) handleListModels(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("RecipeVault: returning %d models in response (expected %d from RecipeVault)", len(response), len(recipeVaultModels))
 	
+	if view == "lite" {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"models": buildModelLiteViews(response),
+		})
+		return
+	}
+	
 	writeJSON(w, http.StatusOK, map[string]any{
 		"models":         response,
 		"chainSource":    a.vaultClient.IsEnabled(),
@@ -475,43 +712,91 @@ func (a *App) handleListModels(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// modelStatsIndex holds Grid stats keyed every way lookupModelStats matches names
+type modelStatsIndex struct {
+	byName       map[string]aipg.ModelStatus
+	byNormalized map[string]aipg.ModelStatus
+}
+
+// indexModelStats indexes Grid stats by exact, lowercase and normalized name for lookupModelStats
+func indexModelStats(stats []aipg.ModelStatus) modelStatsIndex {
+	byName := make(map[string]aipg.ModelStatus, len(stats)*2)
+	byNormalized := make(map[string]aipg.ModelStatus, len(stats))
+	for _, s := range stats {
+		// Index by lowercase name
+		byName[strings.ToLower(s.Name)] = s
+		// Also index by exact name for case-sensitive matches
+		byName[s.Name] = s
+		byNormalized[normalizeStatsName(s.Name)] = s
+	}
+	return modelStatsIndex{byName: byName, byNormalized: byNormalized}
+}
+
+// normalizeStatsName lowercases a name and folds hyphens and dots to underscores
+func normalizeStatsName(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(strings.ToLower(name), "-", "_"), ".", "_")
+}
+
 // lookupModelStats finds model stats using the preset ID and all known aliases
 // This handles naming variations between what workers report and our preset IDs
-func lookupModelStats(presetID string, byName map[string]aipg.ModelStatus) aipg.ModelStatus {
+func lookupModelStats(presetID string, index modelStatsIndex) aipg.ModelStatus {
+	return lookupModelStatsWith(presetID, index, modelAliases())
+}
+
+// lookupModelStatsWith is lookupModelStats against a given alias table
+func lookupModelStatsWith(presetID string, index modelStatsIndex, aliasTable map[string][]string) aipg.ModelStatus {
+	stat, _ := resolveModelStats(presetID, index, aliasTable)
+	return stat
+}
+
+// How resolveModelStats matched a preset to a Grid entry, in the order tried
+const (
+	matchExact        = "exact"
+	matchLowercase    = "lowercase"
+	matchAlias        = "alias"
+	matchReverseAlias = "reverseAlias"
+	matchNormalized   = "normalized"
+)
+
+// resolveModelStats is lookupModelStatsWith that also reports which step
+// matched; the step is empty when nothing did
+func resolveModelStats(presetID string, index modelStatsIndex, aliasTable map[string][]string) (aipg.ModelStatus, string) {
+	byName := index.byName
+	
 	// Try exact match first
 	if stat, ok := byName[presetID]; ok {
-		return stat
+		return stat, matchExact
 	}
 	
 	// Try lowercase match
 	presetLower := strings.ToLower(presetID)
 	if stat, ok := byName[presetLower]; ok {
-		return stat
+		return stat, matchLowercase
 	}
 	
 	// Try aliases for this preset ID
-	if aliases, ok := modelNameAliases[presetID]; ok {
+	if aliases, ok := aliasTable[presetID]; ok {
 		for _, alias := range aliases {
 			if stat, ok := byName[strings.ToLower(alias)]; ok {
-				return stat
+				return stat, matchAlias
 			}
 			if stat, ok := byName[alias]; ok {
-				return stat
+				return stat, matchAlias
 			}
 		}
 	}
 	
 	// Also check if any alias list contains our preset ID (reverse lookup)
-	for _, aliases := range modelNameAliases {
+	for _, aliases := range aliasTable {
 		for _, alias := range aliases {
 			if strings.EqualFold(alias, presetID) {
 				// Found preset ID as an alias, try the canonical name and other aliases
 				for _, a := range aliases {
 					if stat, ok := byName[strings.ToLower(a)]; ok {
-						return stat
+						return stat, matchReverseAlias
 					}
 					if stat, ok := byName[a]; ok {
-						return stat
+						return stat, matchReverseAlias
 					}
 				}
 			}
@@ -519,16 +804,51 @@ func lookupModelStats(presetID string, byName map[string]aipg.ModelStatus) aipg.
 	}
 	
 	// Try normalized matching (replace hyphens/underscores/dots)
-	normalized := strings.ReplaceAll(strings.ReplaceAll(presetLower, "-", "_"), ".", "_")
-	for name, stat := range byName {
-		nameNorm := strings.ReplaceAll(strings.ReplaceAll(strings.ToLower(name), "-", "_"), ".", "_")
-		if nameNorm == normalized {
-			return stat
-		}
+	if stat, ok := index.byNormalized[normalizeStatsName(presetID)]; ok {
+		return stat, matchNormalized
 	}
 	
 	// Return empty stats if not found
-	return aipg.ModelStatus{}
+	return aipg.ModelStatus{}, ""
+}
+
+// galleryModelNames expands a ?model= filter into every name an item of that
+// model may have been saved under: the preset ID, its display name and aliases
+func (a *App) galleryModelNames(model string) []string {
+	seen := map[string]bool{}
+	var names []string
+	add := func(name string) {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if key != "" && !seen[key] {
+			seen[key] = true
+			names = append(names, key)
+		}
+	}
+	
+	add(model)
+	for _, preset := range a.catalog.List() {
+		if strings.EqualFold(preset.ID, model) || strings.EqualFold(preset.DisplayName, model) {
+			add(preset.ID)
+			add(preset.DisplayName)
+		}
+	}
+	for id, aliases := range modelAliases() {
+		group := append([]string{id}, aliases...)
+		for _, name := range group {
+			if seen[strings.ToLower(name)] {
+				for _, alias := range group {
+					add(alias)
+				}
+				break
+			}
+		}
+	}
+	for _, preset := range a.catalog.List() {
+		if seen[strings.ToLower(preset.ID)] {
+			add(preset.DisplayName)
+		}
+	}
+	return names
 }
 
 // handleGetStyles returns the curated styles/models configuration
@@ -557,18 +877,14 @@ func (a *App) handleGetModel(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
 	defer cancel()
 
-	stats, err := a.client.FetchModelStats(ctx)
+	stats, err := a.fetchModelStats(ctx)
 	if err != nil {
 		writeError(w, http.StatusBadGateway, err)
 		return
 	}
 
 	// Build name lookup map
-	byName := make(map[string]aipg.ModelStatus, len(stats))
-	for _, s := range stats {
-		byName[strings.ToLower(s.Name)] = s
-		byName[s.Name] = s
-	}
+	byName := indexModelStats(stats)
 
 	// Use the same lookup logic as handleListModels
 	match := lookupModelStats(preset.ID, byName)
@@ -579,59 +895,96 @@ func (a *App) handleGetModel(w http.ResponseWriter, r *http.Request) {
 		chainModel, _ = a.vaultClient.FindModel(ctx, preset.ID)
 	}
 
-	writeJSON(w, http.StatusOK, buildModelView(preset, match, chainModel))
+	writeJSON(w, http.StatusOK, buildModelView(preset, match, chainModel, a.statusThresholds))
 }
 
 func (a *App) handleCreateJob(w http.ResponseWriter, r *http.Request) {
+	if !requireJSONBody(w, r) {
+		return
+	}
+	// ?track=true also returns the job's first status, saving the client an immediate poll
+	track := false
+	if raw := r.URL.Query().Get("track"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.New("track must be true or false"))
+			return
+		}
+		track = parsed
+	}
+
 	var req CreateJobRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, createJobMaxBytes)).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("request body is over %d MB", createJobMaxBytes>>20))
+			return
+		}
 		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %w", err))
 		return
 	}
 
-	if err := req.Validate(); err != nil {
-		writeError(w, http.StatusBadRequest, err)
+	// Without a key of its own or a default, the job goes out on the Grid's anonymous key
+	apiKey, anonymous := a.resolveAPIKey(req.APIKey)
+	sub, err := a.prepareJob(r.Context(), req, apiKey, anonymous)
+	if err != nil {
+		writeValidationError(w, err)
 		return
 	}
 
-	preset, ok := a.catalog.Get(req.ModelID)
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	// Bursts wait in the submit queue; a job whose turn hasn't come yet is
+	// answered with its queue position instead of a job ID
+	jobID, queued, ok := a.submitJob(ctx, w, r, sub)
 	if !ok {
-		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown model: %s", req.ModelID))
 		return
 	}
 
-	payload := buildCreateJobPayload(req, preset)
-	
-	log.Printf("📤 Creating job: modelId=%s, preset.ID=%s, preset.Type=%s, gridName=%s, payload.Models=%v, mediaType=%s", 
-		req.ModelID, preset.ID, preset.Type, getGridModelName(preset.ID), payload.Models, payload.MediaType)
-	
-	// Debug: log the full params for troubleshooting
-	if paramsJSON, err := json.Marshal(payload.Params); err == nil {
-		log.Printf("📤 Job params: %s", string(paramsJSON))
+	body := map[string]any{
+		"status": "queued",
+		"models": sub.payload.Models,
 	}
-
-	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
-	defer cancel()
-
-	apiKey := req.APIKey
-	if apiKey == "" {
-		apiKey = a.cfg.DefaultAPIKey
+	if sub.fallback != "" {
+		body["fallbackModel"] = sub.fallback
+	}
+	if anonymous {
+		body["anonymous"] = true
 	}
-	if apiKey == "" {
-		writeError(w, http.StatusBadRequest, errors.New("apiKey is required"))
+	if queued != nil {
+		body["status"] = queued.Status
+		body["queueId"] = queued.QueueID
+		body["queuePosition"] = queued.QueuePosition
+		writeJSON(w, http.StatusAccepted, body)
 		return
 	}
+	body["jobId"] = jobID
+	if track {
+		view := a.initialJobView(ctx, jobID)
+		body["status"] = view.Status
+		body["job"] = view
+	}
+	writeJSON(w, http.StatusAccepted, body)
+}
 
-	resp, err := a.client.CreateJob(ctx, payload, apiKey, a.cfg.ClientAgent)
-	if err != nil {
-		writeError(w, http.StatusBadGateway, err)
-		return
+// writeInsufficientKudos responds 402 with the Grid's message and, when the
+// Grid will tell us, the key's current kudos balance
+func (a *App) writeInsufficientKudos(w http.ResponseWriter, r *http.Request, apiKey string, kudosErr *aipg.InsufficientKudosError) {
+	body := map[string]any{
+		"error":  "Not enough kudos to run this job: " + kudosErr.Message,
+		"status": http.StatusPaymentRequired,
 	}
 
-	writeJSON(w, http.StatusAccepted, map[string]any{
-		"jobId":  resp.ID,
-		"status": "queued",
-	})
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	if user, err := a.client.FindUser(ctx, apiKey); err == nil {
+		body["kudos"] = user.Kudos
+	} else {
+		log.Printf("Warning: could not fetch kudos balance: %v", err)
+	}
+
+	writeJSON(w, http.StatusPaymentRequired, body)
 }
 
 func (a *App) handleJobStatus(w http.ResponseWriter, r *http.Request) {
@@ -641,16 +994,52 @@ func (a *App) handleJobStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	waitSeconds, err := queryInt(r, "wait", 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	wait := time.Duration(waitSeconds) * time.Second
+	if wait > maxJobStatusWait {
+		wait = maxJobStatusWait
+	}
+
+	// A long-poll gets its wait on top of the usual status budget
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second+wait)
 	defer cancel()
 
-	status, err := a.client.JobStatus(ctx, jobID)
+	status, err := a.waitForJob(ctx, jobID, wait)
+	view, code, err := a.viewForStatus(ctx, jobID, status, err)
 	if err != nil {
-		writeError(w, http.StatusBadGateway, err)
+		writeError(w, code, err)
 		return
 	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+// viewForStatus turns a Grid status lookup into the view GET /api/jobs/{id}
+// serves, or the HTTP status and error the lookup fails with
+func (a *App) viewForStatus(ctx context.Context, jobID string, status *aipg.JobStatusResponse, err error) (JobView, int, error) {
+	if err != nil {
+		if errors.Is(err, aipg.ErrJobNotFound) {
+			// The Grid can briefly 404 right after creation - report it as queued
+			if a.jobs.InGrace(jobID) {
+				return pendingJobView(jobID), http.StatusOK, nil
+			}
+			// The Grid forgets jobs after a while; serve the result we kept
+			if view, ok := a.storedJobResult(jobID); ok {
+				return a.presentJobView(ctx, view), http.StatusOK, nil
+			}
+			return JobView{}, http.StatusNotFound, fmt.Errorf("job %s not found or expired", jobID)
+		}
+		return JobView{}, http.StatusBadGateway, err
+	}
 
-	writeJSON(w, http.StatusOK, buildJobView(status))
+	view := a.buildJobView(status)
+	if view.Status == "completed" {
+		a.saveJobResult(jobID, view)
+	}
+	return a.presentJobView(ctx, view), http.StatusOK, nil
 }
 
 type ModelView struct {
@@ -662,15 +1051,46 @@ type ModelView struct {
 	Capabilities         []string             `json:"capabilities"`
 	Samplers             []string             `json:"samplers"`
 	Schedulers           []string             `json:"schedulers"`
+	// Samplers/Schedulers with labels and default/recommended flags, merged with the chain's allowed lists
+	SamplerOptions       []ParamOption        `json:"samplerOptions"`
+	SchedulerOptions     []ParamOption        `json:"schedulerOptions"`
 	Status               string               `json:"status"`
 	OnlineWorkers        int                  `json:"onlineWorkers"`
 	QueueLength          int                  `json:"queueLength"`
 	EstimatedWaitSeconds float64              `json:"estimatedWaitSeconds"`
 	Defaults             models.ModelDefaults `json:"defaults"`
 	Limits               models.ModelLimits   `json:"limits"`
+	// Resolution choices that fit Limits, for UIs that offer a dropdown instead of free-form sizes
+	DimensionPresets     []models.DimensionPreset `json:"dimensionPresets,omitempty"`
 	// Chain-derived fields
 	OnChain     bool                      `json:"onChain"`
 	Constraints *ChainConstraintsView     `json:"constraints,omitempty"`
+	// True when a chain constraint narrowed Limits below the preset's ranges
+	LimitsConstrainedByChain bool `json:"limitsConstrainedByChain"`
+}
+
+// ModelLiteView is the trimmed model shape returned by /api/models?view=lite
+// for clients (e.g. mobile pickers) that only need identity and availability
+type ModelLiteView struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+	Type        string `json:"type"`
+	Status      string `json:"status"`
+	QueueLength int    `json:"queueLength"`
+}
+
+func buildModelLiteViews(views []ModelView) []ModelLiteView {
+	out := make([]ModelLiteView, 0, len(views))
+	for _, v := range views {
+		out = append(out, ModelLiteView{
+			ID:          v.ID,
+			DisplayName: v.DisplayName,
+			Type:        v.Type,
+			Status:      v.Status,
+			QueueLength: v.QueueLength,
+		})
+	}
+	return out
 }
 
 // ChainConstraintsView represents blockchain-derived generation constraints
@@ -680,14 +1100,13 @@ type ChainConstraintsView struct {
 	CfgMin   float64 `json:"cfgMin,omitempty"`
 	CfgMax   float64 `json:"cfgMax,omitempty"`
 	ClipSkip int     `json:"clipSkip,omitempty"`
+	// Allowed samplers/schedulers whose on-chain hash matched no known name,
+	// as hex; informational only, never offered as choices
+	UnknownSamplers   []string `json:"unknownSamplers,omitempty"`
+	UnknownSchedulers []string `json:"unknownSchedulers,omitempty"`
 }
 
-func buildModelView(preset models.ModelPreset, stat aipg.ModelStatus, chainModel *modelvault.OnChainModel) ModelView {
-	status := "offline"
-	if stat.ParseCount() > 0 {
-		status = "online"
-	}
-	
+func buildModelView(preset models.ModelPreset, stat aipg.ModelStatus, chainModel *modelvault.OnChainModel, thresholds statusThresholds) ModelView {
 	view := ModelView{
 		ID:                   preset.ID,
 		DisplayName:          preset.DisplayName,
@@ -697,15 +1116,24 @@ func buildModelView(preset models.ModelPreset, stat aipg.ModelStatus, chainModel
 		Capabilities:         preset.Capabilities,
 		Samplers:             preset.Samplers,
 		Schedulers:           preset.Schedulers,
-		Status:               status,
+		Status:               modelStatus(stat, thresholds),
 		OnlineWorkers:        stat.ParseCount(),
 		QueueLength:          stat.ParseQueued(),
 		EstimatedWaitSeconds: stat.ParseETA(),
 		Defaults:             preset.Defaults,
 		Limits:               preset.Limits,
+		DimensionPresets:     preset.Limits.DimensionPresets(),
 		OnChain:              chainModel != nil,
 	}
 	
+	var chainSamplers, chainSchedulers, unknownSamplers, unknownSchedulers []string
+	if chainModel != nil && chainModel.Constraints != nil {
+		chainSamplers, unknownSamplers = splitUnresolved(chainModel.Constraints.AllowedSamplers)
+		chainSchedulers, unknownSchedulers = splitUnresolved(chainModel.Constraints.AllowedSchedulers)
+	}
+	view.SamplerOptions = buildParamOptions(preset.Samplers, chainSamplers, preset.Defaults.Sampler, recommendedSamplers)
+	view.SchedulerOptions = buildParamOptions(preset.Schedulers, chainSchedulers, preset.Defaults.Scheduler, recommendedSchedulers)
+	
 	// Merge chain model data if available
 	if chainModel != nil {
 		// Override description if chain has a better one
@@ -721,25 +1149,12 @@ func buildModelView(preset models.ModelPreset, stat aipg.ModelStatus, chainModel
 				CfgMin:   chainModel.Constraints.CfgMin,
 				CfgMax:   chainModel.Constraints.CfgMax,
 				ClipSkip: int(chainModel.Constraints.ClipSkip),
+				UnknownSamplers:   unknownSamplers,
+				UnknownSchedulers: unknownSchedulers,
 			}
 			
 			// Update limits from chain constraints if they're more restrictive
-			if view.Limits.Steps != nil && chainModel.Constraints.StepsMax > 0 {
-				if int(chainModel.Constraints.StepsMax) < view.Limits.Steps.Max {
-					view.Limits.Steps.Max = int(chainModel.Constraints.StepsMax)
-				}
-				if int(chainModel.Constraints.StepsMin) > view.Limits.Steps.Min {
-					view.Limits.Steps.Min = int(chainModel.Constraints.StepsMin)
-				}
-			}
-			if view.Limits.CfgScale != nil && chainModel.Constraints.CfgMax > 0 {
-				if chainModel.Constraints.CfgMax < view.Limits.CfgScale.Max {
-					view.Limits.CfgScale.Max = chainModel.Constraints.CfgMax
-				}
-				if chainModel.Constraints.CfgMin > view.Limits.CfgScale.Min {
-					view.Limits.CfgScale.Min = chainModel.Constraints.CfgMin
-				}
-			}
+			view.Limits, view.LimitsConstrainedByChain = chainLimits(view.Limits, chainModel.Constraints)
 		}
 	}
 	
@@ -759,6 +1174,144 @@ type CreateJobRequest struct {
 	SourceMask       string           `json:"sourceMask"`
 	SourceProcessing string           `json:"sourceProcessing"`
 	MediaType        string           `json:"mediaType"` // "image" or "video"
+	// TrustedWorkers limits the job to trusted workers; nil means true.
+	// Set false to allow any worker, which is often faster.
+	TrustedWorkers   *bool            `json:"trustedWorkers,omitempty"`
+	// Shared offers the output to the Grid's shared dataset. It is independent of
+	// Public (gallery visibility); nil keeps the old behaviour of following Public.
+	Shared           *bool            `json:"shared,omitempty"`
+	// Extra passes advanced Grid fields we don't model through to the payload's
+	// extra map; only keys in allowedExtraKeys are accepted
+	Extra             map[string]any `json:"extra,omitempty"`
+	Loras             []LoraRef      `json:"loras,omitempty"`
+	TextualInversions []TIRef        `json:"textualInversions,omitempty"`
+}
+
+// LoraRef attaches a LoRA to a job. Name is the Grid's LoRA name or CivitAI
+// ID; the weights default to 1 when omitted.
+type LoraRef struct {
+	Name      string   `json:"name"`
+	Model     *float64 `json:"model,omitempty"`
+	Clip      *float64 `json:"clip,omitempty"`
+	IsVersion bool     `json:"isVersion,omitempty"` // Name is a CivitAI version ID
+}
+
+// LoRA limits accepted by the Grid
+const (
+	maxLoras      = 5
+	maxLoraWeight = 5.0
+)
+
+// validate checks the name and that both weights are within the Grid's bounds
+func (l LoraRef) validate() error {
+	name := strings.TrimSpace(l.Name)
+	if name == "" {
+		return errors.New("lora name is required")
+	}
+	if len(name) > 255 {
+		return fmt.Errorf("lora name %.20q... is too long", name)
+	}
+	for _, w := range []struct {
+		label  string
+		weight *float64
+	}{{"model", l.Model}, {"clip", l.Clip}} {
+		if w.weight != nil && (math.IsNaN(*w.weight) || math.Abs(*w.weight) > maxLoraWeight) {
+			return fmt.Errorf("lora %s %s weight must be between -%g and %g", name, w.label, maxLoraWeight, maxLoraWeight)
+		}
+	}
+	return nil
+}
+
+// TIRef applies a textual inversion (embedding) to a job. InjectInto is
+// "prompt", "negative", or empty when the prompt already references the
+// embedding itself; Strength defaults to 1 and only applies when injecting.
+type TIRef struct {
+	Name       string   `json:"name"`
+	InjectInto string   `json:"injectInto,omitempty"`
+	Strength   *float64 `json:"strength,omitempty"`
+}
+
+// Textual inversion limits accepted by the Grid
+const (
+	maxTIs        = 20
+	maxTIStrength = 5.0
+)
+
+// tiInjectTargets maps InjectInto values to the Grid's inject_ti values
+var tiInjectTargets = map[string]string{
+	"":         "",
+	"prompt":   "prompt",
+	"negative": "negprompt",
+}
+
+func (t TIRef) validate() error {
+	name := strings.TrimSpace(t.Name)
+	if name == "" {
+		return errors.New("textual inversion name is required")
+	}
+	if len(name) > 255 {
+		return fmt.Errorf("textual inversion name %.20q... is too long", name)
+	}
+	if _, ok := tiInjectTargets[t.InjectInto]; !ok {
+		return fmt.Errorf("textual inversion %s injectInto must be prompt or negative, got %q", name, t.InjectInto)
+	}
+	if t.Strength != nil && (math.IsNaN(*t.Strength) || math.Abs(*t.Strength) > maxTIStrength) {
+		return fmt.Errorf("textual inversion %s strength must be between -%g and %g", name, maxTIStrength, maxTIStrength)
+	}
+	return nil
+}
+
+// gridTIs converts textual inversion refs to the Grid's params.tis entries
+func gridTIs(refs []TIRef) []map[string]any {
+	tis := make([]map[string]any, len(refs))
+	for i, ref := range refs {
+		ti := map[string]any{"name": strings.TrimSpace(ref.Name)}
+		if target := tiInjectTargets[ref.InjectInto]; target != "" {
+			strength := 1.0
+			if ref.Strength != nil {
+				strength = *ref.Strength
+			}
+			ti["inject_ti"] = target
+			ti["strength"] = strength
+		}
+		tis[i] = ti
+	}
+	return tis
+}
+
+// gridLoras converts LoRA refs to the Grid's params.loras entries
+func gridLoras(refs []LoraRef) []map[string]any {
+	weight := func(w *float64) float64 {
+		if w == nil {
+			return 1
+		}
+		return *w
+	}
+	loras := make([]map[string]any, len(refs))
+	for i, ref := range refs {
+		loras[i] = map[string]any{
+			"name":       strings.TrimSpace(ref.Name),
+			"model":      weight(ref.Model),
+			"clip":       weight(ref.Clip),
+			"is_version": ref.IsVersion,
+		}
+	}
+	return loras
+}
+
+// allowedExtraKeys are the Grid fields a job may set through CreateJobRequest.Extra
+// LoRAs and textual inversions aren't here: they have their own validated
+// CreateJobRequest fields and go into params.
+var allowedExtraKeys = map[string]bool{
+	"special":            true,
+	"extra_texts":        true,
+	"transparent":        true,
+	"workflow":           true,
+	"facefixer_strength": true,
+	"post_processing":    true,
+	"control_type":       true,
+	"image_is_control":   true,
+	"return_control_map": true,
 }
 
 type GenerationParams struct {
@@ -774,77 +1327,150 @@ type GenerationParams struct {
 	FPS       int     `json:"fps"`
 	Tiling    bool    `json:"tiling"`
 	HiresFix  bool    `json:"hiresFix"`
+	// Images to generate in one job; 0 means one
+	Count int `json:"count"`
 }
 
 func (r CreateJobRequest) Validate() error {
+	var errs ValidationErrors
+	r.validate(&errs)
+	return errs.Err()
+}
+
+// validate records every problem with the request in errs
+func (r CreateJobRequest) validate(errs *ValidationErrors) {
 	if strings.TrimSpace(r.Prompt) == "" {
-		return errors.New("prompt is required")
+		errs.Add("prompt", "prompt is required")
 	}
 	if strings.TrimSpace(r.ModelID) == "" {
-		return errors.New("modelId is required")
+		errs.Add("modelId", "modelId is required")
+	}
+	var disallowed []string
+	for key := range r.Extra {
+		if !allowedExtraKeys[key] {
+			disallowed = append(disallowed, key)
+		}
+	}
+	if len(disallowed) > 0 {
+		sort.Strings(disallowed)
+		errs.Addf("extra", "extra fields not allowed: %s", strings.Join(disallowed, ", "))
+	}
+	if len(r.Loras) > maxLoras {
+		errs.Addf("loras", "at most %d loras can be attached, got %d", maxLoras, len(r.Loras))
+	} else {
+		for i, lora := range r.Loras {
+			if err := lora.validate(); err != nil {
+				errs.Add(fmt.Sprintf("loras[%d]", i), err.Error())
+			}
+		}
+	}
+	if r.Params.Count < 0 {
+		errs.Addf("params.count", "count must be positive, got %d", r.Params.Count)
+	}
+	if len(r.TextualInversions) > maxTIs {
+		errs.Addf("textualInversions", "at most %d textual inversions can be applied, got %d", maxTIs, len(r.TextualInversions))
+	} else {
+		for i, ti := range r.TextualInversions {
+			if err := ti.validate(); err != nil {
+				errs.Add(fmt.Sprintf("textualInversions[%d]", i), err.Error())
+			}
+		}
 	}
-	return nil
 }
 
-// mapSamplerName converts ComfyUI sampler names to Grid API format
-// The Grid API expects specific sampler names with k_ prefix
-func mapSamplerName(sampler string) string {
-	samplerMap := map[string]string{
-		// Direct mappings
-		"uni_pc":           "dpmsolver",
-		"unipc":            "dpmsolver",
-		"uni_pc_bh2":       "dpmsolver",
-		"dpm_2":            "k_dpm_2",
-		"dpm_2_ancestral":  "k_dpm_2_a",
-		"euler":            "k_euler",
-		"euler_ancestral":  "k_euler_a",
-		"heun":             "k_heun",
-		"lms":              "k_lms",
-		"dpm_fast":         "k_dpm_fast",
-		"dpm_adaptive":     "k_dpm_adaptive",
-		"dpmpp_2s_ancestral": "k_dpmpp_2s_a",
-		"dpmpp_2m":         "k_dpmpp_2m",
-		"dpmpp_sde":        "k_dpmpp_sde",
-		"ddim":             "DDIM",
-		// Already in correct format - pass through
-		"k_euler":          "k_euler",
-		"k_euler_a":        "k_euler_a",
-		"k_dpm_2":          "k_dpm_2",
-		"k_dpm_2_a":        "k_dpm_2_a",
-		"k_heun":           "k_heun",
-		"k_lms":            "k_lms",
-		"k_dpm_fast":       "k_dpm_fast",
-		"k_dpm_adaptive":   "k_dpm_adaptive",
-		"k_dpmpp_2s_a":     "k_dpmpp_2s_a",
-		"k_dpmpp_2m":       "k_dpmpp_2m",
-		"k_dpmpp_sde":      "k_dpmpp_sde",
-		"DDIM":             "DDIM",
-		"dpmsolver":        "dpmsolver",
-		"lcm":              "lcm",
+// Fallbacks for samplers and schedulers the Grid won't recognise, by model
+// type. Images default to k_euler with karras; video workflows (WAN, LTX) are
+// built around UniPC, which the Grid calls dpmsolver, with the simple schedule.
+var samplingFallbacks = map[string]struct{ Sampler, Scheduler string }{
+	"image": {Sampler: "k_euler", Scheduler: "karras"},
+	"video": {Sampler: "dpmsolver", Scheduler: "simple"},
+}
+
+// samplingFallback picks the fallback for a preset by its type, or by the
+// model family its ID names when the type is missing
+func samplingFallback(preset models.ModelPreset) (sampler, scheduler string) {
+	kind := preset.Type
+	if kind == "" {
+		switch prompts.DetectCategory(preset.ID) {
+		case prompts.CategoryWANVideo, prompts.CategoryLTXVideo:
+			kind = "video"
+		default:
+			kind = "image"
+		}
 	}
+	fallback, ok := samplingFallbacks[kind]
+	if !ok {
+		fallback = samplingFallbacks["image"]
+	}
+	return fallback.Sampler, fallback.Scheduler
+}
 
+// pickSampler maps the requested sampler, falling back to the preset's own
+// default and then to the default for its model type when either is unknown
+func pickSampler(requested string, preset models.ModelPreset) string {
+	typeDefault, _ := samplingFallback(preset)
+	return mapSamplerName(requested, mapSamplerName(preset.Defaults.Sampler, typeDefault))
+}
+
+// samplerAliases maps ComfyUI sampler names, and Grid names that pass
+// through unchanged, to the names the Grid API expects
+var samplerAliases = map[string]string{
+	// Direct mappings
+	"uni_pc":           "dpmsolver",
+	"unipc":            "dpmsolver",
+	"uni_pc_bh2":       "dpmsolver",
+	"dpm_2":            "k_dpm_2",
+	"dpm_2_ancestral":  "k_dpm_2_a",
+	"euler":            "k_euler",
+	"euler_ancestral":  "k_euler_a",
+	"heun":             "k_heun",
+	"lms":              "k_lms",
+	"dpm_fast":         "k_dpm_fast",
+	"dpm_adaptive":     "k_dpm_adaptive",
+	"dpmpp_2s_ancestral": "k_dpmpp_2s_a",
+	"dpmpp_2m":         "k_dpmpp_2m",
+	"dpmpp_sde":        "k_dpmpp_sde",
+	"ddim":             "DDIM",
+	// Already in correct format - pass through
+	"k_euler":          "k_euler",
+	"k_euler_a":        "k_euler_a",
+	"k_dpm_2":          "k_dpm_2",
+	"k_dpm_2_a":        "k_dpm_2_a",
+	"k_heun":           "k_heun",
+	"k_lms":            "k_lms",
+	"k_dpm_fast":       "k_dpm_fast",
+	"k_dpm_adaptive":   "k_dpm_adaptive",
+	"k_dpmpp_2s_a":     "k_dpmpp_2s_a",
+	"k_dpmpp_2m":       "k_dpmpp_2m",
+	"k_dpmpp_sde":      "k_dpmpp_sde",
+	"DDIM":             "DDIM",
+	"dpmsolver":        "dpmsolver",
+	"lcm":              "lcm",
+}
+
+// mapSamplerName converts ComfyUI sampler names to Grid API format
+// The Grid API expects specific sampler names with k_ prefix
+func mapSamplerName(sampler, fallback string) string {
 	// Case-insensitive lookup
 	lowerSampler := strings.ToLower(sampler)
-	if mapped, ok := samplerMap[lowerSampler]; ok {
+	if mapped, ok := samplerAliases[lowerSampler]; ok {
 		return mapped
 	}
-	if mapped, ok := samplerMap[sampler]; ok {
+	if mapped, ok := samplerAliases[sampler]; ok {
 		return mapped
 	}
 
-	// Default to k_euler if unknown
-	return "k_euler"
+	return fallback
 }
 
 func buildCreateJobPayload(req CreateJobRequest, preset models.ModelPreset) aipg.CreateJobPayload {
 	// Process prompts: enhance positive, provide default negative
-	enhancedPrompt, finalNegative := prompts.ProcessPrompts(req.Prompt, req.NegativePrompt, preset.ID)
+	enhancedPrompt, finalNegative := prompts.ProcessPrompts(req.Prompt, req.NegativePrompt, preset.ID, preset.Limits.PromptTokens)
 	
 	log.Printf("Prompt processing: original=%d chars, enhanced=%d chars, negative=%d chars",
 		len(req.Prompt), len(enhancedPrompt), len(finalNegative))
 	
-	rawSampler := pickString(req.Params.Sampler, preset.Defaults.Sampler)
-	mappedSampler := mapSamplerName(rawSampler)
+	mappedSampler := pickSampler(req.Params.Sampler, preset)
 	
 	// Get final values - validate user input against model limits
 	// User values are used if provided and within range, otherwise clamped to valid range
@@ -853,7 +1479,8 @@ func buildCreateJobPayload(req CreateJobRequest, preset models.ModelPreset) aipg
 	steps := pickIntInRange(req.Params.Steps, preset.Defaults.Steps, preset.Limits.Steps)
 	cfgScale := pickFloatInRange(req.Params.CfgScale, preset.Defaults.CfgScale, preset.Limits.CfgScale)
 	denoise := pickFloat(req.Params.Denoise, preset.Defaults.Denoise) // No limits for denoise
-	scheduler := pickString(req.Params.Scheduler, preset.Defaults.Scheduler)
+	_, typeScheduler := samplingFallback(preset)
+	scheduler := pickString(req.Params.Scheduler, pickString(preset.Defaults.Scheduler, typeScheduler))
 	
 	// Video parameters - validate against limits
 	videoLength := pickIntInRange(req.Params.Length, preset.Defaults.Length, preset.Limits.Length)
@@ -888,6 +1515,9 @@ func buildCreateJobPayload(req CreateJobRequest, preset models.ModelPreset) aipg
 	if req.Params.Seed != "" {
 		params["seed"] = req.Params.Seed
 	}
+	if req.Params.Count > 1 {
+		params["n"] = req.Params.Count
+	}
 	
 	// Video-specific parameters - comfy_bridge expects these at top level
 	if videoLength > 0 {
@@ -897,6 +1527,12 @@ func buildCreateJobPayload(req CreateJobRequest, preset models.ModelPreset) aipg
 	if fps > 0 {
 		params["fps"] = fps
 	}
+	if len(req.Loras) > 0 {
+		params["loras"] = gridLoras(req.Loras)
+	}
+	if len(req.TextualInversions) > 0 {
+		params["tis"] = gridTIs(req.TextualInversions)
+	}
 
 	// Convert preset ID to Grid API model name
 	gridModelName := getGridModelName(preset.ID)
@@ -925,15 +1561,24 @@ func buildCreateJobPayload(req CreateJobRequest, preset models.ModelPreset) aipg
 		mediaType = preset.Type
 	}
 	
+	trustedWorkers := true
+	if req.TrustedWorkers != nil {
+		trustedWorkers = *req.TrustedWorkers
+	}
+	shared := req.Public
+	if req.Shared != nil {
+		shared = *req.Shared
+	}
+	
 	payload := aipg.CreateJobPayload{
 		Prompt:           enhancedPrompt,
 		NegativePrompt:   finalNegative,
 		Models:           []string{gridModelName},
 		NSFW:             req.NSFW,
 		CensorNSFW:       !req.NSFW,
-		TrustedWorkers:   true,
+		TrustedWorkers:   trustedWorkers,
 		R2:               true,
-		Shared:           req.Public,
+		Shared:           shared,
 		Params:           params,
 		WalletAddress:    req.WalletAddress,
 		SourceProcessing: sourceProcessing,
@@ -946,6 +1591,12 @@ func buildCreateJobPayload(req CreateJobRequest, preset models.ModelPreset) aipg
 	if req.SourceMask != "" {
 		payload.SourceMask = req.SourceMask
 	}
+	if len(req.Extra) > 0 {
+		payload.Extra = make(map[string]any, len(req.Extra))
+		for key, value := range req.Extra {
+			payload.Extra[key] = value
+		}
+	}
 	
 	// Log the full payload for video debugging
 	if preset.Type == "video" {
@@ -957,6 +1608,43 @@ func buildCreateJobPayload(req CreateJobRequest, preset models.ModelPreset) aipg
 	return payload
 }
 
+// checkFrameCeiling caps a video job's frame count server-wide. It runs on
+// the payload, whose length is already clamped to the preset's own limit, so
+// whichever of the two is lower is the one that applies.
+func (a *App) checkFrameCeiling(preset models.ModelPreset, payload aipg.CreateJobPayload) error {
+	if preset.Type != "video" || a.cfg.MaxVideoFrames <= 0 {
+		return nil
+	}
+	if length, _ := payload.Params["length"].(int); length > a.cfg.MaxVideoFrames {
+		return fmt.Errorf("%d frames is above the server limit of %d per video", length, a.cfg.MaxVideoFrames)
+	}
+	return nil
+}
+
+// checkPixelCeiling enforces the server-wide size limits after preset limits
+// are applied, as a safety valve against a preset that allows too much
+func (a *App) checkPixelCeiling(preset models.ModelPreset, payload aipg.CreateJobPayload) error {
+	width, _ := payload.Params["width"].(int)
+	height, _ := payload.Params["height"].(int)
+	pixels := width * height
+
+	if preset.Type == "video" {
+		length, _ := payload.Params["length"].(int)
+		if length > 0 {
+			pixels *= length
+		}
+		if a.cfg.MaxVideoPixels > 0 && pixels > a.cfg.MaxVideoPixels {
+			return fmt.Errorf("%dx%d at %d frames is %d pixels, above the server limit of %d", width, height, length, pixels, a.cfg.MaxVideoPixels)
+		}
+		return nil
+	}
+
+	if a.cfg.MaxImagePixels > 0 && pixels > a.cfg.MaxImagePixels {
+		return fmt.Errorf("%dx%d is %d pixels, above the server limit of %d", width, height, pixels, a.cfg.MaxImagePixels)
+	}
+	return nil
+}
+
 type JobView struct {
 	JobID         string           `json:"jobId"`
 	Status        string           `json:"status"`
@@ -967,8 +1655,26 @@ type JobView struct {
 	Finished      int              `json:"finished"`
 	Waiting       int              `json:"waiting"`
 	Generations   []GenerationView `json:"generations"`
+	FaultReason   *FaultReason     `json:"faultReason,omitempty"`
+	// Generations dropped by WORKER_FILTER_MODE=hide, and the job resubmitted
+	// in their place when all of them were
+	HiddenGenerations int    `json:"hiddenGenerations,omitempty"`
+	RetriedAs         string `json:"retriedAs,omitempty"`
+	// Why a finished job has nothing to show; set with jobCompletedEmpty
+	Message string `json:"message,omitempty"`
 }
 
+// jobCompletedEmpty is the status of a job the Grid finished without any
+// output, e.g. every image censored or a worker that returned nothing, so
+// clients can say so instead of waiting for images that won't come
+const jobCompletedEmpty = "completed_empty"
+
+// Messages for jobCompletedEmpty, by cause
+const (
+	emptyJobMessage    = "The job finished without producing any output. Every result may have been censored, or the worker returned nothing."
+	filteredJobMessage = "The job finished, but every result came from a worker this gallery filters out."
+)
+
 type GenerationView struct {
 	ID         string `json:"id"`
 	Seed       string `json:"seed"`
@@ -978,12 +1684,18 @@ type GenerationView struct {
 	Base64     string `json:"base64,omitempty"`
 	WorkerID   string `json:"workerId,omitempty"`
 	WorkerName string `json:"workerName,omitempty"`
+	// Grid model that produced it, which differs from the request when a fallback ran it
+	Model string `json:"model,omitempty"`
+	// "blocked" or "untrusted" when the worker filter caught it
+	WorkerVerdict string `json:"workerVerdict,omitempty"`
 }
 
-func buildJobView(resp *aipg.JobStatusResponse) JobView {
+func (a *App) buildJobView(resp *aipg.JobStatusResponse) JobView {
 	status := "queued"
 	if resp.Faulted {
 		status = "faulted"
+	} else if resp.Done && len(resp.Generations) == 0 {
+		status = jobCompletedEmpty
 	} else if resp.Done {
 		status = "completed"
 	} else if resp.Processing > 0 {
@@ -994,39 +1706,42 @@ func buildJobView(resp *aipg.JobStatusResponse) JobView {
 	for _, gen := range resp.Generations {
 		view := GenerationView{
 			ID:         gen.ID,
-			Seed:       fmt.Sprintf("%v", gen.Seed),
+			Seed:       gen.Seed.String(),
 			MimeType:   gen.Mime,
 			WorkerID:   gen.WorkerID,
 			WorkerName: gen.Worker,
+			Model:      gen.Model,
 		}
-		switch {
-		case gen.Video != "":
-			view.Kind = "video"
-			view.URL = r2.ConvertToCDNURL(gen.Video)
-		case strings.Contains(strings.ToLower(gen.Mime), "video"):
-			view.Kind = "video"
+		// Classify first so a video never reaches the base64 inlining below
+		view.Kind = generationKind(gen)
+		if view.Kind == "video" {
+			if !strings.Contains(strings.ToLower(view.MimeType), "video") {
+				view.MimeType = "video/mp4"
+			}
 			rawURL := firstNonEmpty(gen.Video, gen.ImgURL, gen.Img)
-			if rawURL != "" {
-				view.URL = r2.ConvertToCDNURL(rawURL)
+			if rawURL != "" && !strings.HasPrefix(rawURL, "data:") {
+				view.URL = a.r2Client.ConvertToCDNURL(rawURL)
+			} else if gen.ID != "" {
+				// Videos live on the CDN under the generation ID (stored with a .webp key)
+				view.URL = a.mediaFallbackURL(gen.ID, gen.Mime)
 			}
-		default:
-			view.Kind = "image"
+		} else {
 			rawURL := firstNonEmpty(gen.ImgURL, gen.Img)
-			view.Base64 = normalizeBase64(gen.Image)
+			view.Base64 = normalizeBase64(gen.Image, gen.Mime)
 			if view.Base64 == "" && strings.HasPrefix(rawURL, "data:image") {
 				view.Base64 = rawURL
 				view.URL = ""
 			} else if rawURL != "" {
-				view.URL = r2.ConvertToCDNURL(rawURL)
+				view.URL = a.r2Client.ConvertToCDNURL(rawURL)
 			} else if gen.ID != "" && view.Base64 == "" {
 				// Fallback: construct R2 URL from generation ID when Grid API returns empty URL
-				view.URL = fmt.Sprintf("https://images.aipg.art/%s.webp", gen.ID)
+				view.URL = a.mediaFallbackURL(gen.ID, gen.Mime)
 			}
 		}
 		views = append(views, view)
 	}
 
-	return JobView{
+	view := JobView{
 		JobID:         resp.ID,
 		Status:        status,
 		Faulted:       resp.Faulted,
@@ -1036,7 +1751,12 @@ func buildJobView(resp *aipg.JobStatusResponse) JobView {
 		Finished:      resp.Finished,
 		Waiting:       resp.Waiting,
 		Generations:   views,
+		FaultReason:   buildFaultReason(resp),
 	}
+	if status == jobCompletedEmpty {
+		view.Message = emptyJobMessage
+	}
+	return view
 }
 
 func writeJSON(w http.ResponseWriter, status int, payload any) {
@@ -1052,46 +1772,149 @@ func writeError(w http.ResponseWriter, status int, err error) {
 	})
 }
 
+// galleryIncludeNSFW works out whether a gallery listing shows NSFW items:
+// an explicit includeNsfw param wins, then the requesting wallet's saved
+// preference, and otherwise they're shown as they always have been
+func (a *App) galleryIncludeNSFW(r *http.Request) (bool, error) {
+	if raw := r.URL.Query().Get("includeNsfw"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return false, errors.New("includeNsfw must be true or false")
+		}
+		return parsed, nil
+	}
+	if wallet := walletFromRequest(r); wallet != "" && a.settingsStore != nil {
+		settings, err := a.settingsStore.GetSettings(wallet)
+		if err != nil {
+			log.Printf("Warning: failed to load settings for %s: %v", wallet, err)
+		} else if settings.ShowNSFW != nil {
+			return *settings.ShowNSFW, nil
+		}
+	}
+	return true, nil
+}
+
+// walletFromRequest returns the normalized wallet address the client connected with
+func walletFromRequest(r *http.Request) string {
+	return strings.ToLower(strings.TrimSpace(r.Header.Get("X-Wallet-Address")))
+}
+
+// requireJSONBody rejects a body declared as anything other than JSON with 415,
+// so a form-encoded or text post gets a clear error instead of a decode failure.
+// A missing Content-Type is let through for lenient clients.
+func requireJSONBody(w http.ResponseWriter, r *http.Request) bool {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil && mediaType == "application/json" {
+		return true
+	}
+	writeError(w, http.StatusUnsupportedMediaType, fmt.Errorf("request body must be application/json, got %q", contentType))
+	return false
+}
+
+// queryInt parses an optional non-negative integer query parameter, returning
+// fallback only when the parameter is absent
+func queryInt(r *http.Request, name string, fallback int) (int, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer, got %q", name, raw)
+	}
+	return n, nil
+}
+
+// queryLimit parses the limit parameter: absent means fallback, larger than max
+// (when max > 0) is capped, and zero, negative or non-numeric values are rejected
+func queryLimit(r *http.Request, fallback, max int) (int, error) {
+	limit, err := queryInt(r, "limit", fallback)
+	if err != nil || limit == 0 {
+		return 0, fmt.Errorf("limit must be a positive integer, got %q", r.URL.Query().Get("limit"))
+	}
+	if max > 0 && limit > max {
+		limit = max
+	}
+	return limit, nil
+}
+
 // Gallery handlers
 
 func (a *App) handleListGallery(w http.ResponseWriter, r *http.Request) {
-	typeFilter := r.URL.Query().Get("type")
+	a.listGallery(w, r, gallery.ListOptions{Type: r.URL.Query().Get("type"), Seed: r.URL.Query().Get("seed")})
+}
+
+// listGallery serves one page of the public gallery. base sets the type,
+// shuffle seed and featured filter; paging, search, model and NSFW options
+// come from the request.
+func (a *App) listGallery(w http.ResponseWriter, r *http.Request, base gallery.ListOptions) {
 	searchQuery := r.URL.Query().Get("q")
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
+	searchFields, err := gallery.ParseSearchFields(r.URL.Query().Get("searchFields"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
 	
-	limit := 25 // Default page size
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
+	pageSize := galleryPageSize(base.Type)
+	limit, err := queryLimit(r, pageSize.fallback, pageSize.max)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
 	}
 	
-	offset := 0
-	if offsetStr != "" {
-		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
-			offset = o
-		}
+	includeNSFW, err := a.galleryIncludeNSFW(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
 	}
 	
-	result := a.galleryStore.List(typeFilter, limit, offset, searchQuery)
+	var models []string
+	if model := strings.TrimSpace(r.URL.Query().Get("model")); model != "" {
+		models = a.galleryModelNames(model)
+	}
 	
+	opts := base
+	opts.Limit = limit
+	opts.Offset = offset
+	opts.Search = searchQuery
+	opts.SearchFields = searchFields
+	opts.IncludeNSFW = includeNSFW
+	opts.Models = models
+	if streamer, ok := a.galleryStore.(gallery.ListStreamer); ok {
+		streamGalleryList(r.Context(), w, streamer, opts)
+		return
+	}
+	
+	result := a.galleryStore.List(r.Context(), opts)
+	
+	w.Header().Set("X-Total-Count", strconv.Itoa(result.Total))
 	writeJSON(w, http.StatusOK, result)
 }
 
 type JobParamsRequest struct {
-	Width      *int     `json:"width,omitempty"`
-	Height     *int     `json:"height,omitempty"`
-	Steps      *int     `json:"steps,omitempty"`
-	CfgScale   *float64 `json:"cfgScale,omitempty"`
-	Sampler    *string  `json:"sampler,omitempty"`
-	Scheduler  *string  `json:"scheduler,omitempty"`
-	Seed       *string  `json:"seed,omitempty"`
-	Denoise    *float64 `json:"denoise,omitempty"`
-	Length     *int     `json:"length,omitempty"`
-	Fps        *int     `json:"fps,omitempty"`
-	Tiling     *bool    `json:"tiling,omitempty"`
-	HiresFix   *bool    `json:"hiresFix,omitempty"`
+	Width          *int     `json:"width,omitempty"`
+	Height         *int     `json:"height,omitempty"`
+	Steps          *int     `json:"steps,omitempty"`
+	CfgScale       *float64 `json:"cfgScale,omitempty"`
+	Sampler        *string  `json:"sampler,omitempty"`
+	Scheduler      *string  `json:"scheduler,omitempty"`
+	Seed           *string  `json:"seed,omitempty"`
+	Denoise        *float64 `json:"denoise,omitempty"`
+	Length         *int     `json:"length,omitempty"`
+	Fps            *int     `json:"fps,omitempty"`
+	Tiling         *bool    `json:"tiling,omitempty"`
+	HiresFix       *bool    `json:"hiresFix,omitempty"`
+	ClipSkip       *int     `json:"clipSkip,omitempty"`
+	PostProcessing []string `json:"postProcessing,omitempty"`
 }
 
 type AddToGalleryRequest struct {
@@ -1109,6 +1932,9 @@ type AddToGalleryRequest struct {
 }
 
 func (a *App) handleAddToGallery(w http.ResponseWriter, r *http.Request) {
+	if !requireJSONBody(w, r) {
+		return
+	}
 	var req AddToGalleryRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, err)
@@ -1119,23 +1945,30 @@ func (a *App) handleAddToGallery(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, errors.New("jobId and prompt are required"))
 		return
 	}
+	// Saving under a wallet puts the item in that wallet's profile, so the wallet has to sign
+	if req.WalletAddress != "" && verifiedWalletFromRequest(r) != strings.ToLower(strings.TrimSpace(req.WalletAddress)) {
+		writeError(w, http.StatusUnauthorized, errors.New("signed wallet request required - sign in with your wallet to save"))
+		return
+	}
 	
 	// Convert request params to gallery params
 	var galleryParams *gallery.JobParams
 	if req.Params != nil {
 		galleryParams = &gallery.JobParams{
-			Width:     req.Params.Width,
-			Height:    req.Params.Height,
-			Steps:     req.Params.Steps,
-			CfgScale:  req.Params.CfgScale,
-			Sampler:   req.Params.Sampler,
-			Scheduler: req.Params.Scheduler,
-			Seed:      req.Params.Seed,
-			Denoise:   req.Params.Denoise,
-			Length:    req.Params.Length,
-			Fps:       req.Params.Fps,
-			Tiling:    req.Params.Tiling,
-			HiresFix:  req.Params.HiresFix,
+			Width:          req.Params.Width,
+			Height:         req.Params.Height,
+			Steps:          req.Params.Steps,
+			CfgScale:       req.Params.CfgScale,
+			Sampler:        req.Params.Sampler,
+			Scheduler:      req.Params.Scheduler,
+			Seed:           req.Params.Seed,
+			Denoise:        req.Params.Denoise,
+			Length:         req.Params.Length,
+			Fps:            req.Params.Fps,
+			Tiling:         req.Params.Tiling,
+			HiresFix:       req.Params.HiresFix,
+			ClipSkip:       req.Params.ClipSkip,
+			PostProcessing: req.Params.PostProcessing,
 		}
 	}
 	
@@ -1150,10 +1983,18 @@ func (a *App) handleAddToGallery(w http.ResponseWriter, r *http.Request) {
 		IsPublic:       req.IsPublic,
 		WalletAddress:  req.WalletAddress,
 		Params:         galleryParams,
+		GridParams:     a.recordedGridParams(req.JobID),
 		MediaURLs:      req.MediaURLs,
 	}
 	
-	a.galleryStore.Add(item)
+	if err := a.galleryStore.Add(r.Context(), item); err != nil {
+		if errors.Is(err, gallery.ErrNotOwner) {
+			writeError(w, http.StatusForbidden, errors.New("you can only update your own gallery items"))
+			return
+		}
+		writeError(w, http.StatusInternalServerError, errors.New("failed to save to gallery"))
+		return
+	}
 	
 	log.Printf("Gallery: added job %s (model=%s, type=%s, wallet=%s, public=%v)", req.JobID, req.ModelName, req.Type, req.WalletAddress, req.IsPublic)
 	
@@ -1164,26 +2005,41 @@ func (a *App) handleAddToGallery(w http.ResponseWriter, r *http.Request) {
 }
 
 func (a *App) handleListByWallet(w http.ResponseWriter, r *http.Request) {
-	wallet := chi.URLParam(r, "wallet")
+	// A blank wallet would match every anonymous item
+	wallet := strings.TrimSpace(chi.URLParam(r, "wallet"))
 	if wallet == "" {
-		writeError(w, http.StatusBadRequest, errors.New("wallet address is required"))
+		writeError(w, http.StatusBadRequest, gallery.ErrWalletRequired)
+		return
+	}
+	
+	limit, err := queryLimit(r, 100, 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
 		return
 	}
 	
-	limitStr := r.URL.Query().Get("limit")
-	limit := 100
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-			limit = l
+	// Keyset pagination: pass the previous response's nextCursor as beforeCursor
+	var before *gallery.WalletCursor
+	if token := r.URL.Query().Get("beforeCursor"); token != "" {
+		cursor, err := gallery.ParseWalletCursor(token)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.New("invalid beforeCursor"))
+			return
 		}
+		before = cursor
 	}
 	
-	items := a.galleryStore.ListByWallet(wallet, limit)
+	page, err := a.galleryStore.ListByWalletPage(r.Context(), wallet, limit, before)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
 	
 	writeJSON(w, http.StatusOK, map[string]any{
-		"items":  items,
-		"count":  len(items),
-		"wallet": wallet,
+		"items":      page.Items,
+		"count":      len(page.Items),
+		"wallet":     wallet,
+		"nextCursor": page.NextCursor,
 	})
 }
 
@@ -1195,7 +2051,7 @@ func (a *App) handleGetGalleryItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	item := a.galleryStore.Get(jobID)
+	item := a.galleryStore.Get(r.Context(), jobID)
 	if item == nil {
 		writeError(w, http.StatusNotFound, errors.New("gallery item not found"))
 		return
@@ -1212,7 +2068,7 @@ func (a *App) handleGetGalleryMedia(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	item := a.galleryStore.Get(jobID)
+	item := a.galleryStore.Get(r.Context(), jobID)
 	if item == nil {
 		writeError(w, http.StatusNotFound, errors.New("gallery item not found"))
 		return
@@ -1233,7 +2089,7 @@ func (a *App) handleGetGalleryMedia(w http.ResponseWriter, r *http.Request) {
 			if gen.ID != "" {
 				genIDs = append(genIDs, gen.ID)
 				// Build CDN URL using generation ID
-				cdnURL := "https://images.aipg.art/" + gen.ID + ".webp"
+				cdnURL := a.mediaFallbackURL(gen.ID, gen.Mime)
 				urls = append(urls, cdnURL)
 			}
 		}
@@ -1243,7 +2099,7 @@ func (a *App) handleGetGalleryMedia(w http.ResponseWriter, r *http.Request) {
 		if len(urls) > 0 {
 			writeJSON(w, http.StatusOK, map[string]any{
 				"jobId":    jobID,
-				"mediaUrls": urls,
+				"mediaUrls": a.mediaURLs(urls),
 				"type":     item.Type,
 				"source":   "grid-api",
 			})
@@ -1255,7 +2111,7 @@ func (a *App) handleGetGalleryMedia(w http.ResponseWriter, r *http.Request) {
 	if a.r2Client != nil && len(item.GenerationIDs) > 0 {
 		urls := make([]string, 0, len(item.GenerationIDs))
 		for _, genID := range item.GenerationIDs {
-			url, err := a.r2Client.GenerateMediaURL(ctx, genID, item.Type)
+			url, err := a.r2Client.GenerateMediaURL(ctx, genID, generationMime(*item, genID))
 			if err != nil {
 				log.Printf("Warning: failed to generate R2 URL for %s: %v", genID, err)
 				continue
@@ -1266,7 +2122,7 @@ func (a *App) handleGetGalleryMedia(w http.ResponseWriter, r *http.Request) {
 		if len(urls) > 0 {
 			writeJSON(w, http.StatusOK, map[string]any{
 				"jobId":    jobID,
-				"mediaUrls": urls,
+				"mediaUrls": a.mediaURLs(urls),
 				"type":     item.Type,
 				"source":   "r2",
 			})
@@ -1285,7 +2141,7 @@ func (a *App) handleGetGalleryMedia(w http.ResponseWriter, r *http.Request) {
 					cachedURLs = append(cachedURLs, cachedURL)
 				} else {
 					// Otherwise convert to CDN format
-					cdnURL := r2.ConvertToCDNURL(cachedURL)
+					cdnURL := a.r2Client.ConvertToCDNURL(cachedURL)
 					if cdnURL != "" {
 						cachedURLs = append(cachedURLs, cdnURL)
 					}
@@ -1294,7 +2150,7 @@ func (a *App) handleGetGalleryMedia(w http.ResponseWriter, r *http.Request) {
 		}
 		writeJSON(w, http.StatusOK, map[string]any{
 			"jobId":    jobID,
-			"mediaUrls": cachedURLs,
+			"mediaUrls": a.mediaURLs(cachedURLs),
 			"type":     item.Type,
 			"source":   "cache",
 			"error":    "Job may have expired from Grid API",
@@ -1304,10 +2160,10 @@ func (a *App) handleGetGalleryMedia(w http.ResponseWriter, r *http.Request) {
 	
 	// Absolute fallback - return CDN URL using job ID
 	// This may work for older uploads that used job ID as filename
-	fallbackURL := "https://images.aipg.art/" + jobID + ".webp"
+	fallbackURL := a.r2Client.CDNURL(jobID + ".webp")
 	writeJSON(w, http.StatusOK, map[string]any{
 		"jobId":    jobID,
-		"mediaUrls": []string{fallbackURL},
+		"mediaUrls": a.mediaURLs([]string{fallbackURL}),
 		"type":     item.Type,
 		"source":   "fallback",
 	})
@@ -1321,15 +2177,15 @@ func (a *App) handleDeleteGalleryItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	// Get wallet address from header
-	requestWallet := strings.ToLower(strings.TrimSpace(r.Header.Get("X-Wallet-Address")))
+	// Get the signing wallet from the request headers
+	requestWallet := verifiedWalletFromRequest(r)
 	if requestWallet == "" {
-		writeError(w, http.StatusUnauthorized, errors.New("wallet address required - connect your wallet to delete"))
+		writeError(w, http.StatusUnauthorized, errors.New("signed wallet request required - sign in with your wallet to delete"))
 		return
 	}
 	
 	// Get the item first to check ownership
-	item := a.galleryStore.Get(jobID)
+	item := a.galleryStore.Get(r.Context(), jobID)
 	if item == nil {
 		writeError(w, http.StatusNotFound, errors.New("gallery item not found"))
 		return
@@ -1346,7 +2202,7 @@ func (a *App) handleDeleteGalleryItem(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	// Remove from gallery store
-	err := a.galleryStore.Delete(jobID)
+	err := a.galleryStore.Delete(r.Context(), jobID)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, errors.New("failed to remove from gallery"))
 		return
@@ -1370,15 +2226,15 @@ func (a *App) handlePublishGalleryItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	// Get wallet address from header - required for publishing
-	requestWallet := strings.ToLower(strings.TrimSpace(r.Header.Get("X-Wallet-Address")))
+	// Get the signing wallet from the request headers - required for publishing
+	requestWallet := verifiedWalletFromRequest(r)
 	if requestWallet == "" {
-		writeError(w, http.StatusUnauthorized, errors.New("wallet address required - connect your wallet to publish"))
+		writeError(w, http.StatusUnauthorized, errors.New("signed wallet request required - sign in with your wallet to publish"))
 		return
 	}
 	
 	// Get the item first to check ownership
-	item := a.galleryStore.Get(jobID)
+	item := a.galleryStore.Get(r.Context(), jobID)
 	if item == nil {
 		writeError(w, http.StatusNotFound, errors.New("gallery item not found"))
 		return
@@ -1392,7 +2248,7 @@ func (a *App) handlePublishGalleryItem(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	// Update to public
-	err := a.galleryStore.SetPublic(jobID, true)
+	err := a.galleryStore.SetPublic(r.Context(), jobID, true)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, errors.New("failed to publish image"))
 		return
@@ -1408,13 +2264,85 @@ func (a *App) handlePublishGalleryItem(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// UpdateGalleryItemRequest lists the fields an owner may correct after publishing.
+// Media, params and seed are deliberately absent so they can't be rewritten.
+type UpdateGalleryItemRequest struct {
+	Prompt *string `json:"prompt,omitempty"`
+	IsNSFW *bool   `json:"isNsfw,omitempty"`
+}
+
+// handleUpdateGalleryItem lets the owner fix an item's displayed prompt or NSFW flag
+func (a *App) handleUpdateGalleryItem(w http.ResponseWriter, r *http.Request) {
+	jobID := chi.URLParam(r, "id")
+	if jobID == "" {
+		writeError(w, http.StatusBadRequest, errors.New("job ID is required"))
+		return
+	}
+	
+	requestWallet := verifiedWalletFromRequest(r)
+	if requestWallet == "" {
+		writeError(w, http.StatusUnauthorized, errors.New("signed wallet request required - sign in with your wallet to edit"))
+		return
+	}
+	
+	var req UpdateGalleryItemRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields() // reject attempts to edit immutable fields
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid payload (only prompt and isNsfw can be edited): %w", err))
+		return
+	}
+	if req.Prompt == nil && req.IsNSFW == nil {
+		writeError(w, http.StatusBadRequest, errors.New("nothing to update - provide prompt and/or isNsfw"))
+		return
+	}
+	if req.Prompt != nil {
+		trimmed := strings.TrimSpace(*req.Prompt)
+		if trimmed == "" {
+			writeError(w, http.StatusBadRequest, errors.New("prompt cannot be empty"))
+			return
+		}
+		req.Prompt = &trimmed
+	}
+	
+	item := a.galleryStore.Get(r.Context(), jobID)
+	if item == nil {
+		writeError(w, http.StatusNotFound, errors.New("gallery item not found"))
+		return
+	}
+	
+	// Unlike delete, legacy items without a wallet can't be edited by anyone
+	if strings.ToLower(strings.TrimSpace(item.WalletAddress)) != requestWallet {
+		writeError(w, http.StatusForbidden, errors.New("you can only edit your own gallery items"))
+		return
+	}
+	
+	err := a.galleryStore.Update(r.Context(), jobID, gallery.ItemUpdate{Prompt: req.Prompt, IsNSFW: req.IsNSFW})
+	if errors.Is(err, gallery.ErrItemNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, errors.New("failed to update gallery item"))
+		return
+	}
+	
+	log.Printf("Gallery: edited job %s by wallet %s", jobID, requestWallet)
+	
+	writeJSON(w, http.StatusOK, a.galleryStore.Get(r.Context(), jobID))
+}
+
 // Favorites handlers
 func (a *App) handleAddFavorite(w http.ResponseWriter, r *http.Request) {
 	jobID := chi.URLParam(r, "jobId")
-	wallet := strings.ToLower(strings.TrimSpace(r.Header.Get("X-Wallet-Address")))
+	wallet := verifiedWalletFromRequest(r)
 	
-	if jobID == "" || wallet == "" {
-		writeError(w, http.StatusBadRequest, errors.New("jobId and wallet address required"))
+	if jobID == "" {
+		writeError(w, http.StatusBadRequest, errors.New("jobId is required"))
+		return
+	}
+	if wallet == "" {
+		writeError(w, http.StatusUnauthorized, errors.New("signed wallet request required - sign in with your wallet to manage favorites"))
 		return
 	}
 	
@@ -1437,10 +2365,14 @@ func (a *App) handleAddFavorite(w http.ResponseWriter, r *http.Request) {
 
 func (a *App) handleRemoveFavorite(w http.ResponseWriter, r *http.Request) {
 	jobID := chi.URLParam(r, "jobId")
-	wallet := strings.ToLower(strings.TrimSpace(r.Header.Get("X-Wallet-Address")))
+	wallet := verifiedWalletFromRequest(r)
 	
-	if jobID == "" || wallet == "" {
-		writeError(w, http.StatusBadRequest, errors.New("jobId and wallet address required"))
+	if jobID == "" {
+		writeError(w, http.StatusBadRequest, errors.New("jobId is required"))
+		return
+	}
+	if wallet == "" {
+		writeError(w, http.StatusUnauthorized, errors.New("signed wallet request required - sign in with your wallet to manage favorites"))
 		return
 	}
 	
@@ -1473,12 +2405,10 @@ func (a *App) handleGetFavorites(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	limitStr := r.URL.Query().Get("limit")
-	limit := 100
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-			limit = l
-		}
+	limit, err := queryLimit(r, 100, 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
 	}
 	
 	items := a.favoritesStore.GetFavoritedItems(wallet, limit)
@@ -1593,7 +2523,35 @@ func firstNonEmpty(values ...string) string {
 	return ""
 }
 
-func normalizeBase64(raw string) string {
+// videoExtensions mark a media URL as video regardless of the reported MIME type
+var videoExtensions = []string{".mp4", ".webm", ".mov", ".mkv", ".avi"}
+
+// generationKind reports whether a generation is a "video" or an "image".
+// Workers sometimes report an image MIME for video output, so the video field,
+// MIME type, data URI prefix and URL extension are all considered.
+func generationKind(gen aipg.Generation) string {
+	if gen.Video != "" || strings.Contains(strings.ToLower(gen.Mime), "video") {
+		return "video"
+	}
+	for _, candidate := range []string{gen.ImgURL, gen.Img, strings.TrimSpace(gen.Image)} {
+		lower := strings.ToLower(candidate)
+		if strings.HasPrefix(lower, "data:video") {
+			return "video"
+		}
+		if u, err := url.Parse(lower); err == nil && !strings.HasPrefix(lower, "data:") {
+			for _, ext := range videoExtensions {
+				if strings.HasSuffix(u.Path, ext) {
+					return "video"
+				}
+			}
+		}
+	}
+	return "image"
+}
+
+// normalizeBase64 turns raw base64 from the Grid into a data URL typed by the
+// MIME type the Grid reported for it
+func normalizeBase64(raw, mime string) string {
 	data := strings.TrimSpace(raw)
 	if data == "" {
 		return ""
@@ -1602,7 +2560,7 @@ func normalizeBase64(raw string) string {
 		return data
 	}
 	if len(data) > 50 {
-		return "data:image/webp;base64," + data
+		return "data:" + imageMimeType(mime) + ";base64," + data
 	}
 	return ""
 }
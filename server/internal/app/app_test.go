@@ -0,0 +1,47 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/aipg"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/config"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/modelvault"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/recipevault"
+)
+
+// newTestApp builds an App backed by an in-memory file store and pointed at
+// the given Grid base URL, with the blockchain clients disabled.
+func newTestApp(t *testing.T, gridURL string) *App {
+	t.Helper()
+
+	vaultClient, _ := modelvault.NewClient(nil, "", false)
+	recipeVaultClient, _ := recipevault.NewClient(nil, "", false)
+	// Fake grids answer 5xx on purpose; one try keeps call counts exact
+	client := aipg.NewClient(gridURL, "test-agent")
+	client.SetRetryPolicy(aipg.RetryPolicy{MaxAttempts: 1})
+
+	return &App{
+		cfg: config.Config{
+			ClientAgent:   "test-agent",
+			DefaultAPIKey: "test-key",
+		},
+		client:            client,
+		vaultClient:       vaultClient,
+		recipeVaultClient: recipeVaultClient,
+		galleryStore:      &gallery.FileStoreAdapter{Store: gallery.NewStore("", 100)},
+		jobs:              newJobTracker(),
+		notifier:          newModelNotifier(),
+		scheduler:         newPollScheduler(1, 0, 0),
+		health:            newHealthChecks(),
+	}
+}
+
+// serve runs a request through the full router and returns the recorder
+func serve(a *App, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, req)
+	return rec
+}
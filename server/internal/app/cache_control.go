@@ -0,0 +1,58 @@
+package app
+
+import "net/http"
+
+// Cache-Control policies by endpoint class. Anything under /api that doesn't
+// pick one is no-store, since most responses are per-wallet or change quickly.
+const (
+	// Job status, wallet data, errors
+	cacheNoStore = "no-store"
+	// Live model stats and counts that are fine a few seconds stale
+	cacheShort = "public, max-age=15"
+	// Server config that only changes on deploy
+	cacheMedium = "public, max-age=300"
+	// On-chain recipe workflows, which never change once registered
+	cacheImmutable = "public, max-age=31536000, immutable"
+)
+
+// withCacheControl sets policy on successful responses and no-store on errors.
+// A handler that sets Cache-Control itself keeps its own value.
+func withCacheControl(policy string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&cacheControlWriter{ResponseWriter: w, policy: policy}, r)
+		})
+	}
+}
+
+type cacheControlWriter struct {
+	http.ResponseWriter
+	policy      string
+	wroteHeader bool
+}
+
+func (w *cacheControlWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if w.Header().Get("Cache-Control") == "" {
+			policy := w.policy
+			if status >= http.StatusBadRequest {
+				policy = cacheNoStore
+			}
+			w.Header().Set("Cache-Control", policy)
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *cacheControlWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer to flush
+func (w *cacheControlWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCacheControlPerEndpoint(t *testing.T) {
+	const creator = "0x1111111111111111111111111111111111111111"
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/status/models" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		w.Write([]byte(`{"id":"job-1","done":true}`))
+	}))
+	defer grid.Close()
+
+	a := newTestApp(t, grid.URL)
+	a.recipes = memoryRecipeReader{
+		1: {RecipeID: 1, IsPublic: true, WorkflowData: []byte(`{}`)},
+		2: {RecipeID: 2, Creator: creator, IsPublic: false, WorkflowData: []byte(`{}`)},
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		wallet string
+		want   string
+	}{
+		{name: "model stats", path: "/api/models", want: cacheShort},
+		{name: "public recipe workflow", path: "/api/recipes/1/raw", want: cacheImmutable},
+		{name: "public recipe", path: "/api/recipes/1", want: cacheImmutable},
+		{name: "private recipe for its creator", path: "/api/recipes/2/raw", wallet: creator, want: cacheNoStore},
+		{name: "missing recipe", path: "/api/recipes/9", want: cacheNoStore},
+		{name: "job status", path: "/api/jobs/job-1", want: cacheNoStore},
+		{name: "wallet settings", path: "/api/profile/" + creator + "/settings", wallet: creator, want: cacheNoStore},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.wallet != "" {
+				req.Header.Set("X-Wallet-Address", tc.wallet)
+			}
+			rec := serve(a, req)
+			if got := rec.Header().Get("Cache-Control"); got != tc.want {
+				t.Errorf("Cache-Control = %q (status %d), want %q", got, rec.Code, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/aipg"
+)
+
+type clientIPKey struct{}
+
+// clientIP works out the address of whoever made the request. Forwarding
+// headers are only believed when the direct peer is a trusted proxy, since
+// anyone can send them. X-Forwarded-For is read right to left, skipping our
+// own proxies, so a client can't spoof its address by prepending entries.
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	peer := remoteAddr(r)
+	if !peer.IsValid() {
+		return r.RemoteAddr
+	}
+	if !isTrustedProxy(peer, trusted) {
+		return peer.String()
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		var nearest netip.Addr
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				// Garbage in the chain; everything left of it is unverifiable
+				break
+			}
+			nearest = addr.Unmap()
+			if !isTrustedProxy(nearest, trusted) {
+				break
+			}
+		}
+		// Either the first untrusted hop or, when every hop is ours, the client furthest back
+		if nearest.IsValid() {
+			return nearest.String()
+		}
+	}
+	if addr, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return addr.Unmap().String()
+	}
+	return peer.String()
+}
+
+// remoteAddr parses the connection's peer address, which may or may not carry a port
+func remoteAddr(r *http.Request) netip.Addr {
+	host := r.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
+
+func isTrustedProxy(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// withClientIP resolves the client address once per request for handlers and logs
+func (a *App) withClientIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), clientIPKey{}, clientIP(r, a.cfg.TrustedProxies))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// clientIPFromContext returns the address withClientIP resolved, or "" outside a request
+func clientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+// accessLog logs each request once it's answered, under the client address
+// withClientIP resolved rather than the proxy's, so it must run after it
+func accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		log.Printf("%s %s %s %d %dB %v request=%s", clientIPFromContext(r.Context()), r.Method, r.URL.RequestURI(), status, ww.BytesWritten(), time.Since(start).Round(time.Millisecond), aipg.RequestID(r.Context()))
+	})
+}
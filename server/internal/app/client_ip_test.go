@@ -0,0 +1,78 @@
+package app
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("fd00::/8")}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		realIP     string
+		want       string
+	}{
+		{name: "direct client", remoteAddr: "203.0.113.7:51234", want: "203.0.113.7"},
+		{name: "untrusted peer can't spoof forwarded", remoteAddr: "203.0.113.7:51234", forwarded: []string{"198.51.100.1"}, want: "203.0.113.7"},
+		{name: "untrusted peer can't spoof real ip", remoteAddr: "203.0.113.7:51234", realIP: "198.51.100.1", want: "203.0.113.7"},
+		{name: "trusted proxy forwards client", remoteAddr: "10.1.2.3:8080", forwarded: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{name: "prepended spoof is ignored", remoteAddr: "10.1.2.3:8080", forwarded: []string{"1.2.3.4, 198.51.100.1, 10.9.9.9"}, want: "198.51.100.1"},
+		{name: "multiple headers join", remoteAddr: "10.1.2.3:8080", forwarded: []string{"198.51.100.1", "10.9.9.9"}, want: "198.51.100.1"},
+		{name: "all hops trusted", remoteAddr: "10.1.2.3:8080", forwarded: []string{"10.5.5.5, 10.9.9.9"}, want: "10.5.5.5"},
+		{name: "garbage hop stops the walk", remoteAddr: "10.1.2.3:8080", forwarded: []string{"198.51.100.1, nonsense"}, realIP: "198.51.100.9", want: "198.51.100.9"},
+		{name: "trusted proxy real ip", remoteAddr: "10.1.2.3:8080", realIP: "198.51.100.2", want: "198.51.100.2"},
+		{name: "trusted proxy without headers", remoteAddr: "10.1.2.3:8080", want: "10.1.2.3"},
+		{name: "ipv6 proxy", remoteAddr: "[fd00::1]:443", forwarded: []string{"2001:db8::5"}, want: "2001:db8::5"},
+		{name: "mapped ipv4 peer", remoteAddr: "[::ffff:10.1.2.3]:80", forwarded: []string{"198.51.100.1"}, want: "198.51.100.1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for _, v := range tc.forwarded {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			if tc.realIP != "" {
+				req.Header.Set("X-Real-IP", tc.realIP)
+			}
+			if got := clientIP(req, trusted); got != tc.want {
+				t.Errorf("clientIP = %s, want %s", got, tc.want)
+			}
+		})
+	}
+
+	// With nothing trusted, headers never matter
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.1.2.3:8080"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	if got := clientIP(req, nil); got != "10.1.2.3" {
+		t.Errorf("clientIP without trusted proxies = %s, want peer", got)
+	}
+}
+
+func TestAccessLogUsesClientIP(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	a := newTestApp(t, "")
+	a.cfg.TrustedProxies = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.RemoteAddr = "10.1.2.3:8080"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	serve(a, req)
+
+	line := logged.String()
+	if !strings.Contains(line, "198.51.100.1 GET /health 200") || strings.Contains(line, "10.1.2.3") {
+		t.Errorf("access log = %q, want the forwarded client and not the proxy", line)
+	}
+}
@@ -0,0 +1,354 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+)
+
+const (
+	maxCollectionNameLength = 80
+	maxSlugAttempts         = 5
+)
+
+// slugSuffix returns the random part of a collection slug; tests replace it to force collisions
+var slugSuffix = func() string {
+	const alphabet = "abcdefghijkmnpqrstuvwxyz23456789"
+	b := make([]byte, 6)
+	rand.Read(b)
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+	return string(b)
+}
+
+// collectionSlug builds a URL-safe slug from a collection name plus a random suffix
+func collectionSlug(name string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+			dash = false
+		} else if !dash && sb.Len() > 0 {
+			sb.WriteByte('-')
+			dash = true
+		}
+		if sb.Len() >= 40 {
+			break
+		}
+	}
+	base := strings.Trim(sb.String(), "-")
+	if base == "" {
+		base = "collection"
+	}
+	return base + "-" + slugSuffix()
+}
+
+type CreateCollectionRequest struct {
+	Name string `json:"name"`
+}
+
+type CollectionItemRequest struct {
+	JobID string `json:"jobId"`
+}
+
+// collectionItemVisible reports whether wallet may see item in a collection:
+// public items, and private ones the wallet owns
+func collectionItemVisible(item *gallery.GalleryItem, wallet string) bool {
+	return item != nil && (item.IsPublic || strings.ToLower(item.WalletAddress) == wallet)
+}
+
+// loadOwnedCollection resolves the {id} URL param to a collection owned by the
+// requesting wallet, writing the error response and returning nil otherwise
+func (a *App) loadOwnedCollection(w http.ResponseWriter, r *http.Request) *gallery.Collection {
+	if a.collectionStore == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("collections not available"))
+		return nil
+	}
+
+	wallet := verifiedWalletFromRequest(r)
+	if wallet == "" {
+		writeError(w, http.StatusUnauthorized, errors.New("signed wallet request required - sign in with your wallet to manage collections"))
+		return nil
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid collection ID"))
+		return nil
+	}
+
+	collection, err := a.collectionStore.Get(r.Context(), id)
+	if errors.Is(err, gallery.ErrCollectionNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return nil
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return nil
+	}
+	if strings.ToLower(collection.WalletAddress) != wallet {
+		writeError(w, http.StatusForbidden, errors.New("you can only access your own collections"))
+		return nil
+	}
+	return collection
+}
+
+// handleCreateCollection creates an empty named collection for the requesting wallet
+func (a *App) handleCreateCollection(w http.ResponseWriter, r *http.Request) {
+	if a.collectionStore == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("collections not available"))
+		return
+	}
+
+	wallet := verifiedWalletFromRequest(r)
+	if wallet == "" {
+		writeError(w, http.StatusUnauthorized, errors.New("signed wallet request required - sign in with your wallet to create collections"))
+		return
+	}
+
+	var req CreateCollectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %w", err))
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > maxCollectionNameLength {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("name is required and must be at most %d characters", maxCollectionNameLength))
+		return
+	}
+
+	collection, err := a.collectionStore.Create(r.Context(), wallet, name)
+	if errors.Is(err, gallery.ErrCollectionExists) {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	log.Printf("Collections: wallet %s created %q (%d)", wallet, name, collection.ID)
+	writeJSON(w, http.StatusCreated, collection)
+}
+
+// handleListCollections returns the requesting wallet's collections
+func (a *App) handleListCollections(w http.ResponseWriter, r *http.Request) {
+	wallet := strings.ToLower(strings.TrimSpace(chi.URLParam(r, "wallet")))
+	if wallet == "" {
+		writeError(w, http.StatusBadRequest, errors.New("wallet address required"))
+		return
+	}
+	if verifiedWalletFromRequest(r) != wallet {
+		writeError(w, http.StatusForbidden, errors.New("you can only list your own collections"))
+		return
+	}
+	if a.collectionStore == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("collections not available"))
+		return
+	}
+
+	collections, err := a.collectionStore.ListByWallet(r.Context(), wallet)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"wallet":      wallet,
+		"collections": collections,
+		"count":       len(collections),
+	})
+}
+
+// handleGetCollectionItems returns a collection's gallery items in the order
+// they were added. Other wallets' items made private since they were collected
+// are left out.
+func (a *App) handleGetCollectionItems(w http.ResponseWriter, r *http.Request) {
+	collection := a.loadOwnedCollection(w, r)
+	if collection == nil {
+		return
+	}
+
+	jobIDs, err := a.collectionStore.ItemJobIDs(r.Context(), collection.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	wallet := verifiedWalletFromRequest(r)
+	items := make([]gallery.GalleryItem, 0, len(jobIDs))
+	for _, jobID := range jobIDs {
+		// Items deleted from the gallery simply drop out of the collection view
+		if item := a.galleryStore.Get(r.Context(), jobID); collectionItemVisible(item, wallet) {
+			items = append(items, *item)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"collection": collection,
+		"items":      items,
+		"count":      len(items),
+	})
+}
+
+// handleAddCollectionItem adds a gallery item to a collection. Wallets can
+// collect their own items and other people's public items.
+func (a *App) handleAddCollectionItem(w http.ResponseWriter, r *http.Request) {
+	collection := a.loadOwnedCollection(w, r)
+	if collection == nil {
+		return
+	}
+
+	var req CollectionItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %w", err))
+		return
+	}
+	if req.JobID == "" {
+		writeError(w, http.StatusBadRequest, errors.New("jobId is required"))
+		return
+	}
+
+	item := a.galleryStore.Get(r.Context(), req.JobID)
+	wallet := verifiedWalletFromRequest(r)
+	// Someone else's private item is reported as missing so its existence isn't leaked
+	if !collectionItemVisible(item, wallet) {
+		writeError(w, http.StatusNotFound, errors.New("gallery item not found"))
+		return
+	}
+
+	if err := a.collectionStore.AddItem(r.Context(), collection.ID, req.JobID); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"success":      true,
+		"collectionId": collection.ID,
+		"jobId":        req.JobID,
+	})
+}
+
+// handleRemoveCollectionItem removes a gallery item from a collection
+func (a *App) handleRemoveCollectionItem(w http.ResponseWriter, r *http.Request) {
+	collection := a.loadOwnedCollection(w, r)
+	if collection == nil {
+		return
+	}
+
+	jobID := chi.URLParam(r, "jobId")
+	if err := a.collectionStore.RemoveItem(r.Context(), collection.ID, jobID); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"success":      true,
+		"collectionId": collection.ID,
+		"jobId":        jobID,
+	})
+}
+
+// handlePublishCollection makes a collection public and returns its shareable slug
+func (a *App) handlePublishCollection(w http.ResponseWriter, r *http.Request) {
+	collection := a.loadOwnedCollection(w, r)
+	if collection == nil {
+		return
+	}
+
+	// Keep an existing slug so previously shared links stay valid
+	slug := collection.Slug
+	err := gallery.ErrSlugTaken
+	if slug != "" {
+		err = a.collectionStore.Publish(r.Context(), collection.ID, slug)
+	} else {
+		for attempt := 0; attempt < maxSlugAttempts && errors.Is(err, gallery.ErrSlugTaken); attempt++ {
+			slug = collectionSlug(collection.Name)
+			err = a.collectionStore.Publish(r.Context(), collection.ID, slug)
+		}
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to publish collection: %w", err))
+		return
+	}
+
+	log.Printf("Collections: wallet %s published %d as %s", collection.WalletAddress, collection.ID, slug)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"success":      true,
+		"collectionId": collection.ID,
+		"isPublic":     true,
+		"slug":         slug,
+	})
+}
+
+// handleUnpublishCollection makes a collection private again
+func (a *App) handleUnpublishCollection(w http.ResponseWriter, r *http.Request) {
+	collection := a.loadOwnedCollection(w, r)
+	if collection == nil {
+		return
+	}
+
+	if err := a.collectionStore.Unpublish(r.Context(), collection.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"success":      true,
+		"collectionId": collection.ID,
+		"isPublic":     false,
+	})
+}
+
+// handleGetPublicCollection returns a published collection by slug to anyone.
+// Only public gallery items are included, even when the owner is asking.
+func (a *App) handleGetPublicCollection(w http.ResponseWriter, r *http.Request) {
+	if a.collectionStore == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("collections not available"))
+		return
+	}
+
+	collection, err := a.collectionStore.GetBySlug(r.Context(), chi.URLParam(r, "slug"))
+	if err == nil && !collection.IsPublic {
+		err = gallery.ErrCollectionNotFound
+	}
+	if errors.Is(err, gallery.ErrCollectionNotFound) {
+		writeError(w, http.StatusNotFound, gallery.ErrCollectionNotFound)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	jobIDs, err := a.collectionStore.ItemJobIDs(r.Context(), collection.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	items := make([]gallery.GalleryItem, 0, len(jobIDs))
+	for _, jobID := range jobIDs {
+		if item := a.galleryStore.Get(r.Context(), jobID); item != nil && item.IsPublic {
+			items = append(items, *item)
+		}
+	}
+	collection.ItemCount = len(items)
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"collection": collection,
+		"items":      items,
+		"count":      len(items),
+	})
+}
@@ -0,0 +1,365 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+)
+
+// memoryCollectionStore is an in-memory gallery.CollectionStore for handler tests
+type memoryCollectionStore struct {
+	mu          sync.Mutex
+	takenSlugs  map[string]bool // slugs owned by collections outside this store
+	nextID      int64
+	collections map[int64]*gallery.Collection
+	items       map[int64][]string
+}
+
+func newMemoryCollectionStore() *memoryCollectionStore {
+	return &memoryCollectionStore{
+		collections: make(map[int64]*gallery.Collection),
+		items:       make(map[int64][]string),
+		takenSlugs:  make(map[string]bool),
+	}
+}
+
+func (m *memoryCollectionStore) Create(ctx context.Context, wallet, name string) (*gallery.Collection, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range m.collections {
+		if c.WalletAddress == strings.ToLower(wallet) && c.Name == name {
+			return nil, gallery.ErrCollectionExists
+		}
+	}
+	m.nextID++
+	c := &gallery.Collection{ID: m.nextID, WalletAddress: strings.ToLower(wallet), Name: name, CreatedAt: time.Now()}
+	m.collections[c.ID] = c
+	return c, nil
+}
+
+func (m *memoryCollectionStore) Get(ctx context.Context, id int64) (*gallery.Collection, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.collections[id]
+	if !ok {
+		return nil, gallery.ErrCollectionNotFound
+	}
+	out := *c
+	out.ItemCount = len(m.items[id])
+	return &out, nil
+}
+
+func (m *memoryCollectionStore) ListByWallet(ctx context.Context, wallet string) ([]gallery.Collection, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]gallery.Collection, 0)
+	for id := m.nextID; id > 0; id-- {
+		if c, ok := m.collections[id]; ok && c.WalletAddress == strings.ToLower(wallet) {
+			cp := *c
+			cp.ItemCount = len(m.items[id])
+			out = append(out, cp)
+		}
+	}
+	return out, nil
+}
+
+func (m *memoryCollectionStore) AddItem(ctx context.Context, collectionID int64, jobID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.items[collectionID] {
+		if existing == jobID {
+			return nil
+		}
+	}
+	m.items[collectionID] = append(m.items[collectionID], jobID)
+	return nil
+}
+
+func (m *memoryCollectionStore) RemoveItem(ctx context.Context, collectionID int64, jobID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := m.items[collectionID][:0]
+	for _, existing := range m.items[collectionID] {
+		if existing != jobID {
+			kept = append(kept, existing)
+		}
+	}
+	m.items[collectionID] = kept
+	return nil
+}
+
+func (m *memoryCollectionStore) ItemJobIDs(ctx context.Context, collectionID int64) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string{}, m.items[collectionID]...), nil
+}
+
+func (m *memoryCollectionStore) GetBySlug(ctx context.Context, slug string) (*gallery.Collection, error) {
+	m.mu.Lock()
+	var id int64
+	for _, c := range m.collections {
+		if c.Slug == slug {
+			id = c.ID
+		}
+	}
+	m.mu.Unlock()
+	return m.Get(ctx, id)
+}
+
+func (m *memoryCollectionStore) Publish(ctx context.Context, id int64, slug string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.takenSlugs[slug] {
+		return gallery.ErrSlugTaken
+	}
+	for _, c := range m.collections {
+		if c.Slug == slug && c.ID != id {
+			return gallery.ErrSlugTaken
+		}
+	}
+	m.collections[id].IsPublic = true
+	m.collections[id].Slug = slug
+	return nil
+}
+
+func (m *memoryCollectionStore) Unpublish(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.collections[id].IsPublic = false
+	return nil
+}
+
+func newCollectionsTestApp(t *testing.T) *App {
+	t.Helper()
+	a := newTestApp(t, "")
+	a.collectionStore = newMemoryCollectionStore()
+	for _, item := range []gallery.GalleryItem{
+		{JobID: "mine-1", Prompt: "one", WalletAddress: testAddr("owner")},
+		{JobID: "mine-2", Prompt: "two", WalletAddress: testAddr("owner"), IsPublic: true},
+		{JobID: "theirs-public", Prompt: "three", WalletAddress: testAddr("other"), IsPublic: true},
+		{JobID: "theirs-private", Prompt: "four", WalletAddress: testAddr("other")},
+	} {
+		a.galleryStore.Add(context.Background(), item)
+	}
+	return a
+}
+
+// collectionRequest builds a request signed by the named test wallet, or an
+// anonymous one when wallet is empty
+func collectionRequest(t *testing.T, method, target, wallet, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if wallet != "" {
+		signAs(t, req, wallet)
+	}
+	return req
+}
+
+func TestCollectionMembership(t *testing.T) {
+	a := newCollectionsTestApp(t)
+
+	rec := serve(a, collectionRequest(t, http.MethodPost, "/api/collections", "owner", `{"name":"landscapes"}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body %s", rec.Code, rec.Body)
+	}
+	var created gallery.Collection
+	json.NewDecoder(rec.Body).Decode(&created)
+
+	if rec := serve(a, collectionRequest(t, http.MethodPost, "/api/collections", "owner", `{"name":"landscapes"}`)); rec.Code != http.StatusConflict {
+		t.Errorf("duplicate create status = %d, want 409", rec.Code)
+	}
+
+	add := func(wallet, jobID string) int {
+		return serve(a, collectionRequest(t, http.MethodPost, "/api/collections/1/items", wallet, `{"jobId":"`+jobID+`"}`)).Code
+	}
+	for _, jobID := range []string{"theirs-public", "mine-1", "mine-2", "mine-1"} {
+		if code := add("owner", jobID); code != http.StatusOK {
+			t.Errorf("add %s status = %d, want 200", jobID, code)
+		}
+	}
+	if code := add("owner", "theirs-private"); code != http.StatusNotFound {
+		t.Errorf("add someone else's private item status = %d, want 404", code)
+	}
+	if code := add("other", "theirs-public"); code != http.StatusForbidden {
+		t.Errorf("add to someone else's collection status = %d, want 403", code)
+	}
+	if code := add("", "mine-1"); code != http.StatusUnauthorized {
+		t.Errorf("add without wallet status = %d, want 401", code)
+	}
+
+	if rec := serve(a, collectionRequest(t, http.MethodDelete, "/api/collections/1/items/theirs-public", "owner", "")); rec.Code != http.StatusOK {
+		t.Fatalf("remove status = %d", rec.Code)
+	}
+
+	rec = serve(a, collectionRequest(t, http.MethodGet, "/api/collections/1/items", "owner", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("items status = %d", rec.Code)
+	}
+	var resp struct {
+		Items []gallery.GalleryItem `json:"items"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	var got []string
+	for _, item := range resp.Items {
+		got = append(got, item.JobID)
+	}
+	if strings.Join(got, ",") != "mine-1,mine-2" {
+		t.Errorf("items = %v, want [mine-1 mine-2] in insertion order", got)
+	}
+
+	if rec := serve(a, collectionRequest(t, http.MethodGet, "/api/collections/1/items", "other", "")); rec.Code != http.StatusForbidden {
+		t.Errorf("non-owner items status = %d, want 403", rec.Code)
+	}
+
+	rec = serve(a, collectionRequest(t, http.MethodGet, "/api/collections/wallet/"+testAddr("owner"), "owner", ""))
+	var list struct {
+		Collections []gallery.Collection `json:"collections"`
+	}
+	json.NewDecoder(rec.Body).Decode(&list)
+	if len(list.Collections) != 1 || list.Collections[0].ID != created.ID || list.Collections[0].ItemCount != 2 {
+		t.Errorf("collections = %+v, want one with 2 items", list.Collections)
+	}
+}
+
+func TestCollectionItemsHideOthersPrivateItems(t *testing.T) {
+	a := newCollectionsTestApp(t)
+	if rec := serve(a, collectionRequest(t, http.MethodPost, "/api/collections", "owner", `{"name":"mixed"}`)); rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body %s", rec.Code, rec.Body)
+	}
+	for _, jobID := range []string{"theirs-public", "mine-1"} {
+		if rec := serve(a, collectionRequest(t, http.MethodPost, "/api/collections/1/items", "owner", `{"jobId":"`+jobID+`"}`)); rec.Code != http.StatusOK {
+			t.Fatalf("add %s status = %d", jobID, rec.Code)
+		}
+	}
+
+	// The other wallet takes its item private after it was collected
+	theirs := a.galleryStore.Get(context.Background(), "theirs-public")
+	theirs.IsPublic = false
+	if err := a.galleryStore.Add(context.Background(), *theirs); err != nil {
+		t.Fatalf("make private: %v", err)
+	}
+
+	rec := serve(a, collectionRequest(t, http.MethodGet, "/api/collections/1/items", "owner", ""))
+	var resp struct {
+		Items []gallery.GalleryItem `json:"items"`
+		Count int                   `json:"count"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Count != 1 || len(resp.Items) != 1 || resp.Items[0].JobID != "mine-1" {
+		t.Errorf("items = %+v, want only the owner's own private item", resp.Items)
+	}
+}
+
+func TestCollectionsUnavailableWithoutStore(t *testing.T) {
+	a := newTestApp(t, "")
+	rec := serve(a, collectionRequest(t, http.MethodPost, "/api/collections", "owner", `{"name":"x"}`))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", rec.Code)
+	}
+}
+
+func TestCollectionSlug(t *testing.T) {
+	orig := slugSuffix
+	defer func() { slugSuffix = orig }()
+	slugSuffix = func() string { return "x1" }
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Landscapes", "landscapes-x1"},
+		{"  Client work: 2024!! ", "client-work-2024-x1"},
+		{"日本", "collection-x1"},
+		{strings.Repeat("a", 60), strings.Repeat("a", 40) + "-x1"},
+	}
+	for _, tc := range tests {
+		if got := collectionSlug(tc.name); got != tc.want {
+			t.Errorf("collectionSlug(%q) = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestPublishCollectionRetriesSlugCollisions(t *testing.T) {
+	orig := slugSuffix
+	defer func() { slugSuffix = orig }()
+	suffixes := []string{"aaa", "bbb", "ccc"}
+	slugSuffix = func() string {
+		s := suffixes[0]
+		suffixes = suffixes[1:]
+		return s
+	}
+
+	a := newCollectionsTestApp(t)
+	store := a.collectionStore.(*memoryCollectionStore)
+	store.takenSlugs["landscapes-aaa"] = true
+	store.takenSlugs["landscapes-bbb"] = true
+	store.Create(context.Background(), testAddr("owner"), "Landscapes")
+
+	rec := serve(a, collectionRequest(t, http.MethodPost, "/api/collections/1/publish", "owner", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("publish status = %d, body %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Slug string `json:"slug"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Slug != "landscapes-ccc" {
+		t.Errorf("slug = %q, want landscapes-ccc after two collisions", resp.Slug)
+	}
+
+	// Re-publishing keeps the slug instead of generating a new one
+	if rec := serve(a, collectionRequest(t, http.MethodPost, "/api/collections/1/publish", "owner", "")); !strings.Contains(rec.Body.String(), "landscapes-ccc") {
+		t.Errorf("re-publish body = %s, want same slug", rec.Body)
+	}
+
+	if rec := serve(a, collectionRequest(t, http.MethodPost, "/api/collections/1/publish", "other", "")); rec.Code != http.StatusForbidden {
+		t.Errorf("non-owner publish status = %d, want 403", rec.Code)
+	}
+}
+
+func TestPublicCollectionVisibility(t *testing.T) {
+	a := newCollectionsTestApp(t)
+	store := a.collectionStore.(*memoryCollectionStore)
+	c, _ := store.Create(context.Background(), testAddr("owner"), "mixed")
+	for _, jobID := range []string{"mine-1", "mine-2", "theirs-public"} {
+		store.AddItem(context.Background(), c.ID, jobID)
+	}
+
+	// Not published yet
+	if rec := serve(a, collectionRequest(t, http.MethodGet, "/api/collections/mixed-abc", "", "")); rec.Code != http.StatusNotFound {
+		t.Fatalf("unpublished status = %d, want 404", rec.Code)
+	}
+
+	store.Publish(context.Background(), c.ID, "mixed-abc")
+	for _, wallet := range []string{"", "owner"} {
+		rec := serve(a, collectionRequest(t, http.MethodGet, "/api/collections/mixed-abc", wallet, ""))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("public status = %d, body %s", rec.Code, rec.Body)
+		}
+		var resp struct {
+			Items []gallery.GalleryItem `json:"items"`
+		}
+		json.NewDecoder(rec.Body).Decode(&resp)
+		var got []string
+		for _, item := range resp.Items {
+			got = append(got, item.JobID)
+		}
+		// mine-1 is private and stays hidden from the public view
+		if strings.Join(got, ",") != "mine-2,theirs-public" {
+			t.Errorf("wallet %q sees %v, want [mine-2 theirs-public]", wallet, got)
+		}
+	}
+
+	if rec := serve(a, collectionRequest(t, http.MethodPost, "/api/collections/1/unpublish", "owner", "")); rec.Code != http.StatusOK {
+		t.Fatalf("unpublish status = %d", rec.Code)
+	}
+	if rec := serve(a, collectionRequest(t, http.MethodGet, "/api/collections/mixed-abc", "", "")); rec.Code != http.StatusNotFound {
+		t.Errorf("unpublished status = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+)
+
+// maxCompareItems caps how many generations one comparison can include
+const maxCompareItems = 6
+
+type CompareRequest struct {
+	JobIDs []string `json:"jobIds"`
+}
+
+// CompareEntry is one slot in a comparison, aligned with the requested job IDs
+type CompareEntry struct {
+	JobID  string               `json:"jobId"`
+	Status int                  `json:"status"` // 200, or 404 when missing or not visible
+	Item   *gallery.GalleryItem `json:"item,omitempty"`
+}
+
+// CompareResponse lists the entries in request order. Differences maps each
+// setting that varies to its value per entry (null for missing entries or
+// unset values); Common holds the settings every found item shares.
+type CompareResponse struct {
+	Entries     []CompareEntry   `json:"entries"`
+	Differences map[string][]any `json:"differences"`
+	Common      map[string]any   `json:"common"`
+}
+
+// handleCompareGallery returns several generations side by side with the
+// settings that differ between them. Private items are only included for their owner.
+func (a *App) handleCompareGallery(w http.ResponseWriter, r *http.Request) {
+	var req CompareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %w", err))
+		return
+	}
+	if len(req.JobIDs) < 2 {
+		writeError(w, http.StatusBadRequest, errors.New("at least two jobIds are required"))
+		return
+	}
+	if len(req.JobIDs) > maxCompareItems {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("at most %d jobIds can be compared", maxCompareItems))
+		return
+	}
+
+	wallet := verifiedWalletFromRequest(r)
+	entries := make([]CompareEntry, len(req.JobIDs))
+	settings := make([]map[string]any, len(req.JobIDs))
+	for i, jobID := range req.JobIDs {
+		jobID = strings.TrimSpace(jobID)
+		entries[i] = CompareEntry{JobID: jobID, Status: http.StatusNotFound}
+
+		item := a.galleryStore.Get(r.Context(), jobID)
+		if item == nil || (!item.IsPublic && (wallet == "" || !strings.EqualFold(item.WalletAddress, wallet))) {
+			continue
+		}
+		entries[i].Status = http.StatusOK
+		entries[i].Item = item
+		settings[i] = comparableSettings(item)
+	}
+
+	differences, common := diffSettings(settings)
+	writeJSON(w, http.StatusOK, CompareResponse{
+		Entries:     entries,
+		Differences: differences,
+		Common:      common,
+	})
+}
+
+// comparableSettings flattens what produced an item into one map: its params
+// (keyed by their JSON names) plus the model and prompts
+func comparableSettings(item *gallery.GalleryItem) map[string]any {
+	settings := map[string]any{
+		"modelId":        item.ModelID,
+		"prompt":         item.Prompt,
+		"negativePrompt": item.NegativePrompt,
+	}
+	if item.Params != nil {
+		// Round-trip through JSON so omitted params stay absent and pointers are dereferenced
+		raw, _ := json.Marshal(item.Params)
+		var params map[string]any
+		json.Unmarshal(raw, &params)
+		for k, v := range params {
+			settings[k] = v
+		}
+	}
+	return settings
+}
+
+// diffSettings splits settings into the keys whose values differ between the
+// found entries and the keys they all share. Nil entries (items not found)
+// are skipped when deciding but still get a null slot in each difference.
+func diffSettings(settings []map[string]any) (map[string][]any, map[string]any) {
+	keys := make(map[string]bool)
+	found := 0
+	for _, s := range settings {
+		if s == nil {
+			continue
+		}
+		found++
+		for k := range s {
+			keys[k] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	differences := make(map[string][]any)
+	common := make(map[string]any)
+	for _, key := range sorted {
+		values := make([]any, len(settings))
+		var first any
+		seen, same := false, true
+		for i, s := range settings {
+			if s == nil {
+				continue
+			}
+			values[i] = s[key]
+			if !seen {
+				first, seen = s[key], true
+			} else if !reflect.DeepEqual(first, s[key]) {
+				same = false
+			}
+		}
+		if same && found > 0 {
+			common[key] = first
+		} else {
+			differences[key] = values
+		}
+	}
+	return differences, common
+}
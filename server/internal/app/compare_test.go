@@ -0,0 +1,78 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+)
+
+func TestDiffSettings(t *testing.T) {
+	settings := []map[string]any{
+		{"modelId": "FLUX.1-dev", "prompt": "a fox", "steps": 20.0, "width": 1024.0},
+		nil, // a job that wasn't found
+		{"modelId": "Chroma", "prompt": "a fox", "steps": 20.0, "width": 1024.0, "sampler": "euler"},
+	}
+
+	differences, common := diffSettings(settings)
+
+	wantDiff := map[string][]any{
+		"modelId": {"FLUX.1-dev", nil, "Chroma"},
+		"sampler": {nil, nil, "euler"},
+	}
+	if !reflect.DeepEqual(differences, wantDiff) {
+		t.Errorf("differences = %v, want %v", differences, wantDiff)
+	}
+	wantCommon := map[string]any{"prompt": "a fox", "steps": 20.0, "width": 1024.0}
+	if !reflect.DeepEqual(common, wantCommon) {
+		t.Errorf("common = %v, want %v", common, wantCommon)
+	}
+}
+
+func TestCompareGallery(t *testing.T) {
+	a := newTestApp(t, "")
+	steps20, steps30 := 20, 30
+	a.galleryStore.Add(context.Background(), gallery.GalleryItem{JobID: "a", ModelID: "FLUX.1-dev", Prompt: "a fox", IsPublic: true, Params: &gallery.JobParams{Steps: &steps20}})
+	a.galleryStore.Add(context.Background(), gallery.GalleryItem{JobID: "b", ModelID: "Chroma", Prompt: "a fox", IsPublic: true, Params: &gallery.JobParams{Steps: &steps30}})
+	a.galleryStore.Add(context.Background(), gallery.GalleryItem{JobID: "private", ModelID: "Chroma", WalletAddress: "0x" + strings.ToUpper(testAddr("owner")[2:])})
+
+	rec := serve(a, httptest.NewRequest(http.MethodPost, "/api/gallery/compare", strings.NewReader(`{"jobIds":["a","missing","b","private"]}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	var resp CompareResponse
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+
+	statuses := []int{}
+	for _, e := range resp.Entries {
+		statuses = append(statuses, e.Status)
+	}
+	if !reflect.DeepEqual(statuses, []int{200, 404, 200, 404}) {
+		t.Errorf("statuses = %v, want the private item hidden from strangers", statuses)
+	}
+
+	// The owner sees it, whatever the case of the stored address
+	req := httptest.NewRequest(http.MethodPost, "/api/gallery/compare", strings.NewReader(`{"jobIds":["a","private"]}`))
+	signAs(t, req, "owner")
+	var owned CompareResponse
+	json.Unmarshal(serve(a, req).Body.Bytes(), &owned)
+	if len(owned.Entries) != 2 || owned.Entries[1].Status != http.StatusOK {
+		t.Errorf("owner entries = %+v, want their private item", owned.Entries)
+	}
+	if got := resp.Differences["steps"]; len(got) != 4 || got[0] != 20.0 || got[2] != 30.0 {
+		t.Errorf("steps difference = %v", got)
+	}
+	if resp.Common["prompt"] != "a fox" {
+		t.Errorf("common = %v, want the shared prompt", resp.Common)
+	}
+
+	tooMany := `{"jobIds":["1","2","3","4","5","6","7"]}`
+	if rec := serve(a, httptest.NewRequest(http.MethodPost, "/api/gallery/compare", strings.NewReader(tooMany))); rec.Code != http.StatusBadRequest {
+		t.Errorf("compare 7 items = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,38 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCORSPreflightAndExposedHeaders(t *testing.T) {
+	a := newTestApp(t, "")
+	a.cfg.CORSMaxAge = 10 * time.Minute
+
+	preflight := httptest.NewRequest(http.MethodOptions, "/api/gallery/job-1", nil)
+	preflight.Header.Set("Origin", "https://aipg.art")
+	preflight.Header.Set("Access-Control-Request-Method", http.MethodPatch)
+	rec := serve(a, preflight)
+	if got := rec.Header().Get("Access-Control-Max-Age"); got != "600" {
+		t.Errorf("Access-Control-Max-Age = %q, want 600", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Methods"); !strings.Contains(got, http.MethodPatch) {
+		t.Errorf("Access-Control-Allow-Methods = %q, want PATCH allowed", got)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/gallery", nil)
+	req.Header.Set("Origin", "https://aipg.art")
+	rec = serve(a, req)
+	exposed := rec.Header().Get("Access-Control-Expose-Headers")
+	for _, header := range []string{"X-Total-Count", "X-Ratelimit-Remaining", "Retry-After"} {
+		if !strings.Contains(strings.ToLower(exposed), strings.ToLower(header)) {
+			t.Errorf("Access-Control-Expose-Headers = %q, missing %s", exposed, header)
+		}
+	}
+	if rec.Header().Get("X-Total-Count") != "0" {
+		t.Errorf("X-Total-Count = %q, want 0", rec.Header().Get("X-Total-Count"))
+	}
+}
@@ -0,0 +1,278 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/r2"
+)
+
+// Download metadata modes: the file as stored, metadata written into it where
+// the format allows (PNG), or the file and a JSON sidecar zipped together
+const (
+	downloadRaw     = "none"
+	downloadEmbed   = "embed"
+	downloadSidecar = "sidecar"
+)
+
+// downloadMaxBytes caps what's buffered to add metadata; raw downloads stream
+const downloadMaxBytes = 256 << 20
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// DownloadMetadata is what made a generation, as embedded in or zipped
+// alongside a download
+type DownloadMetadata struct {
+	JobID          string             `json:"jobId"`
+	Prompt         string             `json:"prompt"`
+	NegativePrompt string             `json:"negativePrompt,omitempty"`
+	ModelID        string             `json:"modelId,omitempty"`
+	ModelName      string             `json:"modelName,omitempty"`
+	Seed           string             `json:"seed,omitempty"`
+	Params         *gallery.JobParams `json:"params,omitempty"`
+	CreatedAt      string             `json:"createdAt,omitempty"`
+	Source         string             `json:"source"`
+}
+
+func newDownloadMetadata(item *gallery.GalleryItem, mediaURL string) DownloadMetadata {
+	meta := DownloadMetadata{
+		JobID:          item.JobID,
+		Prompt:         item.Prompt,
+		NegativePrompt: item.NegativePrompt,
+		ModelID:        item.ModelID,
+		ModelName:      item.ModelName,
+		Params:         item.Params,
+		Source:         mediaURL,
+	}
+	if item.Params != nil && item.Params.Seed != nil {
+		meta.Seed = *item.Params.Seed
+	}
+	if item.CreatedAt > 0 {
+		meta.CreatedAt = time.UnixMilli(item.CreatedAt).UTC().Format(time.RFC3339)
+	}
+	return meta
+}
+
+// handleDownloadGalleryMedia sends one of a gallery item's files as an
+// attachment. ?index picks the file (default 0). ?metadata=embed writes the
+// prompt, model, seed and params into PNGs as text chunks, and zips other
+// formats with a JSON sidecar; ?metadata=sidecar always zips. Without it the
+// file is passed through untouched.
+func (a *App) handleDownloadGalleryMedia(w http.ResponseWriter, r *http.Request) {
+	item := a.galleryStore.Get(r.Context(), chi.URLParam(r, "id"))
+	wallet := verifiedWalletFromRequest(r)
+	if item == nil || (!item.IsPublic && (wallet == "" || strings.ToLower(item.WalletAddress) != wallet)) {
+		writeError(w, http.StatusNotFound, errors.New("gallery item not found"))
+		return
+	}
+
+	mode := r.URL.Query().Get("metadata")
+	if mode == "" {
+		mode = downloadRaw
+	}
+	if mode != downloadRaw && mode != downloadEmbed && mode != downloadSidecar {
+		writeError(w, http.StatusBadRequest, errors.New("metadata must be none, embed or sidecar"))
+		return
+	}
+	index, err := queryInt(r, "index", 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if index >= len(item.MediaURLs) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("item %s has no file %d", item.JobID, index))
+		return
+	}
+	mediaURL := a.mediaURLs([]string{item.MediaURLs[index]})[0]
+	if !a.downloadableURL(mediaURL) {
+		writeError(w, http.StatusUnprocessableEntity, errors.New("this file isn't stored on the gallery's media hosts and can't be downloaded here"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Minute)
+	defer cancel()
+	resp, err := a.fetchMedia(ctx, mediaURL)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	name := fmt.Sprintf("aipg-%s-%d", item.JobID, index)
+	if mode == downloadRaw {
+		head := make([]byte, 512)
+		n, _ := io.ReadFull(resp.Body, head)
+		head = head[:n]
+		contentType := http.DetectContentType(head)
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+mediaExtension(contentType)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(head)
+		if _, err := io.Copy(w, resp.Body); err != nil {
+			log.Printf("Warning: download of %s cut short: %v", mediaURL, err)
+		}
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, downloadMaxBytes+1))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	if len(data) > downloadMaxBytes {
+		writeError(w, http.StatusBadGateway, fmt.Errorf("file is over %d MB, download it without metadata", downloadMaxBytes>>20))
+		return
+	}
+	contentType := http.DetectContentType(data)
+	meta := newDownloadMetadata(item, mediaURL)
+
+	if mode == downloadEmbed && contentType == "image/png" {
+		out, err := embedPNGMetadata(data, meta)
+		if err != nil {
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".png"))
+		w.Write(out)
+		return
+	}
+
+	// Formats without a text slot we can write get the metadata beside them
+	sidecar, _ := json.MarshalIndent(meta, "", "  ")
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".zip"))
+	zw := zip.NewWriter(w)
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{
+		{name + mediaExtension(contentType), data},
+		{name + ".json", sidecar},
+	} {
+		f, err := zw.Create(file.name)
+		if err == nil {
+			_, err = f.Write(file.data)
+		}
+		if err != nil {
+			log.Printf("Warning: download of %s cut short: %v", mediaURL, err)
+			return
+		}
+	}
+	zw.Close()
+}
+
+// downloadableURL limits server-side fetches to the gallery's own media hosts,
+// since item URLs come from clients
+func (a *App) downloadableURL(mediaURL string) bool {
+	u, err := url.Parse(mediaURL)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host := strings.ToLower(u.Host)
+	if u.Scheme == "https" && (host == r2.PublicMediaHost || strings.HasSuffix(host, ".r2.cloudflarestorage.com")) {
+		return true
+	}
+	cdn, err := url.Parse(a.cfg.MediaCDNBase)
+	return err == nil && cdn.Host != "" && u.Scheme == cdn.Scheme && strings.EqualFold(u.Host, cdn.Host)
+}
+
+func (a *App) fetchMedia(ctx context.Context, mediaURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, mediaURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch media: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetch media: %s returned %d", mediaURL, resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// mediaExtension maps a sniffed content type to a file extension. Stored
+// videos keep a .webp name on the CDN, so the name can't be trusted.
+func mediaExtension(contentType string) string {
+	switch contentType {
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpg"
+	case "image/webp":
+		return ".webp"
+	case "image/gif":
+		return ".gif"
+	case "video/mp4":
+		return ".mp4"
+	case "video/webm":
+		return ".webm"
+	}
+	return ".bin"
+}
+
+// embedPNGMetadata inserts the metadata after a PNG's header as iTXt chunks:
+// prompt, negative_prompt, model and seed for quick reading, and the whole
+// record as JSON under "generation"
+func embedPNGMetadata(data []byte, meta DownloadMetadata) ([]byte, error) {
+	// The signature, then IHDR: length, type, 13 bytes of data, CRC
+	const ihdrEnd = 8 + 4 + 4 + 13 + 4
+	if len(data) < ihdrEnd || !bytes.Equal(data[:8], pngSignature) || string(data[12:16]) != "IHDR" {
+		return nil, errors.New("not a valid PNG")
+	}
+	record, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	out.Write(data[:ihdrEnd])
+	model := meta.ModelName
+	if model == "" {
+		model = meta.ModelID
+	}
+	for _, text := range [][2]string{
+		{"prompt", meta.Prompt},
+		{"negative_prompt", meta.NegativePrompt},
+		{"model", model},
+		{"seed", meta.Seed},
+		{"generation", string(record)},
+	} {
+		if text[1] != "" {
+			writePNGText(&out, text[0], text[1])
+		}
+	}
+	out.Write(data[ihdrEnd:])
+	return out.Bytes(), nil
+}
+
+// writePNGText writes an uncompressed iTXt chunk, which unlike tEXt holds UTF-8
+func writePNGText(w *bytes.Buffer, keyword, text string) {
+	var chunk bytes.Buffer
+	chunk.WriteString("iTXt")
+	chunk.WriteString(keyword)
+	// Null separator, no compression, then empty language tag and translated keyword
+	chunk.Write([]byte{0, 0, 0, 0, 0})
+	chunk.WriteString(text)
+
+	binary.Write(w, binary.BigEndian, uint32(chunk.Len()-4))
+	w.Write(chunk.Bytes())
+	binary.Write(w, binary.BigEndian, crc32.ChecksumIEEE(chunk.Bytes()))
+}
@@ -0,0 +1,148 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+)
+
+// readPNGText returns a PNG's iTXt chunks by keyword
+func readPNGText(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	texts := make(map[string]string)
+	for pos := 8; pos+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		kind := string(data[pos+4 : pos+8])
+		body := data[pos+8 : pos+8+length]
+		if kind == "iTXt" {
+			keyword, rest, _ := bytes.Cut(body, []byte{0})
+			// Compression flag and method, then language and translated keyword
+			parts := bytes.SplitN(rest[2:], []byte{0}, 3)
+			texts[string(keyword)] = string(parts[2])
+		}
+		pos += 12 + length
+	}
+	return texts
+}
+
+func TestDownloadGalleryMedia(t *testing.T) {
+	var pngFile bytes.Buffer
+	png.Encode(&pngFile, image.NewNRGBA(image.Rect(0, 0, 4, 4)))
+	webpFile := []byte("RIFF\x1a\x00\x00\x00WEBPVP8L\x0d\x00\x00\x00\x2f\x27\x40\x07\x00\x00\x00\x00\x00\x00")
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gen-png.webp":
+			w.Write(pngFile.Bytes())
+		case "/gen-webp.webp":
+			w.Write(webpFile)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer cdn.Close()
+
+	a := newTestApp(t, "")
+	a.cfg.MediaCDNBase = cdn.URL
+	seed := "1234"
+	a.galleryStore.Add(context.Background(), gallery.GalleryItem{
+		JobID:     "job-1",
+		Prompt:    "a lighthouse at dusk, 夕暮れ",
+		ModelName: "FLUX.1-dev",
+		IsPublic:  true,
+		Params:    &gallery.JobParams{Seed: &seed},
+		MediaURLs: []string{"https://images.aipg.art/gen-png.webp", "https://images.aipg.art/gen-webp.webp", "https://elsewhere.example/gen.png"},
+	})
+	a.galleryStore.Add(context.Background(), gallery.GalleryItem{JobID: "job-private", WalletAddress: testAddr("owner"), MediaURLs: []string{"https://images.aipg.art/gen-png.webp"}})
+	download := func(query string) *httptest.ResponseRecorder {
+		return serve(a, httptest.NewRequest(http.MethodGet, "/api/gallery/job-1/download"+query, nil))
+	}
+
+	t.Run("raw by default", func(t *testing.T) {
+		rec := download("")
+		if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), pngFile.Bytes()) {
+			t.Fatalf("status = %d, body changed: %v", rec.Code, !bytes.Equal(rec.Body.Bytes(), pngFile.Bytes()))
+		}
+		if cd := rec.Header().Get("Content-Disposition"); !strings.Contains(cd, "aipg-job-1-0.png") {
+			t.Errorf("Content-Disposition = %q, want the sniffed .png extension", cd)
+		}
+	})
+
+	t.Run("PNG gets text chunks", func(t *testing.T) {
+		rec := download("?metadata=embed")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+		}
+		if _, err := png.Decode(bytes.NewReader(rec.Body.Bytes())); err != nil {
+			t.Fatalf("embedded PNG no longer decodes: %v", err)
+		}
+		texts := readPNGText(t, rec.Body.Bytes())
+		if texts["prompt"] != "a lighthouse at dusk, 夕暮れ" || texts["model"] != "FLUX.1-dev" || texts["seed"] != "1234" {
+			t.Errorf("text chunks = %v", texts)
+		}
+		var meta DownloadMetadata
+		if err := json.Unmarshal([]byte(texts["generation"]), &meta); err != nil || meta.JobID != "job-1" || meta.Params == nil {
+			t.Errorf("generation chunk = %q (%v)", texts["generation"], err)
+		}
+	})
+
+	t.Run("other formats get a sidecar", func(t *testing.T) {
+		rec := download("?metadata=embed&index=1")
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/zip" {
+			t.Fatalf("status = %d, type %q", rec.Code, rec.Header().Get("Content-Type"))
+		}
+		zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files := make(map[string][]byte)
+		for _, f := range zr.File {
+			rc, _ := f.Open()
+			files[f.Name], _ = io.ReadAll(rc)
+			rc.Close()
+		}
+		if !bytes.Equal(files["aipg-job-1-1.webp"], webpFile) {
+			t.Errorf("zip files = %v, want the untouched WebP", len(files))
+		}
+		var meta DownloadMetadata
+		if err := json.Unmarshal(files["aipg-job-1-1.json"], &meta); err != nil || meta.Prompt != "a lighthouse at dusk, 夕暮れ" || meta.Seed != "1234" {
+			t.Errorf("sidecar = %s (%v)", files["aipg-job-1-1.json"], err)
+		}
+	})
+
+	for name, tc := range map[string]struct {
+		path   string
+		wallet string
+		want   int
+	}{
+		"unknown mode":       {"/api/gallery/job-1/download?metadata=exif", "", http.StatusBadRequest},
+		"no such file":       {"/api/gallery/job-1/download?index=9", "", http.StatusNotFound},
+		"foreign host":       {"/api/gallery/job-1/download?index=2", "", http.StatusUnprocessableEntity},
+		"private, not owner": {"/api/gallery/job-private/download", "other", http.StatusNotFound},
+		"private, owner":     {"/api/gallery/job-private/download", "owner", http.StatusOK},
+		"private, unsigned":  {"/api/gallery/job-private/download", "unsigned", http.StatusNotFound},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		switch tc.wallet {
+		case "":
+		case "unsigned":
+			// Naming the owner without their signature
+			req.Header.Set("X-Wallet-Address", testAddr("owner"))
+		default:
+			signAs(t, req, tc.wallet)
+		}
+		if rec := serve(a, req); rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d", name, rec.Code, tc.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+)
+
+// Explore seeds are client-supplied strings; anything longer is refused so the
+// shuffle key stays cheap to compute
+const (
+	exploreSeedBytes  = 8
+	maxExploreSeedLen = 64
+)
+
+// handleExploreGallery serves the public gallery in a random order that holds
+// still for a session. Callers without a seed get a fresh one in the response
+// and send it back as ?seed= for later pages, so paging never repeats or skips
+// items the way a plain random order would.
+func (a *App) handleExploreGallery(w http.ResponseWriter, r *http.Request) {
+	seed := strings.TrimSpace(r.URL.Query().Get("seed"))
+	if len(seed) > maxExploreSeedLen {
+		writeError(w, http.StatusBadRequest, errors.New("seed is too long"))
+		return
+	}
+	if seed == "" {
+		var err error
+		if seed, err = newExploreSeed(); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	a.listGallery(w, r, gallery.ListOptions{Type: r.URL.Query().Get("type"), Seed: seed})
+}
+
+func newExploreSeed() (string, error) {
+	b := make([]byte, exploreSeedBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+)
+
+// handleListFeaturedGallery serves the public items an admin has featured,
+// most recently featured first. It takes the same type, search, model and
+// paging parameters as the main gallery list.
+func (a *App) handleListFeaturedGallery(w http.ResponseWriter, r *http.Request) {
+	a.listGallery(w, r, gallery.ListOptions{Type: r.URL.Query().Get("type"), Featured: true})
+}
+
+// handleSetFeatured features (POST) or unfeatures (DELETE) a gallery item.
+// Only public items can be featured; featuring one again moves it to the
+// front of the featured list.
+func (a *App) handleSetFeatured(featured bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jobID := chi.URLParam(r, "id")
+		item := a.galleryStore.Get(r.Context(), jobID)
+		if item == nil {
+			writeError(w, http.StatusNotFound, errors.New("gallery item not found"))
+			return
+		}
+		if featured && !item.IsPublic {
+			writeError(w, http.StatusConflict, fmt.Errorf("gallery item %s is private; only public items can be featured", jobID))
+			return
+		}
+
+		if err := a.galleryStore.SetFeatured(r.Context(), jobID, featured); err != nil {
+			if errors.Is(err, gallery.ErrItemNotFound) {
+				writeError(w, http.StatusNotFound, errors.New("gallery item not found"))
+				return
+			}
+			writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to update featured flag: %w", err))
+			return
+		}
+
+		log.Printf("Gallery: set featured=%t on job %s", featured, jobID)
+		writeJSON(w, http.StatusOK, map[string]any{
+			"jobId":    jobID,
+			"featured": featured,
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+)
+
+func TestFeaturedGallery(t *testing.T) {
+	a := newTestApp(t, "")
+	a.cfg.AdminToken = "s3cret"
+	for _, item := range []gallery.GalleryItem{
+		{JobID: "fox", Prompt: "a red fox", Type: "image", IsPublic: true},
+		{JobID: "owl", Prompt: "an owl", Type: "image", IsPublic: true},
+		{JobID: "river", Prompt: "a river", Type: "video", IsPublic: true},
+		{JobID: "private", Prompt: "a secret", Type: "image"},
+	} {
+		a.galleryStore.Add(context.Background(), item)
+	}
+
+	setFeatured := func(method, jobID, auth string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, "/api/admin/gallery/"+jobID+"/featured", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", "Bearer "+auth)
+		}
+		// FeaturedAt has millisecond resolution; keep each feature distinct
+		time.Sleep(2 * time.Millisecond)
+		return serve(a, req)
+	}
+	featured := func(query string) []string {
+		t.Helper()
+		rec := serve(a, httptest.NewRequest(http.MethodGet, "/api/gallery/featured"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET featured%s = %d: %s", query, rec.Code, rec.Body.String())
+		}
+		var result gallery.ListResult
+		if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		ids := make([]string, len(result.Items))
+		for i, item := range result.Items {
+			if !item.Featured || item.FeaturedAt == 0 {
+				t.Errorf("featured item %s = %+v, want featured with a timestamp", item.JobID, item)
+			}
+			ids[i] = item.JobID
+		}
+		return ids
+	}
+
+	if rec := setFeatured(http.MethodPost, "fox", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("feature without token = %d, want 401", rec.Code)
+	}
+	if got := featured(""); len(got) != 0 {
+		t.Errorf("featured before any picks = %v, want none", got)
+	}
+
+	for _, id := range []string{"fox", "river", "owl"} {
+		if rec := setFeatured(http.MethodPost, id, "s3cret"); rec.Code != http.StatusOK {
+			t.Fatalf("feature %s = %d: %s", id, rec.Code, rec.Body.String())
+		}
+	}
+	if got, want := strings.Join(featured(""), ","), "owl,river,fox"; got != want {
+		t.Errorf("featured = [%s], want most recent first [%s]", got, want)
+	}
+	if got, want := strings.Join(featured("?type=image"), ","), "owl,fox"; got != want {
+		t.Errorf("featured images = [%s], want [%s]", got, want)
+	}
+
+	// Featuring again moves an item to the front; unfeaturing drops it
+	setFeatured(http.MethodPost, "fox", "s3cret")
+	if rec := setFeatured(http.MethodDelete, "river", "s3cret"); rec.Code != http.StatusOK {
+		t.Fatalf("unfeature = %d: %s", rec.Code, rec.Body.String())
+	}
+	if got, want := strings.Join(featured(""), ","), "fox,owl"; got != want {
+		t.Errorf("featured after changes = [%s], want [%s]", got, want)
+	}
+	if item := a.galleryStore.Get(context.Background(), "river"); item.Featured || item.FeaturedAt != 0 {
+		t.Errorf("unfeatured item = %+v, want flag and timestamp cleared", item)
+	}
+
+	if rec := setFeatured(http.MethodPost, "private", "s3cret"); rec.Code != http.StatusConflict {
+		t.Errorf("feature private item = %d, want 409", rec.Code)
+	}
+	if rec := setFeatured(http.MethodPost, "missing", "s3cret"); rec.Code != http.StatusNotFound {
+		t.Errorf("feature missing item = %d, want 404", rec.Code)
+	}
+
+	// An item made private after featuring drops out of the public list
+	a.galleryStore.Add(context.Background(), gallery.GalleryItem{JobID: "owl", Prompt: "an owl", Type: "image"})
+	if got, want := strings.Join(featured(""), ","), "fox"; got != want {
+		t.Errorf("featured after owl went private = [%s], want [%s]", got, want)
+	}
+}
@@ -0,0 +1,38 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+)
+
+type galleryPageLimits struct {
+	fallback int
+	max      int
+}
+
+// Page sizes per gallery feed. Videos are much heavier to load than images,
+// so their pages are smaller.
+var (
+	defaultGalleryPageLimits = galleryPageLimits{fallback: 25, max: 100}
+	galleryFeedPageLimits    = map[string]galleryPageLimits{
+		gallery.TypeImage: defaultGalleryPageLimits,
+		gallery.TypeVideo: {fallback: 12, max: 48},
+	}
+)
+
+// galleryPageSize returns the page limits for a type filter; the mixed feed uses the defaults
+func galleryPageSize(typeFilter string) galleryPageLimits {
+	if limits, ok := galleryFeedPageLimits[typeFilter]; ok {
+		return limits
+	}
+	return defaultGalleryPageLimits
+}
+
+// handleListGalleryFeed serves the gallery for one item type. Each feed pages
+// on its own, so offset and nextOffset only count items of that type.
+func (a *App) handleListGalleryFeed(itemType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		a.listGallery(w, r, gallery.ListOptions{Type: itemType, Seed: r.URL.Query().Get("seed")})
+	}
+}
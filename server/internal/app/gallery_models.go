@@ -0,0 +1,95 @@
+package app
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+)
+
+// galleryModelsTTL is how long the distinct-models listing is reused; new
+// items only need to show up in the filter dropdown eventually
+const galleryModelsTTL = 30 * time.Second
+
+// galleryModelCounts loads the models used in the public gallery, counting
+// NSFW items only when includeNSFW is set, through that view's cache when set
+func (a *App) galleryModelCounts(ctx context.Context, includeNSFW bool) ([]gallery.ModelCount, error) {
+	load := func(ctx context.Context) ([]gallery.ModelCount, error) {
+		return a.galleryStore.ModelCounts(ctx, includeNSFW)
+	}
+	cached := a.galleryModels
+	if includeNSFW {
+		cached = a.galleryModelsNSFW
+	}
+	if cached == nil {
+		return load(ctx)
+	}
+	counts, stale, err := cached.Get(ctx, load)
+	if stale {
+		log.Printf("Warning: gallery model counts unavailable, serving cached counts")
+	}
+	return counts, err
+}
+
+// handleListGalleryModels returns the distinct models in public gallery items
+// with their item counts, most used first, for the gallery's model filter.
+// NSFW items are counted under the same rules as the gallery list.
+func (a *App) handleListGalleryModels(w http.ResponseWriter, r *http.Request) {
+	includeNSFW, err := a.galleryIncludeNSFW(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	counts, err := a.galleryModelCounts(r.Context(), includeNSFW)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if counts == nil {
+		counts = []gallery.ModelCount{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"models": counts,
+		"count":  len(counts),
+	})
+}
+
+// resolveGalleryModelID maps a model name stored on a gallery item to its
+// preset ID, matching preset IDs, display names, aliases and Grid names
+func (a *App) resolveGalleryModelID(name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", false
+	}
+	aliases := modelAliases()
+	for _, preset := range a.catalog.List() {
+		candidates := append([]string{preset.ID, preset.DisplayName, getGridModelName(preset.ID)}, aliases[preset.ID]...)
+		for _, candidate := range candidates {
+			if strings.EqualFold(candidate, name) {
+				return preset.ID, true
+			}
+		}
+	}
+	return "", false
+}
+
+// backfillGalleryModelIDs gives items saved before model IDs were stored the
+// preset ID their model name resolves to, where it resolves to one
+func (a *App) backfillGalleryModelIDs() {
+	pg, ok := a.galleryStore.(*gallery.PostgresStore)
+	if !ok {
+		return
+	}
+	updated, err := pg.BackfillModelIDs(context.Background(), a.resolveGalleryModelID)
+	if err != nil {
+		log.Printf("Warning: gallery model ID backfill failed: %v", err)
+		return
+	}
+	if updated > 0 {
+		log.Printf("Gallery: backfilled model IDs on %d items", updated)
+	}
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+)
+
+// galleryStreamFlushEvery is how many items are written between flushes
+const galleryStreamFlushEvery = 25
+
+// streamGalleryList writes the gallery.ListResult envelope while the store is
+// still scanning, so memory stays flat however large the page. Items come
+// first and total, hasMore, nextOffset and any shuffle seed close the object
+// once the page is done; X-Total-Count carries the total up front as well.
+func streamGalleryList(ctx context.Context, w http.ResponseWriter, store gallery.ListStreamer, opts gallery.ListOptions) {
+	flusher := http.NewResponseController(w)
+	enc := json.NewEncoder(w)
+	started := false
+	begin := func(total int) error {
+		started = true
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Total-Count", strconv.Itoa(total))
+		w.WriteHeader(http.StatusOK)
+		_, err := io.WriteString(w, `{"items":[`)
+		return err
+	}
+
+	count := 0
+	total, err := store.StreamList(ctx, opts, func(total int, item gallery.GalleryItem) error {
+		if !started {
+			if err := begin(total); err != nil {
+				return err
+			}
+		}
+		if count > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+		count++
+		if count%galleryStreamFlushEvery == 0 {
+			// Not every writer can flush; the data still goes out at the end
+			_ = flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		// Same as List: log and return whatever was read
+		log.Printf("Error streaming gallery items: %v", err)
+	}
+	if !started {
+		if err := begin(total); err != nil {
+			return
+		}
+	}
+
+	next := opts.Offset + count
+	fmt.Fprintf(w, `],"total":%d,"hasMore":%t,"nextOffset":%d`, total, next < total, next)
+	if opts.Seed != "" {
+		seed, _ := json.Marshal(opts.Seed)
+		fmt.Fprintf(w, `,"seed":%s`, seed)
+	}
+	io.WriteString(w, "}\n")
+}
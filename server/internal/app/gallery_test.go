@@ -0,0 +1,582 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/cache"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/models"
+)
+
+func TestUpdateGalleryItem(t *testing.T) {
+	seed := "42"
+	newApp := func(t *testing.T) *App {
+		a := newTestApp(t, "http://grid.invalid")
+		a.galleryStore.Add(context.Background(), gallery.GalleryItem{
+			JobID:         "job-1",
+			Prompt:        "a catt on a mat",
+			IsPublic:      true,
+			WalletAddress: "0x" + strings.ToUpper(testAddr("owner")[2:]),
+			MediaURLs:     []string{"https://images.aipg.art/gen-1.webp"},
+			Params:        &gallery.JobParams{Seed: &seed},
+		})
+		return a
+	}
+	patch := func(a *App, wallet, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/api/gallery/job-1", strings.NewReader(body))
+		switch wallet {
+		case "":
+		case "unsigned":
+			req.Header.Set("X-Wallet-Address", testAddr("owner"))
+		default:
+			signAs(t, req, wallet)
+		}
+		return serve(a, req)
+	}
+
+	t.Run("owner edits prompt and nsfw flag", func(t *testing.T) {
+		a := newApp(t)
+		rec := patch(a, "owner", `{"prompt":"  a cat on a mat ","isNsfw":true}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+		var item gallery.GalleryItem
+		if err := json.NewDecoder(rec.Body).Decode(&item); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if item.Prompt != "a cat on a mat" || !item.IsNSFW {
+			t.Errorf("item = %+v, want corrected prompt and isNsfw", item)
+		}
+		if item.EditedAt == 0 {
+			t.Error("editedAt not recorded")
+		}
+		if len(item.MediaURLs) != 1 || item.Params == nil || *item.Params.Seed != seed {
+			t.Errorf("immutable fields changed: %+v", item)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		wallet string
+		body   string
+		want   int
+	}{
+		{name: "non-owner", wallet: "someoneelse", body: `{"prompt":"mine now"}`, want: http.StatusForbidden},
+		{name: "no wallet", body: `{"prompt":"anon"}`, want: http.StatusUnauthorized},
+		{name: "unsigned owner header", wallet: "unsigned", body: `{"prompt":"spoofed"}`, want: http.StatusUnauthorized},
+		{name: "immutable field", wallet: "owner", body: `{"params":{"seed":"1"}}`, want: http.StatusBadRequest},
+		{name: "empty prompt", wallet: "owner", body: `{"prompt":"   "}`, want: http.StatusBadRequest},
+		{name: "nothing to update", wallet: "owner", body: `{}`, want: http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := newApp(t)
+			rec := patch(a, tc.wallet, tc.body)
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tc.want, rec.Body.String())
+			}
+			if item := a.galleryStore.Get(context.Background(), "job-1"); item.Prompt != "a catt on a mat" || item.EditedAt != 0 {
+				t.Errorf("item modified by rejected edit: %+v", item)
+			}
+		})
+	}
+}
+
+func TestListByWalletCursor(t *testing.T) {
+	a := newTestApp(t, "")
+	for i, id := range []string{"a", "b", "c"} {
+		a.galleryStore.Add(context.Background(), gallery.GalleryItem{JobID: id, WalletAddress: "0xowner", CreatedAt: int64(100 + i)})
+	}
+
+	rec := serve(a, httptest.NewRequest(http.MethodGet, "/api/gallery/wallet/0xowner?limit=2", nil))
+	var first struct {
+		Items      []gallery.GalleryItem `json:"items"`
+		NextCursor string                `json:"nextCursor"`
+	}
+	json.NewDecoder(rec.Body).Decode(&first)
+	if len(first.Items) != 2 || first.Items[0].JobID != "c" || first.NextCursor == "" {
+		t.Fatalf("first page = %+v", first)
+	}
+
+	rec = serve(a, httptest.NewRequest(http.MethodGet, "/api/gallery/wallet/0xowner?limit=2&beforeCursor="+first.NextCursor, nil))
+	var second struct {
+		Items      []gallery.GalleryItem `json:"items"`
+		NextCursor string                `json:"nextCursor"`
+	}
+	json.NewDecoder(rec.Body).Decode(&second)
+	if len(second.Items) != 1 || second.Items[0].JobID != "a" || second.NextCursor != "" {
+		t.Errorf("second page = %+v", second)
+	}
+
+	rec = serve(a, httptest.NewRequest(http.MethodGet, "/api/gallery/wallet/0xowner?beforeCursor=nope", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid cursor status = %d, want 400", rec.Code)
+	}
+}
+
+func TestListByBlankWalletRejected(t *testing.T) {
+	a := newTestApp(t, "")
+	a.galleryStore.Add(context.Background(), gallery.GalleryItem{JobID: "anon-1", IsPublic: true})
+
+	rec := serve(a, httptest.NewRequest(http.MethodGet, "/api/gallery/wallet/%20%20", nil))
+	if rec.Code != http.StatusBadRequest || strings.Contains(rec.Body.String(), "anon-1") {
+		t.Errorf("blank wallet: status = %d, body = %s; want 400", rec.Code, rec.Body)
+	}
+}
+
+func TestListEndpointsRejectBadPaging(t *testing.T) {
+	a := newTestApp(t, "")
+
+	for _, path := range []string{
+		"/api/gallery?limit=abc",
+		"/api/gallery?limit=-5",
+		"/api/gallery?limit=0",
+		"/api/gallery?offset=-1",
+		"/api/gallery?offset=ten",
+		"/api/gallery?q=fox&searchFields=prompt,wallet",
+		"/api/gallery/wallet/0xabc?limit=1.5",
+	} {
+		rec := serve(a, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s = %d, want 400", path, rec.Code)
+			continue
+		}
+		if !strings.Contains(rec.Body.String(), "must be a") {
+			t.Errorf("GET %s error = %s, want it to name the bad parameter", path, rec.Body.String())
+		}
+	}
+
+	for _, path := range []string{"/api/gallery", "/api/gallery?limit=500&offset=0", "/api/gallery/wallet/0xabc?limit=10"} {
+		if rec := serve(a, httptest.NewRequest(http.MethodGet, path, nil)); rec.Code != http.StatusOK {
+			t.Errorf("GET %s = %d, want 200", path, rec.Code)
+		}
+	}
+}
+
+func TestListGalleryByModel(t *testing.T) {
+	a := newTestApp(t, "")
+	a.catalog = models.NewCatalog([]models.ModelPreset{{ID: "FLUX.1-dev", DisplayName: "FLUX.1 Dev", Type: "image"}})
+	for _, item := range []gallery.GalleryItem{
+		{JobID: "preset-id", ModelID: "FLUX.1-dev", Prompt: "a red fox", Type: "image", IsPublic: true},
+		{JobID: "grid-alias", ModelName: "flux1_dev", Prompt: "a red barn", Type: "image", IsPublic: true},
+		{JobID: "display-name", ModelName: "FLUX.1 Dev", Prompt: "a blue fox", Type: "image", IsPublic: true},
+		{JobID: "other-model", ModelID: "Chroma", Prompt: "a red fox", Type: "image", IsPublic: true},
+		{JobID: "video", ModelID: "flux.1-dev", Prompt: "a red fox", Type: "video", IsPublic: true},
+	} {
+		a.galleryStore.Add(context.Background(), item)
+	}
+
+	list := func(query string) []string {
+		t.Helper()
+		rec := serve(a, httptest.NewRequest(http.MethodGet, "/api/gallery?"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET ?%s = %d: %s", query, rec.Code, rec.Body.String())
+		}
+		var result gallery.ListResult
+		if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		ids := make([]string, len(result.Items))
+		for i, item := range result.Items {
+			ids[i] = item.JobID
+		}
+		return ids
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "model=flux1-dev", want: "video,display-name,grid-alias,preset-id"},
+		{query: "model=FLUX.1%20Dev&type=image", want: "display-name,grid-alias,preset-id"},
+		{query: "model=flux.1-dev&type=image&q=red", want: "grid-alias,preset-id"},
+		{query: "model=CHROMA", want: "other-model"},
+		{query: "q=chroma", want: ""},
+		{query: "q=chroma&searchFields=prompt,model", want: "other-model"},
+		{query: "model=unknown-model", want: ""},
+	}
+	for _, tc := range tests {
+		if got := strings.Join(list(tc.query), ","); got != tc.want {
+			t.Errorf("GET ?%s = [%s], want [%s]", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestListGalleryModels(t *testing.T) {
+	a := newTestApp(t, "")
+	a.galleryModels = cache.New[[]gallery.ModelCount](time.Minute)
+
+	get := func(query ...string) []gallery.ModelCount {
+		t.Helper()
+		rec := serve(a, httptest.NewRequest(http.MethodGet, "/api/gallery/models"+strings.Join(query, ""), nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+		}
+		var body struct {
+			Models []gallery.ModelCount `json:"models"`
+			Count  int                  `json:"count"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if body.Models == nil || body.Count != len(body.Models) {
+			t.Fatalf("body = %+v, want a non-null list and matching count", body)
+		}
+		return body.Models
+	}
+
+	if models := get("?includeNsfw=false"); len(models) != 0 {
+		t.Fatalf("empty gallery = %+v, want no models", models)
+	}
+
+	for i, item := range []gallery.GalleryItem{
+		{ModelName: "Chroma", IsPublic: true},
+		{ModelName: "FLUX.1-dev", IsPublic: true},
+		{ModelName: "FLUX.1-dev", IsPublic: true},
+		{ModelID: "SDXL 1.0", IsPublic: true},
+		{ModelName: "SDXL 1.0", IsPublic: true},
+		{ModelName: "SDXL 1.0", IsPublic: true},
+		{ModelName: "SDXL 1.0", IsPublic: false},
+		{ModelName: "ltxv", IsPublic: false},
+		{ModelName: "Chroma", IsPublic: true, IsNSFW: true},
+		{ModelName: "Pony", IsPublic: true, IsNSFW: true},
+		{IsPublic: true},
+	} {
+		item.JobID = fmt.Sprintf("job-%d", i)
+		a.galleryStore.Add(context.Background(), item)
+	}
+	if models := get("?includeNsfw=false"); len(models) != 0 {
+		t.Fatalf("models = %+v, want the cached empty listing until it expires", models)
+	}
+	a.galleryModels.Invalidate()
+
+	want := []gallery.ModelCount{{Model: "SDXL 1.0", Count: 3}, {Model: "FLUX.1-dev", Count: 2}, {Model: "Chroma", Count: 1}}
+	if got := get("?includeNsfw=false"); !reflect.DeepEqual(got, want) {
+		t.Errorf("models = %+v, want %+v without NSFW items", got, want)
+	}
+
+	// NSFW items count when the listing would show them, as it does by default
+	want = []gallery.ModelCount{{Model: "SDXL 1.0", Count: 3}, {Model: "Chroma", Count: 2}, {Model: "FLUX.1-dev", Count: 2}, {Model: "Pony", Count: 1}}
+	for _, query := range []string{"", "?includeNsfw=true"} {
+		if got := get(query); !reflect.DeepEqual(got, want) {
+			t.Errorf("models with NSFW (%q) = %+v, want %+v", query, got, want)
+		}
+	}
+	if rec := serve(a, httptest.NewRequest(http.MethodGet, "/api/gallery/models?includeNsfw=maybe", nil)); rec.Code != http.StatusBadRequest {
+		t.Errorf("includeNsfw=maybe status = %d, want 400", rec.Code)
+	}
+}
+
+func TestListGalleryStreamsSameItems(t *testing.T) {
+	a := newTestApp(t, "http://grid.invalid")
+	for i := 0; i < 60; i++ {
+		a.galleryStore.Add(context.Background(), gallery.GalleryItem{
+			JobID:     fmt.Sprintf("job-%02d", i),
+			Prompt:    fmt.Sprintf("prompt %d", i),
+			Type:      "image",
+			IsPublic:  i%7 != 0,
+			MediaURLs: []string{fmt.Sprintf("https://images.aipg.art/%d.webp", i)},
+			CreatedAt: int64(i),
+		})
+	}
+
+	for _, opts := range []gallery.ListOptions{
+		{Limit: 100},
+		{Limit: 20, Offset: 10},
+		{Limit: 20, Offset: 500},
+	} {
+		want := a.galleryStore.List(context.Background(), opts)
+
+		url := fmt.Sprintf("/api/gallery?limit=%d&offset=%d", opts.Limit, opts.Offset)
+		rec := serve(a, httptest.NewRequest(http.MethodGet, url, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d", url, rec.Code)
+		}
+		if got := rec.Header().Get("X-Total-Count"); got != fmt.Sprint(want.Total) {
+			t.Errorf("%s: X-Total-Count = %s, want %d", url, got, want.Total)
+		}
+		var got gallery.ListResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("%s: streamed body doesn't decode: %v\n%s", url, err, rec.Body.String())
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: streamed %+v\nwant %+v", url, got, want)
+		}
+	}
+}
+
+func TestListGalleryPagesNewestFirst(t *testing.T) {
+	a := newTestApp(t, "http://grid.invalid")
+	for i := 0; i < 23; i++ {
+		a.galleryStore.Add(context.Background(), gallery.GalleryItem{JobID: fmt.Sprintf("job-%02d", i), Type: "image", IsPublic: true, CreatedAt: int64(i + 1)})
+	}
+
+	var ids []string
+	for offset, pages := 0, 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("paging never ended")
+		}
+		rec := serve(a, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/gallery?limit=10&offset=%d", offset), nil))
+		var page gallery.ListResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatalf("offset %d: %v", offset, err)
+		}
+		if page.Total != 23 {
+			t.Errorf("offset %d: total = %d, want 23", offset, page.Total)
+		}
+		for _, item := range page.Items {
+			ids = append(ids, item.JobID)
+		}
+		if !page.HasMore {
+			break
+		}
+		offset = page.NextOffset
+	}
+
+	if len(ids) != 23 {
+		t.Fatalf("paged through %d items, want 23", len(ids))
+	}
+	for i, id := range ids {
+		if want := fmt.Sprintf("job-%02d", 22-i); id != want {
+			t.Errorf("item %d = %s, want %s (newest first)", i, id, want)
+		}
+	}
+}
+
+func TestGalleryTypeFeeds(t *testing.T) {
+	a := newTestApp(t, "http://grid.invalid")
+	for i := 0; i < 30; i++ {
+		item := gallery.GalleryItem{
+			JobID:     fmt.Sprintf("image-%02d", i),
+			Type:      "image",
+			IsPublic:  true,
+			MediaURLs: []string{fmt.Sprintf("https://images.aipg.art/%d.webp", i)},
+		}
+		if i < 15 {
+			// Saved without a type; the .mp4 media makes it a video
+			item.JobID = fmt.Sprintf("video-%02d", i)
+			item.Type = ""
+			item.MediaURLs = []string{fmt.Sprintf("https://images.aipg.art/%d.mp4", i)}
+		}
+		a.galleryStore.Add(context.Background(), item)
+	}
+
+	feed := func(url string) gallery.ListResult {
+		t.Helper()
+		rec := serve(a, httptest.NewRequest(http.MethodGet, url, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d", url, rec.Code)
+		}
+		var result gallery.ListResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatalf("%s: %v", url, err)
+		}
+		return result
+	}
+
+	videos := feed("/api/gallery/videos")
+	if videos.Total != 15 || len(videos.Items) != 12 || !videos.HasMore || videos.NextOffset != 12 {
+		t.Errorf("videos: total=%d items=%d hasMore=%v next=%d, want 15/12/true/12", videos.Total, len(videos.Items), videos.HasMore, videos.NextOffset)
+	}
+	for _, item := range videos.Items {
+		if item.Type != gallery.TypeVideo {
+			t.Errorf("video feed returned %s (%s)", item.JobID, item.Type)
+		}
+	}
+	if rest := feed("/api/gallery/videos?offset=12&limit=500"); len(rest.Items) != 3 || rest.HasMore {
+		t.Errorf("videos page 2: %d items, hasMore=%v", len(rest.Items), rest.HasMore)
+	}
+
+	images := feed("/api/gallery/images?limit=100")
+	if images.Total != 15 || len(images.Items) != 15 || images.HasMore {
+		t.Errorf("images: total=%d items=%d hasMore=%v", images.Total, len(images.Items), images.HasMore)
+	}
+	for _, item := range images.Items {
+		if item.Type != gallery.TypeImage {
+			t.Errorf("image feed returned %s (%s)", item.JobID, item.Type)
+		}
+	}
+
+	if mixed := feed("/api/gallery?limit=100"); mixed.Total != 30 {
+		t.Errorf("mixed feed total = %d, want 30", mixed.Total)
+	}
+}
+
+func TestResolveGalleryModelID(t *testing.T) {
+	a := newTestApp(t, "http://grid.invalid")
+	a.catalog = models.NewCatalog([]models.ModelPreset{{ID: "FLUX.1-dev", DisplayName: "Flux Dev", Type: "image"}})
+
+	for name, want := range map[string]string{
+		"FLUX.1-dev": "FLUX.1-dev",
+		"flux dev":   "FLUX.1-dev",
+		"unknown":    "",
+		"":           "",
+	} {
+		got, ok := a.resolveGalleryModelID(name)
+		if got != want || ok != (want != "") {
+			t.Errorf("resolveGalleryModelID(%q) = %q, %v; want %q", name, got, ok, want)
+		}
+	}
+}
+
+func TestExploreSeedPagination(t *testing.T) {
+	a := newTestApp(t, "http://grid.invalid")
+	for i := 0; i < 40; i++ {
+		a.galleryStore.Add(context.Background(), gallery.GalleryItem{
+			JobID:     fmt.Sprintf("explore-%02d", i),
+			Type:      gallery.TypeImage,
+			IsPublic:  true,
+			MediaURLs: []string{fmt.Sprintf("https://images.aipg.art/%d.webp", i)},
+		})
+	}
+
+	page := func(url string) gallery.ListResult {
+		t.Helper()
+		rec := serve(a, httptest.NewRequest(http.MethodGet, url, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", url, rec.Code, rec.Body)
+		}
+		var result gallery.ListResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatalf("%s: %v", url, err)
+		}
+		return result
+	}
+	ids := func(items []gallery.GalleryItem) []string {
+		out := make([]string, len(items))
+		for i, item := range items {
+			out[i] = item.JobID
+		}
+		return out
+	}
+
+	first := page("/api/gallery/explore?limit=15")
+	if first.Seed == "" {
+		t.Fatal("explore without a seed should issue one")
+	}
+	seen := make(map[string]bool)
+	var order []string
+	for offset := 0; ; {
+		result := page(fmt.Sprintf("/api/gallery/explore?seed=%s&limit=15&offset=%d", first.Seed, offset))
+		if result.Seed != first.Seed {
+			t.Fatalf("seed = %q, want %q", result.Seed, first.Seed)
+		}
+		for _, id := range ids(result.Items) {
+			if seen[id] {
+				t.Errorf("%s returned on more than one page", id)
+			}
+			seen[id] = true
+			order = append(order, id)
+		}
+		if !result.HasMore {
+			break
+		}
+		offset = result.NextOffset
+	}
+	if len(seen) != 40 {
+		t.Errorf("paged through %d items, want 40", len(seen))
+	}
+	if got := ids(first.Items); !reflect.DeepEqual(got, order[:15]) {
+		t.Errorf("first page changed between requests: %v vs %v", got, order[:15])
+	}
+
+	again := page("/api/gallery/explore?seed=" + first.Seed + "&limit=40")
+	if !reflect.DeepEqual(ids(again.Items), order) {
+		t.Error("same seed gave a different order")
+	}
+	other := page("/api/gallery/explore?seed=another-session&limit=40")
+	if reflect.DeepEqual(ids(other.Items), order) {
+		t.Error("different seeds gave the same order")
+	}
+
+	rec := serve(a, httptest.NewRequest(http.MethodGet, "/api/gallery/explore?seed="+strings.Repeat("x", 65), nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("overlong seed: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestGalleryResponseTimestamps(t *testing.T) {
+	a := newTestApp(t, "http://grid.invalid")
+	created := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+	a.galleryStore.Add(context.Background(), gallery.GalleryItem{JobID: "job-1", IsPublic: true, WalletAddress: "0xabc", CreatedAt: created.UnixMilli()})
+
+	for _, path := range []string{"/api/gallery", "/api/gallery/images", "/api/gallery/wallet/0xabc", "/api/gallery/job-1"} {
+		rec := serve(a, httptest.NewRequest(http.MethodGet, path, nil))
+		var body struct {
+			Items     []map[string]any `json:"items"`
+			CreatedAt any              `json:"createdAt"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		got := body.CreatedAt
+		if len(body.Items) > 0 {
+			got = body.Items[0]["createdAt"]
+		}
+		if got != "2025-03-04T05:06:07Z" {
+			t.Errorf("%s: createdAt = %v (status %d), want RFC3339", path, got, rec.Code)
+		}
+	}
+}
+
+func TestAddToGalleryKeepsGridParams(t *testing.T) {
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"id":"job-wave"}`))
+	}))
+	defer grid.Close()
+
+	a := newTestApp(t, grid.URL)
+	a.catalog = models.NewCatalog(testPresets)
+	a.jobRequests = newMemoryJobRequestStore()
+
+	job := `{"modelId":"FLUX.1-dev","prompt":"a wave","params":{"steps":12,"seed":"42"}}`
+	if rec := serve(a, httptest.NewRequest(http.MethodPost, "/api/jobs", strings.NewReader(job))); rec.Code != http.StatusAccepted {
+		t.Fatalf("create status = %d: %s", rec.Code, rec.Body.String())
+	}
+	add := `{"jobId":"job-wave","prompt":"a wave","type":"image","isPublic":true}`
+	if rec := serve(a, httptest.NewRequest(http.MethodPost, "/api/gallery", strings.NewReader(add))); rec.Code != http.StatusOK {
+		t.Fatalf("add status = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec := serve(a, httptest.NewRequest(http.MethodGet, "/api/gallery/job-wave", nil))
+	var item struct {
+		GridParams map[string]any `json:"gridParams"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&item); err != nil {
+		t.Fatal(err)
+	}
+	if item.GridParams["steps"] != float64(12) || item.GridParams["seed"] != "42" || item.GridParams["sampler_name"] == nil {
+		t.Errorf("gridParams = %v, want the params the job was submitted with", item.GridParams)
+	}
+}
+
+func TestAddToGalleryReAddNeedsOwner(t *testing.T) {
+	a := newTestApp(t, "")
+	add := func(wallet string, signed bool) int {
+		body := `{"jobId":"job-1","prompt":"a cat","type":"image","isPublic":true,"walletAddress":"` + testAddr(wallet) + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/gallery", strings.NewReader(body))
+		if signed {
+			signAs(t, req, wallet)
+		}
+		return serve(a, req).Code
+	}
+	if code := add("owner", true); code != http.StatusOK {
+		t.Fatalf("first add: status = %d", code)
+	}
+	if code := add("owner", true); code != http.StatusOK {
+		t.Errorf("owner re-add: status = %d, want 200", code)
+	}
+	if code := add("other", true); code != http.StatusForbidden {
+		t.Errorf("re-add by another wallet: status = %d, want 403", code)
+	}
+	if code := add("owner", false); code != http.StatusUnauthorized {
+		t.Errorf("unsigned save under a wallet: status = %d, want 401", code)
+	}
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/aipg"
+)
+
+// Health check states. A "warn" check is reported but keeps the server ready;
+// a "fail" check makes /health/ready return 503.
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// HealthCheck is the latest result of one readiness sub-check
+type HealthCheck struct {
+	Status    string    `json:"status"`
+	Message   string    `json:"message,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+	// Check-specific data, e.g. which models a sync check found missing
+	Details any `json:"details,omitempty"`
+}
+
+// healthChecks holds readiness sub-check results reported by background checks
+type healthChecks struct {
+	mu     sync.RWMutex
+	checks map[string]HealthCheck
+}
+
+func newHealthChecks() *healthChecks {
+	return &healthChecks{checks: make(map[string]HealthCheck)}
+}
+
+// Set records the result of a named check
+func (h *healthChecks) Set(name, status, message string) {
+	h.SetDetails(name, status, message, nil)
+}
+
+// SetDetails records the result of a named check along with its details
+func (h *healthChecks) SetDetails(name, status, message string, details any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[name] = HealthCheck{Status: status, Message: message, CheckedAt: time.Now(), Details: details}
+}
+
+// Snapshot returns a copy of all check results
+func (h *healthChecks) Snapshot() map[string]HealthCheck {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	out := make(map[string]HealthCheck, len(h.checks))
+	for name, check := range h.checks {
+		out[name] = check
+	}
+	return out
+}
+
+// checkDefaultAPIKey verifies AIPG_API_KEY against the Grid's user endpoint
+// so a wrong key in the environment shows up at deploy time instead of as
+// confusing per-job failures. The result is never fatal.
+func (a *App) checkDefaultAPIKey(ctx context.Context) {
+	if a.cfg.DefaultAPIKey == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	user, err := a.client.FindUser(ctx, a.cfg.DefaultAPIKey)
+	switch {
+	case errors.Is(err, aipg.ErrInvalidAPIKey):
+		log.Printf("⚠️  AIPG_API_KEY was rejected by the Grid - jobs without a user-supplied key will fail: %v", err)
+		a.health.Set("defaultApiKey", checkWarn, "AIPG_API_KEY was rejected by the Grid")
+	case err != nil:
+		log.Printf("Warning: could not verify AIPG_API_KEY: %v", err)
+		a.health.Set("defaultApiKey", checkWarn, "could not verify AIPG_API_KEY: "+err.Error())
+	default:
+		log.Printf("AIPG_API_KEY verified for Grid user %s", user.Username)
+		a.health.Set("defaultApiKey", checkOK, "")
+	}
+}
+
+// handleReady reports readiness along with the result of each sub-check
+func (a *App) handleReady(w http.ResponseWriter, r *http.Request) {
+	checks := a.health.Snapshot()
+
+	status, code := "ready", http.StatusOK
+	for _, check := range checks {
+		switch check.Status {
+		case checkFail:
+			status, code = "not_ready", http.StatusServiceUnavailable
+		case checkWarn:
+			if code == http.StatusOK {
+				status = "degraded"
+			}
+		}
+	}
+
+	writeJSON(w, code, map[string]any{
+		"status": status,
+		"checks": checks,
+	})
+}
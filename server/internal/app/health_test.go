@@ -0,0 +1,118 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/models"
+)
+
+func TestDefaultAPIKeyCheckInReadiness(t *testing.T) {
+	tests := []struct {
+		name       string
+		userStatus int
+		wantCheck  string
+		wantReady  string
+	}{
+		{name: "valid key", userStatus: http.StatusOK, wantCheck: checkOK, wantReady: "ready"},
+		{name: "rejected key", userStatus: http.StatusNotFound, wantCheck: checkWarn, wantReady: "degraded"},
+		{name: "grid unavailable", userStatus: http.StatusBadGateway, wantCheck: checkWarn, wantReady: "degraded"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("apikey") != "test-key" {
+					t.Errorf("apikey header = %q", r.Header.Get("apikey"))
+				}
+				w.WriteHeader(tc.userStatus)
+				w.Write([]byte(`{"id":1,"username":"ops#1","kudos":10}`))
+			}))
+			defer grid.Close()
+
+			a := newTestApp(t, grid.URL)
+			a.checkDefaultAPIKey(context.Background())
+
+			// Readiness never fails on the key check - it only degrades
+			rec := serve(a, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", rec.Code)
+			}
+			var resp struct {
+				Status string                 `json:"status"`
+				Checks map[string]HealthCheck `json:"checks"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if resp.Status != tc.wantReady {
+				t.Errorf("status = %q, want %q", resp.Status, tc.wantReady)
+			}
+			if got := resp.Checks["defaultApiKey"].Status; got != tc.wantCheck {
+				t.Errorf("defaultApiKey check = %q, want %q", got, tc.wantCheck)
+			}
+		})
+	}
+}
+
+func TestModelSyncCheck(t *testing.T) {
+	var body atomic.Value
+	body.Store(`[{"name":"alpha","count":1},{"name":"beta","count":2},{"name":"gamma","count":1},{"name":"delta","count":3}]`)
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body.Load().(string)))
+	}))
+	defer grid.Close()
+
+	a := newTestApp(t, grid.URL)
+	a.cfg.ModelSyncMinPercent = 50
+	a.catalog = models.NewCatalog([]models.ModelPreset{{ID: "alpha"}, {ID: "beta"}, {ID: "gamma"}, {ID: "delta"}})
+
+	ready := func() (string, HealthCheck, ModelSyncDetails) {
+		t.Helper()
+		rec := serve(a, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+		var resp struct {
+			Status string `json:"status"`
+			Checks map[string]struct {
+				HealthCheck
+				Details ModelSyncDetails `json:"details"`
+			} `json:"checks"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		check := resp.Checks["modelSync"]
+		return resp.Status, check.HealthCheck, check.Details
+	}
+
+	// Above the threshold
+	a.checkModelSync(context.Background())
+	status, check, details := ready()
+	if status != "ready" || check.Status != checkOK || details.MatchedPercent != 100 {
+		t.Fatalf("in sync: ready %q, check %+v, details %+v", status, check, details)
+	}
+
+	// A Grid rename leaves only alpha matching: 25% is below the 50% threshold
+	body.Store(`[{"name":"alpha","count":1},{"name":"beta-v2","count":2},{"name":"gamma","count":0}]`)
+	a.checkModelSync(context.Background())
+	status, check, details = ready()
+	if status != "degraded" || check.Status != checkWarn {
+		t.Fatalf("out of sync: ready %q, check %+v", status, check)
+	}
+	if details.Matched != 1 || details.Total != 4 || details.MatchedPercent != 25 {
+		t.Errorf("details = %+v, want 1 of 4 matched", details)
+	}
+	if want := []string{"beta", "delta", "gamma"}; fmt.Sprint(details.NewlyUnmatched) != fmt.Sprint(want) {
+		t.Errorf("newlyUnmatched = %v, want %v", details.NewlyUnmatched, want)
+	}
+
+	// Still broken on the next run, but nothing newly so
+	a.checkModelSync(context.Background())
+	if _, _, details = ready(); len(details.NewlyUnmatched) != 0 || len(details.Unmatched) != 3 {
+		t.Errorf("repeat check details = %+v, want 3 unmatched and none new", details)
+	}
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Batch status bounds: most job IDs per request, and Grid lookups in flight at once
+const (
+	maxBatchStatusJobs    = 50
+	batchStatusConcurrent = 8
+)
+
+// BatchStatusRequest is the body of POST /api/jobs/status/batch
+type BatchStatusRequest struct {
+	JobIDs []string `json:"jobIds"`
+}
+
+// BatchStatusResult is one job's outcome in a batch status response. Code is
+// the status GET /api/jobs/{id} would have answered with.
+type BatchStatusResult struct {
+	JobID string   `json:"jobId"`
+	Code  int      `json:"code"`
+	Job   *JobView `json:"job,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+// handleBatchJobStatus returns the status of several jobs at once, looked up
+// concurrently. A job that fails doesn't fail the batch; its result carries
+// the error instead. Results come back in request order.
+func (a *App) handleBatchJobStatus(w http.ResponseWriter, r *http.Request) {
+	if !requireJSONBody(w, r) {
+		return
+	}
+	var req BatchStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %w", err))
+		return
+	}
+	switch {
+	case len(req.JobIDs) == 0:
+		writeError(w, http.StatusBadRequest, errors.New("jobIds is required"))
+		return
+	case len(req.JobIDs) > maxBatchStatusJobs:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("at most %d jobIds per batch", maxBatchStatusJobs))
+		return
+	}
+	for i, id := range req.JobIDs {
+		req.JobIDs[i] = strings.TrimSpace(id)
+		if req.JobIDs[i] == "" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("jobIds[%d] is empty", i))
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"results": a.batchJobStatus(ctx, req.JobIDs),
+	})
+}
+
+// batchJobStatus looks up each distinct job once, at most
+// batchStatusConcurrent at a time; repeated IDs share the lookup
+func (a *App) batchJobStatus(ctx context.Context, jobIDs []string) []BatchStatusResult {
+	unique := make([]string, 0, len(jobIDs))
+	byID := make(map[string]*BatchStatusResult, len(jobIDs))
+	for _, id := range jobIDs {
+		if byID[id] == nil {
+			byID[id] = &BatchStatusResult{JobID: id}
+			unique = append(unique, id)
+		}
+	}
+
+	ids := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < min(batchStatusConcurrent, len(unique)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range ids {
+				a.fillBatchResult(ctx, byID[id])
+			}
+		}()
+	}
+	for _, id := range unique {
+		ids <- id
+	}
+	close(ids)
+	wg.Wait()
+
+	results := make([]BatchStatusResult, len(jobIDs))
+	for i, id := range jobIDs {
+		results[i] = *byID[id]
+	}
+	return results
+}
+
+func (a *App) fillBatchResult(ctx context.Context, result *BatchStatusResult) {
+	if err := ctx.Err(); err != nil {
+		result.Code = http.StatusGatewayTimeout
+		result.Error = err.Error()
+		return
+	}
+	status, err := a.jobStatus(ctx, result.JobID)
+	view, code, err := a.viewForStatus(ctx, result.JobID, status, err)
+	result.Code = code
+	if err != nil {
+		result.Error = err.Error()
+		return
+	}
+	result.Job = &view
+}
@@ -0,0 +1,98 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBatchJobStatus(t *testing.T) {
+	var calls atomic.Int32
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		switch r.URL.Path {
+		case "/generate/status/done":
+			w.Write([]byte(`{"id":"done","done":true,"generations":[{"id":"gen-1"}]}`))
+		case "/generate/status/busy":
+			w.Write([]byte(`{"id":"busy","processing":1,"wait_time":30}`))
+		case "/generate/status/broken":
+			w.Write([]byte(`{"id":"broken","faulted":true,"message":"worker crashed"}`))
+		case "/generate/status/flaky":
+			http.Error(w, "upstream exploded", http.StatusInternalServerError)
+		default:
+			http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+		}
+	}))
+	defer grid.Close()
+	a := newTestApp(t, grid.URL)
+
+	body := `{"jobIds":["done","busy","broken","flaky","gone","done"]}`
+	rec := serve(a, httptest.NewRequest(http.MethodPost, "/api/jobs/status/batch", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (%s)", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Results []BatchStatusResult `json:"results"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		id     string
+		code   int
+		status string
+	}{
+		{"done", http.StatusOK, "completed"},
+		{"busy", http.StatusOK, "processing"},
+		{"broken", http.StatusOK, "faulted"},
+		{"flaky", http.StatusBadGateway, ""},
+		{"gone", http.StatusNotFound, ""},
+		{"done", http.StatusOK, "completed"},
+	}
+	if len(resp.Results) != len(want) {
+		t.Fatalf("%d results, want %d", len(resp.Results), len(want))
+	}
+	for i, w := range want {
+		got := resp.Results[i]
+		if got.JobID != w.id || got.Code != w.code {
+			t.Errorf("result %d = %s/%d, want %s/%d", i, got.JobID, got.Code, w.id, w.code)
+			continue
+		}
+		switch {
+		case w.status == "" && (got.Job != nil || got.Error == ""):
+			t.Errorf("%s: want an error and no job, got %+v", w.id, got)
+		case w.status != "" && (got.Job == nil || got.Job.Status != w.status):
+			t.Errorf("%s: job = %+v, want status %s", w.id, got.Job, w.status)
+		}
+	}
+	if got := resp.Results[2].Job; got != nil && (got.FaultReason == nil || got.FaultReason.Message != "worker crashed") {
+		t.Errorf("faulted job reason = %+v", got.FaultReason)
+	}
+	// The repeated ID is looked up once
+	if n := calls.Load(); n != 5 {
+		t.Errorf("grid calls = %d, want 5", n)
+	}
+}
+
+func TestBatchJobStatusRejectsBadRequests(t *testing.T) {
+	a := newTestApp(t, "http://grid.invalid")
+	ids := make([]string, maxBatchStatusJobs+1)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("%q", fmt.Sprintf("job-%d", i))
+	}
+	for _, body := range []string{
+		`{"jobIds":[]}`,
+		`{"jobIds":["ok"," "]}`,
+		`{"jobIds":[` + strings.Join(ids, ",") + `]}`,
+	} {
+		rec := serve(a, httptest.NewRequest(http.MethodPost, "/api/jobs/status/batch", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%.40s: status = %d, want 400", body, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"strings"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/aipg"
+)
+
+const (
+	faultRetryable = "retryable"
+	faultPermanent = "permanent"
+)
+
+// FaultReason explains why a job faulted and whether resubmitting it is worth trying
+type FaultReason struct {
+	Message string `json:"message"`
+	Class   string `json:"class"`
+	// Generations the Grid reported as faulted or censored
+	FaultedGenerations int `json:"faultedGenerations,omitempty"`
+}
+
+// Grid fault messages that resubmitting the same request won't fix. Anything
+// not listed here is treated as a transient worker problem.
+var permanentFaultMarkers = []string{
+	"censor",
+	"csam",
+	"nsfw",
+	"invalid",
+	"not allowed",
+	"forbidden",
+	"unsupported",
+	"validation",
+	"kudos",
+	"too large",
+	"exceeds",
+	"not found",
+}
+
+// Generation states the Grid uses for outputs it refused to return
+var permanentGenerationStates = map[string]bool{
+	"censored": true,
+	"csam":     true,
+}
+
+// buildFaultReason returns nil unless the job faulted
+func buildFaultReason(resp *aipg.JobStatusResponse) *FaultReason {
+	if !resp.Faulted {
+		return nil
+	}
+
+	reason := &FaultReason{Message: strings.TrimSpace(resp.Message)}
+	permanentState := ""
+	for _, gen := range resp.Generations {
+		state := strings.ToLower(gen.State)
+		if state == "faulted" || permanentGenerationStates[state] {
+			reason.FaultedGenerations++
+		}
+		if permanentGenerationStates[state] && permanentState == "" {
+			permanentState = state
+		}
+	}
+
+	switch {
+	case permanentState != "":
+		if reason.Message == "" {
+			reason.Message = "generation was " + permanentState
+		}
+		reason.Class = faultPermanent
+	case reason.Message == "":
+		reason.Message = "job faulted on the worker"
+		reason.Class = faultRetryable
+	default:
+		reason.Class = classifyFault(reason.Message)
+	}
+	return reason
+}
+
+// classifyFault maps a Grid fault message to retryable or permanent
+func classifyFault(message string) string {
+	lower := strings.ToLower(message)
+	for _, marker := range permanentFaultMarkers {
+		if strings.Contains(lower, marker) {
+			return faultPermanent
+		}
+	}
+	return faultRetryable
+}
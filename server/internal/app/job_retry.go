@@ -0,0 +1,184 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/aipg"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+)
+
+// hashAPIKey lets a retry prove it uses the original key without storing the key
+func hashAPIKey(apiKey string) string {
+	if apiKey == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// recordJobRequest stores the request behind a submitted job, and the params
+// it went to the Grid with, so it can be retried and published with them.
+// Failures are logged and otherwise ignored - they only cost the ability to retry.
+func (a *App) recordJobRequest(ctx context.Context, jobID string, sub *jobSubmission) {
+	if a.jobRequests == nil {
+		return
+	}
+	req := sub.req
+	req.APIKey = ""
+	body, err := json.Marshal(req)
+	if err != nil {
+		return
+	}
+	params, err := json.Marshal(sub.payload.Params)
+	if err != nil {
+		return
+	}
+	rec := gallery.RecordedJobRequest{
+		JobID:         jobID,
+		WalletAddress: req.WalletAddress,
+		APIKeyHash:    hashAPIKey(sub.apiKey),
+		RequestID:     aipg.RequestID(ctx),
+		Request:       body,
+		GridParams:    params,
+		RetryOf:       sub.retryOf,
+	}
+	if err := a.jobRequests.RecordJobRequest(rec); err != nil {
+		log.Printf("Warning: failed to record request for job %s: %v", jobID, err)
+	}
+}
+
+// recordedGridParams returns the params map a job was submitted to the Grid
+// with, or nil if none was recorded
+func (a *App) recordedGridParams(jobID string) json.RawMessage {
+	if a.jobRequests == nil {
+		return nil
+	}
+	recorded, err := a.jobRequests.GetJobRequest(jobID)
+	if err != nil {
+		log.Printf("Warning: failed to load request for job %s: %v", jobID, err)
+		return nil
+	}
+	if recorded == nil || len(recorded.GridParams) == 0 {
+		return nil
+	}
+	return recorded.GridParams
+}
+
+type RetryJobRequest struct {
+	APIKey string `json:"apiKey"`
+}
+
+// handleRetryJob resubmits a faulted job with its original parameters as a new
+// job. The retry goes out on the key the original was submitted with: the
+// caller presents it, or, for a job that ran on the server's own key, comes
+// from the wallet that created it. It passes the same validation, limits and
+// submit queue as a new job, and permanent faults aren't retried.
+func (a *App) handleRetryJob(w http.ResponseWriter, r *http.Request) {
+	jobID := chi.URLParam(r, "id")
+	if a.jobRequests == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("job retry not available"))
+		return
+	}
+
+	var body RetryJobRequest
+	if r.ContentLength != 0 {
+		if !requireJSONBody(w, r) {
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %w", err))
+			return
+		}
+	}
+
+	recorded, err := a.jobRequests.GetJobRequest(jobID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if recorded == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("the original parameters for job %s were not recorded, so it can't be retried - please submit it again from the generator", jobID))
+		return
+	}
+
+	// Never swap in another key: a job submitted with the caller's own key
+	// doesn't match the server's, so it can only be retried by presenting it
+	apiKey, anonymous := a.resolveAPIKey(body.APIKey)
+	if apiKey == "" || hashAPIKey(apiKey) != recorded.APIKeyHash {
+		writeError(w, http.StatusForbidden, errors.New("retry requires the API key the job was submitted with"))
+		return
+	}
+	if body.APIKey == "" && (recorded.WalletAddress == "" || verifiedWalletFromRequest(r) != recorded.WalletAddress) {
+		writeError(w, http.StatusForbidden, errors.New("retry requires the wallet or API key that created the job"))
+		return
+	}
+
+	var req CreateJobRequest
+	if err := json.Unmarshal(recorded.Request, &req); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("stored request for job %s is unreadable: %w", jobID, err))
+		return
+	}
+	if _, ok := a.catalog.Get(req.ModelID); !ok {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("model %s is no longer available", req.ModelID))
+		return
+	}
+	// Server limits may have tightened since the original was submitted
+	sub, err := a.prepareJob(r.Context(), req, apiKey, anonymous)
+	if err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	// Only faulted jobs are retried, and only when the fault could go away;
+	// an expired job can't be checked, so it's allowed
+	status, err := a.client.JobStatus(ctx, jobID)
+	switch {
+	case errors.Is(err, aipg.ErrJobNotFound):
+	case err != nil:
+		writeError(w, http.StatusBadGateway, err)
+		return
+	case !status.Faulted:
+		writeError(w, http.StatusConflict, fmt.Errorf("job %s has not faulted", jobID))
+		return
+	default:
+		if reason := buildFaultReason(status); reason.Class == faultPermanent {
+			writeError(w, http.StatusUnprocessableEntity, fmt.Errorf("job %s failed permanently (%s); retrying it would fail the same way", jobID, reason.Message))
+			return
+		}
+	}
+
+	newID, queued, ok := a.submitJob(ctx, w, r, sub)
+	if !ok {
+		return
+	}
+	if queued != nil {
+		writeJSON(w, http.StatusAccepted, map[string]any{
+			"status":        queued.Status,
+			"queueId":       queued.QueueID,
+			"queuePosition": queued.QueuePosition,
+			"retryOf":       jobID,
+		})
+		return
+	}
+
+	log.Printf("🔁 Retried job %s as %s (model=%s)", jobID, newID, req.ModelID)
+
+	writeJSON(w, http.StatusAccepted, map[string]any{
+		"jobId":   newID,
+		"status":  "queued",
+		"retryOf": jobID,
+	})
+}
@@ -0,0 +1,192 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/models"
+)
+
+// memoryJobRequestStore is an in-memory gallery.JobRequestStore for handler tests
+type memoryJobRequestStore struct {
+	mu       sync.Mutex
+	requests map[string]gallery.RecordedJobRequest
+}
+
+func newMemoryJobRequestStore() *memoryJobRequestStore {
+	return &memoryJobRequestStore{requests: make(map[string]gallery.RecordedJobRequest)}
+}
+
+func (m *memoryJobRequestStore) RecordJobRequest(rec gallery.RecordedJobRequest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec.WalletAddress = strings.ToLower(rec.WalletAddress)
+	rec.RetriedAs = m.requests[rec.JobID].RetriedAs
+	m.requests[rec.JobID] = rec
+	return nil
+}
+
+func (m *memoryJobRequestStore) SetRetriedAs(jobID, newJobID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if rec, ok := m.requests[jobID]; ok {
+		rec.RetriedAs = newJobID
+		m.requests[jobID] = rec
+	}
+	return nil
+}
+
+func (m *memoryJobRequestStore) GetJobRequest(jobID string) (*gallery.RecordedJobRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, ok := m.requests[jobID]
+	if !ok {
+		return nil, nil
+	}
+	return &rec, nil
+}
+
+func TestRetryJob(t *testing.T) {
+	var mu sync.Mutex
+	var submitted []map[string]any
+	var keys []string
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/generate/async":
+			var payload map[string]any
+			json.NewDecoder(r.Body).Decode(&payload)
+			mu.Lock()
+			submitted = append(submitted, payload)
+			keys = append(keys, r.Header.Get("apikey"))
+			id := fmt.Sprintf("new-job-%d", len(submitted))
+			mu.Unlock()
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"id":"` + id + `"}`))
+		case r.URL.Path == "/generate/status/job-running":
+			w.Write([]byte(`{"id":"job-running","faulted":false,"processing":1}`))
+		case r.URL.Path == "/generate/status/job-censored":
+			w.Write([]byte(`{"id":"job-censored","faulted":true,"message":"Image was censored by the worker"}`))
+		case strings.HasPrefix(r.URL.Path, "/generate/status/"):
+			w.Write([]byte(`{"faulted":true}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer grid.Close()
+
+	a := newTestApp(t, grid.URL)
+	a.catalog = models.NewCatalog(testPresets)
+	store := newMemoryJobRequestStore()
+	a.jobRequests = store
+
+	// A job created through the API has its request recorded, minus the key
+	body := `{"modelId":"FLUX.1-dev","prompt":"a red fox","walletAddress":"` + testAddr("owner") + `","apiKey":"user-key","params":{"steps":12}}`
+	rec := serve(a, httptest.NewRequest(http.MethodPost, "/api/jobs", strings.NewReader(body)))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("create status = %d, body %s", rec.Code, rec.Body)
+	}
+	recorded, _ := store.GetJobRequest("new-job-1")
+	if recorded == nil || strings.Contains(string(recorded.Request), "user-key") || recorded.APIKeyHash == "" {
+		t.Fatalf("recorded request = %+v, want stored without the raw API key", recorded)
+	}
+	// Pretend that job faulted under a stable ID
+	for _, id := range []string{"job-faulted", "job-running", "job-censored"} {
+		store.RecordJobRequest(gallery.RecordedJobRequest{JobID: id, WalletAddress: recorded.WalletAddress, APIKeyHash: recorded.APIKeyHash, Request: recorded.Request})
+	}
+	// and one that ran on the server's default key
+	store.RecordJobRequest(gallery.RecordedJobRequest{JobID: "job-server-key", WalletAddress: recorded.WalletAddress, APIKeyHash: hashAPIKey("test-key"), Request: recorded.Request})
+	store.RecordJobRequest(gallery.RecordedJobRequest{JobID: "job-big", WalletAddress: recorded.WalletAddress, APIKeyHash: recorded.APIKeyHash, Request: []byte(`{"modelId":"FLUX.1-dev","prompt":"p","params":{"count":4}}`)})
+
+	retry := func(jobID, wallet, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/jobs/"+jobID+"/retry", strings.NewReader(body))
+		if wallet != "" {
+			signAs(t, req, wallet)
+		}
+		return serve(a, req)
+	}
+	lastKey := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return keys[len(keys)-1]
+	}
+
+	t.Run("original key resubmits the recorded job", func(t *testing.T) {
+		rec := retry("job-faulted", "", `{"apiKey":"user-key"}`)
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+		}
+		var resp map[string]any
+		json.NewDecoder(rec.Body).Decode(&resp)
+		if resp["retryOf"] != "job-faulted" || resp["jobId"] == "" {
+			t.Errorf("response = %v", resp)
+		}
+		mu.Lock()
+		last := submitted[len(submitted)-1]
+		mu.Unlock()
+		params, _ := last["params"].(map[string]any)
+		if !strings.Contains(last["prompt"].(string), "a red fox") || params["steps"] != float64(12) {
+			t.Errorf("resubmitted payload = %v, want the original prompt and params", last)
+		}
+		if key := lastKey(); key != "user-key" {
+			t.Errorf("resubmitted on key %q, want the original", key)
+		}
+	})
+
+	t.Run("wallet alone can't move a job onto the default key", func(t *testing.T) {
+		if rec := retry("job-faulted", "owner", ""); rec.Code != http.StatusForbidden {
+			t.Errorf("status = %d, want 403", rec.Code)
+		}
+	})
+
+	t.Run("job on the default key is retried by its wallet", func(t *testing.T) {
+		if rec := retry("job-server-key", "owner", ""); rec.Code != http.StatusAccepted {
+			t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+		}
+		if key := lastKey(); key != "test-key" {
+			t.Errorf("resubmitted on key %q, want the default key it ran on", key)
+		}
+		if rec := retry("job-server-key", "other", ""); rec.Code != http.StatusForbidden {
+			t.Errorf("other wallet: status = %d, want 403", rec.Code)
+		}
+	})
+
+	t.Run("unrecorded job", func(t *testing.T) {
+		rec := retry("job-unknown", "owner", "")
+		if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "not recorded") {
+			t.Errorf("status = %d, body %s; want 404 explaining params weren't recorded", rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("different wallet and key", func(t *testing.T) {
+		if rec := retry("job-faulted", "other", `{"apiKey":"other-key"}`); rec.Code != http.StatusForbidden {
+			t.Errorf("status = %d, want 403", rec.Code)
+		}
+	})
+
+	t.Run("job that has not faulted", func(t *testing.T) {
+		if rec := retry("job-running", "", `{"apiKey":"user-key"}`); rec.Code != http.StatusConflict {
+			t.Errorf("status = %d, want 409", rec.Code)
+		}
+	})
+
+	t.Run("permanent fault", func(t *testing.T) {
+		if rec := retry("job-censored", "", `{"apiKey":"user-key"}`); rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("status = %d, want 422", rec.Code)
+		}
+	})
+
+	t.Run("server limits apply", func(t *testing.T) {
+		a.cfg.MaxImagesPerJob = 2
+		defer func() { a.cfg.MaxImagesPerJob = 0 }()
+		rec := retry("job-big", "", `{"apiKey":"user-key"}`)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "params.count") {
+			t.Errorf("status = %d, body %s; want the count limit", rec.Code, rec.Body)
+		}
+	})
+}
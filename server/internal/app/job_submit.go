@@ -0,0 +1,183 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/aipg"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/models"
+)
+
+var errAPIKeyRequired = errors.New("apiKey is required")
+
+// createJobMaxBytes caps a create-job body. Sources are shrunk to
+// SOURCE_IMAGE_MAX_BYTES after decoding, so this leaves room for a base64
+// original and its mask.
+const createJobMaxBytes = 32 << 20
+
+// jobSubmission is a job request that passed validation, with its Grid payload.
+// New jobs and retries both go to the Grid through prepareJob and sendJob.
+type jobSubmission struct {
+	req       CreateJobRequest
+	preset    models.ModelPreset
+	payload   aipg.CreateJobPayload
+	apiKey    string
+	anonymous bool
+	// Fallback preset added by sendJob because the requested model was offline
+	fallback string
+	// Job the worker filter is resubmitting this one for
+	retryOf string
+}
+
+// offlineModelError refuses a job for a model with no workers when
+// FALLBACK_MODEL_MODE is reject, naming the model to resubmit with
+type offlineModelError struct {
+	model    string
+	fallback string
+}
+
+func (e *offlineModelError) Error() string {
+	return fmt.Sprintf("model %s has no workers online, try %s", e.model, e.fallback)
+}
+
+// resolveAPIKey returns the key a job goes out on: its own, else the default
+// key, else the Grid's anonymous key, in which case anonymous is true
+func (a *App) resolveAPIKey(requested string) (apiKey string, anonymous bool) {
+	apiKey = requested
+	if apiKey == "" {
+		apiKey = a.cfg.DefaultAPIKey
+	}
+	if apiKey == "" && a.cfg.AnonAPIKey != "" {
+		return a.cfg.AnonAPIKey, true
+	}
+	return apiKey, false
+}
+
+// prepareJob checks req against the request rules and the server's limits and
+// builds its Grid payload. Problems are collected into ValidationErrors so the
+// client sees them all at once.
+func (a *App) prepareJob(ctx context.Context, req CreateJobRequest, apiKey string, anonymous bool) (*jobSubmission, error) {
+	var errs ValidationErrors
+	req.validate(&errs)
+	// Corrupt sources are caught here instead of failing on a worker, and huge
+	// ones are shrunk rather than bounced by the Grid
+	limits := sourceImageLimits{maxDimension: a.cfg.SourceImageMaxDimension, maxBytes: a.cfg.SourceImageMaxBytes}
+	if source, size, err := prepareSourceImage(req.SourceImage, limits); err != nil {
+		errs.Add("sourceImage", err.Error())
+	} else if mask, err := prepareSourceMask(req.SourceMask, size); err != nil {
+		errs.Add("sourceMask", err.Error())
+	} else {
+		req.SourceImage, req.SourceMask = source, mask
+	}
+	if max := a.cfg.MaxImagesPerJob; max > 0 && req.Params.Count > max {
+		errs.Addf("params.count", "count %d is above the server limit of %d per job", req.Params.Count, max)
+	} else if max := a.cfg.AnonMaxImagesPerJob; anonymous && max > 0 && req.Params.Count > max {
+		errs.Addf("params.count", "count %d is above the limit of %d per anonymous job; use an API key for more", req.Params.Count, max)
+	}
+
+	preset, ok := a.catalog.Get(req.ModelID)
+	if !ok && strings.TrimSpace(req.ModelID) != "" {
+		errs.Addf("modelId", "unknown model: %s", req.ModelID)
+	}
+
+	var payload aipg.CreateJobPayload
+	if ok {
+		// Out-of-range params are rejected rather than quietly clamped, against
+		// the chain's tighter bounds when the model has them
+		preset = a.presetWithChainLimits(ctx, preset)
+		req.validateParams(preset, &errs)
+		payload = buildCreateJobPayload(req, preset)
+		if err := a.checkPixelCeiling(preset, payload); err != nil {
+			errs.Add("params", err.Error())
+		}
+		if err := a.checkFrameCeiling(preset, payload); err != nil {
+			errs.Add("params.length", err.Error())
+		}
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	if apiKey == "" {
+		return nil, errAPIKeyRequired
+	}
+	if anonymous {
+		// The anonymous key only submits to the shared dataset
+		payload.Shared = true
+	}
+	return &jobSubmission{req: req, preset: preset, payload: payload, apiKey: apiKey, anonymous: anonymous}, nil
+}
+
+// sendJob submits a prepared job through the offline-model fallback and the
+// submit queue, then tracks it and records its request. A job whose turn in
+// the queue hasn't come yet is returned as a QueuedSubmission instead of an ID.
+func (a *App) sendJob(ctx context.Context, sub *jobSubmission) (string, *QueuedSubmission, error) {
+	log.Printf("📤 Creating job: modelId=%s, preset.ID=%s, preset.Type=%s, gridName=%s, payload.Models=%v, mediaType=%s, client=%s, anonymous=%t",
+		sub.req.ModelID, sub.preset.ID, sub.preset.Type, getGridModelName(sub.preset.ID), sub.payload.Models, sub.payload.MediaType, clientIPFromContext(ctx), sub.anonymous)
+
+	// Debug: log the full params for troubleshooting
+	if paramsJSON, err := json.Marshal(sub.payload.Params); err == nil {
+		log.Printf("📤 Job params: %s", string(paramsJSON))
+	}
+
+	// An offline model would leave the job queued forever
+	if fallback, ok := a.fallbackFor(ctx, sub.preset); ok {
+		if a.cfg.FallbackModelMode == fallbackReject {
+			return "", nil, &offlineModelError{model: sub.preset.ID, fallback: fallback.ID}
+		}
+		sub.payload.Models = append(sub.payload.Models, getGridModelName(fallback.ID))
+		sub.fallback = fallback.ID
+		log.Printf("📤 Model %s is offline, adding fallback %s", sub.preset.ID, fallback.ID)
+	}
+
+	submit := func(ctx context.Context) (string, error) {
+		resp, err := a.client.CreateJob(ctx, sub.payload, sub.apiKey, a.cfg.ClientAgent)
+		if err != nil {
+			return "", err
+		}
+		a.jobs.Track(resp.ID)
+		a.recordJobRequest(ctx, resp.ID, sub)
+		if sub.retryOf != "" && a.jobRequests != nil {
+			if err := a.jobRequests.SetRetriedAs(sub.retryOf, resp.ID); err != nil {
+				log.Printf("Warning: failed to link job %s to its retry %s: %v", sub.retryOf, resp.ID, err)
+			}
+		}
+		return resp.ID, nil
+	}
+	// Bursts wait in the submit queue
+	return a.submissions.submit(ctx, a.cfg.SubmitQueueWait, submit)
+}
+
+// submitJob applies the anonymous rate limit and sends a prepared job. When it
+// returns false the response has already been written.
+func (a *App) submitJob(ctx context.Context, w http.ResponseWriter, r *http.Request, sub *jobSubmission) (string, *QueuedSubmission, bool) {
+	if sub.anonymous && !a.anonLimiter.check(w, r) {
+		return "", nil, false
+	}
+	jobID, queued, err := a.sendJob(ctx, sub)
+	if err != nil {
+		var kudosErr *aipg.InsufficientKudosError
+		var offline *offlineModelError
+		switch {
+		case errors.As(err, &offline):
+			writeJSON(w, http.StatusConflict, map[string]any{
+				"error":          offline.Error(),
+				"status":         http.StatusConflict,
+				"suggestedModel": offline.fallback,
+			})
+		case errors.Is(err, errSubmitQueueFull):
+			w.Header().Set("Retry-After", "5")
+			writeError(w, http.StatusServiceUnavailable, err)
+		case errors.As(err, &kudosErr):
+			a.writeInsufficientKudos(w, r, sub.apiKey, kudosErr)
+		default:
+			writeError(w, http.StatusBadGateway, err)
+		}
+		return "", nil, false
+	}
+	return jobID, queued, true
+}
@@ -0,0 +1,193 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/aipg"
+)
+
+// Defaults for how long a freshly submitted job may 404 before we believe it.
+const (
+	defaultJobNotFoundGrace      = 15 * time.Second
+	defaultJobNotFoundRetries    = 3
+	defaultJobNotFoundRetryDelay = 500 * time.Millisecond
+)
+
+// Long-poll bounds for GET /api/jobs/{id}?wait=<seconds>. The cap keeps a
+// held request under the 30 second idle timeout common to proxies and load
+// balancers, so a long-poll comes back as a status rather than a 504.
+const (
+	maxJobStatusWait       = 25 * time.Second
+	defaultJobWaitInterval = 2 * time.Second
+)
+
+// maxSavedJobResults bounds how many stored job results the tracker
+// remembers; past it the set starts over, costing one more save per job
+const maxSavedJobResults = 10000
+
+// jobTracker remembers when jobs were submitted so a status poll can tell a
+// job the Grid hasn't propagated yet from one that has expired or never existed.
+type jobTracker struct {
+	mu         sync.Mutex
+	created    map[string]time.Time
+	grace      time.Duration
+	retries    int
+	retryDelay time.Duration
+	// How often a long-poll re-checks the Grid
+	waitInterval time.Duration
+	// Completed jobs whose result is already stored, so polls don't save again
+	saved map[string]struct{}
+}
+
+func newJobTracker() *jobTracker {
+	return &jobTracker{
+		created:    make(map[string]time.Time),
+		saved:      make(map[string]struct{}),
+		grace:      defaultJobNotFoundGrace,
+		retries:    defaultJobNotFoundRetries,
+		retryDelay: defaultJobNotFoundRetryDelay,
+
+		waitInterval: defaultJobWaitInterval,
+	}
+}
+
+// Track records that a job was just submitted to the Grid
+func (t *jobTracker) Track(jobID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	// Drop entries whose grace window has passed so the map stays small
+	for id, createdAt := range t.created {
+		if now.Sub(createdAt) > t.grace {
+			delete(t.created, id)
+		}
+	}
+	t.created[jobID] = now
+}
+
+// InGrace reports whether the job was submitted recently enough that a
+// "not found" from the Grid should be treated as "not propagated yet"
+func (t *jobTracker) InGrace(jobID string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	createdAt, ok := t.created[jobID]
+	return ok && time.Since(createdAt) <= t.grace
+}
+
+// ResultSaved reports whether a completed job's result was already stored
+func (t *jobTracker) ResultSaved(jobID string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, ok := t.saved[jobID]
+	return ok
+}
+
+// MarkResultSaved records that a completed job's result is stored
+func (t *jobTracker) MarkResultSaved(jobID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.saved) >= maxSavedJobResults {
+		t.saved = make(map[string]struct{})
+	}
+	t.saved[jobID] = struct{}{}
+}
+
+// jobStatus fetches a job's status from the Grid, briefly retrying while a
+// freshly created job is still propagating. The returned error wraps
+// aipg.ErrJobNotFound if the Grid still doesn't know the job.
+func (a *App) jobStatus(ctx context.Context, jobID string) (*aipg.JobStatusResponse, error) {
+	status, err := a.client.JobStatus(ctx, jobID)
+	for attempt := 0; attempt < a.jobs.retries && errors.Is(err, aipg.ErrJobNotFound) && a.jobs.InGrace(jobID); attempt++ {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(a.jobs.retryDelay):
+		}
+		status, err = a.client.JobStatus(ctx, jobID)
+	}
+	return status, err
+}
+
+// waitForJob long-polls a job until it finishes, faults or wait elapses, then
+// returns its latest status
+func (a *App) waitForJob(ctx context.Context, jobID string, wait time.Duration) (*aipg.JobStatusResponse, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		status, err := a.jobStatus(ctx, jobID)
+		if err != nil || status.Done || status.Faulted || !time.Now().Add(a.jobs.waitInterval).Before(deadline) {
+			return status, err
+		}
+		select {
+		case <-ctx.Done():
+			return status, nil
+		case <-time.After(a.jobs.waitInterval):
+		}
+	}
+}
+
+// initialJobView fetches a just-created job's first status. The Grid may not
+// know the job yet, and a failed lookup shouldn't fail a job that was accepted,
+// so both come back as the queued placeholder.
+func (a *App) initialJobView(ctx context.Context, jobID string) JobView {
+	status, err := a.jobStatus(ctx, jobID)
+	if err != nil {
+		if !errors.Is(err, aipg.ErrJobNotFound) {
+			log.Printf("Warning: initial status for job %s failed: %v", jobID, err)
+		}
+		return pendingJobView(jobID)
+	}
+	return a.presentJobView(ctx, a.buildJobView(status))
+}
+
+// saveJobResult keeps a completed job's view so its permalink outlives the
+// Grid's copy. Each job is saved once; later polls of it skip the database.
+// Failures are logged and retried on the next poll.
+func (a *App) saveJobResult(jobID string, view JobView) {
+	if a.jobResults == nil || len(view.Generations) == 0 || a.jobs.ResultSaved(jobID) {
+		return
+	}
+	body, err := json.Marshal(view)
+	if err != nil {
+		return
+	}
+	if err := a.jobResults.SaveJobResult(jobID, body); err != nil {
+		log.Printf("Warning: failed to save result for job %s: %v", jobID, err)
+		return
+	}
+	a.jobs.MarkResultSaved(jobID)
+}
+
+// storedJobResult returns the saved view of a completed job, if there is one
+func (a *App) storedJobResult(jobID string) (JobView, bool) {
+	if a.jobResults == nil {
+		return JobView{}, false
+	}
+	body, err := a.jobResults.GetJobResult(jobID)
+	if err != nil {
+		log.Printf("Warning: failed to load stored result for job %s: %v", jobID, err)
+		return JobView{}, false
+	}
+	var view JobView
+	if body == nil || json.Unmarshal(body, &view) != nil {
+		return JobView{}, false
+	}
+	return view, true
+}
+
+// pendingJobView is returned for a job the Grid hasn't propagated yet
+func pendingJobView(jobID string) JobView {
+	return JobView{
+		JobID:       jobID,
+		Status:      "queued",
+		Generations: []GenerationView{},
+	}
+}
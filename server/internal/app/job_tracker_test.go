@@ -0,0 +1,219 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJobStatusRetriesWhileJobPropagates(t *testing.T) {
+	var calls atomic.Int32
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id":"job-1","done":false,"processing":1}`))
+	}))
+	defer grid.Close()
+
+	a := newTestApp(t, grid.URL)
+	a.jobs.retryDelay = time.Millisecond
+	a.jobs.Track("job-1")
+
+	rec := serve(a, httptest.NewRequest(http.MethodGet, "/api/jobs/job-1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+	var view JobView
+	if err := json.NewDecoder(rec.Body).Decode(&view); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if view.Status != "processing" {
+		t.Errorf("Status = %q, want processing", view.Status)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("grid calls = %d, want 3", got)
+	}
+}
+
+func TestJobStatusNotFound(t *testing.T) {
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+	}))
+	defer grid.Close()
+
+	tests := []struct {
+		name       string
+		track      bool
+		grace      time.Duration
+		wantCode   int
+		wantStatus string
+	}{
+		{name: "fresh job reported as queued", track: true, grace: time.Minute, wantCode: http.StatusOK, wantStatus: "queued"},
+		{name: "job past grace window", track: true, grace: 0, wantCode: http.StatusNotFound},
+		{name: "unknown job", track: false, grace: time.Minute, wantCode: http.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := newTestApp(t, grid.URL)
+			a.jobs.retryDelay = time.Millisecond
+			a.jobs.grace = tc.grace
+			if tc.track {
+				a.jobs.Track("job-1")
+				time.Sleep(time.Millisecond)
+			}
+
+			rec := serve(a, httptest.NewRequest(http.MethodGet, "/api/jobs/job-1", nil))
+			if rec.Code != tc.wantCode {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tc.wantCode, rec.Body)
+			}
+			if tc.wantStatus == "" {
+				return
+			}
+			var view JobView
+			if err := json.NewDecoder(rec.Body).Decode(&view); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if view.Status != tc.wantStatus {
+				t.Errorf("Status = %q, want %q", view.Status, tc.wantStatus)
+			}
+		})
+	}
+}
+
+func TestJobStatusLongPoll(t *testing.T) {
+	var calls atomic.Int32
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.Write([]byte(`{"id":"job-1","done":false,"processing":1}`))
+			return
+		}
+		w.Write([]byte(`{"id":"job-1","done":true,"finished":1,"generations":[{"id":"gen-1","img":"https://images.aipg.art/gen-1.webp"}]}`))
+	}))
+	defer grid.Close()
+
+	a := newTestApp(t, grid.URL)
+	a.jobs.waitInterval = time.Millisecond
+
+	rec := serve(a, httptest.NewRequest(http.MethodGet, "/api/jobs/job-1?wait=5", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+	var view JobView
+	if err := json.NewDecoder(rec.Body).Decode(&view); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if view.Status != "completed" || calls.Load() != 3 {
+		t.Errorf("status = %q after %d grid calls, want completed after 3", view.Status, calls.Load())
+	}
+
+	rec = serve(a, httptest.NewRequest(http.MethodGet, "/api/jobs/job-1?wait=soon", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("wait=soon status = %d, want 400", rec.Code)
+	}
+}
+
+func TestJobStatusLongPollStopsOnDisconnect(t *testing.T) {
+	var calls atomic.Int32
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`{"id":"job-1","done":false,"processing":1}`))
+	}))
+	defer grid.Close()
+
+	a := newTestApp(t, grid.URL)
+	a.jobs.waitInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/api/jobs/job-1?wait=300", nil).WithContext(ctx)
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	serve(a, req)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("long-poll ran %v after the client left, want it to stop promptly", elapsed)
+	}
+	polled := calls.Load()
+	time.Sleep(50 * time.Millisecond)
+	if calls.Load() != polled {
+		t.Errorf("grid polled %d more times after the handler returned", calls.Load()-polled)
+	}
+}
+
+// memoryJobResultStore is an in-memory gallery.JobResultStore for handler tests
+type memoryJobResultStore struct {
+	mu      sync.Mutex
+	results map[string][]byte
+	saves   int
+}
+
+func (m *memoryJobResultStore) SaveJobResult(jobID string, result []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.saves++
+	if _, ok := m.results[jobID]; !ok {
+		m.results[jobID] = result
+	}
+	return nil
+}
+
+func (m *memoryJobResultStore) GetJobResult(jobID string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.results[jobID], nil
+}
+
+func TestJobStatusServesStoredResultAfterExpiry(t *testing.T) {
+	var expired atomic.Bool
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if expired.Load() {
+			http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"id":"job-1","done":true,"finished":1,"generations":[{"id":"gen-1","img":"https://cdn.example.com/gen-1.webp","seed":"42"}]}`))
+	}))
+	defer grid.Close()
+
+	a := newTestApp(t, grid.URL)
+	store := &memoryJobResultStore{results: make(map[string][]byte)}
+	a.jobResults = store
+
+	rec := serve(a, httptest.NewRequest(http.MethodGet, "/api/jobs/job-1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+	if store.results["job-1"] == nil {
+		t.Fatal("completed job result was not stored")
+	}
+	// Polling a completed job again doesn't go back to the store
+	serve(a, httptest.NewRequest(http.MethodGet, "/api/jobs/job-1", nil))
+	if store.saves != 1 {
+		t.Errorf("result saved %d times over two polls, want once", store.saves)
+	}
+
+	expired.Store(true)
+	rec = serve(a, httptest.NewRequest(http.MethodGet, "/api/jobs/job-1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status after expiry = %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+	var view JobView
+	if err := json.NewDecoder(rec.Body).Decode(&view); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if view.Status != "completed" || len(view.Generations) != 1 || view.Generations[0].ID != "gen-1" {
+		t.Errorf("stored view = %+v, want the completed job with gen-1", view)
+	}
+
+	rec = serve(a, httptest.NewRequest(http.MethodGet, "/api/jobs/job-2", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown expired job status = %d, want 404", rec.Code)
+	}
+}
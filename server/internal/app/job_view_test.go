@@ -0,0 +1,196 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/aipg"
+)
+
+func TestBuildJobViewVideoNeverInlinesBase64(t *testing.T) {
+	a := &App{}
+	longPayload := strings.Repeat("A", 200)
+
+	tests := []struct {
+		name    string
+		gen     aipg.Generation
+		wantURL string
+	}{
+		{
+			name:    "image mime but mp4 url",
+			gen:     aipg.Generation{ID: "gen-1", Mime: "image/webp", ImgURL: "https://cdn.example.com/out/gen-1.mp4", Image: longPayload},
+			wantURL: "https://images.aipg.art/gen-1.mp4",
+		},
+		{
+			name:    "image mime with video field",
+			gen:     aipg.Generation{ID: "gen-2", Mime: "image/png", Video: "https://cdn.example.com/gen-2.webp", Image: longPayload},
+			wantURL: "https://images.aipg.art/gen-2.webp",
+		},
+		{
+			name:    "data uri video without url falls back to cdn",
+			gen:     aipg.Generation{ID: "gen-3", Mime: "application/octet-stream", Image: "data:video/mp4;base64," + longPayload},
+			wantURL: "https://images.aipg.art/gen-3.webp",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			view := a.buildJobView(&aipg.JobStatusResponse{ID: "job", Done: true, Generations: []aipg.Generation{tc.gen}})
+			got := view.Generations[0]
+			if got.Kind != "video" {
+				t.Errorf("kind = %q, want video", got.Kind)
+			}
+			if !strings.HasPrefix(got.MimeType, "video/") {
+				t.Errorf("mimeType = %q, want a video MIME", got.MimeType)
+			}
+			if got.Base64 != "" {
+				t.Errorf("video generation was inlined as base64 (%d chars)", len(got.Base64))
+			}
+			if got.URL != tc.wantURL {
+				t.Errorf("url = %q, want %q", got.URL, tc.wantURL)
+			}
+		})
+	}
+}
+
+func TestBuildJobViewImageStillInlines(t *testing.T) {
+	a := &App{}
+	gen := aipg.Generation{ID: "gen-img", Mime: "image/webp", Image: strings.Repeat("A", 200)}
+	got := a.buildJobView(&aipg.JobStatusResponse{Generations: []aipg.Generation{gen}}).Generations[0]
+	if got.Kind != "image" || !strings.HasPrefix(got.Base64, "data:image/webp;base64,") {
+		t.Errorf("image generation = %+v, want inlined base64", got)
+	}
+}
+
+func TestBuildJobViewFaultReason(t *testing.T) {
+	a := &App{}
+	tests := []struct {
+		name        string
+		resp        aipg.JobStatusResponse
+		wantClass   string
+		wantMessage string
+		wantFaulted int
+	}{
+		{
+			name:        "worker timeout",
+			resp:        aipg.JobStatusResponse{Faulted: true, Message: "Worker timed out while processing the request"},
+			wantClass:   faultRetryable,
+			wantMessage: "Worker timed out while processing the request",
+		},
+		{
+			name:        "cuda out of memory",
+			resp:        aipg.JobStatusResponse{Faulted: true, Message: "CUDA out of memory"},
+			wantClass:   faultRetryable,
+			wantMessage: "CUDA out of memory",
+		},
+		{
+			name:        "invalid model",
+			resp:        aipg.JobStatusResponse{Faulted: true, Message: "Invalid model requested"},
+			wantClass:   faultPermanent,
+			wantMessage: "Invalid model requested",
+		},
+		{
+			name:        "nsfw refused",
+			resp:        aipg.JobStatusResponse{Faulted: true, Message: "This prompt appears to violate our NSFW policy"},
+			wantClass:   faultPermanent,
+			wantMessage: "This prompt appears to violate our NSFW policy",
+		},
+		{
+			name:        "censored generation without message",
+			resp:        aipg.JobStatusResponse{Faulted: true, Generations: []aipg.Generation{{ID: "g1", State: "censored"}}},
+			wantClass:   faultPermanent,
+			wantMessage: "generation was censored",
+			wantFaulted: 1,
+		},
+		{
+			name:        "faulted generation without message",
+			resp:        aipg.JobStatusResponse{Faulted: true, Generations: []aipg.Generation{{ID: "g1", State: "faulted"}, {ID: "g2", State: "ok"}}},
+			wantClass:   faultRetryable,
+			wantMessage: "job faulted on the worker",
+			wantFaulted: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := a.buildJobView(&tc.resp).FaultReason
+			if got == nil {
+				t.Fatal("faultReason missing for faulted job")
+			}
+			if got.Class != tc.wantClass || got.Message != tc.wantMessage || got.FaultedGenerations != tc.wantFaulted {
+				t.Errorf("faultReason = %+v, want class %q message %q faulted %d", got, tc.wantClass, tc.wantMessage, tc.wantFaulted)
+			}
+		})
+	}
+
+	if got := a.buildJobView(&aipg.JobStatusResponse{Done: true, Message: "ok"}).FaultReason; got != nil {
+		t.Errorf("faultReason = %+v for a completed job, want nil", got)
+	}
+}
+
+func TestBuildJobViewMultipleGenerations(t *testing.T) {
+	a := &App{}
+	resp := &aipg.JobStatusResponse{
+		ID:       "job",
+		Done:     true,
+		Finished: 3,
+		Generations: []aipg.Generation{
+			{ID: "gen-1", Seed: "11", ImgURL: "https://cdn.example.com/gen-1.webp"},
+			{ID: "gen-2", Seed: "12", ImgURL: "https://cdn.example.com/gen-2.webp"},
+			{ID: "gen-3", Seed: "13"},
+		},
+	}
+
+	view := a.buildJobView(resp)
+	if view.Status != "completed" || view.Finished != 3 || len(view.Generations) != 3 {
+		t.Fatalf("view = %+v, want 3 completed generations", view)
+	}
+	for i, gen := range view.Generations {
+		wantID := fmt.Sprintf("gen-%d", i+1)
+		if gen.ID != wantID || gen.Seed != fmt.Sprintf("1%d", i+1) || gen.URL != "https://images.aipg.art/"+wantID+".webp" {
+			t.Errorf("generation %d = %+v, want %s with its own seed and URL", i, gen, wantID)
+		}
+	}
+}
+
+func TestBuildJobViewDoneWithoutGenerations(t *testing.T) {
+	a := &App{}
+	view := a.buildJobView(&aipg.JobStatusResponse{ID: "job", Done: true, Finished: 1})
+	if view.Status != jobCompletedEmpty || view.Message == "" || len(view.Generations) != 0 {
+		t.Errorf("view = %+v, want completed_empty with a message", view)
+	}
+
+	// Faulted wins, and a job still running with nothing yet is just running
+	if view := a.buildJobView(&aipg.JobStatusResponse{ID: "job", Done: true, Faulted: true}); view.Status != "faulted" || view.Message != "" {
+		t.Errorf("faulted job: status = %q, message = %q", view.Status, view.Message)
+	}
+	if view := a.buildJobView(&aipg.JobStatusResponse{ID: "job", Processing: 1}); view.Status != "processing" {
+		t.Errorf("running job: status = %q, want processing", view.Status)
+	}
+	view = a.buildJobView(&aipg.JobStatusResponse{ID: "job", Done: true, Generations: []aipg.Generation{{ID: "gen-1"}}})
+	if view.Status != "completed" || view.Message != "" {
+		t.Errorf("job with output: status = %q, message = %q", view.Status, view.Message)
+	}
+}
+
+func TestBuildJobViewMediaURLs(t *testing.T) {
+	a := &App{}
+	tests := []struct {
+		name string
+		gen  aipg.Generation
+		want string
+	}{
+		{name: "bare object key", gen: aipg.Generation{ID: "g1", Mime: "image/webp", Img: "g1.webp"}, want: "https://images.aipg.art/g1.webp"},
+		{name: "bare key without extension", gen: aipg.Generation{ID: "g2", Img: "g2"}, want: "https://images.aipg.art/g2.webp"},
+		{name: "presigned R2 URL", gen: aipg.Generation{ID: "g3", ImgURL: "https://acct.r2.cloudflarestorage.com/bucket/g3.webp?X-Amz-Signature=abc"}, want: "https://images.aipg.art/g3.webp"},
+		{name: "no URL at all", gen: aipg.Generation{ID: "g4", Mime: "image/png"}, want: "https://images.aipg.art/g4.png"},
+		{name: "video key", gen: aipg.Generation{ID: "g5", Mime: "video/mp4", Video: "g5.webp"}, want: "https://images.aipg.art/g5.webp"},
+	}
+	for _, tc := range tests {
+		got := a.buildJobView(&aipg.JobStatusResponse{Done: true, Generations: []aipg.Generation{tc.gen}}).Generations[0]
+		if got.URL != tc.want {
+			t.Errorf("%s: URL = %q, want %q", tc.name, got.URL, tc.want)
+		}
+	}
+}
@@ -0,0 +1,487 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/aipg"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/models"
+)
+
+// testPresets is a small catalog shared by the job creation tests
+var testPresets = []models.ModelPreset{
+	{
+		ID:       "FLUX.1-dev",
+		Type:     "image",
+		Defaults: models.ModelDefaults{Width: 1024, Height: 1024, Steps: 20, CfgScale: 3.5, Sampler: "euler", Scheduler: "simple"},
+		Limits: models.ModelLimits{
+			Width:    &models.RangeInt{Min: 512, Max: 2048, Step: 64},
+			Height:   &models.RangeInt{Min: 512, Max: 2048, Step: 64},
+			Steps:    &models.RangeInt{Min: 1, Max: 50, Step: 1},
+			CfgScale: &models.RangeFloat{Min: 1, Max: 10, Step: 0.5},
+		},
+	},
+	{
+		ID:       "wan2.2-t2v-a14b",
+		Type:     "video",
+		Defaults: models.ModelDefaults{Width: 832, Height: 480, Steps: 20, CfgScale: 5, Sampler: "uni_pc", Scheduler: "simple", Length: 81, FPS: 16},
+		Limits: models.ModelLimits{
+			Length: &models.RangeInt{Min: 17, Max: 121, Step: 4},
+			FPS:    &models.RangeInt{Min: 8, Max: 30, Step: 1},
+		},
+	},
+}
+
+func TestCreateJobInsufficientKudos(t *testing.T) {
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/generate/async":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"Need 12 kudos upfront","rc":"KudosUpfront"}`))
+		case "/find_user":
+			w.Write([]byte(`{"id":7,"username":"tester#7","kudos":3.5}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer grid.Close()
+
+	a := newTestApp(t, grid.URL)
+	a.catalog = models.NewCatalog(testPresets)
+
+	body := `{"modelId":"FLUX.1-dev","prompt":"a lighthouse"}`
+	rec := serve(a, httptest.NewRequest(http.MethodPost, "/api/jobs", strings.NewReader(body)))
+	if rec.Code != http.StatusPaymentRequired {
+		t.Fatalf("status = %d, want 402 (body %s)", rec.Code, rec.Body)
+	}
+
+	var resp map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp["kudos"] != 3.5 {
+		t.Errorf("kudos = %v, want 3.5", resp["kudos"])
+	}
+	if msg, _ := resp["error"].(string); !strings.Contains(msg, "kudos") {
+		t.Errorf("error = %q, want a kudos message", msg)
+	}
+}
+
+func TestCreateJobPropagatesRequestID(t *testing.T) {
+	var gridID string
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gridID = r.Header.Get(aipg.RequestIDHeader)
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"id":"job-traced"}`))
+	}))
+	defer grid.Close()
+
+	a := newTestApp(t, grid.URL)
+	a.catalog = models.NewCatalog(testPresets)
+	store := newMemoryJobRequestStore()
+	a.jobRequests = store
+
+	submit := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/jobs", strings.NewReader(`{"modelId":"FLUX.1-dev","prompt":"a lighthouse"}`))
+		if id != "" {
+			req.Header.Set(aipg.RequestIDHeader, id)
+		}
+		rec := serve(a, req)
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+		}
+		return rec
+	}
+
+	rec := submit("trace-123")
+	if gridID != "trace-123" || rec.Header().Get(aipg.RequestIDHeader) != "trace-123" {
+		t.Errorf("grid saw %q, response echoed %q; want the caller's ID on both", gridID, rec.Header().Get(aipg.RequestIDHeader))
+	}
+	if recorded, _ := store.GetJobRequest("job-traced"); recorded == nil || recorded.RequestID != "trace-123" {
+		t.Errorf("recorded job = %+v, want request ID stored with it", recorded)
+	}
+
+	for _, id := range []string{"", "bad id\nwith newline"} {
+		rec := submit(id)
+		echoed := rec.Header().Get(aipg.RequestIDHeader)
+		if echoed == "" || echoed == id || gridID != echoed {
+			t.Errorf("incoming %q: grid saw %q, response echoed %q; want one generated ID on both", id, gridID, echoed)
+		}
+	}
+}
+
+func TestPostBodiesRequireJSON(t *testing.T) {
+	a := newTestApp(t, "http://grid.invalid")
+	a.catalog = models.NewCatalog(testPresets)
+
+	tests := []struct {
+		contentType string
+		want        int
+	}{
+		{contentType: "application/x-www-form-urlencoded", want: http.StatusUnsupportedMediaType},
+		{contentType: "text/plain", want: http.StatusUnsupportedMediaType},
+		{contentType: "multipart/form-data; boundary=x", want: http.StatusUnsupportedMediaType},
+		{contentType: "not a media type;;", want: http.StatusUnsupportedMediaType},
+		// Accepted content types get past the check and fail validation on the empty body
+		{contentType: "application/json", want: http.StatusBadRequest},
+		{contentType: "Application/JSON; charset=utf-8", want: http.StatusBadRequest},
+		{contentType: "", want: http.StatusBadRequest},
+	}
+	for _, path := range []string{"/api/jobs", "/api/gallery"} {
+		for _, tc := range tests {
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{}`))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			rec := serve(a, req)
+			if rec.Code != tc.want {
+				t.Errorf("POST %s as %q = %d, want %d (%s)", path, tc.contentType, rec.Code, tc.want, rec.Body.String())
+			}
+		}
+	}
+}
+
+func TestCreateJobPixelCeiling(t *testing.T) {
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"id":"job-1"}`))
+	}))
+	defer grid.Close()
+
+	a := newTestApp(t, grid.URL)
+	a.catalog = models.NewCatalog(testPresets)
+	a.cfg.MaxImagePixels = 1024 * 1024
+	a.cfg.MaxVideoPixels = 832 * 480 * 81
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "image at ceiling", body: `{"modelId":"FLUX.1-dev","prompt":"p","params":{"width":1024,"height":1024}}`, want: http.StatusAccepted},
+		{name: "image above ceiling within preset", body: `{"modelId":"FLUX.1-dev","prompt":"p","params":{"width":1024,"height":1088}}`, want: http.StatusBadRequest},
+		{name: "video at ceiling", body: `{"modelId":"wan2.2-t2v-a14b","prompt":"p","params":{"width":832,"height":480,"length":81}}`, want: http.StatusAccepted},
+		{name: "video above ceiling by length", body: `{"modelId":"wan2.2-t2v-a14b","prompt":"p","params":{"width":832,"height":480,"length":85}}`, want: http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := serve(a, httptest.NewRequest(http.MethodPost, "/api/jobs", strings.NewReader(tc.body)))
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tc.want, rec.Body.String())
+			}
+			if tc.want == http.StatusBadRequest && !strings.Contains(rec.Body.String(), "server limit") {
+				t.Errorf("error = %s, want it to name the server limit", rec.Body.String())
+			}
+		})
+	}
+
+	a.cfg.MaxImagePixels = 0
+	body := `{"modelId":"FLUX.1-dev","prompt":"p","params":{"width":2048,"height":2048}}`
+	if rec := serve(a, httptest.NewRequest(http.MethodPost, "/api/jobs", strings.NewReader(body))); rec.Code != http.StatusAccepted {
+		t.Errorf("disabled ceiling status = %d, want 202", rec.Code)
+	}
+}
+
+func TestCreateJobFrameCeiling(t *testing.T) {
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"id":"job-1"}`))
+	}))
+	defer grid.Close()
+
+	a := newTestApp(t, grid.URL)
+	a.catalog = models.NewCatalog(testPresets)
+	a.cfg.MaxVideoFrames = 81 // below the preset's own limit of 121
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "at the cap", body: `{"modelId":"wan2.2-t2v-a14b","prompt":"p","params":{"length":81,"fps":24}}`, want: http.StatusAccepted},
+		{name: "above the cap within the preset", body: `{"modelId":"wan2.2-t2v-a14b","prompt":"p","params":{"length":85}}`, want: http.StatusBadRequest},
+		{name: "above the preset too", body: `{"modelId":"wan2.2-t2v-a14b","prompt":"p","params":{"length":500}}`, want: http.StatusBadRequest},
+		{name: "images are unaffected", body: `{"modelId":"FLUX.1-dev","prompt":"p","params":{"length":500}}`, want: http.StatusAccepted},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := serve(a, httptest.NewRequest(http.MethodPost, "/api/jobs", strings.NewReader(tc.body)))
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tc.want, rec.Body.String())
+			}
+			if tc.want == http.StatusBadRequest && !strings.Contains(rec.Body.String(), `"params.length"`) {
+				t.Errorf("error = %s, want it reported on params.length", rec.Body.String())
+			}
+		})
+	}
+
+	// A preset limit tighter than the cap is the one reported
+	a.cfg.MaxVideoFrames = 200
+	body := `{"modelId":"wan2.2-t2v-a14b","prompt":"p","params":{"length":500}}`
+	if rec := serve(a, httptest.NewRequest(http.MethodPost, "/api/jobs", strings.NewReader(body))); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "17-121") {
+		t.Errorf("cap above the preset limit: status = %d, body = %s; want 400 naming the preset range", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCreateJobCountLimit(t *testing.T) {
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"id":"job-1"}`))
+	}))
+	defer grid.Close()
+	a := newTestApp(t, grid.URL)
+	a.catalog = models.NewCatalog(testPresets)
+	a.cfg.MaxImagesPerJob = 4
+
+	for body, want := range map[string]int{
+		`{"modelId":"FLUX.1-dev","prompt":"p","params":{"count":4}}`: http.StatusAccepted,
+		`{"modelId":"FLUX.1-dev","prompt":"p","params":{"count":5}}`: http.StatusBadRequest,
+	} {
+		if rec := serve(a, httptest.NewRequest(http.MethodPost, "/api/jobs", strings.NewReader(body))); rec.Code != want {
+			t.Errorf("%s: status = %d, want %d (%s)", body, rec.Code, want, rec.Body.String())
+		}
+	}
+}
+
+func TestCreateJobOfflineModelFallback(t *testing.T) {
+	var sentModels []string
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/status/models"):
+			w.Write([]byte(`[{"name":"SDXL 1.0","count":3},{"name":"FLUX.1-dev","count":0}]`))
+		case strings.HasSuffix(r.URL.Path, "/generate/async"):
+			var payload aipg.CreateJobPayload
+			json.NewDecoder(r.Body).Decode(&payload)
+			sentModels = payload.Models
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"id":"job-1"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer grid.Close()
+	a := newTestApp(t, grid.URL)
+	a.catalog = models.NewCatalog(append([]models.ModelPreset{{ID: "SDXL 1.0", Type: "image"}}, testPresets...))
+	a.cfg.FallbackModelID = "SDXL 1.0"
+	a.cfg.FallbackModelMode = fallbackAppend
+
+	create := func(modelID string) *httptest.ResponseRecorder {
+		sentModels = nil
+		body := `{"modelId":"` + modelID + `","prompt":"p"}`
+		return serve(a, httptest.NewRequest(http.MethodPost, "/api/jobs", strings.NewReader(body)))
+	}
+
+	rec := create("FLUX.1-dev")
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("append status = %d, want 202 (%s)", rec.Code, rec.Body.String())
+	}
+	if len(sentModels) != 2 || sentModels[0] != "FLUX.1-dev" || sentModels[1] != "SDXL 1.0" {
+		t.Errorf("models sent to grid = %v, want [FLUX.1-dev SDXL 1.0]", sentModels)
+	}
+	var accepted struct {
+		FallbackModel string `json:"fallbackModel"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &accepted)
+	if accepted.FallbackModel != "SDXL 1.0" {
+		t.Errorf("fallbackModel = %q, want SDXL 1.0", accepted.FallbackModel)
+	}
+
+	// The fallback itself is online, so it's sent alone
+	if rec := create("SDXL 1.0"); rec.Code != http.StatusAccepted || len(sentModels) != 1 {
+		t.Errorf("online model: status = %d, models = %v", rec.Code, sentModels)
+	}
+
+	a.cfg.FallbackModelMode = fallbackReject
+	rec = create("FLUX.1-dev")
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("reject status = %d, want 409 (%s)", rec.Code, rec.Body.String())
+	}
+	if sentModels != nil {
+		t.Errorf("rejected job reached the grid with %v", sentModels)
+	}
+	var conflict struct {
+		SuggestedModel string `json:"suggestedModel"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &conflict)
+	if conflict.SuggestedModel != "SDXL 1.0" {
+		t.Errorf("suggestedModel = %q, want SDXL 1.0", conflict.SuggestedModel)
+	}
+}
+
+func TestCreateJobReportsAllValidationErrors(t *testing.T) {
+	a := newTestApp(t, "http://grid.invalid")
+	a.catalog = models.NewCatalog(testPresets)
+	a.cfg.MaxImagesPerJob = 4
+
+	body := `{"modelId":"no-such-model","prompt":" ","params":{"count":9},"textualInversions":[{"name":""}]}`
+	rec := serve(a, httptest.NewRequest(http.MethodPost, "/api/jobs", strings.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+	var resp struct {
+		Error  string            `json:"error"`
+		Fields map[string]string `json:"fields"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"prompt", "modelId", "params.count", "textualInversions[0]"} {
+		if resp.Fields[field] == "" {
+			t.Errorf("no error for %s in %v", field, resp.Fields)
+		}
+	}
+	if !strings.Contains(resp.Error, "unknown model: no-such-model") || !strings.Contains(resp.Error, "prompt is required") {
+		t.Errorf("error = %q, want every message", resp.Error)
+	}
+}
+
+func TestCreateJobTrack(t *testing.T) {
+	var propagated atomic.Bool
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/generate/async"):
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"id":"job-1"}`))
+		case !propagated.Load():
+			http.NotFound(w, r)
+		default:
+			w.Write([]byte(`{"id":"job-1","done":false,"processing":1,"wait_time":12}`))
+		}
+	}))
+	defer grid.Close()
+	a := newTestApp(t, grid.URL)
+	a.catalog = models.NewCatalog(testPresets)
+	a.jobs.retries = 0
+
+	create := func(query string) map[string]json.RawMessage {
+		t.Helper()
+		body := `{"modelId":"FLUX.1-dev","prompt":"p"}`
+		rec := serve(a, httptest.NewRequest(http.MethodPost, "/api/jobs"+query, strings.NewReader(body)))
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("%s: status = %d (%s)", query, rec.Code, rec.Body.String())
+		}
+		var resp map[string]json.RawMessage
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return resp
+	}
+
+	if resp := create(""); resp["job"] != nil {
+		t.Errorf("plain create returned a job view: %s", resp["job"])
+	}
+
+	// Not on the Grid yet: the grace logic reports it queued
+	resp := create("?track=true")
+	var job JobView
+	if err := json.Unmarshal(resp["job"], &job); err != nil {
+		t.Fatalf("job: %v", err)
+	}
+	if string(resp["jobId"]) != `"job-1"` || job.JobID != "job-1" || job.Status != "queued" {
+		t.Errorf("unpropagated: jobId = %s, job = %+v", resp["jobId"], job)
+	}
+
+	propagated.Store(true)
+	resp = create("?track=true")
+	json.Unmarshal(resp["job"], &job)
+	if job.Status != "processing" || job.WaitTime != 12 || string(resp["status"]) != `"processing"` {
+		t.Errorf("propagated: status = %s, job = %+v", resp["status"], job)
+	}
+
+	body := `{"modelId":"FLUX.1-dev","prompt":"p"}`
+	if rec := serve(a, httptest.NewRequest(http.MethodPost, "/api/jobs?track=maybe", strings.NewReader(body))); rec.Code != http.StatusBadRequest {
+		t.Errorf("track=maybe status = %d, want 400", rec.Code)
+	}
+}
+
+func TestCreateJobAnonymousKey(t *testing.T) {
+	type submitted struct {
+		key    string
+		shared bool
+	}
+	var got []submitted
+	var mu sync.Mutex
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload aipg.CreateJobPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		got = append(got, submitted{key: r.Header.Get("apikey"), shared: payload.Shared})
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"id":"job-1"}`))
+	}))
+	defer grid.Close()
+	a := newTestApp(t, grid.URL)
+	a.catalog = models.NewCatalog(testPresets)
+	a.cfg.DefaultAPIKey = ""
+	a.cfg.AnonAPIKey = "anon-key"
+	a.cfg.AnonMaxImagesPerJob = 1
+	a.anonLimiter = newRateLimiter(2, time.Hour)
+
+	create := func(body string) *httptest.ResponseRecorder {
+		return serve(a, httptest.NewRequest(http.MethodPost, "/api/jobs", strings.NewReader(body)))
+	}
+
+	rec := create(`{"modelId":"FLUX.1-dev","prompt":"p","public":false}`)
+	if rec.Code != http.StatusAccepted || !strings.Contains(rec.Body.String(), `"anonymous":true`) {
+		t.Fatalf("anonymous job: status = %d (%s)", rec.Code, rec.Body.String())
+	}
+	if len(got) != 1 || got[0] != (submitted{key: "anon-key", shared: true}) {
+		t.Errorf("submitted %+v, want the anon key and shared", got)
+	}
+
+	// Anonymous jobs get the tighter image limit
+	if rec := create(`{"modelId":"FLUX.1-dev","prompt":"p","params":{"count":2}}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("count 2: status = %d, want 400", rec.Code)
+	}
+
+	// A caller's own key isn't anonymous or limited
+	for i := 0; i < 3; i++ {
+		rec := create(`{"modelId":"FLUX.1-dev","prompt":"p","apiKey":"own-key","params":{"count":2}}`)
+		if rec.Code != http.StatusAccepted || strings.Contains(rec.Body.String(), "anonymous") {
+			t.Fatalf("own key: status = %d (%s)", rec.Code, rec.Body.String())
+		}
+	}
+	if last := got[len(got)-1]; last.key != "own-key" || last.shared {
+		t.Errorf("own key submitted %+v", last)
+	}
+
+	if rec := create(`{"modelId":"FLUX.1-dev","prompt":"p"}`); rec.Code != http.StatusAccepted {
+		t.Fatalf("second anonymous job: status = %d", rec.Code)
+	}
+	rec = create(`{"modelId":"FLUX.1-dev","prompt":"p"}`)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("third anonymous job: status = %d, Retry-After = %q; want 429", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	// Without an anon key, keyless jobs are still refused
+	a.cfg.AnonAPIKey = ""
+	if rec := create(`{"modelId":"FLUX.1-dev","prompt":"p"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("no keys: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestCreateJobAnonymousUnlimited(t *testing.T) {
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"id":"job-1"}`))
+	}))
+	defer grid.Close()
+	a := newTestApp(t, grid.URL)
+	a.catalog = models.NewCatalog(testPresets)
+	a.cfg.DefaultAPIKey = ""
+	a.cfg.AnonAPIKey = "anon-key"
+	// ANON_RATE_LIMIT=0 turns the limiter off
+	a.anonLimiter = newRateLimiter(0, time.Hour)
+
+	for i := 1; i <= 3; i++ {
+		rec := serve(a, httptest.NewRequest(http.MethodPost, "/api/jobs", strings.NewReader(`{"modelId":"FLUX.1-dev","prompt":"p"}`)))
+		if rec.Code != http.StatusAccepted || rec.Header().Get("X-RateLimit-Limit") != "" {
+			t.Fatalf("anonymous job %d: status = %d, headers = %v (%s)", i, rec.Code, rec.Header(), rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package app
+
+import "github.com/aipowergrid/aipg-art-gallery/server/internal/r2"
+
+// mediaURLs points public media URLs at MEDIA_CDN_BASE when one is set;
+// presigned and other non-public URLs are left alone
+func (a *App) mediaURLs(urls []string) []string {
+	if a.cfg.MediaCDNBase == "" {
+		return urls
+	}
+	out := make([]string, len(urls))
+	for i, u := range urls {
+		out[i] = r2.RewriteMediaURL(u, a.cfg.MediaCDNBase)
+	}
+	return out
+}
+
+// withMediaCDN is view with its generation URLs moved to MEDIA_CDN_BASE.
+// Stored results keep the original URLs so a CDN change applies to them too.
+func (a *App) withMediaCDN(view JobView) JobView {
+	if a.cfg.MediaCDNBase == "" || len(view.Generations) == 0 {
+		return view
+	}
+	gens := make([]GenerationView, len(view.Generations))
+	for i, gen := range view.Generations {
+		gen.URL = r2.RewriteMediaURL(gen.URL, a.cfg.MediaCDNBase)
+		gens[i] = gen
+	}
+	view.Generations = gens
+	return view
+}
@@ -0,0 +1,51 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+)
+
+func TestMediaCDNRewrite(t *testing.T) {
+	presigned := "https://acct.r2.cloudflarestorage.com/horde-permanent/private.webp?X-Amz-Signature=abc"
+	gridUp := true
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !gridUp {
+			http.Error(w, "down", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"id":"job-1","done":true,"generations":[{"id":"gen-1"}]}`))
+	}))
+	defer grid.Close()
+	a := newTestApp(t, grid.URL)
+	a.cfg.MediaCDNBase = "https://cdn.example.com"
+
+	rec := serve(a, httptest.NewRequest(http.MethodGet, "/api/jobs/job-1", nil))
+	var view JobView
+	json.Unmarshal(rec.Body.Bytes(), &view)
+	if len(view.Generations) != 1 || view.Generations[0].URL != "https://cdn.example.com/gen-1.webp" {
+		t.Errorf("job status generations = %+v, want the CDN URL", view.Generations)
+	}
+
+	// Cached gallery URLs: public ones move to the CDN, presigned ones stay put
+	gridUp = false
+	a.galleryStore.Add(context.Background(), gallery.GalleryItem{
+		JobID:     "job-2",
+		IsPublic:  true,
+		MediaURLs: []string{"https://images.aipg.art/gen-2.webp", presigned},
+	})
+	rec = serve(a, httptest.NewRequest(http.MethodGet, "/api/gallery/job-2/media", nil))
+	var media struct {
+		MediaURLs []string `json:"mediaUrls"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &media)
+	want := []string{"https://cdn.example.com/gen-2.webp", presigned}
+	if strings.Join(media.MediaURLs, " ") != strings.Join(want, " ") {
+		t.Errorf("media URLs = %v, want %v", media.MediaURLs, want)
+	}
+}
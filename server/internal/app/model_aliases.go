@@ -0,0 +1,154 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConfirmedAlias is an operator-approved mapping from a preset to the name its
+// workers report to the Grid
+type ConfirmedAlias struct {
+	PresetID    string    `json:"presetId"`
+	GridName    string    `json:"gridName"`
+	ConfirmedBy string    `json:"confirmedBy,omitempty"`
+	ConfirmedAt time.Time `json:"confirmedAt"`
+}
+
+// activeAliases is modelNameAliases plus every confirmed alias, swapped in
+// whole whenever the alias file is reloaded so lookups never need a lock
+var activeAliases atomic.Pointer[map[string][]string]
+
+// confirmedGridNames maps each preset with a confirmed alias to the Grid name
+// jobs for it are submitted under, the latest confirmation winning
+var confirmedGridNames atomic.Pointer[map[string]string]
+
+// modelAliases returns the alias table lookups should use
+func modelAliases() map[string][]string {
+	if aliases := activeAliases.Load(); aliases != nil {
+		return *aliases
+	}
+	return modelNameAliases
+}
+
+// aliasStore keeps confirmed aliases in a JSON file next to the gallery data,
+// so alias fixes don't need a redeploy
+type aliasStore struct {
+	mu      sync.Mutex
+	path    string
+	entries []ConfirmedAlias
+}
+
+// newAliasStore loads the alias file at path, if any, and activates its aliases.
+// A missing file is fine; an unreadable one is an error.
+func newAliasStore(path string) (*aliasStore, error) {
+	s := &aliasStore{path: path}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// reload re-reads the alias file and rebuilds the active alias table
+func (s *aliasStore) reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entries []ConfirmedAlias
+	data, err := os.ReadFile(s.path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return fmt.Errorf("invalid alias file %s: %w", s.path, err)
+		}
+	}
+
+	s.entries = entries
+	merged := mergeAliases(modelNameAliases, entries)
+	activeAliases.Store(&merged)
+	gridNames := make(map[string]string, len(entries))
+	for _, e := range entries {
+		if e.PresetID != "" && e.GridName != "" {
+			gridNames[e.PresetID] = e.GridName
+		}
+	}
+	confirmedGridNames.Store(&gridNames)
+	return nil
+}
+
+// Add appends a confirmed alias to the file and reloads it
+func (s *aliasStore) Add(alias ConfirmedAlias) error {
+	s.mu.Lock()
+	entries := append(append([]ConfirmedAlias(nil), s.entries...), alias)
+	err := writeAliasFile(s.path, entries)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.reload()
+}
+
+// writeAliasFile replaces the alias file in one rename so a crash can't leave it half written
+func writeAliasFile(path string, entries []ConfirmedAlias) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// mergeAliases returns a copy of base with each confirmed Grid name added to its preset's aliases
+func mergeAliases(base map[string][]string, entries []ConfirmedAlias) map[string][]string {
+	merged := make(map[string][]string, len(base)+len(entries))
+	for id, aliases := range base {
+		merged[id] = append([]string(nil), aliases...)
+	}
+	for _, e := range entries {
+		if e.PresetID == "" || e.GridName == "" || containsFold(merged[e.PresetID], e.GridName) {
+			continue
+		}
+		merged[e.PresetID] = append(merged[e.PresetID], e.GridName)
+	}
+	return merged
+}
+
+// withAlias is modelAliases with one extra mapping, for checking an alias before saving it
+func withAlias(presetID, gridName string) map[string][]string {
+	return mergeAliases(modelAliases(), []ConfirmedAlias{{PresetID: presetID, GridName: gridName}})
+}
+
+// ConfirmAliasRequest is the body of POST /api/admin/models/aliases
+type ConfirmAliasRequest struct {
+	PresetID    string `json:"presetId"`
+	GridName    string `json:"gridName"`
+	ConfirmedBy string `json:"confirmedBy"`
+}
+
+func (r *ConfirmAliasRequest) normalize() error {
+	r.PresetID = strings.TrimSpace(r.PresetID)
+	r.GridName = strings.TrimSpace(r.GridName)
+	r.ConfirmedBy = strings.TrimSpace(r.ConfirmedBy)
+	switch {
+	case r.PresetID == "" || r.GridName == "":
+		return errors.New("presetId and gridName are required")
+	case r.ConfirmedBy == "":
+		return errors.New("confirmedBy is required")
+	}
+	return nil
+}
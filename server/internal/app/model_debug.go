@@ -0,0 +1,179 @@
+package app
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/aipg"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/models"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/modelvault"
+)
+
+// ModelDebugView explains how one model's view is put together: the preset it
+// starts from, the Grid entry and on-chain model it was matched to and how,
+// and the resulting view the public model endpoint serves
+type ModelDebugView struct {
+	Preset models.ModelPreset `json:"preset"`
+	Grid   GridMatchDebug     `json:"grid"`
+	Chain  ChainMatchDebug    `json:"chain"`
+	View   ModelView          `json:"view"`
+}
+
+// GridMatchDebug is the Grid stats lookup for a preset
+type GridMatchDebug struct {
+	MatchedName string `json:"matchedName,omitempty"`
+	// exact, lowercase, alias, reverseAlias or normalized; empty when nothing matched
+	Via string `json:"via,omitempty"`
+	// Alias table entries for the preset, built in and confirmed
+	Aliases []string `json:"aliases"`
+	// The Grid entry used, as sent and as parsed
+	Stat *GridModelStatsView `json:"stat,omitempty"`
+	// Likely Grid names, when nothing with workers matched
+	Suggestions []AliasSuggestion `json:"suggestions,omitempty"`
+	Error       string            `json:"error,omitempty"`
+}
+
+// ChainMatchDebug is the ModelVault lookup for a preset
+type ChainMatchDebug struct {
+	Enabled bool `json:"enabled"`
+	// Key the model was found under, and exact, lowercase or normalized
+	MatchedKey string          `json:"matchedKey,omitempty"`
+	Via        string          `json:"via,omitempty"`
+	Model      *ChainModelView `json:"model,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// ChainModelView is an on-chain model record with its constraints in full
+type ChainModelView struct {
+	ModelHash    string                       `json:"modelHash"`
+	ModelType    string                       `json:"modelType"`
+	FileName     string                       `json:"fileName"`
+	DisplayName  string                       `json:"displayName"`
+	Description  string                       `json:"description,omitempty"`
+	BaseModel    string                       `json:"baseModel,omitempty"`
+	Architecture string                       `json:"architecture,omitempty"`
+	IsActive     bool                         `json:"isActive"`
+	IsNSFW       bool                         `json:"isNsfw"`
+	Constraints  *modelvault.ModelConstraints `json:"constraints,omitempty"`
+}
+
+// handleModelDebug shows every input behind one model's view in one place.
+// Stats come from the Grid uncached; a failed source is reported in its
+// section rather than failing the whole response.
+func (a *App) handleModelDebug(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	preset, ok := a.catalog.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("model %s not found", id))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	stats, statsErr := a.client.FetchModelStats(ctx)
+	var chainModels map[string]*modelvault.OnChainModel
+	var chainErr error
+	if a.vaultClient.IsEnabled() {
+		chainModels, chainErr = a.vaultClient.FetchAllModels(ctx)
+	}
+
+	debug := buildModelDebug(preset, stats, statsErr, chainModels, chainErr, a.statusThresholds)
+	debug.Chain.Enabled = a.vaultClient.IsEnabled()
+	writeJSON(w, http.StatusOK, debug)
+}
+
+// buildModelDebug resolves a preset against Grid stats and chain models the
+// way the model endpoints do, recording each match and the view it produces
+func buildModelDebug(preset models.ModelPreset, stats []aipg.ModelStatus, statsErr error, chainModels map[string]*modelvault.OnChainModel, chainErr error, thresholds statusThresholds) ModelDebugView {
+	aliases := modelAliases()
+	debug := ModelDebugView{
+		Preset: preset,
+		Grid:   GridMatchDebug{Aliases: append([]string{}, aliases[preset.ID]...)},
+	}
+
+	var stat aipg.ModelStatus
+	if statsErr != nil {
+		debug.Grid.Error = statsErr.Error()
+	} else {
+		var via string
+		stat, via = resolveModelStats(preset.ID, indexModelStats(stats), aliases)
+		if via != "" {
+			debug.Grid.MatchedName = stat.Name
+			debug.Grid.Via = via
+			debug.Grid.Stat = &GridModelStatsView{
+				ModelStatus: stat,
+				Parsed: ParsedModelStats{
+					Count:       stat.ParseCount(),
+					Queued:      stat.ParseQueued(),
+					Jobs:        stat.ParseJobs(),
+					ETA:         stat.ParseETA(),
+					Performance: stat.ParsePerformance(),
+				},
+			}
+		}
+		if stat.ParseCount() <= 0 {
+			names := make([]string, 0, len(stats))
+			for _, s := range stats {
+				names = append(names, s.Name)
+			}
+			debug.Grid.Suggestions = suggestGridNames(preset.ID, names, maxAliasSuggestions)
+		}
+	}
+
+	chainModel, key, via := findChainModel(preset.ID, chainModels)
+	if chainErr != nil {
+		debug.Chain.Error = chainErr.Error()
+	}
+	if chainModel != nil {
+		debug.Chain.MatchedKey = key
+		debug.Chain.Via = via
+		debug.Chain.Model = &ChainModelView{
+			ModelHash:    "0x" + hex.EncodeToString(chainModel.ModelHash[:]),
+			ModelType:    chainModel.ModelType.String(),
+			FileName:     chainModel.FileName,
+			DisplayName:  chainModel.DisplayName,
+			Description:  chainModel.Description,
+			BaseModel:    chainModel.BaseModel,
+			Architecture: chainModel.Architecture,
+			IsActive:     chainModel.IsActive,
+			IsNSFW:       chainModel.IsNSFW,
+			Constraints:  chainModel.Constraints,
+		}
+	}
+
+	debug.View = buildModelView(preset, stat, chainModel, thresholds)
+	return debug
+}
+
+// findChainModel matches a preset ID to a chain model as the vault client's
+// FindModel does, by exact key, lowercase key, then with dots and hyphens
+// folded to underscores, and reports the key and the step that matched
+func findChainModel(presetID string, chainModels map[string]*modelvault.OnChainModel) (*modelvault.OnChainModel, string, string) {
+	if m := chainModels[presetID]; m != nil {
+		return m, presetID, matchExact
+	}
+	if lower := strings.ToLower(presetID); chainModels[lower] != nil {
+		return chainModels[lower], lower, matchLowercase
+	}
+
+	keys := make([]string, 0, len(chainModels))
+	for key := range chainModels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	normalized := normalizeStatsName(presetID)
+	for _, key := range keys {
+		if normalizeStatsName(key) == normalized {
+			return chainModels[key], key, matchNormalized
+		}
+	}
+	return nil, "", ""
+}
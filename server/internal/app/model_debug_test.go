@@ -0,0 +1,96 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/aipg"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/models"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/modelvault"
+)
+
+func TestBuildModelDebug(t *testing.T) {
+	catalog := models.NewCatalog(testPresets)
+	flux, _ := catalog.Get("FLUX.1-dev")
+	stats := []aipg.ModelStatus{
+		{Name: "flux1-dev", Count: json.RawMessage(`"3"`), Queued: json.RawMessage(`4`)},
+		{Name: "sdxl", Count: json.RawMessage(`1`)},
+	}
+	chain := map[string]*modelvault.OnChainModel{
+		"flux_1_dev": {
+			ModelHash:   [32]byte{0xab},
+			ModelType:   modelvault.ImageModel,
+			DisplayName: "flux_1_dev",
+			IsActive:    true,
+			Constraints: &modelvault.ModelConstraints{StepsMin: 1, StepsMax: 12, AllowedSamplers: []string{"k_euler"}},
+		},
+	}
+
+	debug := buildModelDebug(flux, stats, nil, chain, nil, statusThresholds{})
+	if debug.Preset.ID != "FLUX.1-dev" {
+		t.Errorf("preset = %q", debug.Preset.ID)
+	}
+	if g := debug.Grid; g.MatchedName != "flux1-dev" || g.Via != matchAlias || g.Stat == nil || g.Stat.Parsed.Count != 3 || len(g.Suggestions) != 0 {
+		t.Errorf("grid = %+v, want flux1-dev matched by alias with 3 workers", g)
+	}
+	if string(debug.Grid.Stat.Count) != `"3"` {
+		t.Errorf("raw count = %s, want the Grid's own \"3\"", debug.Grid.Stat.Count)
+	}
+	c := debug.Chain
+	if c.MatchedKey != "flux_1_dev" || c.Via != matchNormalized || c.Model == nil || c.Model.ModelType != "image" {
+		t.Fatalf("chain = %+v, want flux_1_dev matched after normalizing", c)
+	}
+	if c.Model.ModelHash[:4] != "0xab" || c.Model.Constraints.StepsMax != 12 {
+		t.Errorf("chain model = %+v", c.Model)
+	}
+	if v := debug.View; !v.OnChain || v.OnlineWorkers != 3 || v.Limits.Steps.Max != 12 || !v.LimitsConstrainedByChain {
+		t.Errorf("view = %+v, want the chain's steps limit applied", v)
+	}
+
+	// Failed sources are reported where they happened; the rest still resolves
+	debug = buildModelDebug(flux, nil, errors.New("grid down"), nil, errors.New("rpc down"), statusThresholds{})
+	if debug.Grid.Error != "grid down" || debug.Grid.Stat != nil || debug.Chain.Error != "rpc down" || debug.Chain.Model != nil {
+		t.Errorf("errors = %+v / %+v", debug.Grid, debug.Chain)
+	}
+	if debug.View.ID != "FLUX.1-dev" || debug.View.Status != modelOffline {
+		t.Errorf("view without sources = %+v, want the offline preset", debug.View)
+	}
+}
+
+func TestAdminModelDebug(t *testing.T) {
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"name":"wan2_2_t2v_14b","count":0},{"name":"flux1-dev","count":1}]`))
+	}))
+	defer grid.Close()
+
+	a := newTestApp(t, grid.URL)
+	a.cfg.AdminToken = "s3cret"
+	a.catalog = models.NewCatalog(testPresets)
+	get := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/models/"+id+"/debug", nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		return serve(a, req)
+	}
+
+	rec := get("wan2.2-t2v-a14b")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var debug ModelDebugView
+	if err := json.NewDecoder(rec.Body).Decode(&debug); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if debug.Grid.MatchedName != "wan2_2_t2v_14b" || debug.Grid.Via == "" || debug.View.Status != modelOffline {
+		t.Errorf("grid = %+v, status %q; want the idle WAN entry matched", debug.Grid, debug.View.Status)
+	}
+	if debug.Chain.Enabled || debug.Chain.Model != nil {
+		t.Errorf("chain = %+v, want disabled", debug.Chain)
+	}
+
+	if rec := get("nope"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown model status = %d, want 404", rec.Code)
+	}
+}
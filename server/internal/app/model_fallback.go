@@ -0,0 +1,45 @@
+package app
+
+import (
+	"context"
+	"log"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/models"
+)
+
+// Fallback behaviours for FALLBACK_MODEL_MODE
+const (
+	// Add the fallback to the job's model list so any worker of either can take it
+	fallbackAppend = "append"
+	// Refuse the job with 409 and name the fallback so the user can resubmit
+	fallbackReject = "reject"
+)
+
+// fallbackFor returns the configured fallback preset when the requested preset
+// has no workers on the Grid and isn't active on chain. The fallback must be a
+// different preset of the same type. If stats can't be fetched the job goes
+// ahead as requested.
+func (a *App) fallbackFor(ctx context.Context, preset models.ModelPreset) (models.ModelPreset, bool) {
+	if a.cfg.FallbackModelID == "" || a.cfg.FallbackModelID == preset.ID {
+		return models.ModelPreset{}, false
+	}
+	fallback, ok := a.catalog.Get(a.cfg.FallbackModelID)
+	if !ok || fallback.Type != preset.Type {
+		return models.ModelPreset{}, false
+	}
+
+	stats, err := a.fetchModelStats(ctx)
+	if err != nil {
+		log.Printf("Warning: skipping fallback check for %s: %v", preset.ID, err)
+		return models.ModelPreset{}, false
+	}
+	if lookupModelStats(preset.ID, indexModelStats(stats)).ParseCount() > 0 {
+		return models.ModelPreset{}, false
+	}
+	if a.vaultClient.IsEnabled() {
+		if chainModel, _ := a.vaultClient.FindModel(ctx, preset.ID); chainModel != nil && chainModel.IsActive {
+			return models.ModelPreset{}, false
+		}
+	}
+	return fallback, true
+}
@@ -0,0 +1,185 @@
+package app
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/aipg"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/models"
+)
+
+// UnmatchedModel is a preset whose Grid stats lookup found nothing, or only a
+// model no worker is serving
+type UnmatchedModel struct {
+	PresetID    string `json:"presetId"`
+	DisplayName string `json:"displayName"`
+	// Grid name the lookup resolved to; empty when nothing matched
+	MatchedName string `json:"matchedName,omitempty"`
+	Workers     int    `json:"workers"`
+	// Grid names most likely to be this preset, best first
+	Suggestions []AliasSuggestion `json:"suggestions"`
+}
+
+// unmatchedModels runs lookupModelStats for every preset and reports the ones
+// without a serving Grid model, so missing alias entries are easy to spot
+func unmatchedModels(presets []models.ModelPreset, stats []aipg.ModelStatus) []UnmatchedModel {
+	index := indexModelStats(stats)
+	names := make([]string, 0, len(stats))
+	for _, s := range stats {
+		names = append(names, s.Name)
+	}
+
+	out := make([]UnmatchedModel, 0)
+	for _, preset := range presets {
+		stat := lookupModelStats(preset.ID, index)
+		if stat.Name != "" && stat.ParseCount() > 0 {
+			continue
+		}
+		out = append(out, UnmatchedModel{
+			PresetID:    preset.ID,
+			DisplayName: preset.DisplayName,
+			MatchedName: stat.Name,
+			Workers:     stat.ParseCount(),
+			Suggestions: suggestGridNames(preset.ID, names, maxAliasSuggestions),
+		})
+	}
+	return out
+}
+
+// Suggestion tuning: how many Grid names to propose per preset, the lowest
+// score worth showing, and how much edit distance counts against shared tokens
+const (
+	maxAliasSuggestions = 3
+	minSuggestionScore  = 0.3
+	editScoreWeight     = 0.6
+)
+
+// AliasSuggestion is a Grid name that probably refers to an unmatched preset
+type AliasSuggestion struct {
+	Name string `json:"name"`
+	// 1 for names that differ only in case and punctuation, down to 0
+	Score float64 `json:"score"`
+}
+
+// suggestGridNames ranks Grid names by how likely they are to be presetID,
+// best first, keeping at most n that score at least minSuggestionScore
+func suggestGridNames(presetID string, names []string, n int) []AliasSuggestion {
+	suggestions := make([]AliasSuggestion, 0, len(names))
+	for _, name := range names {
+		if score := aliasScore(presetID, name); score >= minSuggestionScore {
+			suggestions = append(suggestions, AliasSuggestion{Name: name, Score: score})
+		}
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].Name < suggestions[j].Name
+	})
+	if len(suggestions) > n {
+		suggestions = suggestions[:n]
+	}
+	return suggestions
+}
+
+// aliasScore blends edit similarity of the folded names with the overlap of
+// their tokens, rounded to two decimals. Edit distance catches typos and
+// punctuation; tokens catch reordered or extra words like a "fp8" suffix.
+func aliasScore(presetID, gridName string) float64 {
+	a, b := foldModelName(presetID), foldModelName(gridName)
+	longest := max(len([]rune(a)), len([]rune(b)))
+	if longest == 0 {
+		return 0
+	}
+	edit := 1 - float64(levenshtein(a, b))/float64(longest)
+	score := editScoreWeight*edit + (1-editScoreWeight)*tokenOverlap(modelNameTokens(presetID), modelNameTokens(gridName))
+	return math.Round(score*100) / 100
+}
+
+// modelNameTokens splits a name into lowercase words and numbers, so
+// "FLUX.1-dev", "flux1_dev" and "flux 1 dev" all give flux, 1, dev
+func modelNameTokens(name string) []string {
+	var tokens []string
+	var current []rune
+	flush := func() {
+		if len(current) > 0 {
+			tokens = append(tokens, string(current))
+			current = current[:0]
+		}
+	}
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case len(current) > 0 && unicode.IsDigit(r) != unicode.IsDigit(current[len(current)-1]):
+			flush()
+			current = append(current, r)
+		default:
+			current = append(current, r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+// tokenOverlap is the Jaccard similarity of two token lists
+func tokenOverlap(a, b []string) float64 {
+	set := make(map[string]bool, len(a))
+	for _, t := range a {
+		set[t] = true
+	}
+	union := len(set)
+	shared := 0
+	seen := make(map[string]bool, len(b))
+	for _, t := range b {
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		if set[t] {
+			shared++
+		} else {
+			union++
+		}
+	}
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
+
+// foldModelName lowercases a name and drops everything but letters and digits,
+// so "FLUX.1-dev" and "flux1_dev" compare equal
+func foldModelName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// levenshtein is the edit distance between a and b, counted in runes
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,50 @@
+package app
+
+import "testing"
+
+func TestSuggestGridNamesRanksSeparatorVariantsFirst(t *testing.T) {
+	grid := []string{"wan2_2_t2v_5b", "wan2_2_t2v_14b", "wan2_2_t2v_14b_hq", "flux1-dev", "sdxl", "ltxv"}
+
+	tests := []struct {
+		presetID string
+		want     string
+	}{
+		{presetID: "wan2.2-t2v-14b", want: "wan2_2_t2v_14b"},
+		{presetID: "wan2-2-t2v-14b-hq", want: "wan2_2_t2v_14b_hq"},
+		{presetID: "FLUX.1_dev", want: "flux1-dev"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.presetID, func(t *testing.T) {
+			got := suggestGridNames(tc.presetID, grid, maxAliasSuggestions)
+			if len(got) == 0 || got[0].Name != tc.want {
+				t.Fatalf("suggestions = %+v, want %q first", got, tc.want)
+			}
+			if got[0].Score != 1 {
+				t.Errorf("score for a separator-only variant = %v, want 1", got[0].Score)
+			}
+			for _, s := range got[1:] {
+				if s.Score >= got[0].Score {
+					t.Errorf("%q scored %v, not below the exact variant", s.Name, s.Score)
+				}
+			}
+		})
+	}
+}
+
+func TestAliasScore(t *testing.T) {
+	if typo, unrelated := aliasScore("Juggernaut XL", "juggernautxl_v9"), aliasScore("Juggernaut XL", "ltxv"); typo <= unrelated {
+		t.Errorf("versioned name scored %v, unrelated %v; want the versioned name higher", typo, unrelated)
+	}
+	if got := suggestGridNames("Juggernaut XL", []string{"ltxv", "chroma"}, maxAliasSuggestions); len(got) != 0 {
+		t.Errorf("suggestions = %+v, want unrelated names dropped", got)
+	}
+}
+
+func TestModelNameTokens(t *testing.T) {
+	for _, name := range []string{"FLUX.1-dev", "flux1_dev", "flux 1 dev"} {
+		got := modelNameTokens(name)
+		if len(got) != 3 || got[0] != "flux" || got[1] != "1" || got[2] != "dev" {
+			t.Errorf("modelNameTokens(%q) = %q, want [flux 1 dev]", name, got)
+		}
+	}
+}
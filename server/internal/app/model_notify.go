@@ -0,0 +1,367 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ModelOnlineNotice tells a waiting wallet that a model came online
+type ModelOnlineNotice struct {
+	ModelID       string    `json:"modelId"`
+	Wallet        string    `json:"wallet"`
+	Status        string    `json:"status"`
+	OnlineWorkers int       `json:"onlineWorkers"`
+	At            time.Time `json:"at"`
+}
+
+// Caps on registrations, so one wallet or one model can't grow the waiter
+// lists without bound
+const (
+	maxWaitsPerWallet  = 10
+	maxWaitersPerModel = 500
+)
+
+var (
+	errTooManyWaits   = fmt.Errorf("already waiting on %d models; wait for one to come online first", maxWaitsPerWallet)
+	errTooManyWaiters = errors.New("too many wallets are waiting on this model, try again later")
+)
+
+type modelWaiter struct {
+	wallet      string
+	callbackURL string
+}
+
+// modelNotifier tracks wallets waiting for offline models and notifies them
+// once when the model transitions offline -> online. Waiters without a
+// callback URL get their notice queued until they fetch it.
+type modelNotifier struct {
+	mu         sync.Mutex
+	waiters    map[string]map[string]modelWaiter // model ID -> wallet -> waiter
+	lastOnline map[string]bool                   // last observed state per awaited model
+	pending    map[string][]ModelOnlineNotice    // wallet -> undelivered notices
+	httpClient *http.Client
+}
+
+func newModelNotifier() *modelNotifier {
+	return &modelNotifier{
+		waiters:    make(map[string]map[string]modelWaiter),
+		lastOnline: make(map[string]bool),
+		pending:    make(map[string][]ModelOnlineNotice),
+		httpClient: &http.Client{Timeout: 10 * time.Second, Transport: publicOnlyTransport()},
+	}
+}
+
+// publicOnlyTransport only connects to public addresses. The check runs on
+// the address actually dialed, so a callback host that resolves somewhere
+// else by the time it's called (DNS rebinding) is still caught.
+func publicOnlyTransport() *http.Transport {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: dialPublicOnly}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would make the connection for us, past the check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// dialPublicOnly is a net.Dialer Control hook refusing non-public addresses
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !isPublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("refusing to connect to non-public address %s", addrPort.Addr())
+	}
+	return nil
+}
+
+// Ranges that are global unicast by the standard library's reckoning but
+// still not reachable on the public internet
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+}
+
+// isPublicAddr rejects loopback, private, link-local, multicast and
+// unspecified addresses, including IPv4 ones mapped into IPv6
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkCallbackURL requires an https URL whose host resolves only to public
+// addresses. Delivery checks the dialed address again.
+func checkCallbackURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || !strings.EqualFold(u.Scheme, "https") || u.Hostname() == "" {
+		return errors.New("callbackUrl must be an https URL")
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("callbackUrl host %s doesn't resolve", u.Hostname())
+	}
+	for _, addr := range addrs {
+		if !isPublicAddr(addr) {
+			return errors.New("callbackUrl must point at a public address")
+		}
+	}
+	return nil
+}
+
+// Register adds a waiter for a model that is currently offline. Registering
+// the same wallet twice only updates its callback and returns false. A wallet
+// can wait on maxWaitsPerWallet models, and a model have maxWaitersPerModel.
+func (n *modelNotifier) Register(modelID, wallet, callbackURL string) (bool, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	byWallet := n.waiters[modelID]
+	_, existed := byWallet[wallet]
+	if !existed {
+		waits := 0
+		for _, waiters := range n.waiters {
+			if _, ok := waiters[wallet]; ok {
+				waits++
+			}
+		}
+		if waits >= maxWaitsPerWallet {
+			return false, errTooManyWaits
+		}
+		if len(byWallet) >= maxWaitersPerModel {
+			return false, errTooManyWaiters
+		}
+	}
+	if byWallet == nil {
+		byWallet = make(map[string]modelWaiter)
+		n.waiters[modelID] = byWallet
+	}
+	byWallet[wallet] = modelWaiter{wallet: wallet, callbackURL: callbackURL}
+	n.lastOnline[modelID] = false
+	return !existed, nil
+}
+
+// WatchedModels returns the IDs of models that have at least one waiter
+func (n *modelNotifier) WatchedModels() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	ids := make([]string, 0, len(n.waiters))
+	for id := range n.waiters {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Observe records the current worker count for a watched model. On an
+// offline -> online transition it returns the waiters to notify and clears them.
+func (n *modelNotifier) Observe(modelID string, workers int) []modelWaiter {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	online := workers > 0
+	wasOnline := n.lastOnline[modelID]
+	n.lastOnline[modelID] = online
+	if !online || wasOnline {
+		return nil
+	}
+
+	byWallet := n.waiters[modelID]
+	delete(n.waiters, modelID)
+	delete(n.lastOnline, modelID)
+
+	notify := make([]modelWaiter, 0, len(byWallet))
+	for _, waiter := range byWallet {
+		notify = append(notify, waiter)
+	}
+	return notify
+}
+
+// Deliver POSTs the notice to the waiter's callback, or queues it for later pickup
+func (n *modelNotifier) Deliver(ctx context.Context, waiter modelWaiter, notice ModelOnlineNotice) {
+	if waiter.callbackURL == "" {
+		n.mu.Lock()
+		n.pending[waiter.wallet] = append(n.pending[waiter.wallet], notice)
+		n.mu.Unlock()
+		return
+	}
+
+	body, err := json.Marshal(notice)
+	if err != nil {
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, waiter.callbackURL, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("Warning: model notify callback for %s invalid: %v", waiter.wallet, err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		log.Printf("Warning: model notify callback for %s failed: %v", waiter.wallet, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("Warning: model notify callback for %s returned %d", waiter.wallet, resp.StatusCode)
+	}
+}
+
+// TakePending returns and clears the queued notices for a wallet
+func (n *modelNotifier) TakePending(wallet string) []ModelOnlineNotice {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	notices := n.pending[wallet]
+	delete(n.pending, wallet)
+	if notices == nil {
+		notices = []ModelOnlineNotice{}
+	}
+	return notices
+}
+
+// checkAwaitedModels fetches Grid stats once and notifies waiters of any model that came online
+func (a *App) checkAwaitedModels(ctx context.Context) {
+	watched := a.notifier.WatchedModels()
+	if len(watched) == 0 {
+		return
+	}
+
+	stats, err := a.fetchModelStats(ctx)
+	if err != nil {
+		log.Printf("Warning: model watcher failed to fetch stats: %v", err)
+		return
+	}
+	byName := indexModelStats(stats)
+
+	for _, modelID := range watched {
+		workers := lookupModelStats(modelID, byName).ParseCount()
+		waiters := a.notifier.Observe(modelID, workers)
+		if len(waiters) == 0 {
+			continue
+		}
+
+		log.Printf("Model %s came online (%d workers), notifying %d waiters", modelID, workers, len(waiters))
+		for _, waiter := range waiters {
+			a.notifier.Deliver(ctx, waiter, ModelOnlineNotice{
+				ModelID:       modelID,
+				Wallet:        waiter.wallet,
+				Status:        "online",
+				OnlineWorkers: workers,
+				At:            time.Now(),
+			})
+		}
+	}
+}
+
+type NotifyModelRequest struct {
+	CallbackURL string `json:"callbackUrl,omitempty"`
+}
+
+// handleNotifyModel registers the requesting wallet to be told when an offline model comes online
+func (a *App) handleNotifyModel(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	preset, ok := a.catalog.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("model %s not found", id))
+		return
+	}
+
+	wallet := verifiedWalletFromRequest(r)
+	if wallet == "" {
+		writeError(w, http.StatusUnauthorized, errors.New("signed wallet request required - sign in with your wallet to get notified"))
+		return
+	}
+
+	var req NotifyModelRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %w", err))
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	if req.CallbackURL != "" {
+		if err := checkCallbackURL(ctx, req.CallbackURL); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	stats, err := a.fetchModelStats(ctx)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	if workers := lookupModelStats(preset.ID, indexModelStats(stats)).ParseCount(); workers > 0 {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"modelId":       preset.ID,
+			"status":        "online",
+			"onlineWorkers": workers,
+			"registered":    false,
+		})
+		return
+	}
+
+	added, err := a.notifier.Register(preset.ID, wallet, req.CallbackURL)
+	if err != nil {
+		writeError(w, http.StatusTooManyRequests, err)
+		return
+	}
+	if added {
+		log.Printf("Model notify: wallet %s waiting for %s", wallet, preset.ID)
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]any{
+		"modelId":    preset.ID,
+		"status":     "offline",
+		"registered": true,
+		"duplicate":  !added,
+	})
+}
+
+// handleGetModelNotifications returns (and clears) queued online notices for a wallet
+func (a *App) handleGetModelNotifications(w http.ResponseWriter, r *http.Request) {
+	wallet := strings.ToLower(strings.TrimSpace(chi.URLParam(r, "wallet")))
+	if wallet == "" {
+		writeError(w, http.StatusBadRequest, errors.New("wallet address required"))
+		return
+	}
+	if verifiedWalletFromRequest(r) != wallet {
+		writeError(w, http.StatusForbidden, errors.New("you can only read your own notifications"))
+		return
+	}
+
+	notices := a.notifier.TakePending(wallet)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"wallet":        wallet,
+		"notifications": notices,
+		"count":         len(notices),
+	})
+}
@@ -0,0 +1,176 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/models"
+)
+
+func TestModelNotifierTransitionNotifiesOnce(t *testing.T) {
+	n := newModelNotifier()
+	if added, err := n.Register("FLUX.1-dev", "0xabc", ""); !added || err != nil {
+		t.Fatalf("first Register = %t, %v; want added", added, err)
+	}
+	if added, _ := n.Register("FLUX.1-dev", "0xabc", ""); added {
+		t.Fatal("second Register for same wallet was not deduped")
+	}
+	n.Register("FLUX.1-dev", "0xdef", "")
+
+	if got := n.Observe("FLUX.1-dev", 0); len(got) != 0 {
+		t.Fatalf("Observe(offline) notified %d waiters, want 0", len(got))
+	}
+	if got := n.Observe("FLUX.1-dev", 3); len(got) != 2 {
+		t.Fatalf("Observe(online) notified %d waiters, want 2", len(got))
+	}
+	if got := n.Observe("FLUX.1-dev", 3); len(got) != 0 {
+		t.Fatalf("Observe(still online) notified %d waiters, want 0", len(got))
+	}
+	if len(n.WatchedModels()) != 0 {
+		t.Errorf("waiters not cleared after notification")
+	}
+}
+
+func TestCheckAwaitedModelsDeliversCallbackOnce(t *testing.T) {
+	var workers atomic.Int32
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]any{{"name": "FLUX.1-dev", "count": workers.Load()}})
+	}))
+	defer grid.Close()
+
+	var received atomic.Int32
+	callback := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notice ModelOnlineNotice
+		if err := json.NewDecoder(r.Body).Decode(&notice); err != nil || notice.ModelID != "FLUX.1-dev" {
+			t.Errorf("unexpected notice %+v (err %v)", notice, err)
+		}
+		received.Add(1)
+	}))
+	defer callback.Close()
+
+	a := newTestApp(t, grid.URL)
+	a.catalog = models.NewCatalog([]models.ModelPreset{{ID: "FLUX.1-dev", Type: "image"}})
+	a.notifier.httpClient = callback.Client()
+	a.notifier.Register("FLUX.1-dev", "0xabc", callback.URL)
+	a.notifier.Register("FLUX.1-dev", "0xdef", "")
+
+	ctx := context.Background()
+	a.checkAwaitedModels(ctx) // still offline
+	workers.Store(2)
+	a.checkAwaitedModels(ctx) // transition
+	a.checkAwaitedModels(ctx) // already notified
+
+	if got := received.Load(); got != 1 {
+		t.Errorf("callback received %d notifications, want 1", got)
+	}
+	if got := a.notifier.TakePending("0xdef"); len(got) != 1 {
+		t.Errorf("queued notices for 0xdef = %d, want 1", len(got))
+	}
+}
+
+func TestNotifyModelEndpoint(t *testing.T) {
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	}))
+	defer grid.Close()
+
+	a := newTestApp(t, grid.URL)
+	a.catalog = models.NewCatalog([]models.ModelPreset{{ID: "FLUX.1-dev", Type: "image"}})
+
+	req := signAs(t, httptest.NewRequest(http.MethodPost, "/api/models/FLUX.1-dev/notify", nil), "abc")
+	if rec := serve(a, req); rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202 (body %s)", rec.Code, rec.Body)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/models/FLUX.1-dev/notify", nil)
+	if rec := serve(a, req); rec.Code != http.StatusUnauthorized {
+		t.Errorf("status without wallet = %d, want 401", rec.Code)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/api/models/FLUX.1-dev/notify", nil)
+	req.Header.Set("X-Wallet-Address", testAddr("abc"))
+	if rec := serve(a, req); rec.Code != http.StatusUnauthorized {
+		t.Errorf("status with an unsigned wallet = %d, want 401", rec.Code)
+	}
+}
+
+func TestModelNotifierCaps(t *testing.T) {
+	n := newModelNotifier()
+	for i := 0; i < maxWaitsPerWallet; i++ {
+		if _, err := n.Register(fmt.Sprintf("model-%d", i), "0xabc", ""); err != nil {
+			t.Fatalf("wait %d: %v", i, err)
+		}
+	}
+	if _, err := n.Register("model-extra", "0xabc", ""); !errors.Is(err, errTooManyWaits) {
+		t.Errorf("wait past the wallet cap: err = %v, want errTooManyWaits", err)
+	}
+	if _, err := n.Register("model-0", "0xabc", "https://example.com/hook"); err != nil {
+		t.Errorf("updating an existing wait: %v", err)
+	}
+
+	for i := 1; i < maxWaitersPerModel; i++ {
+		n.Register("model-0", fmt.Sprintf("0x%d", i), "")
+	}
+	if _, err := n.Register("model-0", "0xlate", ""); !errors.Is(err, errTooManyWaiters) {
+		t.Errorf("waiter past the model cap: err = %v, want errTooManyWaiters", err)
+	}
+}
+
+func TestIsPublicAddr(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"::1":              false,
+		"fe80::1":          false,
+		"fd00::1":          false,
+		"::ffff:127.0.0.1": false,
+		"224.0.0.1":        false,
+	} {
+		if got := isPublicAddr(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("isPublicAddr(%s) = %t, want %t", addr, got, want)
+		}
+	}
+}
+
+func TestModelNotifyCallbackStaysOffPrivateNetworks(t *testing.T) {
+	var received atomic.Int32
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+	}))
+	defer internal.Close()
+
+	// Delivery refuses the dialed address, whatever the registration check saw
+	n := newModelNotifier()
+	n.Deliver(context.Background(), modelWaiter{wallet: "0xabc", callbackURL: internal.URL}, ModelOnlineNotice{ModelID: "FLUX.1-dev"})
+	if received.Load() != 0 {
+		t.Error("callback reached a loopback server")
+	}
+
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	}))
+	defer grid.Close()
+	a := newTestApp(t, grid.URL)
+	a.catalog = models.NewCatalog([]models.ModelPreset{{ID: "FLUX.1-dev", Type: "image"}})
+	for _, callback := range []string{"https://127.0.0.1/hook", "https://169.254.169.254/latest/meta-data", "https://[::1]:8443/", "http://example.com/hook"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/models/FLUX.1-dev/notify", strings.NewReader(`{"callbackUrl":"`+callback+`"}`))
+		signAs(t, req, "abc")
+		if rec := serve(a, req); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", callback, rec.Code)
+		}
+	}
+}
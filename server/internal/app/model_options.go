@@ -0,0 +1,156 @@
+package app
+
+import (
+	"strings"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/models"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/modelvault"
+)
+
+// ParamOption is one sampler or scheduler choice for a model, with what the
+// UI needs to label it and highlight good picks
+type ParamOption struct {
+	Value       string `json:"value"`
+	Label       string `json:"label"`
+	Default     bool   `json:"default"`
+	Recommended bool   `json:"recommended"`
+	// Listed in the model's on-chain allowed set
+	OnChain bool `json:"onChain,omitempty"`
+}
+
+// Display names for the sampler and scheduler values used by presets and the Grid
+var paramOptionLabels = map[string]string{
+	"euler":              "Euler",
+	"k_euler":            "Euler",
+	"euler_ancestral":    "Euler Ancestral",
+	"k_euler_a":          "Euler Ancestral",
+	"heun":               "Heun",
+	"k_heun":             "Heun",
+	"lms":                "LMS",
+	"k_lms":              "LMS",
+	"dpm_2":              "DPM2",
+	"k_dpm_2":            "DPM2",
+	"dpm_2_ancestral":    "DPM2 Ancestral",
+	"k_dpm_2_a":          "DPM2 Ancestral",
+	"dpm_fast":           "DPM Fast",
+	"k_dpm_fast":         "DPM Fast",
+	"dpm_adaptive":       "DPM Adaptive",
+	"k_dpm_adaptive":     "DPM Adaptive",
+	"dpmpp_2m":           "DPM++ 2M",
+	"k_dpmpp_2m":         "DPM++ 2M",
+	"dpmpp_sde":          "DPM++ SDE",
+	"k_dpmpp_sde":        "DPM++ SDE",
+	"dpmpp_2s_ancestral": "DPM++ 2S Ancestral",
+	"k_dpmpp_2s_a":       "DPM++ 2S Ancestral",
+	"dpmsolver":          "DPM Solver",
+	"uni_pc":             "UniPC",
+	"ddim":               "DDIM",
+	"lcm":                "LCM",
+	"simple":             "Simple",
+	"karras":             "Karras",
+	"normal":             "Normal",
+	"exponential":        "Exponential",
+	"sgm_uniform":        "SGM Uniform",
+	"beta":               "Beta",
+}
+
+// Choices that give good results on most models; a model's own default is
+// always recommended as well
+var (
+	recommendedSamplers   = map[string]bool{"euler": true, "k_euler": true, "dpmpp_2m": true, "k_dpmpp_2m": true, "uni_pc": true}
+	recommendedSchedulers = map[string]bool{"karras": true, "simple": true}
+)
+
+// Schedulers a model's on-chain constraints may allow without any preset
+// listing them
+var knownSchedulers = []string{"simple", "karras", "normal", "exponential", "sgm_uniform", "beta", "ddim_uniform", "linear_quadratic", "kl_optimal"}
+
+// splitUnresolved separates chain-allowed names from the hex hashes ModelVault
+// couldn't turn back into one, so hashes never become selectable options
+func splitUnresolved(values []string) (names, hashes []string) {
+	for _, v := range values {
+		if modelvault.IsUnresolvedName(v) {
+			hashes = append(hashes, v)
+		} else {
+			names = append(names, v)
+		}
+	}
+	return names, hashes
+}
+
+// knownParamNames lists every sampler and scheduler name the gallery knows:
+// both sides of the sampler aliases, the schedulers above and whatever the
+// presets list or default to. ModelVault hashes them to decode constraints.
+func knownParamNames(presets []models.ModelPreset) (samplers, schedulers []string) {
+	for from, to := range samplerAliases {
+		samplers = append(samplers, from, to)
+	}
+	schedulers = append(schedulers, knownSchedulers...)
+	for _, preset := range presets {
+		samplers = append(samplers, preset.Samplers...)
+		schedulers = append(schedulers, preset.Schedulers...)
+		if preset.Defaults.Sampler != "" {
+			samplers = append(samplers, preset.Defaults.Sampler)
+		}
+		if preset.Defaults.Scheduler != "" {
+			schedulers = append(schedulers, preset.Defaults.Scheduler)
+		}
+	}
+	return samplers, schedulers
+}
+
+// buildParamOptions lists a model's choices in preset order, followed by any
+// the chain allows that the preset doesn't list. A default missing from the
+// preset's list is put first so it can always be selected.
+func buildParamOptions(values, chainAllowed []string, defaultValue string, recommended map[string]bool) []ParamOption {
+	onChain := make(map[string]bool, len(chainAllowed))
+	for _, v := range chainAllowed {
+		onChain[strings.ToLower(v)] = true
+	}
+
+	all := make([]string, 0, len(values)+len(chainAllowed)+1)
+	if defaultValue != "" && !containsFold(values, defaultValue) {
+		all = append(all, defaultValue)
+	}
+	all = append(all, values...)
+	for _, v := range chainAllowed {
+		if !containsFold(all, v) {
+			all = append(all, v)
+		}
+	}
+
+	options := make([]ParamOption, 0, len(all))
+	for _, v := range all {
+		key := strings.ToLower(v)
+		isDefault := strings.EqualFold(v, defaultValue)
+		options = append(options, ParamOption{
+			Value:       v,
+			Label:       paramOptionLabel(key),
+			Default:     isDefault,
+			Recommended: isDefault || recommended[key],
+			OnChain:     onChain[key],
+		})
+	}
+	return options
+}
+
+// paramOptionLabel returns the display name for a value, title-casing unknown ones
+func paramOptionLabel(value string) string {
+	if label, ok := paramOptionLabels[value]; ok {
+		return label
+	}
+	words := strings.Fields(strings.ReplaceAll(value, "_", " "))
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+func containsFold(list []string, value string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+)
+
+// modelSyncState remembers which presets the last sync check found unmatched,
+// so a check can tell operators what a Grid rename just broke
+type modelSyncState struct {
+	mu        sync.Mutex
+	unmatched map[string]bool
+}
+
+// ModelSyncDetails is the modelSync readiness check's detail payload
+type ModelSyncDetails struct {
+	Matched        int      `json:"matched"`
+	Total          int      `json:"total"`
+	MatchedPercent float64  `json:"matchedPercent"`
+	Unmatched      []string `json:"unmatched"`
+	// Presets that matched on the previous check but not on this one
+	NewlyUnmatched []string `json:"newlyUnmatched"`
+}
+
+// checkModelSync reports the share of presets that resolve to a Grid model with
+// workers, warning below MODEL_SYNC_MIN_PERCENT. It only warns: a Grid-side
+// rename shouldn't take the gallery out of rotation.
+func (a *App) checkModelSync(ctx context.Context) {
+	stats, err := a.client.FetchModelStats(ctx)
+	if err != nil {
+		a.health.Set("modelSync", checkWarn, "could not fetch Grid model stats: "+err.Error())
+		return
+	}
+
+	presets := a.catalog.List()
+	if len(presets) == 0 {
+		a.health.Set("modelSync", checkOK, "no presets loaded")
+		return
+	}
+
+	unmatched := unmatchedModels(presets, stats)
+	current := make(map[string]bool, len(unmatched))
+	details := ModelSyncDetails{Total: len(presets), Unmatched: []string{}, NewlyUnmatched: []string{}}
+	for _, m := range unmatched {
+		current[m.PresetID] = true
+		details.Unmatched = append(details.Unmatched, m.PresetID)
+	}
+
+	a.modelSync.mu.Lock()
+	if a.modelSync.unmatched != nil {
+		for id := range current {
+			if !a.modelSync.unmatched[id] {
+				details.NewlyUnmatched = append(details.NewlyUnmatched, id)
+			}
+		}
+	}
+	a.modelSync.unmatched = current
+	a.modelSync.mu.Unlock()
+	sort.Strings(details.Unmatched)
+	sort.Strings(details.NewlyUnmatched)
+
+	details.Matched = details.Total - len(unmatched)
+	details.MatchedPercent = float64(details.Matched) * 100 / float64(details.Total)
+	message := fmt.Sprintf("%d of %d presets resolve to a Grid model with workers", details.Matched, details.Total)
+
+	status := checkOK
+	if details.MatchedPercent < float64(a.cfg.ModelSyncMinPercent) {
+		status = checkWarn
+		log.Printf("Warning: only %s (%.0f%%, threshold %d%%); newly unmatched: %v", message, details.MatchedPercent, a.cfg.ModelSyncMinPercent, details.NewlyUnmatched)
+	}
+	a.health.SetDetails("modelSync", status, message, details)
+}
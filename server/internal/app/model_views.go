@@ -0,0 +1,77 @@
+package app
+
+import (
+	"strings"
+	"time"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/aipg"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/config"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/models"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/modelvault"
+)
+
+// buildModelViews builds the views for a list of presets against stats and
+// chain data indexed once per request. Views come back in preset order.
+//
+// Construction stays sequential: with catalog-sized inputs the per-view work is
+// a handful of map lookups, and fanning out to goroutines measured slower
+// (see BenchmarkBuildModelViews).
+func buildModelViews(presets []models.ModelPreset, stats modelStatsIndex, chainModels map[string]*modelvault.OnChainModel, thresholds statusThresholds) []ModelView {
+	views := make([]ModelView, len(presets))
+	for i, preset := range presets {
+		views[i] = buildModelView(preset, lookupModelStats(preset.ID, stats), chainModelFor(preset.ID, chainModels), thresholds)
+	}
+	return views
+}
+
+// Model availability, from best to worst. "online" and "offline" are the base
+// cases; the others are online models a user should expect to wait for.
+const (
+	modelOnline   = "online"
+	modelBusy     = "busy"     // online, but the queue is deep
+	modelDegraded = "degraded" // only a few workers and a long ETA
+	modelOffline  = "offline"
+)
+
+// statusThresholds decide when an online model is reported busy or degraded
+type statusThresholds struct {
+	busyQueue       int
+	degradedWorkers int
+	degradedETA     time.Duration
+}
+
+func newStatusThresholds(cfg config.Config) statusThresholds {
+	return statusThresholds{
+		busyQueue:       cfg.ModelBusyQueue,
+		degradedWorkers: cfg.ModelDegradedWorkers,
+		degradedETA:     cfg.ModelDegradedETA,
+	}
+}
+
+// modelStatus classifies a model's Grid stats. Degraded wins over busy since
+// a long wait on few workers is the worse experience; a zero threshold disables that state.
+func modelStatus(stat aipg.ModelStatus, t statusThresholds) string {
+	workers := stat.ParseCount()
+	if workers <= 0 {
+		return modelOffline
+	}
+	eta := time.Duration(stat.ParseETA() * float64(time.Second))
+	if t.degradedETA > 0 && workers <= t.degradedWorkers && eta >= t.degradedETA {
+		return modelDegraded
+	}
+	if t.busyQueue > 0 && stat.ParseQueued() >= t.busyQueue {
+		return modelBusy
+	}
+	return modelOnline
+}
+
+// chainModelFor finds a preset's on-chain model by exact or lowercase ID
+func chainModelFor(presetID string, chainModels map[string]*modelvault.OnChainModel) *modelvault.OnChainModel {
+	if chainModels == nil {
+		return nil
+	}
+	if chainModel := chainModels[presetID]; chainModel != nil {
+		return chainModel
+	}
+	return chainModels[strings.ToLower(presetID)]
+}
@@ -0,0 +1,167 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/aipg"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/models"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/modelvault"
+)
+
+// legacyLookupModelStats is the lookup as it was before stats were pre-indexed:
+// the normalized fallback scans every stat name. Kept as the reference for the
+// equivalence test and the benchmark baseline.
+func legacyLookupModelStats(presetID string, byName map[string]aipg.ModelStatus) aipg.ModelStatus {
+	if stat, ok := byName[presetID]; ok {
+		return stat
+	}
+	presetLower := strings.ToLower(presetID)
+	if stat, ok := byName[presetLower]; ok {
+		return stat
+	}
+	if aliases, ok := modelNameAliases[presetID]; ok {
+		for _, alias := range aliases {
+			if stat, ok := byName[strings.ToLower(alias)]; ok {
+				return stat
+			}
+			if stat, ok := byName[alias]; ok {
+				return stat
+			}
+		}
+	}
+	for _, aliases := range modelNameAliases {
+		for _, alias := range aliases {
+			if strings.EqualFold(alias, presetID) {
+				for _, a := range aliases {
+					if stat, ok := byName[strings.ToLower(a)]; ok {
+						return stat
+					}
+					if stat, ok := byName[a]; ok {
+						return stat
+					}
+				}
+			}
+		}
+	}
+	normalized := strings.ReplaceAll(strings.ReplaceAll(presetLower, "-", "_"), ".", "_")
+	for name, stat := range byName {
+		nameNorm := strings.ReplaceAll(strings.ReplaceAll(strings.ToLower(name), "-", "_"), ".", "_")
+		if nameNorm == normalized {
+			return stat
+		}
+	}
+	return aipg.ModelStatus{}
+}
+
+// legacyBuildModelViews is the per-preset loop handleListModels used to run
+func legacyBuildModelViews(presets []models.ModelPreset, stats []aipg.ModelStatus, chainModels map[string]*modelvault.OnChainModel) []ModelView {
+	byName := indexModelStats(stats).byName
+	views := make([]ModelView, 0, len(presets))
+	for _, preset := range presets {
+		stat := legacyLookupModelStats(preset.ID, byName)
+		var chainModel *modelvault.OnChainModel
+		if chainModels != nil {
+			chainModel = chainModels[preset.ID]
+			if chainModel == nil {
+				chainModel = chainModels[strings.ToLower(preset.ID)]
+			}
+		}
+		views = append(views, buildModelView(preset, stat, chainModel, testThresholds))
+	}
+	return views
+}
+
+// parallelBuildModelViews fans view construction out across goroutines; it
+// exists only to benchmark against the sequential builder
+func parallelBuildModelViews(presets []models.ModelPreset, stats modelStatsIndex, chainModels map[string]*modelvault.OnChainModel) []ModelView {
+	views := make([]ModelView, len(presets))
+	var wg sync.WaitGroup
+	for i, preset := range presets {
+		wg.Add(1)
+		go func(i int, preset models.ModelPreset) {
+			defer wg.Done()
+			views[i] = buildModelView(preset, lookupModelStats(preset.ID, stats), chainModelFor(preset.ID, chainModels), testThresholds)
+		}(i, preset)
+	}
+	wg.Wait()
+	return views
+}
+
+// syntheticCatalog mixes presets that match stats exactly, by case, through
+// aliases, only after normalization, and not at all
+func syntheticCatalog(n int) ([]models.ModelPreset, []aipg.ModelStatus, map[string]*modelvault.OnChainModel) {
+	presets := []models.ModelPreset{
+		{ID: "FLUX.1-dev", DisplayName: "FLUX.1 Dev", Limits: models.ModelLimits{Steps: &models.RangeInt{Min: 1, Max: 50}}},
+		{ID: "wan2.2-t2v-a14b", DisplayName: "WAN 2.2"},
+		{ID: "ltxv", DisplayName: "LTX Video", Limits: models.ModelLimits{CfgScale: &models.RangeFloat{Min: 1, Max: 20}}},
+	}
+	stats := []aipg.ModelStatus{
+		{Name: "flux1-dev", Count: json.RawMessage("2"), Queued: json.RawMessage("4")},
+		{Name: "wan2_2_t2v_14b", Count: json.RawMessage("1")},
+	}
+	chain := map[string]*modelvault.OnChainModel{
+		"flux.1-dev": {DisplayName: "FLUX.1-dev", Description: "On-chain FLUX", Constraints: &modelvault.ModelConstraints{StepsMin: 4, StepsMax: 30}},
+		"ltxv":       {DisplayName: "ltxv", Constraints: &modelvault.ModelConstraints{CfgMin: 2, CfgMax: 8}},
+	}
+
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("Model-%d.v1", i)
+		presets = append(presets, models.ModelPreset{ID: id, DisplayName: fmt.Sprintf("Model %d", i)})
+		switch i % 4 {
+		case 0:
+			stats = append(stats, aipg.ModelStatus{Name: id, Count: json.RawMessage("1")})
+		case 1:
+			stats = append(stats, aipg.ModelStatus{Name: strings.ToLower(id), Count: json.RawMessage("2")})
+		case 2:
+			stats = append(stats, aipg.ModelStatus{Name: fmt.Sprintf("model_%d_v1", i), Count: json.RawMessage("3")})
+		}
+		// every fourth preset has no stats and falls through every lookup
+	}
+	for i := 0; i < n; i++ {
+		stats = append(stats, aipg.ModelStatus{Name: fmt.Sprintf("unrelated-worker-model-%d", i)})
+	}
+	return presets, stats, chain
+}
+
+func TestBuildModelViewsMatchesPerModelBuilder(t *testing.T) {
+	presets, stats, chain := syntheticCatalog(40)
+
+	want, _ := json.Marshal(legacyBuildModelViews(presets, stats, chain))
+	got, _ := json.Marshal(buildModelViews(presets, indexModelStats(stats), chain, testThresholds))
+	if string(got) != string(want) {
+		t.Fatalf("batch views differ from per-model views\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestBuildModelViewLeavesCatalogLimitsAlone(t *testing.T) {
+	presets, stats, chain := syntheticCatalog(0)
+	buildModelViews(presets, indexModelStats(stats), chain, testThresholds)
+
+	if presets[0].Limits.Steps.Max != 50 || presets[2].Limits.CfgScale.Max != 20 {
+		t.Errorf("chain constraints narrowed the catalog's limits: steps %+v cfg %+v", presets[0].Limits.Steps, presets[2].Limits.CfgScale)
+	}
+}
+
+func BenchmarkBuildModelViews(b *testing.B) {
+	presets, stats, chain := syntheticCatalog(200)
+
+	b.Run("per-model", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			legacyBuildModelViews(presets, stats, chain)
+		}
+	})
+	b.Run("batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			buildModelViews(presets, indexModelStats(stats), chain, testThresholds)
+		}
+	})
+	b.Run("batch-parallel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			parallelBuildModelViews(presets, indexModelStats(stats), chain)
+		}
+	})
+}
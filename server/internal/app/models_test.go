@@ -0,0 +1,189 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/aipg"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/models"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/modelvault"
+)
+
+// newModelsTestApp serves the given Grid stats JSON and a small two-model catalog
+func newModelsTestApp(t *testing.T, statsJSON string) *App {
+	t.Helper()
+
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(statsJSON))
+	}))
+	t.Cleanup(grid.Close)
+
+	a := newTestApp(t, grid.URL)
+	a.catalog = models.NewCatalog([]models.ModelPreset{
+		{
+			ID:          "FLUX.1-dev",
+			DisplayName: "FLUX.1 Dev",
+			Type:        "image",
+			Description: "A long description",
+			Samplers:    []string{"euler"},
+			Limits:      models.ModelLimits{Steps: &models.RangeInt{Min: 1, Max: 50, Step: 1}},
+		},
+		{ID: "ltxv", DisplayName: "LTX Video", Type: "video", Description: "Video model"},
+	})
+	return a
+}
+
+func TestListModelsLiteView(t *testing.T) {
+	a := newModelsTestApp(t, `[{"name":"FLUX.1-dev","count":2,"queued":5}]`)
+
+	rec := serve(a, httptest.NewRequest(http.MethodGet, "/api/models?view=lite", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+
+	var resp struct {
+		Models []map[string]any `json:"models"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Models) != 2 {
+		t.Fatalf("got %d models, want 2", len(resp.Models))
+	}
+
+	for _, m := range resp.Models {
+		for _, heavy := range []string{"description", "capabilities", "limits", "defaults", "samplers", "constraints"} {
+			if _, ok := m[heavy]; ok {
+				t.Errorf("lite model %v includes heavy field %q", m["id"], heavy)
+			}
+		}
+		if m["id"] == "FLUX.1-dev" && (m["status"] != "online" || m["queueLength"] != float64(5)) {
+			t.Errorf("FLUX lite view = %v, want online with queueLength 5", m)
+		}
+	}
+}
+
+func TestListModelsRejectsUnknownView(t *testing.T) {
+	a := newModelsTestApp(t, `[]`)
+
+	rec := serve(a, httptest.NewRequest(http.MethodGet, "/api/models?view=tiny", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestBuildModelViewFlagsChainConstrainedLimits(t *testing.T) {
+	preset := models.ModelPreset{
+		ID:     "FLUX.1-dev",
+		Limits: models.ModelLimits{Steps: &models.RangeInt{Min: 1, Max: 50, Step: 1}},
+	}
+
+	view := buildModelView(preset, aipg.ModelStatus{}, nil, statusThresholds{})
+	if view.LimitsConstrainedByChain {
+		t.Error("flag set without a chain model")
+	}
+
+	// A constraint no tighter than the preset leaves the flag off
+	loose := &modelvault.OnChainModel{Constraints: &modelvault.ModelConstraints{StepsMin: 1, StepsMax: 80}}
+	if view := buildModelView(preset, aipg.ModelStatus{}, loose, statusThresholds{}); view.LimitsConstrainedByChain {
+		t.Error("flag set although the chain constraint did not narrow anything")
+	}
+
+	tight := &modelvault.OnChainModel{Constraints: &modelvault.ModelConstraints{StepsMin: 1, StepsMax: 30}}
+	view = buildModelView(preset, aipg.ModelStatus{}, tight, statusThresholds{})
+	if !view.LimitsConstrainedByChain || view.Limits.Steps.Max != 30 {
+		t.Errorf("steps = %+v, flag = %v; want max narrowed to 30 and the flag set", view.Limits.Steps, view.LimitsConstrainedByChain)
+	}
+}
+
+// testThresholds mirror the configured defaults
+var testThresholds = statusThresholds{busyQueue: 20, degradedWorkers: 1, degradedETA: 5 * time.Minute}
+
+func TestModelStatus(t *testing.T) {
+	stat := func(count, queued, eta string) aipg.ModelStatus {
+		return aipg.ModelStatus{Count: json.RawMessage(count), Queued: json.RawMessage(queued), Eta: json.RawMessage(eta)}
+	}
+	tests := []struct {
+		name string
+		stat aipg.ModelStatus
+		want string
+	}{
+		{name: "no stats", stat: aipg.ModelStatus{}, want: "offline"},
+		{name: "no workers despite queue", stat: stat("0", "50", "900"), want: "offline"},
+		{name: "healthy", stat: stat("3", "2", "20"), want: "online"},
+		{name: "deep queue", stat: stat("4", "20", "60"), want: "busy"},
+		{name: "one worker long eta", stat: stat("1", "3", "300"), want: "degraded"},
+		{name: "one worker long eta and deep queue", stat: stat("1", "40", "900"), want: "degraded"},
+		{name: "one worker short eta", stat: stat("1", "1", "299"), want: "online"},
+		{name: "many workers long eta", stat: stat("5", "5", "900"), want: "online"},
+		{name: "string-encoded stats", stat: stat(`"2"`, `"25"`, `"10"`), want: "busy"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := modelStatus(tc.stat, testThresholds); got != tc.want {
+				t.Errorf("modelStatus = %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	if got := modelStatus(stat("1", "100", "900"), statusThresholds{}); got != "online" {
+		t.Errorf("with thresholds disabled = %q, want online", got)
+	}
+	if view := buildModelView(models.ModelPreset{ID: "m"}, stat("4", "30", "60"), nil, testThresholds); view.Status != "busy" {
+		t.Errorf("view status = %q, want busy", view.Status)
+	}
+}
+
+func TestModelViewParamOptions(t *testing.T) {
+	// An on-chain hash ModelVault couldn't name
+	unknownHash := "0x" + strings.Repeat("ab", 32)
+	preset := models.ModelPreset{
+		ID:         "SDXL 1.0",
+		Samplers:   []string{"k_euler", "k_dpmpp_sde", "ddim"},
+		Schedulers: []string{"karras", "normal"},
+		Defaults:   models.ModelDefaults{Sampler: "k_dpmpp_sde", Scheduler: "exponential"},
+	}
+	chain := &modelvault.OnChainModel{
+		DisplayName: "SDXL 1.0",
+		Constraints: &modelvault.ModelConstraints{
+			AllowedSamplers:   []string{"K_EULER", unknownHash, "lcm"},
+			AllowedSchedulers: []string{unknownHash},
+		},
+	}
+
+	view := buildModelView(preset, aipg.ModelStatus{}, chain, statusThresholds{})
+
+	wantSamplers := []ParamOption{
+		{Value: "k_euler", Label: "Euler", Recommended: true, OnChain: true},
+		{Value: "k_dpmpp_sde", Label: "DPM++ SDE", Default: true, Recommended: true},
+		{Value: "ddim", Label: "DDIM"},
+		{Value: "lcm", Label: "LCM", OnChain: true},
+	}
+	if !reflect.DeepEqual(view.SamplerOptions, wantSamplers) {
+		t.Errorf("sampler options = %+v\nwant %+v", view.SamplerOptions, wantSamplers)
+	}
+
+	// A default the preset doesn't list is still offered, first
+	wantSchedulers := []ParamOption{
+		{Value: "exponential", Label: "Exponential", Default: true, Recommended: true},
+		{Value: "karras", Label: "Karras", Recommended: true},
+		{Value: "normal", Label: "Normal"},
+	}
+	if !reflect.DeepEqual(view.SchedulerOptions, wantSchedulers) {
+		t.Errorf("scheduler options = %+v\nwant %+v", view.SchedulerOptions, wantSchedulers)
+	}
+
+	// Unnamed hashes are reported with the constraints, never offered
+	if c := view.Constraints; c == nil || !reflect.DeepEqual(c.UnknownSamplers, []string{unknownHash}) || !reflect.DeepEqual(c.UnknownSchedulers, []string{unknownHash}) {
+		t.Errorf("constraints = %+v, want the unknown hashes listed", view.Constraints)
+	}
+
+	if got := paramOptionLabel("dpmpp_3m_sde_gpu"); got != "Dpmpp 3m Sde Gpu" {
+		t.Errorf("fallback label = %q", got)
+	}
+}
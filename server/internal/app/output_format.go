@@ -0,0 +1,61 @@
+package app
+
+import (
+	"mime"
+	"path"
+	"strings"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/r2"
+)
+
+// defaultOutputFormat is what the Grid returns unless a worker reports otherwise
+const defaultOutputFormat = "webp"
+
+// outputFormats are the image formats the Grid may report for a generation
+var outputFormats = map[string]string{
+	"webp": "image/webp",
+	"png":  "image/png",
+	"jpeg": "image/jpeg",
+}
+
+// lookupOutputFormat resolves a format name, "jpg" or a MIME type to its canonical name
+func lookupOutputFormat(format string) (string, bool) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	switch format {
+	case "jpg", "image/jpg":
+		return "jpeg", true
+	}
+	format = strings.TrimPrefix(format, "image/")
+	_, ok := outputFormats[format]
+	return format, ok
+}
+
+// imageMimeType is the MIME type for an image the Grid reported as mime,
+// falling back to WebP, the Grid's default, when it reported nothing we know
+func imageMimeType(mime string) string {
+	if format, ok := lookupOutputFormat(mime); ok {
+		return outputFormats[format]
+	}
+	return outputFormats[defaultOutputFormat]
+}
+
+// mediaFallbackURL is the CDN URL a generation is stored under when the Grid
+// didn't send one, named after the format it reported
+func (a *App) mediaFallbackURL(genID, mime string) string {
+	return a.r2Client.CDNURL(r2.MediaFilename(genID, mime))
+}
+
+// generationMime is the MIME type implied by the stored media URL named after
+// genID, so an object's key can be rebuilt from the item alone. It's "" (WebP)
+// when none of the item's URLs names the generation.
+func generationMime(item gallery.GalleryItem, genID string) string {
+	for _, mediaURL := range item.MediaURLs {
+		name := path.Base(strings.SplitN(mediaURL, "?", 2)[0])
+		ext := path.Ext(name)
+		if strings.TrimSuffix(name, ext) == genID {
+			return mime.TypeByExtension(strings.ToLower(ext))
+		}
+	}
+	return ""
+}
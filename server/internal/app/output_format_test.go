@@ -0,0 +1,72 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/aipg"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/r2"
+)
+
+func TestMediaNamesFollowReportedFormat(t *testing.T) {
+	data := strings.Repeat("A", 64)
+	tests := []struct {
+		mime     string
+		wantURL  string
+		wantData string
+	}{
+		{mime: "", wantURL: "https://images.aipg.art/gen-1.webp", wantData: "data:image/webp;base64,"},
+		{mime: "image/webp", wantURL: "https://images.aipg.art/gen-1.webp", wantData: "data:image/webp;base64,"},
+		{mime: "image/png", wantURL: "https://images.aipg.art/gen-1.png", wantData: "data:image/png;base64,"},
+		{mime: "image/jpeg", wantURL: "https://images.aipg.art/gen-1.jpg", wantData: "data:image/jpeg;base64,"},
+		{mime: "video/mp4", wantURL: "https://images.aipg.art/gen-1.webp", wantData: "data:image/webp;base64,"},
+	}
+	a := &App{}
+	for _, tc := range tests {
+		if got := a.mediaFallbackURL("gen-1", tc.mime); got != tc.wantURL {
+			t.Errorf("mediaFallbackURL(%q) = %q, want %q", tc.mime, got, tc.wantURL)
+		}
+		if got := normalizeBase64(data, tc.mime); got != tc.wantData+data {
+			t.Errorf("normalizeBase64 with %q = %.30s..., want %s prefix", tc.mime, got, tc.wantData)
+		}
+	}
+}
+
+func TestGenerationMimeFromStoredURLs(t *testing.T) {
+	item := gallery.GalleryItem{
+		GenerationIDs: []string{"gen-png", "gen-jpg", "gen-webp", "gen-missing"},
+		MediaURLs: []string{
+			"https://images.aipg.art/prod/gen-png.png",
+			"https://acct.r2.cloudflarestorage.com/bucket/gen-jpg.JPG?X-Amz-Signature=abc",
+			"https://images.aipg.art/gen-webp.webp",
+		},
+	}
+	want := map[string]string{"gen-png": "gen-png.png", "gen-jpg": "gen-jpg.jpg", "gen-webp": "gen-webp.webp", "gen-missing": "gen-missing.webp"}
+	for _, genID := range item.GenerationIDs {
+		if got := r2.MediaFilename(genID, generationMime(item, genID)); got != want[genID] {
+			t.Errorf("object for %s = %q, want %q", genID, got, want[genID])
+		}
+	}
+}
+
+func TestMediaURLsCarryKeyPrefix(t *testing.T) {
+	client, err := r2.NewClient("http://r2.invalid", "transient", "permanent", "id", "secret", "", "", "prod")
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	a := &App{r2Client: client}
+
+	if got := a.mediaFallbackURL("gen-1", "image/png"); got != "https://images.aipg.art/prod/gen-1.png" {
+		t.Errorf("mediaFallbackURL = %q", got)
+	}
+	view := a.buildJobView(&aipg.JobStatusResponse{ID: "job-1", Done: true, Generations: []aipg.Generation{
+		{ID: "g1", ImgURL: "https://acct.r2.cloudflarestorage.com/bucket/prod/g1.webp?X-Amz-Signature=abc"},
+		{ID: "g2", Mime: "video/mp4", Video: "g2.webp"},
+	}})
+	for i, want := range []string{"https://images.aipg.art/prod/g1.webp", "https://images.aipg.art/prod/g2.webp"} {
+		if got := view.Generations[i].URL; got != want {
+			t.Errorf("generation %d URL = %q, want %q", i, got, want)
+		}
+	}
+}
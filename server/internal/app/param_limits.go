@@ -0,0 +1,137 @@
+package app
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/models"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/modelvault"
+)
+
+// chainLimitsTimeout bounds the ModelVault lookup a job submission makes for
+// chain constraints; a cold cache shouldn't hold up the job
+const chainLimitsTimeout = 2 * time.Second
+
+// chainLimits narrows a preset's steps and CFG ranges to the on-chain
+// constraints where those are more restrictive, and reports whether they
+// were. The ranges are pointers shared with the catalog, so narrowed ones are
+// copies.
+func chainLimits(limits models.ModelLimits, constraints *modelvault.ModelConstraints) (models.ModelLimits, bool) {
+	if constraints == nil {
+		return limits, false
+	}
+	narrowed := false
+	if limits.Steps != nil && constraints.StepsMax > 0 {
+		steps := *limits.Steps
+		limits.Steps = &steps
+		if int(constraints.StepsMax) < steps.Max {
+			limits.Steps.Max = int(constraints.StepsMax)
+			narrowed = true
+		}
+		if int(constraints.StepsMin) > steps.Min {
+			limits.Steps.Min = int(constraints.StepsMin)
+			narrowed = true
+		}
+	}
+	if limits.CfgScale != nil && constraints.CfgMax > 0 {
+		cfg := *limits.CfgScale
+		limits.CfgScale = &cfg
+		if constraints.CfgMax < cfg.Max {
+			limits.CfgScale.Max = constraints.CfgMax
+			narrowed = true
+		}
+		if constraints.CfgMin > cfg.Min {
+			limits.CfgScale.Min = constraints.CfgMin
+			narrowed = true
+		}
+	}
+	return limits, narrowed
+}
+
+// presetWithChainLimits is preset with its limits narrowed by the model's
+// on-chain constraints, when ModelVault is enabled and knows the model
+func (a *App) presetWithChainLimits(ctx context.Context, preset models.ModelPreset) models.ModelPreset {
+	if !a.vaultClient.IsEnabled() {
+		return preset
+	}
+	ctx, cancel := context.WithTimeout(ctx, chainLimitsTimeout)
+	defer cancel()
+	chainModel, err := a.vaultClient.FindModel(ctx, preset.ID)
+	if err != nil || chainModel == nil {
+		return preset
+	}
+	preset.Limits, _ = chainLimits(preset.Limits, chainModel.Constraints)
+	return preset
+}
+
+// ValidateParams checks the submitted size, steps, CFG, length and FPS
+// against the preset's limits. Values inside a range are rounded onto its
+// step; values outside are rejected, one field error each. Unset (zero)
+// values are left for the preset defaults.
+func (r *CreateJobRequest) ValidateParams(preset models.ModelPreset) error {
+	var errs ValidationErrors
+	r.validateParams(preset, &errs)
+	return errs.Err()
+}
+
+// validateParams is ValidateParams recording into errs
+func (r *CreateJobRequest) validateParams(preset models.ModelPreset, errs *ValidationErrors) {
+	limits := preset.Limits
+	checkIntParam(errs, "params.width", "width", &r.Params.Width, limits.Width)
+	checkIntParam(errs, "params.height", "height", &r.Params.Height, limits.Height)
+	checkIntParam(errs, "params.steps", "steps", &r.Params.Steps, limits.Steps)
+	checkFloatParam(errs, "params.cfgScale", "cfgScale", &r.Params.CfgScale, limits.CfgScale)
+	checkIntParam(errs, "params.length", "length", &r.Params.Length, limits.Length)
+	checkIntParam(errs, "params.fps", "fps", &r.Params.FPS, limits.FPS)
+}
+
+func checkIntParam(errs *ValidationErrors, field, name string, value *int, limit *models.RangeInt) {
+	switch {
+	case *value < 0:
+		errs.Addf(field, "%s must be positive, got %d", name, *value)
+	case *value == 0 || limit == nil:
+	case *value < limit.Min || *value > limit.Max:
+		errs.Addf(field, "%s %d is outside this model's range of %d-%d", name, *value, limit.Min, limit.Max)
+	default:
+		*value = snapInt(*value, *limit)
+	}
+}
+
+func checkFloatParam(errs *ValidationErrors, field, name string, value *float64, limit *models.RangeFloat) {
+	switch {
+	case *value < 0:
+		errs.Addf(field, "%s must be positive, got %g", name, *value)
+	case *value == 0 || limit == nil:
+	case *value < limit.Min || *value > limit.Max:
+		errs.Addf(field, "%s %g is outside this model's range of %g-%g", name, *value, limit.Min, limit.Max)
+	default:
+		*value = snapFloat(*value, *limit)
+	}
+}
+
+// snapInt rounds value to the nearest step counted from the range's minimum,
+// staying inside the range
+func snapInt(value int, limit models.RangeInt) int {
+	if limit.Step <= 1 {
+		return value
+	}
+	steps := int(math.Round(float64(value-limit.Min) / float64(limit.Step)))
+	snapped := limit.Min + steps*limit.Step
+	for snapped > limit.Max {
+		snapped -= limit.Step
+	}
+	return snapped
+}
+
+// snapFloat is snapInt for float ranges, trimming float noise from the result
+func snapFloat(value float64, limit models.RangeFloat) float64 {
+	if limit.Step <= 0 {
+		return value
+	}
+	snapped := limit.Min + math.Round((value-limit.Min)/limit.Step)*limit.Step
+	for snapped > limit.Max+1e-9 {
+		snapped -= limit.Step
+	}
+	return math.Round(snapped*1e6) / 1e6
+}
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/models"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/modelvault"
+)
+
+func TestValidateParams(t *testing.T) {
+	flux, video := testPresets[0], testPresets[1]
+
+	tests := []struct {
+		name       string
+		preset     models.ModelPreset
+		params     GenerationParams
+		wantFields []string
+		want       GenerationParams
+	}{
+		{name: "unset values are left for defaults", preset: flux},
+		{
+			name:   "in range values snap to the step",
+			preset: flux,
+			params: GenerationParams{Width: 1000, Height: 2047, Steps: 30, CfgScale: 3.7},
+			want:   GenerationParams{Width: 1024, Height: 2048, Steps: 30, CfgScale: 3.5},
+		},
+		{
+			name:       "out of range values are rejected",
+			preset:     flux,
+			params:     GenerationParams{Width: 4096, Height: 256, Steps: 999, CfgScale: 12},
+			wantFields: []string{"params.width", "params.height", "params.steps", "params.cfgScale"},
+		},
+		{
+			name:       "negative values are rejected",
+			preset:     flux,
+			params:     GenerationParams{Steps: -1},
+			wantFields: []string{"params.steps"},
+		},
+		{
+			name:   "video length snaps from the minimum",
+			preset: video,
+			params: GenerationParams{Length: 80, FPS: 24},
+			want:   GenerationParams{Length: 81, FPS: 24},
+		},
+		{
+			name:       "video length and fps are checked",
+			preset:     video,
+			params:     GenerationParams{Length: 200, FPS: 60},
+			wantFields: []string{"params.length", "params.fps"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := CreateJobRequest{Params: tc.params}
+			err := req.ValidateParams(tc.preset)
+			if len(tc.wantFields) == 0 {
+				if err != nil {
+					t.Fatalf("ValidateParams: %v", err)
+				}
+				if req.Params != tc.want {
+					t.Errorf("params = %+v, want %+v", req.Params, tc.want)
+				}
+				return
+			}
+			verrs, ok := err.(ValidationErrors)
+			if !ok {
+				t.Fatalf("err = %v, want ValidationErrors", err)
+			}
+			fields := verrs.Fields()
+			if len(fields) != len(tc.wantFields) {
+				t.Errorf("fields = %v, want %v", fields, tc.wantFields)
+			}
+			for _, field := range tc.wantFields {
+				if fields[field] == "" {
+					t.Errorf("no error for %s in %v", field, fields)
+				}
+			}
+		})
+	}
+}
+
+func TestChainLimitsNarrowPreset(t *testing.T) {
+	preset := testPresets[0]
+	limits, narrowed := chainLimits(preset.Limits, &modelvault.ModelConstraints{StepsMin: 4, StepsMax: 30, CfgMax: 20})
+	if !narrowed || limits.Steps.Min != 4 || limits.Steps.Max != 30 || limits.CfgScale.Max != 10 {
+		t.Errorf("limits = steps %+v cfg %+v narrowed=%t; want steps 4-30 and cfg left at 10", *limits.Steps, *limits.CfgScale, narrowed)
+	}
+	if preset.Limits.Steps.Max != 50 {
+		t.Errorf("catalog steps max = %d, want it untouched", preset.Limits.Steps.Max)
+	}
+	if _, narrowed := chainLimits(preset.Limits, nil); narrowed {
+		t.Error("no constraints should not narrow")
+	}
+
+	req := CreateJobRequest{Params: GenerationParams{Steps: 40}}
+	preset.Limits = limits
+	if err := req.ValidateParams(preset); err == nil || !strings.Contains(err.Error(), "4-30") {
+		t.Errorf("steps above the chain max: err = %v, want the chain range", err)
+	}
+}
+
+func TestCreateJobRejectsOutOfRangeParams(t *testing.T) {
+	var sent bool
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = true
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"id":"job-1"}`))
+	}))
+	defer grid.Close()
+	a := newTestApp(t, grid.URL)
+	a.catalog = models.NewCatalog(testPresets)
+
+	body := `{"modelId":"FLUX.1-dev","prompt":"p","params":{"steps":999,"width":4096,"height":1024}}`
+	rec := serve(a, httptest.NewRequest(http.MethodPost, "/api/jobs", strings.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400 (%s)", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Fields map[string]string `json:"fields"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if !strings.Contains(resp.Fields["params.steps"], "999") || !strings.Contains(resp.Fields["params.width"], "512-2048") || resp.Fields["params.height"] != "" {
+		t.Errorf("fields = %v, want steps and width reported with their ranges", resp.Fields)
+	}
+	if sent {
+		t.Error("rejected job reached the grid")
+	}
+}
@@ -0,0 +1,455 @@
+package app
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/aipg"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/models"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/prompts"
+)
+
+func TestBuildCreateJobPayload(t *testing.T) {
+	catalog := models.NewCatalog(testPresets)
+	flux, _ := catalog.Get("FLUX.1-dev")
+	wan, _ := catalog.Get("wan2.2-t2v-a14b")
+
+	tests := []struct {
+		name       string
+		req        CreateJobRequest
+		preset     models.ModelPreset
+		wantParams map[string]any
+		// Top-level fields other than the prompts and params
+		want aipg.CreateJobPayload
+	}{
+		{
+			name: "image with explicit params",
+			req: CreateJobRequest{
+				ModelID: "FLUX.1-dev",
+				Prompt:  "a lighthouse at dusk",
+				Params: GenerationParams{
+					Width: 768, Height: 1344, Steps: 30, CfgScale: 4.5,
+					Sampler: "euler_ancestral", Scheduler: "Karras", Seed: "123", Tiling: true,
+				},
+				WalletAddress: "0xabc",
+			},
+			preset: flux,
+			wantParams: map[string]any{
+				"sampler_name":       "k_euler_a",
+				"scheduler":          "Karras",
+				"cfg_scale":          4.5,
+				"steps":              30,
+				"karras":             true,
+				"hires_fix":          false,
+				"tiling":             true,
+				"denoising_strength": 0.0,
+				"width":              768,
+				"height":             1344,
+				"seed":               "123",
+			},
+			want: aipg.CreateJobPayload{
+				Models: []string{"FLUX.1-dev"}, CensorNSFW: true, TrustedWorkers: true, R2: true,
+				WalletAddress: "0xabc", SourceProcessing: "txt2img", MediaType: "image",
+			},
+		},
+		{
+			name: "out of range values are clamped to limits",
+			req: CreateJobRequest{
+				ModelID: "FLUX.1-dev",
+				Prompt:  "a lighthouse",
+				Params:  GenerationParams{Width: 4096, Height: 256, Steps: 500, CfgScale: 50, Sampler: "no_such_sampler"},
+			},
+			preset: flux,
+			wantParams: map[string]any{
+				"sampler_name":       "k_euler",
+				"scheduler":          "simple",
+				"cfg_scale":          10.0,
+				"steps":              50,
+				"karras":             false,
+				"hires_fix":          false,
+				"tiling":             false,
+				"denoising_strength": 0.0,
+				"width":              2048,
+				"height":             512,
+			},
+			want: aipg.CreateJobPayload{
+				Models: []string{"FLUX.1-dev"}, CensorNSFW: true, TrustedWorkers: true, R2: true,
+				SourceProcessing: "txt2img", MediaType: "image",
+			},
+		},
+		{
+			name: "video with length and fps",
+			req: CreateJobRequest{
+				ModelID: "wan2.2-t2v-a14b",
+				Prompt:  "waves rolling onto a beach",
+				Params:  GenerationParams{Length: 97, FPS: 24},
+				NSFW:    true,
+				Public:  true,
+			},
+			preset: wan,
+			wantParams: map[string]any{
+				"sampler_name":       "dpmsolver",
+				"scheduler":          "simple",
+				"cfg_scale":          5.0,
+				"steps":              20,
+				"karras":             false,
+				"hires_fix":          false,
+				"tiling":             false,
+				"denoising_strength": 0.0,
+				"width":              832,
+				"height":             480,
+				"length":             97,
+				"video_length":       97,
+				"fps":                24,
+			},
+			want: aipg.CreateJobPayload{
+				Models: []string{"wan2_2_t2v_14b"}, NSFW: true, CensorNSFW: false, TrustedWorkers: true, R2: true,
+				Shared: true, SourceProcessing: "txt2video", MediaType: "video",
+			},
+		},
+		{
+			name: "video from a source image",
+			req: CreateJobRequest{
+				ModelID:     "wan2.2-t2v-a14b",
+				Prompt:      "the statue turns its head",
+				SourceImage: "aW1hZ2U=",
+			},
+			preset: wan,
+			wantParams: map[string]any{
+				"sampler_name":       "dpmsolver",
+				"scheduler":          "simple",
+				"cfg_scale":          5.0,
+				"steps":              20,
+				"karras":             false,
+				"hires_fix":          false,
+				"tiling":             false,
+				"denoising_strength": 0.0,
+				"width":              832,
+				"height":             480,
+				"length":             81,
+				"video_length":       81,
+				"fps":                16,
+			},
+			want: aipg.CreateJobPayload{
+				Models: []string{"wan2_2_t2v_14b"}, CensorNSFW: true, TrustedWorkers: true, R2: true,
+				SourceImage: "aW1hZ2U=", SourceProcessing: "img2video", MediaType: "video",
+			},
+		},
+		{
+			name: "img2img with source and mask",
+			req: CreateJobRequest{
+				ModelID:     "FLUX.1-dev",
+				Prompt:      "make it winter",
+				Params:      GenerationParams{Denoise: 0.6},
+				SourceImage: "aW1hZ2U=",
+				SourceMask:  "bWFzaw==",
+			},
+			preset: flux,
+			wantParams: map[string]any{
+				"sampler_name":       "k_euler",
+				"scheduler":          "simple",
+				"cfg_scale":          3.5,
+				"steps":              20,
+				"karras":             false,
+				"hires_fix":          false,
+				"tiling":             false,
+				"denoising_strength": 0.6,
+				"width":              1024,
+				"height":             1024,
+			},
+			want: aipg.CreateJobPayload{
+				Models: []string{"FLUX.1-dev"}, CensorNSFW: true, TrustedWorkers: true, R2: true,
+				SourceImage: "aW1hZ2U=", SourceMask: "bWFzaw==", SourceProcessing: "img2img", MediaType: "image",
+			},
+		},
+		{
+			name:   "minimal request falls back to preset defaults",
+			req:    CreateJobRequest{ModelID: "FLUX.1-dev", Prompt: "a cat"},
+			preset: flux,
+			wantParams: map[string]any{
+				"sampler_name":       "k_euler",
+				"scheduler":          "simple",
+				"cfg_scale":          3.5,
+				"steps":              20,
+				"karras":             false,
+				"hires_fix":          false,
+				"tiling":             false,
+				"denoising_strength": 0.0,
+				"width":              1024,
+				"height":             1024,
+			},
+			want: aipg.CreateJobPayload{
+				Models: []string{"FLUX.1-dev"}, CensorNSFW: true, TrustedWorkers: true, R2: true,
+				SourceProcessing: "txt2img", MediaType: "image",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := buildCreateJobPayload(tc.req, tc.preset)
+
+			if !reflect.DeepEqual(got.Params, tc.wantParams) {
+				t.Errorf("params =\n%#v\nwant\n%#v", got.Params, tc.wantParams)
+			}
+
+			wantPrompt, wantNegative := prompts.ProcessPrompts(tc.req.Prompt, tc.req.NegativePrompt, tc.preset.ID, tc.preset.Limits.PromptTokens)
+			if got.Prompt != wantPrompt || got.NegativePrompt != wantNegative {
+				t.Errorf("prompts = %q / %q, want %q / %q", got.Prompt, got.NegativePrompt, wantPrompt, wantNegative)
+			}
+
+			got.Params, got.Prompt, got.NegativePrompt = nil, "", ""
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("payload =\n%+v\nwant\n%+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuildCreateJobPayloadWorkerFlags(t *testing.T) {
+	flux, _ := models.NewCatalog(testPresets).Get("FLUX.1-dev")
+	yes, no := true, false
+
+	tests := []struct {
+		name        string
+		req         CreateJobRequest
+		wantTrusted bool
+		wantShared  bool
+	}{
+		{name: "defaults", req: CreateJobRequest{}, wantTrusted: true, wantShared: false},
+		{name: "public without shared follows public", req: CreateJobRequest{Public: true}, wantTrusted: true, wantShared: true},
+		{name: "public but not shared", req: CreateJobRequest{Public: true, Shared: &no}, wantTrusted: true, wantShared: false},
+		{name: "private but shared", req: CreateJobRequest{Shared: &yes}, wantTrusted: true, wantShared: true},
+		{name: "untrusted workers allowed", req: CreateJobRequest{TrustedWorkers: &no}, wantTrusted: false, wantShared: false},
+		{name: "trusted workers explicit", req: CreateJobRequest{TrustedWorkers: &yes}, wantTrusted: true, wantShared: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.req.ModelID, tc.req.Prompt = "FLUX.1-dev", "a cat"
+			got := buildCreateJobPayload(tc.req, flux)
+			if got.TrustedWorkers != tc.wantTrusted || got.Shared != tc.wantShared {
+				t.Errorf("trusted_workers=%v shared=%v, want %v/%v", got.TrustedWorkers, got.Shared, tc.wantTrusted, tc.wantShared)
+			}
+		})
+	}
+}
+
+func TestUnknownSamplerFallsBackByModelType(t *testing.T) {
+	catalog := models.NewCatalog(testPresets)
+	flux, _ := catalog.Get("FLUX.1-dev")
+	wan, _ := catalog.Get("wan2.2-t2v-a14b")
+
+	tests := []struct {
+		name          string
+		preset        models.ModelPreset
+		sampler       string
+		wantSampler   string
+		wantScheduler string
+	}{
+		{name: "known sampler is mapped", preset: wan, sampler: "uni_pc", wantSampler: "dpmsolver", wantScheduler: "simple"},
+		{name: "unknown sampler uses the preset default", preset: flux, sampler: "made_up", wantSampler: "k_euler", wantScheduler: "simple"},
+		{
+			name:        "video preset without a usable default",
+			preset:      models.ModelPreset{ID: "some-video", Type: "video", Defaults: models.ModelDefaults{Sampler: "also_made_up"}},
+			sampler:     "made_up",
+			wantSampler: "dpmsolver", wantScheduler: "simple",
+		},
+		{
+			name:        "untyped preset falls back by model family",
+			preset:      models.ModelPreset{ID: "ltxv-custom"},
+			wantSampler: "dpmsolver", wantScheduler: "simple",
+		},
+		{
+			name:        "image preset without a default",
+			preset:      models.ModelPreset{ID: "some-image", Type: "image"},
+			sampler:     "made_up",
+			wantSampler: "k_euler", wantScheduler: "karras",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := CreateJobRequest{ModelID: tc.preset.ID, Prompt: "a cat", Params: GenerationParams{Sampler: tc.sampler}}
+			got := buildCreateJobPayload(req, tc.preset)
+			if got.Params["sampler_name"] != tc.wantSampler || got.Params["scheduler"] != tc.wantScheduler {
+				t.Errorf("sampler/scheduler = %v/%v, want %s/%s", got.Params["sampler_name"], got.Params["scheduler"], tc.wantSampler, tc.wantScheduler)
+			}
+		})
+	}
+}
+
+func TestCreateJobExtraPassthrough(t *testing.T) {
+	flux, _ := models.NewCatalog(testPresets).Get("FLUX.1-dev")
+
+	allowed := CreateJobRequest{
+		ModelID: "FLUX.1-dev",
+		Prompt:  "a lighthouse",
+		Extra: map[string]any{
+			"special":     map[string]any{"seamless": true},
+			"transparent": true,
+		},
+	}
+	if err := allowed.Validate(); err != nil {
+		t.Fatalf("Validate allowed extra: %v", err)
+	}
+	payload := buildCreateJobPayload(allowed, flux)
+	if !reflect.DeepEqual(payload.Extra, allowed.Extra) {
+		t.Errorf("payload extra = %v, want %v", payload.Extra, allowed.Extra)
+	}
+
+	if payload := buildCreateJobPayload(CreateJobRequest{ModelID: "FLUX.1-dev", Prompt: "p"}, flux); payload.Extra != nil {
+		t.Errorf("payload extra without request extra = %v, want nil", payload.Extra)
+	}
+
+	disallowed := allowed
+	disallowed.Extra = map[string]any{"transparent": true, "wallet_id": "0xother", "models": []any{"x"}}
+	err := disallowed.Validate()
+	if err == nil || err.Error() != "extra fields not allowed: models, wallet_id" {
+		t.Errorf("Validate disallowed extra = %v, want both keys named", err)
+	}
+}
+
+func TestCreateJobLoras(t *testing.T) {
+	flux, _ := models.NewCatalog(testPresets).Get("FLUX.1-dev")
+	weight := func(w float64) *float64 { return &w }
+
+	req := CreateJobRequest{
+		ModelID: "FLUX.1-dev",
+		Prompt:  "a lighthouse",
+		Loras: []LoraRef{
+			{Name: " 247778 ", Model: weight(0.8), Clip: weight(-1.5)},
+			{Name: "Detail Tweaker", IsVersion: true},
+		},
+	}
+	if err := req.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	want := []map[string]any{
+		{"name": "247778", "model": 0.8, "clip": -1.5, "is_version": false},
+		{"name": "Detail Tweaker", "model": 1.0, "clip": 1.0, "is_version": true},
+	}
+	if got := buildCreateJobPayload(req, flux).Params["loras"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("params.loras = %v, want %v", got, want)
+	}
+	if _, ok := buildCreateJobPayload(CreateJobRequest{ModelID: "FLUX.1-dev", Prompt: "p"}, flux).Params["loras"]; ok {
+		t.Error("params.loras set without any loras requested")
+	}
+
+	tests := []struct {
+		name  string
+		loras []LoraRef
+		want  string
+	}{
+		{name: "bounds inclusive", loras: []LoraRef{{Name: "a", Model: weight(5), Clip: weight(-5)}}},
+		{name: "missing name", loras: []LoraRef{{Name: "  "}}, want: "lora name is required"},
+		{name: "model weight too high", loras: []LoraRef{{Name: "a", Model: weight(5.1)}}, want: "lora a model weight must be between -5 and 5"},
+		{name: "clip weight too low", loras: []LoraRef{{Name: "a", Clip: weight(-7)}}, want: "lora a clip weight must be between -5 and 5"},
+		{name: "too many", loras: make([]LoraRef, 6), want: "at most 5 loras can be attached, got 6"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := CreateJobRequest{ModelID: "FLUX.1-dev", Prompt: "p", Loras: tc.loras}
+			err := req.Validate()
+			if tc.want == "" && err != nil {
+				t.Fatalf("Validate = %v, want ok", err)
+			}
+			if tc.want != "" && (err == nil || err.Error() != tc.want) {
+				t.Errorf("Validate = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestCreateJobTextualInversions(t *testing.T) {
+	flux, _ := models.NewCatalog(testPresets).Get("FLUX.1-dev")
+	strength := func(s float64) *float64 { return &s }
+
+	req := CreateJobRequest{
+		ModelID: "FLUX.1-dev",
+		Prompt:  "a lighthouse",
+		TextualInversions: []TIRef{
+			{Name: "72437", InjectInto: "prompt", Strength: strength(0.6)},
+			{Name: " EasyNegative ", InjectInto: "negative"},
+			{Name: "4629"},
+		},
+	}
+	if err := req.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	want := []map[string]any{
+		{"name": "72437", "inject_ti": "prompt", "strength": 0.6},
+		{"name": "EasyNegative", "inject_ti": "negprompt", "strength": 1.0},
+		{"name": "4629"},
+	}
+	if got := buildCreateJobPayload(req, flux).Params["tis"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("params.tis = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		name string
+		tis  []TIRef
+		want string
+	}{
+		{name: "missing name", tis: []TIRef{{InjectInto: "prompt"}}, want: "textual inversion name is required"},
+		{name: "unknown inject target", tis: []TIRef{{Name: "a", InjectInto: "negprompt"}}, want: `textual inversion a injectInto must be prompt or negative, got "negprompt"`},
+		{name: "strength out of range", tis: []TIRef{{Name: "a", InjectInto: "prompt", Strength: strength(-5.5)}}, want: "textual inversion a strength must be between -5 and 5"},
+		{name: "too many", tis: make([]TIRef, 21), want: "at most 20 textual inversions can be applied, got 21"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := CreateJobRequest{ModelID: "FLUX.1-dev", Prompt: "p", TextualInversions: tc.tis}
+			if err := req.Validate(); err == nil || err.Error() != tc.want {
+				t.Errorf("Validate = %v, want %q", err, tc.want)
+			}
+		})
+	}
+
+	extra := CreateJobRequest{ModelID: "FLUX.1-dev", Prompt: "p", Extra: map[string]any{"tis": []any{}}}
+	if err := extra.Validate(); err == nil {
+		t.Error("tis accepted through extra, want it only via textualInversions")
+	}
+}
+
+func TestCreateJobCount(t *testing.T) {
+	flux, _ := models.NewCatalog(testPresets).Get("FLUX.1-dev")
+
+	req := CreateJobRequest{ModelID: "FLUX.1-dev", Prompt: "a lighthouse", Params: GenerationParams{Count: 3}}
+	if got := buildCreateJobPayload(req, flux).Params["n"]; got != 3 {
+		t.Errorf("params.n = %v, want 3", got)
+	}
+	for _, count := range []int{0, 1} {
+		req.Params.Count = count
+		if _, ok := buildCreateJobPayload(req, flux).Params["n"]; ok {
+			t.Errorf("params.n set for count %d, want it left to the Grid default", count)
+		}
+	}
+	req.Params.Count = -1
+	if err := req.Validate(); err == nil {
+		t.Error("Validate accepted a negative count")
+	}
+}
+
+func TestValidateReportsEveryField(t *testing.T) {
+	weight := 9.0
+	req := CreateJobRequest{
+		Params: GenerationParams{Count: -2},
+		Loras:  []LoraRef{{Name: "ok"}, {Name: "a", Model: &weight}},
+		Extra:  map[string]any{"models": []any{"x"}},
+	}
+	var errs ValidationErrors
+	if !errors.As(req.Validate(), &errs) {
+		t.Fatalf("Validate = %v, want ValidationErrors", req.Validate())
+	}
+	want := map[string]string{
+		"prompt":       "prompt is required",
+		"modelId":      "modelId is required",
+		"extra":        "extra fields not allowed: models",
+		"loras[1]":     "lora a model weight must be between -5 and 5",
+		"params.count": "count must be positive, got -2",
+	}
+	if got := errs.Fields(); !reflect.DeepEqual(got, want) {
+		t.Errorf("fields = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,88 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+)
+
+// handleGetSettings returns a wallet's saved preferences
+func (a *App) handleGetSettings(w http.ResponseWriter, r *http.Request) {
+	wallet := strings.ToLower(strings.TrimSpace(chi.URLParam(r, "wallet")))
+	if wallet == "" {
+		writeError(w, http.StatusBadRequest, errors.New("wallet address required"))
+		return
+	}
+
+	if a.settingsStore == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("settings not available"))
+		return
+	}
+
+	settings, err := a.settingsStore.GetSettings(wallet)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"wallet":   wallet,
+		"settings": settings,
+	})
+}
+
+// handleUpdateSettings replaces a wallet's preferences (only the wallet itself may write)
+func (a *App) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
+	wallet := strings.ToLower(strings.TrimSpace(chi.URLParam(r, "wallet")))
+	if wallet == "" {
+		writeError(w, http.StatusBadRequest, errors.New("wallet address required"))
+		return
+	}
+
+	requestWallet := verifiedWalletFromRequest(r)
+	if requestWallet == "" {
+		writeError(w, http.StatusUnauthorized, errors.New("signed wallet request required - sign in with your wallet to change settings"))
+		return
+	}
+	if requestWallet != wallet {
+		writeError(w, http.StatusForbidden, errors.New("you can only change your own settings"))
+		return
+	}
+
+	if a.settingsStore == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("settings not available"))
+		return
+	}
+
+	var settings gallery.UserSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %w", err))
+		return
+	}
+
+	if settings.DefaultModel != "" {
+		if _, ok := a.catalog.Get(settings.DefaultModel); !ok {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("unknown model: %s", settings.DefaultModel))
+			return
+		}
+	}
+
+	if err := a.settingsStore.UpdateSettings(wallet, settings); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	log.Printf("Profile: updated settings for wallet %s", wallet)
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"wallet":   wallet,
+		"settings": settings,
+	})
+}
@@ -0,0 +1,174 @@
+package app
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+)
+
+// exportPageSize is how many items an export reads from the store at a time
+const exportPageSize = 200
+
+// exportCSVHeader is the column order of CSV exports
+var exportCSVHeader = []string{
+	"jobId", "createdAt", "type", "modelId", "modelName", "prompt", "negativePrompt",
+	"isPublic", "isNsfw", "params", "mediaUrls",
+}
+
+// generationExporter writes one export format
+type generationExporter interface {
+	write(item gallery.GalleryItem) error
+	// flush pushes buffered output to the response after each page
+	flush() error
+}
+
+// handleExportGenerations streams every item a wallet has saved as CSV or
+// JSONL. It reads the store a page at a time and writes each page before
+// fetching the next, so a long history never sits in memory.
+func (a *App) handleExportGenerations(w http.ResponseWriter, r *http.Request) {
+	wallet := strings.ToLower(strings.TrimSpace(chi.URLParam(r, "wallet")))
+	if wallet == "" {
+		writeError(w, http.StatusBadRequest, errors.New("wallet address required"))
+		return
+	}
+
+	// The export includes private prompts and media, so the wallet has to sign
+	requestWallet := verifiedWalletFromRequest(r)
+	if requestWallet == "" {
+		writeError(w, http.StatusUnauthorized, errors.New("signed wallet request required - sign in with your wallet to export"))
+		return
+	}
+	if requestWallet != wallet {
+		writeError(w, http.StatusForbidden, errors.New("you can only export your own generations"))
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	contentType := map[string]string{
+		"csv":   "text/csv; charset=utf-8",
+		"jsonl": "application/x-ndjson",
+	}[format]
+	if contentType == "" {
+		writeError(w, http.StatusBadRequest, errors.New("format must be csv or jsonl"))
+		return
+	}
+
+	// Read the first page before committing to a 200 so a store error can still be reported
+	page, err := a.galleryStore.ListByWalletPage(r.Context(), wallet, exportPageSize, nil)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	filename := fmt.Sprintf("aipg-generations-%s-%s.%s", wallet, time.Now().UTC().Format("20060102"), format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	var exporter generationExporter
+	if format == "csv" {
+		exporter = newCSVExporter(w)
+	} else {
+		exporter = &jsonlExporter{enc: json.NewEncoder(w)}
+	}
+	flusher := http.NewResponseController(w)
+
+	for {
+		for _, item := range page.Items {
+			if err := exporter.write(item); err != nil {
+				log.Printf("Warning: export for %s stopped: %v", wallet, err)
+				return
+			}
+		}
+		if err := exporter.flush(); err != nil {
+			log.Printf("Warning: export for %s stopped: %v", wallet, err)
+			return
+		}
+		_ = flusher.Flush()
+
+		if page.NextCursor == "" {
+			return
+		}
+		cursor, err := gallery.ParseWalletCursor(page.NextCursor)
+		if err != nil {
+			log.Printf("Warning: export for %s stopped: %v", wallet, err)
+			return
+		}
+		// Headers are gone by now, so a failure here can only truncate the file
+		if page, err = a.galleryStore.ListByWalletPage(r.Context(), wallet, exportPageSize, cursor); err != nil {
+			log.Printf("Warning: export for %s truncated: %v", wallet, err)
+			return
+		}
+	}
+}
+
+type csvExporter struct {
+	w *csv.Writer
+}
+
+func newCSVExporter(w io.Writer) *csvExporter {
+	e := &csvExporter{w: csv.NewWriter(w)}
+	e.w.Write(exportCSVHeader)
+	return e
+}
+
+func (e *csvExporter) write(item gallery.GalleryItem) error {
+	params := ""
+	if item.Params != nil {
+		raw, err := json.Marshal(item.Params)
+		if err != nil {
+			return err
+		}
+		params = string(raw)
+	}
+	return e.w.Write([]string{
+		item.JobID,
+		time.UnixMilli(item.CreatedAt).UTC().Format(time.RFC3339),
+		item.Type,
+		csvCell(item.ModelID),
+		csvCell(item.ModelName),
+		csvCell(item.Prompt),
+		csvCell(item.NegativePrompt),
+		strconv.FormatBool(item.IsPublic),
+		strconv.FormatBool(item.IsNSFW),
+		params,
+		strings.Join(item.MediaURLs, " "),
+	})
+}
+
+func (e *csvExporter) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// csvCell keeps spreadsheet apps from running user text that starts like a formula
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+type jsonlExporter struct {
+	enc *json.Encoder
+}
+
+func (e *jsonlExporter) write(item gallery.GalleryItem) error {
+	return e.enc.Encode(item)
+}
+
+func (e *jsonlExporter) flush() error { return nil }
@@ -0,0 +1,252 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/models"
+)
+
+// memorySettingsStore is an in-memory gallery.SettingsStore for handler tests
+type memorySettingsStore struct {
+	mu       sync.Mutex
+	settings map[string]gallery.UserSettings
+}
+
+func newMemorySettingsStore() *memorySettingsStore {
+	return &memorySettingsStore{settings: make(map[string]gallery.UserSettings)}
+}
+
+func (m *memorySettingsStore) GetSettings(wallet string) (gallery.UserSettings, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.settings[strings.ToLower(wallet)], nil
+}
+
+func (m *memorySettingsStore) UpdateSettings(wallet string, settings gallery.UserSettings) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.settings[strings.ToLower(wallet)] = settings
+	return nil
+}
+
+func TestSettingsRoundTrip(t *testing.T) {
+	a := newTestApp(t, "")
+	a.catalog = models.NewCatalog([]models.ModelPreset{{ID: "FLUX.1-dev", Type: "image"}})
+	a.settingsStore = newMemorySettingsStore()
+
+	body := `{"showNsfw":true,"defaultModel":"FLUX.1-dev"}`
+	req := httptest.NewRequest(http.MethodPut, "/api/profile/"+strings.ToUpper(testAddr("abc"))+"/settings", strings.NewReader(body))
+	signAs(t, req, "abc")
+	if rec := serve(a, req); rec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+
+	rec := serve(a, httptest.NewRequest(http.MethodGet, "/api/profile/"+testAddr("abc")+"/settings", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET status = %d, want 200", rec.Code)
+	}
+	var resp struct {
+		Settings gallery.UserSettings `json:"settings"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Settings.ShowNSFW == nil || !*resp.Settings.ShowNSFW {
+		t.Errorf("ShowNSFW = %v, want true", resp.Settings.ShowNSFW)
+	}
+	if resp.Settings.DefaultModel != "FLUX.1-dev" {
+		t.Errorf("DefaultModel = %q, want FLUX.1-dev", resp.Settings.DefaultModel)
+	}
+}
+
+func TestUpdateSettingsRejectsOtherWallets(t *testing.T) {
+	a := newTestApp(t, "")
+	a.settingsStore = newMemorySettingsStore()
+
+	tests := []struct {
+		name   string
+		header string
+		body   string
+		want   int
+	}{
+		{name: "no wallet header", header: "", body: `{}`, want: http.StatusUnauthorized},
+		{name: "unsigned wallet header", header: "unsigned", body: `{}`, want: http.StatusUnauthorized},
+		{name: "different wallet", header: "def", body: `{}`, want: http.StatusForbidden},
+		{name: "unknown default model", header: "abc", body: `{"defaultModel":"nope"}`, want: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/api/profile/"+testAddr("abc")+"/settings", strings.NewReader(tc.body))
+			switch tc.header {
+			case "":
+			case "unsigned":
+				req.Header.Set("X-Wallet-Address", testAddr("abc"))
+			default:
+				signAs(t, req, tc.header)
+			}
+			if rec := serve(a, req); rec.Code != tc.want {
+				t.Errorf("status = %d, want %d", rec.Code, tc.want)
+			}
+		})
+	}
+}
+
+func TestListGalleryHonorsNSFWPreference(t *testing.T) {
+	a := newTestApp(t, "")
+	store := newMemorySettingsStore()
+	a.settingsStore = store
+	a.galleryStore.Add(context.Background(), gallery.GalleryItem{JobID: "safe", Prompt: "a cat", IsPublic: true, Type: "image"})
+	a.galleryStore.Add(context.Background(), gallery.GalleryItem{JobID: "nsfw", Prompt: "a cat", IsPublic: true, IsNSFW: true, Type: "image"})
+
+	show, hide := true, false
+	store.UpdateSettings("0xabc", gallery.UserSettings{ShowNSFW: &show})
+	store.UpdateSettings("0xdef", gallery.UserSettings{ShowNSFW: &hide})
+
+	tests := []struct {
+		name   string
+		url    string
+		wallet string
+		want   int
+	}{
+		{name: "anonymous default shows nsfw", url: "/api/gallery", want: 2},
+		{name: "stored preference shows nsfw", url: "/api/gallery", wallet: "0xabc", want: 2},
+		{name: "stored preference hides nsfw", url: "/api/gallery", wallet: "0xdef", want: 1},
+		{name: "explicit param overrides preference", url: "/api/gallery?includeNsfw=false", wallet: "0xabc", want: 1},
+		{name: "explicit param without wallet", url: "/api/gallery?includeNsfw=true", want: 2},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			if tc.wallet != "" {
+				req.Header.Set("X-Wallet-Address", tc.wallet)
+			}
+			rec := serve(a, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", rec.Code)
+			}
+			var result gallery.ListResult
+			if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if result.Total != tc.want {
+				t.Errorf("Total = %d, want %d", result.Total, tc.want)
+			}
+		})
+	}
+}
+
+func TestExportGenerations(t *testing.T) {
+	ownerKey, owner := testWallet(t)
+	otherKey, _ := testWallet(t)
+	a := newTestApp(t, "http://grid.invalid")
+	a.galleryStore = &gallery.FileStoreAdapter{Store: gallery.NewStore("", 1000)}
+	// More than one store page, so the export has to follow the cursor
+	const owned = exportPageSize + 15
+	for i := 0; i < owned; i++ {
+		steps := 20
+		a.galleryStore.Add(context.Background(), gallery.GalleryItem{
+			JobID:         fmt.Sprintf("job-%03d", i),
+			ModelID:       "FLUX.1-dev",
+			Prompt:        fmt.Sprintf("prompt, with comma %d", i),
+			Type:          "image",
+			WalletAddress: owner,
+			CreatedAt:     int64(1700000000000 + i),
+			MediaURLs:     []string{fmt.Sprintf("https://images.aipg.art/%d.webp", i)},
+			Params:        &gallery.JobParams{Steps: &steps},
+		})
+	}
+	a.galleryStore.Add(context.Background(), gallery.GalleryItem{JobID: "someone-else", WalletAddress: "0xother", CreatedAt: 1700000000000})
+
+	export := func(key *ecdsa.PrivateKey, format string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/profile/"+strings.ToUpper(owner)+"/export?format="+format, nil)
+		if key != nil {
+			signWallet(t, req, key, time.Now())
+		}
+		return serve(a, req)
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		rec := export(ownerKey, "csv")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+		if cd := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, `attachment; filename="aipg-generations-`+owner+`-`) || !strings.HasSuffix(cd, `.csv"`) {
+			t.Errorf("Content-Disposition = %q", cd)
+		}
+		rows, err := csv.NewReader(rec.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("export isn't valid CSV: %v", err)
+		}
+		if strings.Join(rows[0], ",") != strings.Join(exportCSVHeader, ",") {
+			t.Errorf("header = %v", rows[0])
+		}
+		if len(rows) != owned+1 {
+			t.Fatalf("rows = %d, want %d plus header", len(rows)-1, owned)
+		}
+		newest := rows[1]
+		if newest[0] != fmt.Sprintf("job-%03d", owned-1) || newest[5] != fmt.Sprintf("prompt, with comma %d", owned-1) {
+			t.Errorf("first row = %v, want newest item", newest)
+		}
+		if newest[9] != `{"steps":20}` || !strings.HasPrefix(newest[10], "https://images.aipg.art/") {
+			t.Errorf("params/media = %q / %q", newest[9], newest[10])
+		}
+	})
+
+	t.Run("jsonl", func(t *testing.T) {
+		rec := export(ownerKey, "jsonl")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+		seen := make(map[string]bool)
+		scanner := bufio.NewScanner(rec.Body)
+		for scanner.Scan() {
+			var item gallery.GalleryItem
+			if err := json.Unmarshal(scanner.Bytes(), &item); err != nil {
+				t.Fatalf("line %q: %v", scanner.Text(), err)
+			}
+			if item.WalletAddress != owner || item.Prompt == "" || item.Params == nil {
+				t.Errorf("item = %+v", item)
+			}
+			seen[item.JobID] = true
+		}
+		if len(seen) != owned {
+			t.Errorf("exported %d distinct items, want %d", len(seen), owned)
+		}
+	})
+
+	for _, tc := range []struct {
+		name string
+		key  *ecdsa.PrivateKey
+		want int
+	}{
+		{"no wallet", nil, http.StatusUnauthorized},
+		{"other wallet", otherKey, http.StatusForbidden},
+	} {
+		if rec := export(tc.key, "csv"); rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.name, rec.Code, tc.want)
+		}
+	}
+	// Naming the owner without their signature isn't enough
+	req := httptest.NewRequest(http.MethodGet, "/api/profile/"+owner+"/export", nil)
+	req.Header.Set("X-Wallet-Address", owner)
+	if rec := serve(a, req); rec.Code != http.StatusUnauthorized {
+		t.Errorf("unsigned owner header: status = %d, want 401", rec.Code)
+	}
+	if rec := export(ownerKey, "xml"); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown format status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimiter allows each key limit requests per fixed window
+type rateLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	clients   map[string]*rateWindow
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type rateWindow struct {
+	count int
+	reset time.Time
+}
+
+// newRateLimiter returns nil, which limits nothing, when limit or window is zero
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	if limit <= 0 || window <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		limit:   limit,
+		window:  window,
+		clients: make(map[string]*rateWindow),
+		now:     time.Now,
+	}
+}
+
+// allow counts a request against key and reports whether it's within the
+// limit, how many requests the window has left and when it resets
+func (l *rateLimiter) allow(key string) (bool, int, time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= l.window {
+		// Drop finished windows so one-off clients don't pile up
+		for k, w := range l.clients {
+			if !now.Before(w.reset) {
+				delete(l.clients, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	w, ok := l.clients[key]
+	if !ok || !now.Before(w.reset) {
+		w = &rateWindow{reset: now.Add(l.window)}
+		l.clients[key] = w
+	}
+	if w.count >= l.limit {
+		return false, 0, w.reset
+	}
+	w.count++
+	return true, l.limit - w.count, w.reset
+}
+
+// middleware limits requests per client IP, answering 429 with Retry-After
+// once a client's window is used up. A nil limiter lets everything through.
+func (l *rateLimiter) middleware(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l.check(w, r) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// check counts r against its client IP and sets the X-RateLimit headers. Over
+// the limit it writes the 429 and returns false. A nil limiter allows everything.
+func (l *rateLimiter) check(w http.ResponseWriter, r *http.Request) bool {
+	if l == nil {
+		return true
+	}
+	key := clientIPFromContext(r.Context())
+	if key == "" {
+		key = r.RemoteAddr
+	}
+	ok, remaining, reset := l.allow(key)
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(l.limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	if !ok {
+		retry := max(int(time.Until(reset).Round(time.Second).Seconds()), 1)
+		w.Header().Set("Retry-After", strconv.Itoa(retry))
+		writeError(w, http.StatusTooManyRequests, errors.New("rate limit exceeded, try again later"))
+	}
+	return ok
+}
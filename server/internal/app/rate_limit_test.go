@@ -0,0 +1,54 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterWindow(t *testing.T) {
+	l := newRateLimiter(2, time.Minute)
+	now := time.Unix(1_700_000_000, 0)
+	l.now = func() time.Time { return now }
+
+	for i, want := range []bool{true, true, false} {
+		if ok, _, _ := l.allow("1.2.3.4"); ok != want {
+			t.Errorf("request %d allowed = %v, want %v", i+1, ok, want)
+		}
+	}
+	if ok, _, _ := l.allow("5.6.7.8"); !ok {
+		t.Error("a different client should have its own window")
+	}
+
+	now = now.Add(time.Minute)
+	if ok, remaining, _ := l.allow("1.2.3.4"); !ok || remaining != 1 {
+		t.Errorf("after the window: allowed = %v, remaining = %d; want true, 1", ok, remaining)
+	}
+}
+
+func TestExploreRateLimited(t *testing.T) {
+	a := newTestApp(t, "http://grid.invalid")
+	a.exploreLimiter = newRateLimiter(3, time.Minute)
+
+	for i := 1; i <= 4; i++ {
+		rec := serve(a, httptest.NewRequest(http.MethodGet, "/api/gallery/explore", nil))
+		if i <= 3 {
+			if rec.Code != http.StatusOK {
+				t.Fatalf("request %d: status = %d", i, rec.Code)
+			}
+			continue
+		}
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("request %d: status = %d, want 429", i, rec.Code)
+		}
+		if rec.Header().Get("Retry-After") == "" || rec.Header().Get("X-RateLimit-Remaining") != "0" {
+			t.Errorf("429 headers = %v", rec.Header())
+		}
+	}
+
+	// The plain gallery list isn't limited
+	if rec := serve(a, httptest.NewRequest(http.MethodGet, "/api/gallery", nil)); rec.Code != http.StatusOK {
+		t.Errorf("/api/gallery status = %d", rec.Code)
+	}
+}
@@ -0,0 +1,184 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/recipevault"
+)
+
+// recipeReader is the slice of the RecipeVault client the recipe endpoints need
+type recipeReader interface {
+	GetRecipe(ctx context.Context, recipeID int64) (*recipevault.OnChainRecipeInfo, error)
+	FetchRecipesByCreator(ctx context.Context, creator, requester string) ([]*recipevault.OnChainRecipeInfo, error)
+}
+
+// recipeContentEncodings maps the on-chain compression enum to an HTTP Content-Encoding
+var recipeContentEncodings = map[int]string{
+	recipevault.CompressionNone:   "",
+	recipevault.CompressionGzip:   "gzip",
+	recipevault.CompressionBrotli: "br",
+}
+
+// RecipeView is the API shape of an on-chain recipe
+type RecipeView struct {
+	ID               int64                  `json:"id"`
+	Name             string                 `json:"name"`
+	Description      string                 `json:"description,omitempty"`
+	Creator          string                 `json:"creator"`
+	IsPublic         bool                   `json:"isPublic"`
+	RecipeRoot       string                 `json:"recipeRoot"`
+	Compression      int                    `json:"compression"`
+	CanCreateNFTs    bool                   `json:"canCreateNFTs"`
+	CreatedAt        time.Time              `json:"createdAt"`
+	Workflow         map[string]interface{} `json:"workflow,omitempty"`
+	WorkflowVerified bool                   `json:"workflowVerified"`
+	WorkflowError    string                 `json:"workflowError,omitempty"`
+}
+
+func newRecipeView(recipe *recipevault.OnChainRecipeInfo) RecipeView {
+	return RecipeView{
+		ID:               recipe.RecipeID,
+		Name:             recipe.Name,
+		Description:      recipe.Description,
+		Creator:          recipe.Creator,
+		IsPublic:         recipe.IsPublic,
+		RecipeRoot:       "0x" + recipe.RecipeRoot,
+		Compression:      recipe.Compression,
+		CanCreateNFTs:    recipe.CanCreateNFTs,
+		CreatedAt:        time.Unix(recipe.CreatedAt, 0).UTC(),
+		Workflow:         recipe.Workflow,
+		WorkflowVerified: recipe.WorkflowVerified,
+		WorkflowError:    recipe.WorkflowError,
+	}
+}
+
+// loadVisibleRecipe parses the {id} param and fetches the recipe, writing the
+// error response on failure. Private recipes are only visible to their creator,
+// proven by a signed request (see verifiedWalletFromRequest).
+func (a *App) loadVisibleRecipe(w http.ResponseWriter, r *http.Request) (*recipevault.OnChainRecipeInfo, bool) {
+	if a.recipes == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("recipes not available"))
+		return nil, false
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, errors.New("invalid recipe id"))
+		return nil, false
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	recipe, err := a.recipes.GetRecipe(ctx, id)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return nil, false
+	}
+	// Unregistered IDs come back zero-valued rather than as a revert
+	if recipe == nil || recipe.RecipeID == 0 || !recipe.VisibleTo(verifiedWalletFromRequest(r)) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("recipe %d not found", id))
+		return nil, false
+	}
+	if !recipe.IsPublic {
+		// Only the creator sees a private recipe, so shared caches must not keep it
+		w.Header().Set("Cache-Control", cacheNoStore)
+	}
+	return recipe, true
+}
+
+// handleGetRecipe returns a recipe's metadata and decoded workflow, including
+// whether the workflow matched its recipeRoot
+func (a *App) handleGetRecipe(w http.ResponseWriter, r *http.Request) {
+	recipe, ok := a.loadVisibleRecipe(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newRecipeView(recipe))
+}
+
+// handleListCreatorRecipes lists the recipes published by an address, newest first.
+// The creator's own wallet also sees their private recipes when the request is
+// signed by it. Workflows are left out of the listing; fetch a recipe by ID
+// for its workflow.
+func (a *App) handleListCreatorRecipes(w http.ResponseWriter, r *http.Request) {
+	if a.recipes == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("recipes not available"))
+		return
+	}
+
+	creator := strings.TrimSpace(chi.URLParam(r, "address"))
+	if !strings.HasPrefix(creator, "0x") || !common.IsHexAddress(creator) {
+		writeError(w, http.StatusBadRequest, errors.New("invalid creator address"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	requester := verifiedWalletFromRequest(r)
+	recipes, err := a.recipes.FetchRecipesByCreator(ctx, creator, requester)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	views := make([]RecipeView, 0, len(recipes))
+	for _, recipe := range recipes {
+		view := newRecipeView(recipe)
+		view.Workflow = nil
+		views = append(views, view)
+	}
+	sort.Slice(views, func(i, j int) bool {
+		if !views[i].CreatedAt.Equal(views[j].CreatedAt) {
+			return views[i].CreatedAt.After(views[j].CreatedAt)
+		}
+		return views[i].ID > views[j].ID
+	})
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"creator":         strings.ToLower(creator),
+		"recipes":         views,
+		"count":           len(views),
+		"includesPrivate": strings.EqualFold(requester, creator),
+	})
+}
+
+// handleRawRecipe serves the exact on-chain workflow bytes so clients can check
+// them against recipeRoot or import them into ComfyUI without our re-encoding.
+// The compression is passed through as Content-Encoding.
+func (a *App) handleRawRecipe(w http.ResponseWriter, r *http.Request) {
+	recipe, ok := a.loadVisibleRecipe(w, r)
+	if !ok {
+		return
+	}
+
+	encoding, known := recipeContentEncodings[recipe.Compression]
+	if known {
+		w.Header().Set("Content-Type", "application/json")
+		if encoding != "" {
+			w.Header().Set("Content-Encoding", encoding)
+		}
+	} else {
+		// Unknown scheme: still hand over the bytes, just don't claim a format
+		w.Header().Set("Content-Type", "application/octet-stream")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="recipe-%d.json"`, recipe.RecipeID))
+	w.Header().Set("Content-Length", strconv.Itoa(len(recipe.WorkflowData)))
+	w.Header().Set("X-Recipe-Root", "0x"+recipe.RecipeRoot)
+	w.Header().Set("X-Recipe-Compression", strconv.Itoa(recipe.Compression))
+	w.Header().Set("X-Recipe-Verified", strconv.FormatBool(recipe.WorkflowVerified))
+	w.WriteHeader(http.StatusOK)
+	w.Write(recipe.WorkflowData)
+}
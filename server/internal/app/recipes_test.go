@@ -0,0 +1,229 @@
+package app
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/recipevault"
+)
+
+type memoryRecipeReader map[int64]*recipevault.OnChainRecipeInfo
+
+func (m memoryRecipeReader) GetRecipe(_ context.Context, id int64) (*recipevault.OnChainRecipeInfo, error) {
+	if recipe, ok := m[id]; ok {
+		return recipe, nil
+	}
+	return &recipevault.OnChainRecipeInfo{}, nil
+}
+
+func (m memoryRecipeReader) FetchRecipesByCreator(_ context.Context, creator, requester string) ([]*recipevault.OnChainRecipeInfo, error) {
+	all := make([]*recipevault.OnChainRecipeInfo, 0, len(m))
+	for _, recipe := range m {
+		all = append(all, recipe)
+	}
+	return recipevault.FilterByCreator(all, creator, requester), nil
+}
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRawRecipeRoundTripsBytes(t *testing.T) {
+	workflow := []byte(`{"3":{"class_type":"KSampler","inputs":{"seed":1}}}`)
+	compressed := gzipBytes(t, workflow)
+
+	a := newTestApp(t, "")
+	a.recipes = memoryRecipeReader{
+		1: {RecipeID: 1, RecipeRoot: "ab12", IsPublic: true, Compression: recipevault.CompressionGzip, WorkflowData: compressed},
+		2: {RecipeID: 2, RecipeRoot: "cd34", IsPublic: true, Compression: recipevault.CompressionNone, WorkflowData: workflow},
+		3: {RecipeID: 3, IsPublic: false, WorkflowData: workflow},
+	}
+
+	rec := serve(a, httptest.NewRequest(http.MethodGet, "/api/recipes/1/raw", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	if !bytes.Equal(rec.Body.Bytes(), compressed) {
+		t.Fatal("raw body differs from the on-chain bytes")
+	}
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Errorf("Content-Encoding = %q, want gzip", got)
+	}
+	if got := rec.Header().Get("X-Recipe-Root"); got != "0xab12" {
+		t.Errorf("X-Recipe-Root = %q, want 0xab12", got)
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded, _ := io.ReadAll(zr); !bytes.Equal(decoded, workflow) {
+		t.Errorf("decoded workflow = %s, want %s", decoded, workflow)
+	}
+
+	rec = serve(a, httptest.NewRequest(http.MethodGet, "/api/recipes/2/raw", nil))
+	if rec.Header().Get("Content-Encoding") != "" || !bytes.Equal(rec.Body.Bytes(), workflow) {
+		t.Errorf("uncompressed recipe: encoding %q, body %s", rec.Header().Get("Content-Encoding"), rec.Body.String())
+	}
+
+	for path, want := range map[string]int{
+		"/api/recipes/3/raw":   http.StatusNotFound, // private
+		"/api/recipes/9/raw":   http.StatusNotFound,
+		"/api/recipes/abc/raw": http.StatusBadRequest,
+	} {
+		if rec := serve(a, httptest.NewRequest(http.MethodGet, path, nil)); rec.Code != want {
+			t.Errorf("GET %s = %d, want %d", path, rec.Code, want)
+		}
+	}
+}
+
+func TestGetRecipeExposesVerification(t *testing.T) {
+	a := newTestApp(t, "")
+	a.recipes = memoryRecipeReader{
+		1: {RecipeID: 1, Name: "flux-basic", RecipeRoot: "ab12", IsPublic: true, WorkflowVerified: true},
+		2: {RecipeID: 2, Name: "tampered", RecipeRoot: "cd34", IsPublic: true, WorkflowError: "workflow hash 0x00 does not match recipeRoot 0xcd34"},
+	}
+
+	var view RecipeView
+	rec := serve(a, httptest.NewRequest(http.MethodGet, "/api/recipes/1", nil))
+	if err := json.Unmarshal(rec.Body.Bytes(), &view); err != nil {
+		t.Fatalf("decode: %v (%s)", err, rec.Body.String())
+	}
+	if !view.WorkflowVerified || view.RecipeRoot != "0xab12" || view.WorkflowError != "" {
+		t.Errorf("verified recipe = %+v", view)
+	}
+
+	view = RecipeView{}
+	rec = serve(a, httptest.NewRequest(http.MethodGet, "/api/recipes/2", nil))
+	json.Unmarshal(rec.Body.Bytes(), &view)
+	if view.WorkflowVerified || view.WorkflowError == "" {
+		t.Errorf("mismatched recipe = %+v, want unverified with an error", view)
+	}
+	rec = serve(a, httptest.NewRequest(http.MethodGet, "/api/recipes/2/raw", nil))
+	if got := rec.Header().Get("X-Recipe-Verified"); got != "false" {
+		t.Errorf("X-Recipe-Verified = %q, want false", got)
+	}
+}
+
+func TestListCreatorRecipes(t *testing.T) {
+	const alice = "0xAbCdEf0123456789abcdef0123456789ABCDEF01"
+	const bob = "0x1111111111111111111111111111111111111111"
+
+	a := newTestApp(t, "")
+	a.recipes = memoryRecipeReader{
+		1: {RecipeID: 1, Name: "Flux-Basic", Creator: alice, IsPublic: true, CreatedAt: 100, Workflow: map[string]interface{}{"1": nil}},
+		2: {RecipeID: 2, Name: "Wan-Video", Creator: alice, IsPublic: true, CreatedAt: 200},
+		3: {RecipeID: 3, Name: "Draft", Creator: alice, IsPublic: false, CreatedAt: 300},
+		4: {RecipeID: 4, Name: "SDXL", Creator: bob, IsPublic: true, CreatedAt: 400},
+	}
+
+	rec := serve(a, httptest.NewRequest(http.MethodGet, "/api/recipes/creator/"+strings.ToLower(alice), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Recipes []RecipeView `json:"recipes"`
+		Count   int          `json:"count"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if body.Count != 2 || len(body.Recipes) != 2 {
+		t.Fatalf("got %d recipes, want alice's 2 public ones: %s", body.Count, rec.Body.String())
+	}
+	if body.Recipes[0].ID != 2 || body.Recipes[1].ID != 1 {
+		t.Errorf("order = %d, %d; want newest first", body.Recipes[0].ID, body.Recipes[1].ID)
+	}
+	if body.Recipes[1].Workflow != nil {
+		t.Error("listing should leave workflows out")
+	}
+	if want := time.Unix(200, 0); !body.Recipes[0].CreatedAt.Equal(want) || !strings.Contains(rec.Body.String(), `"createdAt":"1970-01-01T00:03:20Z"`) {
+		t.Errorf("createdAt = %v, want the chain time %v as RFC3339", body.Recipes[0].CreatedAt, want)
+	}
+
+	for _, addr := range []string{"not-an-address", "AbCdEf0123456789abcdef0123456789ABCDEF01", "0x123"} {
+		if rec := serve(a, httptest.NewRequest(http.MethodGet, "/api/recipes/creator/"+addr, nil)); rec.Code != http.StatusBadRequest {
+			t.Errorf("GET creator %q = %d, want 400", addr, rec.Code)
+		}
+	}
+}
+
+func TestPrivateRecipesVisibleOnlyToCreator(t *testing.T) {
+	aliceKey, alice := testWallet(t)
+	bobKey, _ := testWallet(t)
+
+	a := newTestApp(t, "")
+	a.recipes = memoryRecipeReader{
+		1: {RecipeID: 1, Name: "Published", Creator: alice, IsPublic: true},
+		2: {RecipeID: 2, Name: "Draft", Creator: "0x" + strings.ToUpper(alice[2:]), IsPublic: false},
+	}
+
+	// sign sets the wallet headers: signed by key, or only claiming alice's address
+	sign := func(req *http.Request, key *ecdsa.PrivateKey, claimAlice bool) *http.Request {
+		if key != nil {
+			signWallet(t, req, key, time.Now())
+		} else if claimAlice {
+			req.Header.Set("X-Wallet-Address", alice)
+		}
+		return req
+	}
+	list := func(key *ecdsa.PrivateKey, claimAlice bool) (ids []int64, includesPrivate bool) {
+		req := sign(httptest.NewRequest(http.MethodGet, "/api/recipes/creator/"+alice, nil), key, claimAlice)
+		var body struct {
+			Recipes         []RecipeView `json:"recipes"`
+			IncludesPrivate bool         `json:"includesPrivate"`
+		}
+		json.Unmarshal(serve(a, req).Body.Bytes(), &body)
+		for _, r := range body.Recipes {
+			ids = append(ids, r.ID)
+		}
+		return ids, body.IncludesPrivate
+	}
+	get := func(key *ecdsa.PrivateKey, claimAlice bool) int {
+		return serve(a, sign(httptest.NewRequest(http.MethodGet, "/api/recipes/2", nil), key, claimAlice)).Code
+	}
+
+	if ids, private := list(aliceKey, false); len(ids) != 2 || !private {
+		t.Errorf("creator sees %v (includesPrivate=%v), want both recipes", ids, private)
+	}
+	if code := get(aliceKey, false); code != http.StatusOK {
+		t.Errorf("creator GET private recipe = %d, want 200", code)
+	}
+
+	others := map[string]struct {
+		key        *ecdsa.PrivateKey
+		claimAlice bool
+	}{
+		"anonymous":            {nil, false},
+		"other wallet":         {bobKey, false},
+		"unsigned alice claim": {nil, true},
+	}
+	for name, o := range others {
+		if ids, private := list(o.key, o.claimAlice); len(ids) != 1 || ids[0] != 1 || private {
+			t.Errorf("%s sees %v (includesPrivate=%v), want only the public recipe", name, ids, private)
+		}
+		if code := get(o.key, o.claimAlice); code != http.StatusNotFound {
+			t.Errorf("%s GET private recipe = %d, want 404", name, code)
+		}
+	}
+}
+
+func TestRawRecipeUnavailableWithoutVault(t *testing.T) {
+	a := newTestApp(t, "")
+	if rec := serve(a, httptest.NewRequest(http.MethodGet, "/api/recipes/1/raw", nil)); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", rec.Code)
+	}
+}
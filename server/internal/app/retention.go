@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/r2"
+)
+
+// retentionBatchSize caps how many items one reaper run deletes, so a first
+// run against a large backlog doesn't hold the database for long
+const retentionBatchSize = 500
+
+// RetentionSummary is what one reaper run removed
+type RetentionSummary struct {
+	Pruned        int
+	Failed        int
+	ObjectsPruned int
+}
+
+// pruneExpiredItems deletes private items older than RETENTION_MAX_AGE that
+// nobody favorited or collected, along with their transient R2 objects.
+// Public items are never pruned.
+func (a *App) pruneExpiredItems(ctx context.Context) {
+	store, ok := a.galleryStore.(gallery.RetentionStore)
+	if !ok || a.cfg.RetentionMaxAge <= 0 {
+		return
+	}
+	summary, err := a.pruneItems(ctx, store, time.Now().Add(-a.cfg.RetentionMaxAge))
+	if err != nil {
+		log.Printf("Retention: listing expired items failed: %v", err)
+		return
+	}
+	if summary.Pruned > 0 || summary.Failed > 0 {
+		log.Printf("Retention: pruned %d private items older than %s (%d media objects removed, %d failed)",
+			summary.Pruned, a.cfg.RetentionMaxAge, summary.ObjectsPruned, summary.Failed)
+	}
+}
+
+func (a *App) pruneItems(ctx context.Context, store gallery.RetentionStore, cutoff time.Time) (RetentionSummary, error) {
+	var summary RetentionSummary
+	items, err := store.PrunableItems(ctx, cutoff, retentionBatchSize)
+	if err != nil {
+		return summary, err
+	}
+	for _, item := range items {
+		if ctx.Err() != nil {
+			break
+		}
+		// Media goes first: an orphaned row is retried next run, an orphaned object never is
+		summary.ObjectsPruned += a.deleteItemObjects(ctx, item)
+		if err := a.galleryStore.Delete(ctx, item.JobID); err != nil {
+			log.Printf("Retention: failed to delete %s: %v", item.JobID, err)
+			summary.Failed++
+			continue
+		}
+		summary.Pruned++
+	}
+	return summary, nil
+}
+
+// deleteItemObjects removes an item's media from the transient bucket and
+// returns how many objects went
+func (a *App) deleteItemObjects(ctx context.Context, item gallery.GalleryItem) int {
+	if a.r2Client == nil {
+		return 0
+	}
+	keys := make(map[string]bool)
+	for _, mediaURL := range item.MediaURLs {
+		if key := a.r2Client.KeyFromURL(mediaURL); key != "" {
+			keys[key] = true
+		}
+	}
+	for _, genID := range item.GenerationIDs {
+		keys[a.r2Client.KeyFromURL(r2.MediaFilename(genID, generationMime(item, genID)))] = true
+	}
+
+	deleted := 0
+	for key := range keys {
+		if err := a.r2Client.DeleteObject(ctx, key); err != nil {
+			log.Printf("Retention: failed to delete object %s for %s: %v", key, item.JobID, err)
+			continue
+		}
+		deleted++
+	}
+	return deleted
+}
@@ -0,0 +1,38 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+)
+
+func TestPruneExpiredItems(t *testing.T) {
+	a := newTestApp(t, "")
+	a.cfg.RetentionMaxAge = 30 * 24 * time.Hour
+	old := time.Now().Add(-31 * 24 * time.Hour).UnixMilli()
+	recent := time.Now().Add(-time.Hour).UnixMilli()
+	a.galleryStore.Add(context.Background(), gallery.GalleryItem{JobID: "old-private", CreatedAt: old})
+	a.galleryStore.Add(context.Background(), gallery.GalleryItem{JobID: "old-public", IsPublic: true, CreatedAt: old})
+	a.galleryStore.Add(context.Background(), gallery.GalleryItem{JobID: "new-private", CreatedAt: recent})
+
+	a.pruneExpiredItems(context.Background())
+
+	if a.galleryStore.Get(context.Background(), "old-private") != nil {
+		t.Error("old private item was kept")
+	}
+	for _, id := range []string{"old-public", "new-private"} {
+		if a.galleryStore.Get(context.Background(), id) == nil {
+			t.Errorf("%s was pruned", id)
+		}
+	}
+
+	// Off unless a max age is set
+	a.galleryStore.Add(context.Background(), gallery.GalleryItem{JobID: "old-private-2", CreatedAt: old})
+	a.cfg.RetentionMaxAge = 0
+	a.pruneExpiredItems(context.Background())
+	if a.galleryStore.Get(context.Background(), "old-private-2") == nil {
+		t.Error("pruned with retention disabled")
+	}
+}
@@ -0,0 +1,167 @@
+package app
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// pollScheduler runs every background poller that calls the Grid. Each poller
+// keeps its own interval, but runs are jittered and share one concurrency cap
+// so the server's background load on the Grid stays predictable.
+type pollScheduler struct {
+	maxConcurrent int
+	jitter        time.Duration
+	timeout       time.Duration
+	slots         chan struct{}
+
+	mu      sync.Mutex
+	pollers []*poller
+	active  int
+	peak    int
+}
+
+type poller struct {
+	name     string
+	interval time.Duration
+	run      func(context.Context)
+	stats    PollerStats
+}
+
+// PollerStats describes one registered poller
+type PollerStats struct {
+	Name         string    `json:"name"`
+	Interval     string    `json:"interval"`
+	Runs         int64     `json:"runs"`
+	TimedOut     int64     `json:"timedOut"`
+	Running      bool      `json:"running"`
+	LastStarted  time.Time `json:"lastStarted,omitempty"`
+	LastDuration string    `json:"lastDuration,omitempty"`
+}
+
+// SchedulerStats is the admin view of the poll scheduler
+type SchedulerStats struct {
+	MaxConcurrent int           `json:"maxConcurrent"`
+	Jitter        string        `json:"jitter"`
+	Timeout       string        `json:"timeout"`
+	Active        int           `json:"active"`
+	PeakActive    int           `json:"peakActive"`
+	Pollers       []PollerStats `json:"pollers"`
+}
+
+// newPollScheduler allows at most maxConcurrent poller runs at once (minimum 1),
+// delays each run by up to jitter, and gives each run timeout to finish (0 for no limit)
+func newPollScheduler(maxConcurrent int, jitter, timeout time.Duration) *pollScheduler {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	return &pollScheduler{
+		maxConcurrent: maxConcurrent,
+		jitter:        jitter,
+		timeout:       timeout,
+		slots:         make(chan struct{}, maxConcurrent),
+	}
+}
+
+// Register adds a poller that runs every interval once Start is called
+func (s *pollScheduler) Register(name string, interval time.Duration, run func(context.Context)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pollers = append(s.pollers, &poller{
+		name:     name,
+		interval: interval,
+		run:      run,
+		stats:    PollerStats{Name: name, Interval: interval.String()},
+	})
+}
+
+// Start launches the registered pollers; they stop when ctx is cancelled
+func (s *pollScheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	pollers := append([]*poller(nil), s.pollers...)
+	s.mu.Unlock()
+
+	for _, p := range pollers {
+		log.Printf("Scheduler: polling %s every %v", p.name, p.interval)
+		go s.loop(ctx, p)
+	}
+}
+
+func (s *pollScheduler) loop(ctx context.Context, p *poller) {
+	for {
+		timer := time.NewTimer(p.interval + s.nextJitter())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case s.slots <- struct{}{}:
+		}
+		s.runOnce(ctx, p)
+		<-s.slots
+	}
+}
+
+func (s *pollScheduler) runOnce(ctx context.Context, p *poller) {
+	s.mu.Lock()
+	s.active++
+	if s.active > s.peak {
+		s.peak = s.active
+	}
+	p.stats.Running = true
+	p.stats.LastStarted = time.Now()
+	s.mu.Unlock()
+
+	runCtx, cancel := ctx, context.CancelFunc(func() {})
+	if s.timeout > 0 {
+		runCtx, cancel = context.WithTimeout(ctx, s.timeout)
+	}
+	start := time.Now()
+	p.run(runCtx)
+	timedOut := runCtx.Err() == context.DeadlineExceeded
+	cancel()
+
+	s.mu.Lock()
+	s.active--
+	p.stats.Running = false
+	p.stats.Runs++
+	p.stats.LastDuration = time.Since(start).Round(time.Millisecond).String()
+	if timedOut {
+		p.stats.TimedOut++
+		log.Printf("Warning: scheduled %s run hit the %v timeout", p.name, s.timeout)
+	}
+	s.mu.Unlock()
+}
+
+func (s *pollScheduler) nextJitter() time.Duration {
+	if s.jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(s.jitter)))
+}
+
+// Stats returns a snapshot of the scheduler and its pollers
+func (s *pollScheduler) Stats() SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pollers := make([]PollerStats, len(s.pollers))
+	for i, p := range s.pollers {
+		pollers[i] = p.stats
+	}
+	return SchedulerStats{
+		MaxConcurrent: s.maxConcurrent,
+		Jitter:        s.jitter.String(),
+		Timeout:       s.timeout.String(),
+		Active:        s.active,
+		PeakActive:    s.peak,
+		Pollers:       pollers,
+	}
+}
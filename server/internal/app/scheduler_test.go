@@ -0,0 +1,83 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPollSchedulerRespectsConcurrencyCap(t *testing.T) {
+	const limit = 2
+	s := newPollScheduler(limit, time.Millisecond, time.Second)
+
+	var running, peak int32
+	var mu sync.Mutex
+	runs := map[string]int{}
+	for _, name := range []string{"reconciler", "webhooks", "modelNotify", "stats", "recipes"} {
+		name := name
+		s.Register(name, time.Millisecond, func(ctx context.Context) {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+
+			mu.Lock()
+			runs[name]++
+			mu.Unlock()
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+
+	if got := atomic.LoadInt32(&peak); got > limit {
+		t.Errorf("observed %d concurrent runs, cap is %d", got, limit)
+	}
+	stats := s.Stats()
+	if stats.PeakActive > limit || stats.MaxConcurrent != limit {
+		t.Errorf("stats = %+v, want peak within cap %d", stats, limit)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, p := range stats.Pollers {
+		if runs[p.Name] == 0 {
+			t.Errorf("poller %s never ran", p.Name)
+		}
+	}
+}
+
+func TestPollSchedulerTimesOutRuns(t *testing.T) {
+	s := newPollScheduler(1, 0, 5*time.Millisecond)
+	done := make(chan struct{}, 1)
+	s.Register("slow", time.Millisecond, func(ctx context.Context) {
+		<-ctx.Done()
+		select {
+		case done <- struct{}{}:
+		default:
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("run was not cancelled by the poll timeout")
+	}
+	time.Sleep(5 * time.Millisecond)
+	if stats := s.Stats(); stats.Pollers[0].TimedOut == 0 {
+		t.Errorf("stats = %+v, want the timed out run counted", stats.Pollers[0])
+	}
+}
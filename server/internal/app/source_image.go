@@ -0,0 +1,261 @@
+package app
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"strings"
+
+	// Decoders for the formats browsers hand us besides PNG and JPEG
+	_ "image/gif"
+)
+
+// Limits for the re-encoded source image: JPEG quality for opaque images, and
+// the smallest side length shrinking to fit the byte limit will go down to
+const (
+	sourceImageQuality = 90
+	sourceImageMinSide = 256
+)
+
+// sourceImageMaxPixels bounds a source or mask before it's decoded; a small
+// file can declare a huge canvas, and decoding allocates all of it
+const sourceImageMaxPixels = 40_000_000
+
+// sourceImageLimits bound the img2img source sent to the Grid. Zero disables a limit.
+type sourceImageLimits struct {
+	maxDimension int
+	maxBytes     int
+}
+
+// sourceImage is a decoded source or mask. img is nil for WebP, which we can
+// check but not decode.
+type sourceImage struct {
+	data []byte
+	img  image.Image
+	size image.Point
+}
+
+// decodeSourceImage checks that source, base64 with or without a data URL
+// prefix, holds a whole PNG, JPEG, GIF or WebP image. field names it in errors.
+func decodeSourceImage(field, source string) (*sourceImage, error) {
+	encoded := source
+	if strings.HasPrefix(source, "data:") {
+		meta, rest, ok := strings.Cut(source, ",")
+		if !ok || !strings.HasPrefix(meta, "data:image/") || !strings.HasSuffix(meta, ";base64") {
+			return nil, fmt.Errorf("%s data URL must be a base64 image", field)
+		}
+		encoded = rest
+	}
+	// Line-wrapped base64 is common from some encoders
+	encoded = strings.Join(strings.Fields(encoded), "")
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		if data, err = base64.RawStdEncoding.DecodeString(encoded); err != nil {
+			return nil, fmt.Errorf("%s is not valid base64: %w", field, err)
+		}
+	}
+
+	switch http.DetectContentType(data) {
+	case "image/webp":
+		size, err := webpSize(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a valid WebP image: %w", field, err)
+		}
+		if err := checkSourcePixels(field, size); err != nil {
+			return nil, err
+		}
+		return &sourceImage{data: data, size: size}, nil
+	case "image/png", "image/jpeg", "image/gif":
+		// The header says how much decoding would allocate, so check it first
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s is corrupt: %w", field, err)
+		}
+		if err := checkSourcePixels(field, image.Pt(cfg.Width, cfg.Height)); err != nil {
+			return nil, err
+		}
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s is corrupt: %w", field, err)
+		}
+		return &sourceImage{data: data, img: img, size: img.Bounds().Size()}, nil
+	}
+	return nil, fmt.Errorf("%s must be a PNG, JPEG, GIF or WebP image", field)
+}
+
+func checkSourcePixels(field string, size image.Point) error {
+	if int64(size.X)*int64(size.Y) > sourceImageMaxPixels {
+		return fmt.Errorf("%s is %dx%d, over the %d megapixel limit", field, size.X, size.Y, sourceImageMaxPixels/1_000_000)
+	}
+	return nil
+}
+
+// webpSize reads a WebP image's dimensions from its header
+func webpSize(data []byte) (image.Point, error) {
+	if len(data) < 30 {
+		return image.Point{}, errors.New("truncated header")
+	}
+	chunk := data[12:30]
+	switch string(chunk[:4]) {
+	case "VP8 ":
+		// Lossy: 14-bit sizes after the frame tag and start code
+		if chunk[11] != 0x9d || chunk[12] != 0x01 || chunk[13] != 0x2a {
+			return image.Point{}, errors.New("bad VP8 start code")
+		}
+		return image.Pt(int(chunk[14])|int(chunk[15]&0x3f)<<8, int(chunk[16])|int(chunk[17]&0x3f)<<8), nil
+	case "VP8L":
+		// Lossless: 14-bit sizes minus one, packed after the signature byte
+		if chunk[8] != 0x2f {
+			return image.Point{}, errors.New("bad VP8L signature")
+		}
+		bits := uint32(chunk[9]) | uint32(chunk[10])<<8 | uint32(chunk[11])<<16 | uint32(chunk[12])<<24
+		return image.Pt(int(bits&0x3fff)+1, int(bits>>14&0x3fff)+1), nil
+	case "VP8X":
+		// Extended: 24-bit canvas sizes minus one
+		return image.Pt(
+			(int(chunk[12])|int(chunk[13])<<8|int(chunk[14])<<16)+1,
+			(int(chunk[15])|int(chunk[16])<<8|int(chunk[17])<<16)+1,
+		), nil
+	}
+	return image.Point{}, errors.New("unknown WebP chunk")
+}
+
+// prepareSourceImage validates an img2img source and returns it as the Grid
+// wants it: raw base64 with no data URL prefix. A source over limits is
+// downscaled, keeping its aspect ratio, and re-encoded as JPEG (PNG when it
+// has transparency); its new size is returned, and is zero when the image
+// kept its dimensions. Image URLs are left for the Grid to fetch.
+func prepareSourceImage(source string, limits sourceImageLimits) (string, image.Point, error) {
+	if source == "" || isImageURL(source) {
+		return source, image.Point{}, nil
+	}
+	src, err := decodeSourceImage("sourceImage", source)
+	if err != nil {
+		return "", image.Point{}, err
+	}
+
+	tooLarge := limits.maxBytes > 0 && len(src.data) > limits.maxBytes
+	if !tooLarge && !exceedsDimension(src.size.X, src.size.Y, limits.maxDimension) {
+		return base64.StdEncoding.EncodeToString(src.data), image.Point{}, nil
+	}
+	if src.img == nil {
+		return "", image.Point{}, errors.New("sourceImage is over the size limit and WebP images can't be shrunk here; upload a PNG or JPEG")
+	}
+
+	width, height := fitDimension(src.size.X, src.size.Y, limits.maxDimension)
+	for {
+		scaled := downscale(src.img, width, height)
+		out, err := encodeSourceImage(scaled, scaled.Opaque())
+		if err != nil {
+			return "", image.Point{}, err
+		}
+		// Still over the byte limit: keep shrinking, down to a usable minimum
+		if limits.maxBytes > 0 && len(out) > limits.maxBytes && min(width, height) > sourceImageMinSide {
+			width, height = fitDimension(width, height, max(width, height)*3/4)
+			continue
+		}
+		return base64.StdEncoding.EncodeToString(out), image.Pt(width, height), nil
+	}
+}
+
+// prepareSourceMask validates an inpainting mask and returns it as raw base64.
+// When the source was shrunk to size, the mask is scaled to match so the two
+// still line up; resized masks are always PNG, since JPEG artefacts would blur
+// their edges.
+func prepareSourceMask(mask string, size image.Point) (string, error) {
+	if mask == "" || isImageURL(mask) {
+		return mask, nil
+	}
+	src, err := decodeSourceImage("sourceMask", mask)
+	if err != nil {
+		return "", err
+	}
+	if size == (image.Point{}) || size == src.size {
+		return base64.StdEncoding.EncodeToString(src.data), nil
+	}
+	if src.img == nil {
+		return "", errors.New("sourceMask must be a PNG or JPEG to be resized with its source image")
+	}
+	out, err := encodeSourceImage(downscale(src.img, size.X, size.Y), false)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+func isImageURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+func exceedsDimension(width, height, maxDimension int) bool {
+	return maxDimension > 0 && (width > maxDimension || height > maxDimension)
+}
+
+// fitDimension scales width and height so the longer side is at most maxDimension
+func fitDimension(width, height, maxDimension int) (int, int) {
+	if !exceedsDimension(width, height, maxDimension) {
+		return width, height
+	}
+	if width >= height {
+		return maxDimension, max((height*maxDimension+width/2)/width, 1)
+	}
+	return max((width*maxDimension+height/2)/height, 1), maxDimension
+}
+
+// downscale resizes src to width x height by averaging the source pixels
+// under each destination pixel, which keeps detail when shrinking a lot
+func downscale(src image.Image, width, height int) *image.NRGBA {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	in := image.NewNRGBA(image.Rect(0, 0, srcW, srcH))
+	draw.Draw(in, in.Bounds(), src, bounds.Min, draw.Src)
+	if width >= srcW && height >= srcH {
+		return in
+	}
+
+	out := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcH/height, max((y+1)*srcH/height, y*srcH/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcW/width, max((x+1)*srcW/width, x*srcW/width+1)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := in.Pix[sy*in.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					// Weight colour by alpha so transparent pixels don't bleed into edges
+					alpha := uint64(p[3])
+					r += uint64(p[0]) * alpha
+					g += uint64(p[1]) * alpha
+					b += uint64(p[2]) * alpha
+					a += alpha
+					n++
+				}
+			}
+			o := out.Pix[y*out.Stride+x*4:]
+			if a > 0 {
+				o[0], o[1], o[2] = uint8(r/a), uint8(g/a), uint8(b/a)
+			}
+			o[3] = uint8(a / n)
+		}
+	}
+	return out
+}
+
+// encodeSourceImage writes img as JPEG when lossy is set, otherwise as PNG
+func encodeSourceImage(img *image.NRGBA, lossy bool) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if lossy {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: sourceImageQuality})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	return buf.Bytes(), err
+}
@@ -0,0 +1,219 @@
+package app
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/models"
+)
+
+// testSourceImage is a base64 PNG of the given size, noisy so it doesn't compress away
+func testSourceImage(t *testing.T, width, height int, alpha uint8) string {
+	t.Helper()
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{uint8(rng.Intn(256)), uint8(x), uint8(y), alpha})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+// imageConfig decodes the header of a raw base64 image
+func imageConfig(t *testing.T, source string) (image.Config, string) {
+	t.Helper()
+	data, err := base64.StdEncoding.DecodeString(source)
+	if err != nil {
+		t.Fatalf("result isn't raw base64: %v", err)
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("result isn't an image: %v", err)
+	}
+	return cfg, format
+}
+
+func TestPrepareSourceImageDimensions(t *testing.T) {
+	limits := sourceImageLimits{maxDimension: 100}
+
+	small := testSourceImage(t, 80, 60, 255)
+	if got, size, err := prepareSourceImage(small, limits); err != nil || got != small || size != (image.Point{}) {
+		t.Errorf("an image within the limits changed (size %v, err %v)", size, err)
+	}
+
+	tests := []struct {
+		name          string
+		width, height int
+		alpha         uint8
+		wantW, wantH  int
+		wantFormat    string
+	}{
+		{"landscape", 400, 200, 255, 100, 50, "jpeg"},
+		{"portrait", 150, 300, 255, 50, 100, "jpeg"},
+		{"transparent stays PNG", 300, 300, 128, 100, 100, "png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, size, err := prepareSourceImage(testSourceImage(t, tt.width, tt.height, tt.alpha), limits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg, format := imageConfig(t, got)
+			if cfg.Width != tt.wantW || cfg.Height != tt.wantH || format != tt.wantFormat {
+				t.Errorf("got %dx%d %s, want %dx%d %s", cfg.Width, cfg.Height, format, tt.wantW, tt.wantH, tt.wantFormat)
+			}
+			if size != image.Pt(tt.wantW, tt.wantH) {
+				t.Errorf("reported size %v, want %dx%d", size, tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+func TestPrepareSourceImageBytes(t *testing.T) {
+	source := testSourceImage(t, 1200, 800, 255)
+	limits := sourceImageLimits{maxBytes: 150 << 10}
+	got, _, err := prepareSourceImage(source, limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := base64.StdEncoding.DecodeString(got)
+	if len(data) > limits.maxBytes {
+		t.Errorf("result is %d bytes, want at most %d", len(data), limits.maxBytes)
+	}
+	cfg, _ := imageConfig(t, got)
+	if ratio := float64(cfg.Width) / float64(cfg.Height); cfg.Width >= 1200 || ratio < 1.49 || ratio > 1.51 {
+		t.Errorf("result is %dx%d, want smaller with the 3:2 aspect ratio kept", cfg.Width, cfg.Height)
+	}
+}
+
+func TestPrepareSourceImageFormats(t *testing.T) {
+	raw := testSourceImage(t, 40, 30, 255)
+	wrapped := raw[:20] + "\n" + raw[20:]
+	// A minimal lossless WebP header for a 40x30 image
+	webp := base64.StdEncoding.EncodeToString([]byte("RIFF\x1a\x00\x00\x00WEBPVP8L\x0d\x00\x00\x00\x2f\x27\x40\x07\x00\x00\x00\x00\x00\x00"))
+
+	for name, input := range map[string]string{
+		"raw":           raw,
+		"data URL":      "data:image/png;base64," + raw,
+		"line wrapped":  wrapped,
+		"unpadded":      strings.TrimRight(raw, "="),
+		"webp":          webp,
+		"webp data URL": "data:image/webp;base64," + webp,
+		"http URL":      "https://images.aipg.art/source.png",
+		"no source":     "",
+	} {
+		got, _, err := prepareSourceImage(input, sourceImageLimits{maxDimension: 100})
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		want := raw
+		switch name {
+		case "webp", "webp data URL":
+			want = webp
+		case "http URL", "no source":
+			want = input
+		}
+		if got != want {
+			t.Errorf("%s: got %.40q, want %.40q", name, got, want)
+		}
+	}
+
+	if src, err := decodeSourceImage("sourceImage", webp); err != nil || src.size != image.Pt(40, 30) {
+		t.Errorf("WebP header: size %v, err %v; want 40x30", src, err)
+	}
+	if _, _, err := prepareSourceImage(webp, sourceImageLimits{maxDimension: 20}); err == nil {
+		t.Error("oversized WebP: want an error, it can't be shrunk")
+	}
+	pngBytes, _ := base64.StdEncoding.DecodeString(raw)
+	for name, input := range map[string]string{
+		"not base64":     "not base64!",
+		"not an image":   base64.StdEncoding.EncodeToString([]byte("hello, world")),
+		"truncated PNG":  base64.StdEncoding.EncodeToString(pngBytes[:len(pngBytes)/2]),
+		"text data URL":  "data:text/plain;base64," + raw,
+		"plain data URL": "data:image/png," + raw,
+	} {
+		if _, _, err := prepareSourceImage(input, sourceImageLimits{}); err == nil {
+			t.Errorf("%s: want an error", name)
+		}
+	}
+}
+
+func TestPrepareSourceMask(t *testing.T) {
+	mask := testSourceImage(t, 200, 100, 255)
+
+	got, err := prepareSourceMask("data:image/png;base64,"+mask, image.Pt(50, 25))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg, format := imageConfig(t, got); cfg.Width != 50 || cfg.Height != 25 || format != "png" {
+		t.Errorf("mask is %dx%d %s, want 50x25 png", cfg.Width, cfg.Height, format)
+	}
+
+	// An unshrunk source leaves the mask as it was, minus the prefix
+	if got, err := prepareSourceMask("data:image/png;base64,"+mask, image.Point{}); err != nil || got != mask {
+		t.Errorf("mask for an unchanged source: got %.40q, %v", got, err)
+	}
+	if _, err := prepareSourceMask("%%%", image.Point{}); err == nil {
+		t.Error("corrupt mask: want an error")
+	}
+}
+
+func TestCreateJobRejectsBadSourceImage(t *testing.T) {
+	a := newTestApp(t, "")
+	a.catalog = models.NewCatalog(testPresets)
+
+	for field, body := range map[string]string{
+		"sourceImage": `{"modelId":"FLUX.1-dev","prompt":"p","sourceImage":"%%%"}`,
+		"sourceMask":  `{"modelId":"FLUX.1-dev","prompt":"p","sourceMask":"aGVsbG8="}`,
+	} {
+		rec := serve(a, httptest.NewRequest(http.MethodPost, "/api/jobs", strings.NewReader(body)))
+		var resp struct {
+			Fields map[string]string `json:"fields"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		if rec.Code != http.StatusBadRequest || resp.Fields[field] == "" {
+			t.Errorf("%s: status = %d, fields = %v; want 400 naming it", field, rec.Code, resp.Fields)
+		}
+	}
+}
+
+func TestDecodeSourceImageRejectsHugeCanvas(t *testing.T) {
+	// A tiny PNG whose header claims 100000x100000, with the IHDR checksum fixed up
+	data, _ := base64.StdEncoding.DecodeString(testSourceImage(t, 1, 1, 255))
+	binary.BigEndian.PutUint32(data[16:], 100000)
+	binary.BigEndian.PutUint32(data[20:], 100000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+	_, err := decodeSourceImage("sourceImage", base64.StdEncoding.EncodeToString(data))
+	if err == nil || !strings.Contains(err.Error(), "megapixel") {
+		t.Errorf("err = %v, want the pixel limit before decoding", err)
+	}
+}
+
+func TestCreateJobBodyLimit(t *testing.T) {
+	a := newTestApp(t, "")
+	a.catalog = models.NewCatalog(testPresets)
+
+	body := `{"modelId":"FLUX.1-dev","prompt":"p","sourceImage":"` + strings.Repeat("A", createJobMaxBytes) + `"}`
+	rec := serve(a, httptest.NewRequest(http.MethodPost, "/api/jobs", strings.NewReader(body)))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want 413", rec.Code)
+	}
+}
@@ -0,0 +1,230 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/aipg"
+)
+
+// Queued submission states
+const (
+	submitPending   = "pending"
+	submitSubmitted = "submitted"
+	submitFailed    = "failed"
+)
+
+// How long finished submissions stay queryable, and how long each Grid call may take
+const (
+	submitRetention = 10 * time.Minute
+	submitTimeout   = 30 * time.Second
+)
+
+var errSubmitQueueFull = errors.New("too many jobs are waiting to be submitted, try again shortly")
+
+// QueuedSubmission is a job waiting for, or past, its turn to go to the Grid
+type QueuedSubmission struct {
+	QueueID string `json:"queueId"`
+	Status  string `json:"status"`
+	// Place in line, 1 for next; 0 once submitted
+	QueuePosition int    `json:"queuePosition"`
+	JobID         string `json:"jobId,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// submitQueue meters job submissions to the Grid. Bursts of job creation wait
+// their turn in FIFO order instead of all hitting the Grid at once; each
+// submission starts at least interval after the one before.
+type submitQueue struct {
+	interval time.Duration
+	capacity int
+	wake     chan struct{}
+
+	mu      sync.Mutex
+	pending []*queuedSubmit
+	byID    map[string]*queuedSubmit
+}
+
+type queuedSubmit struct {
+	id     string
+	ctx    context.Context
+	submit func(context.Context) (string, error)
+	done   chan struct{}
+
+	// Set under the queue's lock
+	dispatched bool
+	finished   time.Time
+	jobID      string
+	err        error
+}
+
+// newSubmitQueue returns nil, which submits straight away, when rate is zero.
+// At most capacity submissions wait at once (minimum 1).
+func newSubmitQueue(rate, capacity int) *submitQueue {
+	if rate <= 0 {
+		return nil
+	}
+	return &submitQueue{
+		interval: time.Second / time.Duration(rate),
+		capacity: max(capacity, 1),
+		wake:     make(chan struct{}, 1),
+		byID:     make(map[string]*queuedSubmit),
+	}
+}
+
+// enqueue adds a submission to the back of the line. ctx is kept for the Grid
+// call, so it shouldn't be one that ends with the HTTP request.
+func (q *submitQueue) enqueue(ctx context.Context, submit func(context.Context) (string, error)) (*queuedSubmit, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	for id, entry := range q.byID {
+		if !entry.finished.IsZero() && now.Sub(entry.finished) > submitRetention {
+			delete(q.byID, id)
+		}
+	}
+	if len(q.pending) >= q.capacity {
+		return nil, errSubmitQueueFull
+	}
+
+	entry := &queuedSubmit{id: aipg.NewRequestID(), ctx: ctx, submit: submit, done: make(chan struct{})}
+	q.pending = append(q.pending, entry)
+	q.byID[entry.id] = entry
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return entry, nil
+}
+
+// submit runs fn through the queue, waiting up to wait for its turn. If the
+// turn hasn't come by then, the job stays queued and its status is returned
+// instead. A nil queue runs fn right away.
+func (q *submitQueue) submit(ctx context.Context, wait time.Duration, fn func(context.Context) (string, error)) (string, *QueuedSubmission, error) {
+	if q == nil {
+		jobID, err := fn(ctx)
+		return jobID, nil, err
+	}
+	entry, err := q.enqueue(context.WithoutCancel(ctx), fn)
+	if err != nil {
+		return "", nil, err
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-entry.done:
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	// The submission may have finished just as the wait ran out
+	status, _ := q.status(entry.id)
+	if status.Status == submitPending {
+		return "", &status, nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return entry.jobID, nil, entry.err
+}
+
+// status reports a queued submission by ID
+func (q *submitQueue) status(id string) (QueuedSubmission, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	entry, ok := q.byID[id]
+	if !ok {
+		return QueuedSubmission{}, false
+	}
+	status := QueuedSubmission{QueueID: id, Status: submitPending, JobID: entry.jobID}
+	switch {
+	case !entry.dispatched:
+		for i, e := range q.pending {
+			if e == entry {
+				status.QueuePosition = i + 1
+				break
+			}
+		}
+	case entry.finished.IsZero():
+		// Handed to the Grid, no answer yet
+	case entry.err != nil:
+		status.Status = submitFailed
+		status.Error = entry.err.Error()
+	default:
+		status.Status = submitSubmitted
+	}
+	return status, true
+}
+
+// run dispatches queued submissions in order, one per interval, until ctx is cancelled
+func (q *submitQueue) run(ctx context.Context) {
+	var last time.Time
+	for {
+		if wait := time.Until(last.Add(q.interval)); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		entry := q.pop()
+		if entry == nil {
+			select {
+			case <-q.wake:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+		last = time.Now()
+		// Metering limits how often calls start, not how many are in flight,
+		// so a slow Grid response doesn't hold up the line
+		go q.dispatch(entry)
+	}
+}
+
+func (q *submitQueue) pop() *queuedSubmit {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending) == 0 {
+		return nil
+	}
+	entry := q.pending[0]
+	q.pending = q.pending[1:]
+	entry.dispatched = true
+	return entry
+}
+
+func (q *submitQueue) dispatch(entry *queuedSubmit) {
+	ctx, cancel := context.WithTimeout(entry.ctx, submitTimeout)
+	defer cancel()
+	jobID, err := entry.submit(ctx)
+
+	q.mu.Lock()
+	entry.jobID, entry.err = jobID, err
+	entry.finished = time.Now()
+	q.mu.Unlock()
+	close(entry.done)
+}
+
+// handleQueuedSubmission reports a job that POST /api/jobs left waiting in
+// the submit queue; once submitted it carries the Grid job ID to poll
+func (a *App) handleQueuedSubmission(w http.ResponseWriter, r *http.Request) {
+	if a.submissions == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("submit queue not enabled"))
+		return
+	}
+	status, ok := a.submissions.status(chi.URLParam(r, "id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("queued job not found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
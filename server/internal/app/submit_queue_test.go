@@ -0,0 +1,132 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/models"
+)
+
+func TestSubmitQueueOrderAndRate(t *testing.T) {
+	q := newSubmitQueue(50, 10) // one submission per 20ms
+	var mu sync.Mutex
+	var order []string
+	var started []time.Time
+	var entries []*queuedSubmit
+	for i := 0; i < 5; i++ {
+		id := fmt.Sprintf("job-%d", i)
+		entry, err := q.enqueue(context.Background(), func(context.Context) (string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, id)
+			started = append(started, time.Now())
+			return id, nil
+		})
+		if err != nil {
+			t.Fatalf("enqueue %d: %v", i, err)
+		}
+		entries = append(entries, entry)
+	}
+	if status, _ := q.status(entries[3].id); status.QueuePosition != 4 {
+		t.Errorf("fourth entry position = %d, want 4", status.QueuePosition)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.run(ctx)
+	for _, entry := range entries {
+		<-entry.done
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"job-0", "job-1", "job-2", "job-3", "job-4"}; strings.Join(order, ",") != strings.Join(want, ",") {
+		t.Errorf("submitted %v, want %v", order, want)
+	}
+	for i := 1; i < len(started); i++ {
+		// Allow a little timer slop
+		if gap := started[i].Sub(started[i-1]); gap < q.interval-2*time.Millisecond {
+			t.Errorf("submission %d started %v after the previous, want at least %v", i, gap, q.interval)
+		}
+	}
+	if status, _ := q.status(entries[4].id); status.Status != submitSubmitted || status.JobID != "job-4" || status.QueuePosition != 0 {
+		t.Errorf("finished entry status = %+v", status)
+	}
+}
+
+func TestSubmitQueueFull(t *testing.T) {
+	q := newSubmitQueue(1, 2)
+	noop := func(context.Context) (string, error) { return "", nil }
+	for i := 0; i < 2; i++ {
+		if _, err := q.enqueue(context.Background(), noop); err != nil {
+			t.Fatalf("enqueue %d: %v", i, err)
+		}
+	}
+	if _, err := q.enqueue(context.Background(), noop); err != errSubmitQueueFull {
+		t.Errorf("third enqueue err = %v, want errSubmitQueueFull", err)
+	}
+	if newSubmitQueue(0, 10) != nil {
+		t.Error("rate 0 should disable the queue")
+	}
+}
+
+func TestCreateJobQueued(t *testing.T) {
+	var mu sync.Mutex
+	submitted := 0
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		submitted++
+		id := submitted
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, `{"id":"job-%d"}`, id)
+	}))
+	defer grid.Close()
+	a := newTestApp(t, grid.URL)
+	a.catalog = models.NewCatalog(testPresets)
+	a.cfg.SubmitQueueWait = 50 * time.Millisecond
+	// The first submission goes straight out, the next waits far longer than the test
+	a.submissions = newSubmitQueue(1, 1)
+	a.submissions.interval = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go a.submissions.run(ctx)
+
+	create := func() (*httptest.ResponseRecorder, map[string]any) {
+		rec := serve(a, httptest.NewRequest(http.MethodPost, "/api/jobs", strings.NewReader(`{"modelId":"FLUX.1-dev","prompt":"p"}`)))
+		var body map[string]any
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		return rec, body
+	}
+
+	rec, body := create()
+	if rec.Code != http.StatusAccepted || body["jobId"] != "job-1" {
+		t.Fatalf("first job: status = %d, body = %v", rec.Code, body)
+	}
+
+	rec, body = create()
+	if rec.Code != http.StatusAccepted || body["jobId"] != nil || body["status"] != submitPending || body["queuePosition"] != float64(1) {
+		t.Fatalf("second job: status = %d, body = %v; want pending at position 1", rec.Code, body)
+	}
+	queueID, _ := body["queueId"].(string)
+	rec = serve(a, httptest.NewRequest(http.MethodGet, "/api/jobs/queued/"+queueID, nil))
+	var status QueuedSubmission
+	json.Unmarshal(rec.Body.Bytes(), &status)
+	if rec.Code != http.StatusOK || status.Status != submitPending || status.QueuePosition != 1 {
+		t.Errorf("queued status: %d %+v", rec.Code, status)
+	}
+
+	if rec, _ := create(); rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("full queue: status = %d, want 503 with Retry-After", rec.Code)
+	}
+	if rec := serve(a, httptest.NewRequest(http.MethodGet, "/api/jobs/queued/nope", nil)); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown queue ID: status = %d, want 404", rec.Code)
+	}
+}
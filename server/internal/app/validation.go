@@ -0,0 +1,73 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// FieldError is one problem with one request field
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// ValidationErrors collects every problem with a request instead of stopping
+// at the first, so a form can flag all its bad fields in one round trip
+type ValidationErrors []FieldError
+
+// Add records a problem with field; field names follow the request JSON, e.g. "params.count" or "loras[1]"
+func (v *ValidationErrors) Add(field, message string) {
+	*v = append(*v, FieldError{Field: field, Message: message})
+}
+
+// Addf is Add with a formatted message
+func (v *ValidationErrors) Addf(field, format string, args ...any) {
+	v.Add(field, fmt.Sprintf(format, args...))
+}
+
+// Error joins the messages in the order they were found
+func (v ValidationErrors) Error() string {
+	messages := make([]string, len(v))
+	for i, e := range v {
+		messages[i] = e.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Err returns v as an error, or nil when nothing was recorded
+func (v ValidationErrors) Err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
+
+// Fields maps each field to its message; several problems with one field are joined
+func (v ValidationErrors) Fields() map[string]string {
+	fields := make(map[string]string, len(v))
+	for _, e := range v {
+		if prev, ok := fields[e.Field]; ok {
+			fields[e.Field] = prev + "; " + e.Message
+			continue
+		}
+		fields[e.Field] = e.Message
+	}
+	return fields
+}
+
+// writeValidationError answers 400 with the usual error body, plus a
+// field -> message map when err is a ValidationErrors
+func writeValidationError(w http.ResponseWriter, err error) {
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusBadRequest, map[string]any{
+		"error":  verrs.Error(),
+		"status": http.StatusBadRequest,
+		"fields": verrs.Fields(),
+	})
+}
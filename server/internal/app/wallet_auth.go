@@ -0,0 +1,64 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// X-Wallet-Address on its own is only a claim anyone can send. Every endpoint
+// that reveals a wallet's private items or changes its data goes through
+// verifiedWalletFromRequest, which also needs these two headers: a unix
+// timestamp and the wallet's personal_sign signature over walletAuthMessage.
+// The bare header is still read for display preferences (galleryIncludeNSFW),
+// which GET /api/profile/{wallet}/settings serves to anyone.
+const (
+	walletSignatureHeader = "X-Wallet-Signature"
+	walletTimestampHeader = "X-Wallet-Timestamp"
+)
+
+// walletSignatureMaxAge is how far a signed timestamp may be from the server's
+// clock. A signature can be replayed within this window, so it proves the
+// wallet's holder signed recently, not that they sent this particular request.
+const walletSignatureMaxAge = 5 * time.Minute
+
+// walletAuthMessage is the text a wallet signs to prove itself
+func walletAuthMessage(wallet string, timestamp int64) string {
+	return fmt.Sprintf("AIPG Art Gallery\nWallet: %s\nTimestamp: %d", strings.ToLower(wallet), timestamp)
+}
+
+// verifiedWalletFromRequest returns walletFromRequest when the request carries
+// a fresh signature from that wallet, and "" otherwise
+func verifiedWalletFromRequest(r *http.Request) string {
+	wallet := walletFromRequest(r)
+	if wallet == "" || !common.IsHexAddress(wallet) {
+		return ""
+	}
+	timestamp, err := strconv.ParseInt(r.Header.Get(walletTimestampHeader), 10, 64)
+	if err != nil {
+		return ""
+	}
+	if age := time.Since(time.Unix(timestamp, 0)); age > walletSignatureMaxAge || age < -walletSignatureMaxAge {
+		return ""
+	}
+	sig, err := hexutil.Decode(strings.TrimSpace(r.Header.Get(walletSignatureHeader)))
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return ""
+	}
+	// Wallets return v as 27/28; SigToPub wants 0/1
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pub, err := crypto.SigToPub(accounts.TextHash([]byte(walletAuthMessage(wallet, timestamp))), sig)
+	if err != nil || crypto.PubkeyToAddress(*pub) != common.HexToAddress(wallet) {
+		return ""
+	}
+	return wallet
+}
@@ -0,0 +1,133 @@
+package app
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+)
+
+// testWallet generates a wallet key and returns it with its lowercase address
+func testWallet(t *testing.T) (*ecdsa.PrivateKey, string) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	return key, hexutil.Encode(crypto.PubkeyToAddress(key.PublicKey).Bytes())
+}
+
+// testKey derives a fixed wallet key from name, so fixtures can refer to
+// wallets by name
+func testKey(name string) *ecdsa.PrivateKey {
+	key, err := crypto.ToECDSA(crypto.Keccak256([]byte(name)))
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// testAddr is the lowercase address of testKey(name)
+func testAddr(name string) string {
+	return hexutil.Encode(crypto.PubkeyToAddress(testKey(name).PublicKey).Bytes())
+}
+
+// signAs signs req as the wallet testKey(name)
+func signAs(t *testing.T, req *http.Request, name string) *http.Request {
+	t.Helper()
+	signWallet(t, req, testKey(name), time.Now())
+	return req
+}
+
+// signWallet sets the wallet headers on req as a browser wallet would, signing at ts
+func signWallet(t *testing.T, req *http.Request, key *ecdsa.PrivateKey, ts time.Time) {
+	t.Helper()
+	wallet := hexutil.Encode(crypto.PubkeyToAddress(key.PublicKey).Bytes())
+	sig, err := crypto.Sign(accounts.TextHash([]byte(walletAuthMessage(wallet, ts.Unix()))), key)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	req.Header.Set("X-Wallet-Address", wallet)
+	req.Header.Set(walletTimestampHeader, strconv.FormatInt(ts.Unix(), 10))
+	req.Header.Set(walletSignatureHeader, hexutil.Encode(sig))
+}
+
+func TestVerifiedWalletFromRequest(t *testing.T) {
+	key, wallet := testWallet(t)
+	_, other := testWallet(t)
+
+	signed := func(ts time.Time) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		signWallet(t, req, key, ts)
+		return req
+	}
+
+	if got := verifiedWalletFromRequest(signed(time.Now())); got != wallet {
+		t.Errorf("signed request = %q, want %q", got, wallet)
+	}
+
+	cases := map[string]*http.Request{
+		"stale":  signed(time.Now().Add(-10 * time.Minute)),
+		"future": signed(time.Now().Add(10 * time.Minute)),
+		"unsigned": func() *http.Request {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-Wallet-Address", wallet)
+			return req
+		}(),
+		"other wallet": func() *http.Request {
+			req := signed(time.Now())
+			req.Header.Set("X-Wallet-Address", other)
+			return req
+		}(),
+		"moved timestamp": func() *http.Request {
+			req := signed(time.Now())
+			req.Header.Set(walletTimestampHeader, strconv.FormatInt(time.Now().Unix()-1, 10))
+			return req
+		}(),
+		"garbage signature": func() *http.Request {
+			req := signed(time.Now())
+			req.Header.Set(walletSignatureHeader, "0x1234")
+			return req
+		}(),
+	}
+	for name, req := range cases {
+		if got := verifiedWalletFromRequest(req); got != "" {
+			t.Errorf("%s: verified as %q, want rejected", name, got)
+		}
+	}
+}
+
+func TestGalleryMutationsNeedSignature(t *testing.T) {
+	a := newTestApp(t, "")
+	a.galleryStore.Add(context.Background(), gallery.GalleryItem{JobID: "job-1", Prompt: "a cat", WalletAddress: testAddr("owner")})
+
+	for _, tc := range []struct {
+		method, path string
+	}{
+		{http.MethodPost, "/api/gallery/job-1/publish"},
+		{http.MethodDelete, "/api/gallery/job-1"},
+	} {
+		// Naming the owner isn't enough without their signature
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		req.Header.Set("X-Wallet-Address", testAddr("owner"))
+		if rec := serve(a, req); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s unsigned: status = %d, want 401", tc.method, tc.path, rec.Code)
+		}
+		if rec := serve(a, signAs(t, httptest.NewRequest(tc.method, tc.path, nil), "other")); rec.Code != http.StatusForbidden {
+			t.Errorf("%s %s by another wallet: status = %d, want 403", tc.method, tc.path, rec.Code)
+		}
+		if rec := serve(a, signAs(t, httptest.NewRequest(tc.method, tc.path, nil), "owner")); rec.Code != http.StatusOK {
+			t.Errorf("%s %s by the owner: status = %d, want 200 (%s)", tc.method, tc.path, rec.Code, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/config"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+)
+
+// Worker verdicts on a generation, and what the filter does about them
+const (
+	workerBlocked    = "blocked"
+	workerUntrusted  = "untrusted"
+	workerFilterFlag = "flag"
+	workerFilterHide = "hide"
+)
+
+// workerFilter judges generations by the worker that made them. Blocklisted
+// workers are always caught; with an allowlist, so is every worker not on it.
+// Entries match a worker's ID or name, ignoring case. The zero value passes
+// everything.
+type workerFilter struct {
+	allow map[string]bool
+	block map[string]bool
+	// Drop caught generations from the view instead of only flagging them
+	hide bool
+	// Resubmit a finished job whose every generation was hidden
+	retry bool
+}
+
+func newWorkerFilter(cfg config.Config) workerFilter {
+	return workerFilter{
+		allow: lowerSet(cfg.WorkerAllowlist),
+		block: lowerSet(cfg.WorkerBlocklist),
+		hide:  strings.EqualFold(cfg.WorkerFilterMode, workerFilterHide),
+		retry: cfg.WorkerFilterRetry,
+	}
+}
+
+func lowerSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[strings.ToLower(v)] = true
+	}
+	return set
+}
+
+// verdict is empty for a generation from an acceptable worker
+func (f workerFilter) verdict(gen GenerationView) string {
+	id, name := strings.ToLower(gen.WorkerID), strings.ToLower(gen.WorkerName)
+	switch {
+	case f.block[id] || f.block[name]:
+		return workerBlocked
+	case len(f.allow) > 0 && !f.allow[id] && !f.allow[name]:
+		return workerUntrusted
+	}
+	return ""
+}
+
+// apply flags each caught generation with its verdict, or in hide mode drops
+// it and counts it in HiddenGenerations
+func (f workerFilter) apply(view JobView) JobView {
+	if len(f.allow) == 0 && len(f.block) == 0 {
+		return view
+	}
+	kept := make([]GenerationView, 0, len(view.Generations))
+	for _, gen := range view.Generations {
+		gen.WorkerVerdict = f.verdict(gen)
+		if gen.WorkerVerdict != "" && f.hide {
+			view.HiddenGenerations++
+			continue
+		}
+		kept = append(kept, gen)
+	}
+	view.Generations = kept
+	return view
+}
+
+// Bounds on worker-filter retries: how many jobs' outcomes are remembered in
+// memory, and how many times a job and its replacements are resubmitted
+const (
+	filterRetryMemory   = 1000
+	filterRetryMaxDepth = 2
+)
+
+// filterRetries remembers which jobs were resubmitted for a hidden result, so
+// polling the original again doesn't submit it again. The store's retried_as
+// link covers jobs forgotten here or before a restart.
+type filterRetries struct {
+	mu    sync.Mutex
+	jobs  map[string]string
+	order []string
+}
+
+// claim returns true when the caller should resubmit jobID. Otherwise it
+// returns the job's replacement, "" while it's being submitted or if that failed.
+func (f *filterRetries) claim(jobID string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if newID, ok := f.jobs[jobID]; ok {
+		return newID, false
+	}
+	if f.jobs == nil {
+		f.jobs = make(map[string]string)
+	}
+	if len(f.order) >= filterRetryMemory {
+		delete(f.jobs, f.order[0])
+		f.order = f.order[1:]
+	}
+	f.jobs[jobID] = ""
+	f.order = append(f.order, jobID)
+	return "", true
+}
+
+// finish records the outcome of a claimed resubmission
+func (f *filterRetries) finish(jobID, newID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.jobs[jobID]; ok {
+		f.jobs[jobID] = newID
+	}
+}
+
+// presentJobView is the last step before a job view goes out: the worker
+// filter, then CDN rewriting. A completed job the filter emptied is reported
+// as completed_empty, and resubmitted when WORKER_FILTER_RETRY is on, naming
+// its replacement.
+func (a *App) presentJobView(ctx context.Context, view JobView) JobView {
+	view = a.workers.apply(view)
+	if view.Status == "completed" && view.HiddenGenerations > 0 && len(view.Generations) == 0 {
+		view.Status = jobCompletedEmpty
+		view.Message = filteredJobMessage
+		if a.workers.retry {
+			view.RetriedAs = a.retryFilteredJob(ctx, view.JobID)
+		}
+	}
+	return a.withMediaCDN(view)
+}
+
+// retryFilteredJob resubmits a job's recorded request and returns the new job
+// ID, or "" when it can't be retried. The Grid is called without holding the
+// filterRetries lock; concurrent polls see "" until the submission is done.
+func (a *App) retryFilteredJob(ctx context.Context, jobID string) string {
+	newID, claimed := a.filterRetries.claim(jobID)
+	if !claimed {
+		return newID
+	}
+	newID = a.resubmitFilteredJob(ctx, jobID)
+	a.filterRetries.finish(jobID, newID)
+	return newID
+}
+
+// resubmitFilteredJob sends a job's recorded request through the same checks
+// and submit queue as a new job. Only jobs that ran on the default key are
+// resubmitted, on that key: the server never holds a caller's own key, and
+// the anonymous key's rate limit needs the caller.
+func (a *App) resubmitFilteredJob(ctx context.Context, jobID string) string {
+	if a.jobRequests == nil || a.cfg.DefaultAPIKey == "" {
+		return ""
+	}
+	recorded, err := a.jobRequests.GetJobRequest(jobID)
+	if err != nil || recorded == nil {
+		return ""
+	}
+	if recorded.RetriedAs != "" {
+		return recorded.RetriedAs
+	}
+	if recorded.APIKeyHash != hashAPIKey(a.cfg.DefaultAPIKey) {
+		return ""
+	}
+	if depth := a.filterRetryDepth(recorded); depth >= filterRetryMaxDepth {
+		log.Printf("Warning: job %s is already retry %d of a filtered job, not resubmitting it again", jobID, depth)
+		return ""
+	}
+
+	var req CreateJobRequest
+	if err := json.Unmarshal(recorded.Request, &req); err != nil {
+		return ""
+	}
+	sub, err := a.prepareJob(ctx, req, a.cfg.DefaultAPIKey, false)
+	if err != nil {
+		log.Printf("Warning: not resubmitting filtered job %s: %v", jobID, err)
+		return ""
+	}
+	sub.retryOf = jobID
+	newID, queued, err := a.sendJob(ctx, sub)
+	if err != nil {
+		log.Printf("Warning: resubmitting filtered job %s failed: %v", jobID, err)
+		return ""
+	}
+	if queued != nil {
+		// The queue links the job to its replacement once it's submitted
+		log.Printf("🔁 Job %s only had results from filtered workers, resubmission queued as %s", jobID, queued.QueueID)
+		return ""
+	}
+	log.Printf("🔁 Job %s only had results from filtered workers, resubmitted as %s", jobID, newID)
+	return newID
+}
+
+// filterRetryDepth counts the worker-filter retries that led to a job
+func (a *App) filterRetryDepth(recorded *gallery.RecordedJobRequest) int {
+	depth := 0
+	for recorded != nil && recorded.RetryOf != "" && depth < filterRetryMaxDepth {
+		depth++
+		next, err := a.jobRequests.GetJobRequest(recorded.RetryOf)
+		if err != nil {
+			break
+		}
+		recorded = next
+	}
+	return depth
+}
@@ -0,0 +1,168 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/config"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/gallery"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/models"
+)
+
+func TestWorkerFilterVerdicts(t *testing.T) {
+	view := JobView{Generations: []GenerationView{
+		{ID: "g1", WorkerID: "w-good", WorkerName: "Good Worker"},
+		{ID: "g2", WorkerID: "w-bad", WorkerName: "Bad Worker"},
+		{ID: "g3", WorkerID: "w-other", WorkerName: "Someone"},
+	}}
+	verdicts := func(v JobView) map[string]string {
+		out := make(map[string]string)
+		for _, gen := range v.Generations {
+			out[gen.ID] = gen.WorkerVerdict
+		}
+		return out
+	}
+
+	tests := []struct {
+		name   string
+		cfg    config.Config
+		want   map[string]string
+		hidden int
+	}{
+		{"off", config.Config{}, map[string]string{"g1": "", "g2": "", "g3": ""}, 0},
+		{
+			"blocklist by name",
+			config.Config{WorkerBlocklist: []string{"bad worker"}},
+			map[string]string{"g1": "", "g2": workerBlocked, "g3": ""}, 0,
+		},
+		{
+			"allowlist by ID",
+			config.Config{WorkerAllowlist: []string{"W-GOOD"}},
+			map[string]string{"g1": "", "g2": workerUntrusted, "g3": workerUntrusted}, 0,
+		},
+		{
+			"blocklist beats allowlist",
+			config.Config{WorkerAllowlist: []string{"w-good", "w-bad"}, WorkerBlocklist: []string{"w-bad"}},
+			map[string]string{"g1": "", "g2": workerBlocked, "g3": workerUntrusted}, 0,
+		},
+		{
+			"hide",
+			config.Config{WorkerBlocklist: []string{"w-bad"}, WorkerFilterMode: "hide"},
+			map[string]string{"g1": "", "g3": ""}, 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newWorkerFilter(tt.cfg).apply(view)
+			if v := verdicts(got); len(v) != len(tt.want) {
+				t.Fatalf("generations = %v, want %v", v, tt.want)
+			} else {
+				for id, want := range tt.want {
+					if v[id] != want {
+						t.Errorf("%s verdict = %q, want %q", id, v[id], want)
+					}
+				}
+			}
+			if got.HiddenGenerations != tt.hidden {
+				t.Errorf("hidden = %d, want %d", got.HiddenGenerations, tt.hidden)
+			}
+		})
+	}
+	if len(view.Generations) != 3 || view.Generations[1].WorkerVerdict != "" {
+		t.Error("apply modified the original view")
+	}
+}
+
+func TestWorkerFilterRetry(t *testing.T) {
+	var mu sync.Mutex
+	submits := 0
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/generate/async":
+			mu.Lock()
+			submits++
+			id := fmt.Sprintf("job-%d", submits+1)
+			mu.Unlock()
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"id":"` + id + `"}`))
+		case strings.HasPrefix(r.URL.Path, "/generate/status/"):
+			id := strings.TrimPrefix(r.URL.Path, "/generate/status/")
+			w.Write([]byte(`{"id":"` + id + `","done":true,"generations":[{"id":"gen-1","worker_id":"w-bad","worker_name":"Bad"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer grid.Close()
+	a := newTestApp(t, grid.URL)
+	a.catalog = models.NewCatalog(testPresets)
+	store := newMemoryJobRequestStore()
+	a.jobRequests = store
+	request := []byte(`{"modelId":"FLUX.1-dev","prompt":"p"}`)
+	store.RecordJobRequest(gallery.RecordedJobRequest{JobID: "job-1", APIKeyHash: hashAPIKey("test-key"), Request: request})
+	store.RecordJobRequest(gallery.RecordedJobRequest{JobID: "job-user", APIKeyHash: hashAPIKey("user-key"), Request: request})
+	a.workers = newWorkerFilter(config.Config{WorkerBlocklist: []string{"w-bad"}, WorkerFilterMode: "hide", WorkerFilterRetry: true})
+
+	poll := func(jobID string) JobView {
+		t.Helper()
+		rec := serve(a, httptest.NewRequest(http.MethodGet, "/api/jobs/"+jobID, nil))
+		var view JobView
+		json.Unmarshal(rec.Body.Bytes(), &view)
+		if len(view.Generations) != 0 || view.HiddenGenerations != 1 || view.Status != jobCompletedEmpty {
+			t.Fatalf("poll %s: status = %d, view = %+v; want the generation hidden", jobID, rec.Code, view)
+		}
+		return view
+	}
+	submitted := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return submits
+	}
+
+	for i := 0; i < 2; i++ {
+		if view := poll("job-1"); view.RetriedAs != "job-2" {
+			t.Fatalf("poll %d: retriedAs = %q, want job-2", i, view.RetriedAs)
+		}
+	}
+	// A restart forgets the in-memory record; the stored link still holds
+	a.filterRetries = filterRetries{}
+	if view := poll("job-1"); view.RetriedAs != "job-2" || submitted() != 1 {
+		t.Errorf("after restart: retriedAs = %q after %d submissions, want job-2 submitted once", view.RetriedAs, submitted())
+	}
+
+	// Retries of retries stop at the depth limit
+	if view := poll("job-2"); view.RetriedAs != "job-3" {
+		t.Errorf("first retry: retriedAs = %q, want job-3", view.RetriedAs)
+	}
+	if view := poll("job-3"); view.RetriedAs != "" || submitted() != 2 {
+		t.Errorf("second retry: retriedAs = %q after %d submissions, want no third resubmission", view.RetriedAs, submitted())
+	}
+
+	// The server doesn't hold a caller's own key, so their jobs aren't resubmitted
+	if view := poll("job-user"); view.RetriedAs != "" || submitted() != 2 {
+		t.Errorf("job on the caller's key: retriedAs = %q after %d submissions, want none", view.RetriedAs, submitted())
+	}
+}
+
+func TestFilterRetriesBounded(t *testing.T) {
+	var f filterRetries
+	for i := 0; i < filterRetryMemory+10; i++ {
+		if _, claimed := f.claim(fmt.Sprintf("job-%d", i)); !claimed {
+			t.Fatalf("job-%d: not claimed on first sight", i)
+		}
+	}
+	if len(f.jobs) != filterRetryMemory {
+		t.Errorf("remembering %d jobs, want %d", len(f.jobs), filterRetryMemory)
+	}
+	if _, claimed := f.claim("job-0"); !claimed {
+		t.Error("oldest job still remembered")
+	}
+	f.finish("job-20", "job-new")
+	if newID, claimed := f.claim("job-20"); claimed || newID != "job-new" {
+		t.Errorf("claim after finish = %q, %t; want the replacement", newID, claimed)
+	}
+}
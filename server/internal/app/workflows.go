@@ -0,0 +1,124 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/models"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/recipevault"
+)
+
+// Availability of a model a workflow loads
+const (
+	// Matches a preset that has workers on the Grid
+	workflowModelAvailable = "available"
+	// Matches a preset, but no worker is serving it right now
+	workflowModelUnavailable = "unavailable"
+	// Not a catalog model; usually a VAE or text encoder bundled with a preset
+	workflowModelUnknown = "unknown"
+)
+
+// Precision and packaging suffixes model files carry but preset names don't
+var modelFileSuffixes = []string{"fp8scaled", "fp16scaled", "fp32scaled", "fp8", "fp16", "fp32", "scaled", "compact"}
+
+// WorkflowModelRef is one model file referenced by a workflow
+type WorkflowModelRef struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Preset the file resolved to; empty when unknown
+	PresetID string `json:"presetId,omitempty"`
+	Workers  int    `json:"workers"`
+}
+
+// resolveWorkflowModel finds the preset a workflow's model file refers to by
+// comparing it, without extension or precision suffix, to each preset's ID,
+// aliases and Grid name
+func resolveWorkflowModel(file string, presets []models.ModelPreset) (models.ModelPreset, bool) {
+	want := modelFileCore(file)
+	if want == "" {
+		return models.ModelPreset{}, false
+	}
+	aliases := modelAliases()
+	for _, preset := range presets {
+		names := append([]string{preset.ID, getGridModelName(preset.ID)}, aliases[preset.ID]...)
+		for _, name := range names {
+			if modelFileCore(name) == want {
+				return preset, true
+			}
+		}
+	}
+	return models.ModelPreset{}, false
+}
+
+// modelFileCore folds a model file or preset name down to what identifies the model,
+// so "flux1-dev-fp8.safetensors" and "FLUX.1-dev" compare equal
+func modelFileCore(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	switch strings.ToLower(path.Ext(name)) {
+	case ".safetensors", ".ckpt", ".pt", ".pth", ".gguf":
+		name = strings.TrimSuffix(name, path.Ext(name))
+	}
+	core := foldModelName(name)
+	for _, suffix := range modelFileSuffixes {
+		core = strings.TrimSuffix(core, suffix)
+	}
+	return core
+}
+
+// handleValidateWorkflow reports which models a ComfyUI workflow loads and
+// whether the Grid can run them, so recipe authors can check before registering
+func (a *App) handleValidateWorkflow(w http.ResponseWriter, r *http.Request) {
+	if !requireJSONBody(w, r) {
+		return
+	}
+
+	var workflow map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&workflow); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid workflow: %w", err))
+		return
+	}
+	if len(workflow) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("workflow is empty"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	stats, err := a.fetchModelStats(ctx)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	index := indexModelStats(stats)
+	presets := a.catalog.List()
+
+	refs := make([]WorkflowModelRef, 0)
+	matched, unavailable := 0, 0
+	for _, file := range recipevault.ExtractWorkflowModels(workflow) {
+		ref := WorkflowModelRef{Name: file, Status: workflowModelUnknown}
+		if preset, ok := resolveWorkflowModel(file, presets); ok {
+			matched++
+			ref.PresetID = preset.ID
+			ref.Workers = lookupModelStats(preset.ID, index).ParseCount()
+			ref.Status = workflowModelAvailable
+			if ref.Workers == 0 {
+				ref.Status = workflowModelUnavailable
+				unavailable++
+			}
+		}
+		refs = append(refs, ref)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"models": refs,
+		// Runnable when at least one catalog model is loaded and all of them are served
+		"runnable": matched > 0 && unavailable == 0,
+	})
+}
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/models"
+)
+
+func TestValidateWorkflow(t *testing.T) {
+	grid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"name":"FLUX.1-dev","count":2}]`))
+	}))
+	defer grid.Close()
+	a := newTestApp(t, grid.URL)
+	a.catalog = models.NewCatalog(append([]models.ModelPreset{{ID: "SDXL 1.0", Type: "image"}}, testPresets...))
+
+	workflow := `{
+		"1": {"class_type": "UNETLoader", "inputs": {"unet_name": "flux1-dev-fp8.safetensors"}},
+		"2": {"class_type": "VAELoader", "inputs": {"vae_name": "ae.safetensors"}},
+		"3": {"class_type": "CheckpointLoaderSimple", "inputs": {"ckpt_name": "sdxl_1.0.safetensors"}}
+	}`
+	rec := serve(a, httptest.NewRequest(http.MethodPost, "/api/workflows/validate", strings.NewReader(workflow)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (%s)", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Models   []WorkflowModelRef `json:"models"`
+		Runnable bool               `json:"runnable"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]WorkflowModelRef{
+		"flux1-dev-fp8.safetensors": {Status: workflowModelAvailable, PresetID: "FLUX.1-dev", Workers: 2},
+		"ae.safetensors":            {Status: workflowModelUnknown},
+		"sdxl_1.0.safetensors":      {Status: workflowModelUnavailable, PresetID: "SDXL 1.0"},
+	}
+	if len(body.Models) != len(want) {
+		t.Fatalf("models = %+v, want %d entries", body.Models, len(want))
+	}
+	for _, ref := range body.Models {
+		w, ok := want[ref.Name]
+		if !ok {
+			t.Errorf("unexpected model %q", ref.Name)
+			continue
+		}
+		if ref.Status != w.Status || ref.PresetID != w.PresetID || ref.Workers != w.Workers {
+			t.Errorf("%s = %+v, want %+v", ref.Name, ref, w)
+		}
+	}
+	if body.Runnable {
+		t.Error("runnable = true with an unserved preset")
+	}
+
+	if rec := serve(a, httptest.NewRequest(http.MethodPost, "/api/workflows/validate", strings.NewReader(`{}`))); rec.Code != http.StatusBadRequest {
+		t.Errorf("empty workflow status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,105 @@
+// Package cache provides the small in-process caches shared by the API clients.
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TTLCache holds a single value that is reloaded once it is older than the TTL.
+//
+// Concurrent Gets that find the value missing or expired share one load
+// instead of each hitting the backend. If a reload fails while an expired
+// value is still held, the stale value is returned instead of the error so a
+// flaky upstream doesn't take dependent endpoints down with it.
+type TTLCache[T any] struct {
+	ttl time.Duration
+
+	mu       sync.Mutex
+	value    T
+	loaded   bool
+	expires  time.Time
+	inflight *loadCall[T]
+}
+
+type loadCall[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// New returns an empty cache whose values stay fresh for ttl
+func New[T any](ttl time.Duration) *TTLCache[T] {
+	return &TTLCache[T]{ttl: ttl}
+}
+
+// Get returns the cached value while it is fresh, otherwise loads it.
+// The returned bool reports whether the value is stale (served after a failed reload).
+func (c *TTLCache[T]) Get(ctx context.Context, load func(context.Context) (T, error)) (T, bool, error) {
+	c.mu.Lock()
+	if c.loaded && time.Now().Before(c.expires) {
+		value := c.value
+		c.mu.Unlock()
+		return value, false, nil
+	}
+
+	call := c.inflight
+	if call == nil {
+		call = &loadCall[T]{done: make(chan struct{})}
+		c.inflight = call
+		go c.load(ctx, call, load)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		var zero T
+		return zero, false, ctx.Err()
+	}
+
+	if call.err == nil {
+		return call.value, false, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.loaded {
+		return c.value, true, nil
+	}
+	return call.value, false, call.err
+}
+
+func (c *TTLCache[T]) load(ctx context.Context, call *loadCall[T], load func(context.Context) (T, error)) {
+	// Don't let the first caller's cancellation fail everyone waiting on this load
+	call.value, call.err = load(context.WithoutCancel(ctx))
+
+	c.mu.Lock()
+	if call.err == nil {
+		c.value = call.value
+		c.loaded = true
+		c.expires = time.Now().Add(c.ttl)
+	}
+	c.inflight = nil
+	c.mu.Unlock()
+
+	close(call.done)
+}
+
+// Expires reports when the cached value goes stale; zero if nothing is cached
+func (c *TTLCache[T]) Expires() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.loaded {
+		return time.Time{}
+	}
+	return c.expires
+}
+
+// Invalidate forces the next Get to reload, keeping the old value as a stale fallback
+func (c *TTLCache[T]) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expires = time.Time{}
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTTLCacheExpiry(t *testing.T) {
+	c := New[int](20 * time.Millisecond)
+	var loads int32
+	load := func(context.Context) (int, error) {
+		return int(atomic.AddInt32(&loads, 1)), nil
+	}
+
+	for i := 0; i < 3; i++ {
+		if v, _, err := c.Get(context.Background(), load); err != nil || v != 1 {
+			t.Fatalf("Get = %d, %v; want cached 1", v, err)
+		}
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if v, _, _ := c.Get(context.Background(), load); v != 2 {
+		t.Errorf("Get after expiry = %d, want reloaded 2", v)
+	}
+}
+
+func TestTTLCacheCollapsesConcurrentLoads(t *testing.T) {
+	c := New[string](time.Minute)
+	var loads int32
+	release := make(chan struct{})
+	load := func(context.Context) (string, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return "value", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, _, err := c.Get(context.Background(), load); err != nil || v != "value" {
+				t.Errorf("Get = %q, %v", v, err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&loads); n != 1 {
+		t.Errorf("load called %d times, want 1", n)
+	}
+}
+
+func TestTTLCacheStaleOnError(t *testing.T) {
+	c := New[string](time.Minute)
+	ctx := context.Background()
+	boom := errors.New("rpc down")
+
+	// Nothing cached yet: the error is returned
+	if _, _, err := c.Get(ctx, func(context.Context) (string, error) { return "", boom }); !errors.Is(err, boom) {
+		t.Fatalf("Get on empty cache = %v, want load error", err)
+	}
+
+	c.Get(ctx, func(context.Context) (string, error) { return "good", nil })
+	c.Invalidate()
+
+	v, stale, err := c.Get(ctx, func(context.Context) (string, error) { return "", boom })
+	if err != nil || v != "good" || !stale {
+		t.Errorf("Get with failing reload = %q, stale=%v, %v; want stale \"good\"", v, stale, err)
+	}
+}
+
+func TestTTLCacheWaiterHonoursOwnContext(t *testing.T) {
+	c := New[int](time.Minute)
+	release := make(chan struct{})
+	defer close(release)
+	go c.Get(context.Background(), func(context.Context) (int, error) { <-release; return 1, nil })
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := c.Get(ctx, func(context.Context) (int, error) { return 2, nil }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get = %v, want deadline exceeded while load is still running", err)
+	}
+}
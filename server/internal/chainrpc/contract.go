@@ -0,0 +1,178 @@
+// Package chainrpc binds a contract to a list of RPC endpoints and fails over
+// between them when one is down or keeps rate limiting us.
+package chainrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// MaxRateLimited is how many consecutive 429s an endpoint may return before we move on
+const MaxRateLimited = 3
+
+// Contract is a drop-in replacement for bind.BoundContract's Call that spreads
+// calls over several endpoints. Failover goes round-robin to the next URL and
+// reconnects the ethclient.
+type Contract struct {
+	name    string
+	urls    []string
+	address common.Address
+	abi     abi.ABI
+
+	mu          sync.Mutex
+	current     int
+	client      *ethclient.Client
+	bound       *bind.BoundContract
+	rateLimited int
+}
+
+// Dial connects to the first reachable endpoint in urls; name is used in logs
+func Dial(name string, urls []string, address common.Address, parsed abi.ABI) (*Contract, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("no RPC endpoints configured")
+	}
+
+	c := &Contract{name: name, urls: urls, address: address, abi: parsed}
+	var lastErr error
+	for i := range urls {
+		if lastErr = c.connect(i); lastErr == nil {
+			return c, nil
+		}
+		log.Printf("Warning: %s RPC %s unavailable: %v", name, redactURL(urls[i]), lastErr)
+	}
+	return nil, fmt.Errorf("failed to connect to Ethereum RPC: %w", lastErr)
+}
+
+// connect dials urls[i] and makes it the active endpoint. Caller holds mu or owns c.
+func (c *Contract) connect(i int) error {
+	client, err := ethclient.Dial(c.urls[i])
+	if err != nil {
+		return err
+	}
+	if c.client != nil {
+		c.client.Close()
+	}
+	c.current = i
+	c.client = client
+	c.bound = bind.NewBoundContract(c.address, c.abi, client, client, client)
+	c.rateLimited = 0
+	return nil
+}
+
+// Endpoint returns the URL currently in use (credentials and path stripped)
+func (c *Contract) Endpoint() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return redactURL(c.urls[c.current])
+}
+
+// Call invokes a constant contract method, failing over to the next endpoint on
+// connection errors or after MaxRateLimited consecutive 429s. Each endpoint is
+// tried at most once per call.
+func (c *Contract) Call(opts *bind.CallOpts, results *[]interface{}, method string, params ...interface{}) error {
+	ctx := context.Background()
+	if opts != nil && opts.Context != nil {
+		ctx = opts.Context
+	}
+
+	var err error
+	for attempt := 0; attempt < len(c.urls); attempt++ {
+		c.mu.Lock()
+		bound, idx := c.bound, c.current
+		c.mu.Unlock()
+
+		err = bound.Call(opts, results, method, params...)
+		if err == nil {
+			c.mu.Lock()
+			if c.current == idx {
+				c.rateLimited = 0
+			}
+			c.mu.Unlock()
+			return nil
+		}
+		if ctx.Err() != nil || !c.shouldFailover(idx, err) {
+			return err
+		}
+	}
+	return err
+}
+
+// shouldFailover classifies err from endpoint idx and switches endpoints when
+// warranted. Returns true if the call should be retried on the new endpoint.
+func (c *Contract) shouldFailover(idx int, err error) bool {
+	if len(c.urls) < 2 {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Another caller already moved on; just retry on the new endpoint
+	if c.current != idx {
+		return true
+	}
+
+	switch {
+	case isRateLimited(err):
+		c.rateLimited++
+		if c.rateLimited < MaxRateLimited {
+			return false
+		}
+	case !isConnectionError(err):
+		return false
+	}
+
+	for step := 1; step < len(c.urls); step++ {
+		next := (idx + step) % len(c.urls)
+		if dialErr := c.connect(next); dialErr != nil {
+			log.Printf("Warning: %s RPC %s unavailable: %v", c.name, redactURL(c.urls[next]), dialErr)
+			continue
+		}
+		log.Printf("⚠️ %s RPC failover: %s -> %s (%v)", c.name, redactURL(c.urls[idx]), redactURL(c.urls[next]), err)
+		return true
+	}
+	return false
+}
+
+func isRateLimited(err error) bool {
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == 429
+	}
+	return strings.Contains(err.Error(), "429")
+}
+
+// isConnectionError reports whether the endpoint itself failed, as opposed to the contract call
+func isConnectionError(err error) bool {
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// redactURL keeps RPC API keys (often embedded in the path) out of logs
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "<invalid url>"
+	}
+	return u.Scheme + "://" + u.Host
+}
@@ -0,0 +1,130 @@
+package chainrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const countABI = `[{"inputs":[],"name":"getModelCount","outputs":[{"type":"uint256"}],"stateMutability":"view","type":"function"}]`
+
+// rpcServer answers every eth_call with count, or fails every request with status
+func rpcServer(t *testing.T, status int, count int64, hits *int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		if status != http.StatusOK {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		var req struct {
+			ID json.RawMessage `json:"id"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x%064x"}`, req.ID, count)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func dialTest(t *testing.T, urls ...string) *Contract {
+	t.Helper()
+	parsed, err := abi.JSON(strings.NewReader(countABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := Dial("Test", urls, common.HexToAddress("0x01"), parsed)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	return c
+}
+
+func callCount(c *Contract) (int64, error) {
+	var result []interface{}
+	err := c.Call(&bind.CallOpts{Context: context.Background()}, &result, "getModelCount")
+	if err != nil {
+		return 0, err
+	}
+	return result[0].(*big.Int).Int64(), nil
+}
+
+func TestCallFailsOverWhenPrimaryIsDown(t *testing.T) {
+	var primaryHits, secondaryHits int32
+	primary := rpcServer(t, http.StatusBadGateway, 0, &primaryHits)
+	secondary := rpcServer(t, http.StatusOK, 7, &secondaryHits)
+
+	c := dialTest(t, primary.URL, secondary.URL)
+	got, err := callCount(c)
+	if err != nil || got != 7 {
+		t.Fatalf("Call = %d, %v; want 7 from the secondary", got, err)
+	}
+	if c.Endpoint() != secondary.URL {
+		t.Errorf("Endpoint = %s, want %s", c.Endpoint(), secondary.URL)
+	}
+
+	// Later calls stay on the secondary
+	callCount(c)
+	if primaryHits != 1 || secondaryHits != 2 {
+		t.Errorf("hits primary=%d secondary=%d, want 1 and 2", primaryHits, secondaryHits)
+	}
+}
+
+func TestCallFailsOverAfterRepeatedRateLimits(t *testing.T) {
+	var primaryHits, secondaryHits int32
+	primary := rpcServer(t, http.StatusTooManyRequests, 0, &primaryHits)
+	secondary := rpcServer(t, http.StatusOK, 3, &secondaryHits)
+
+	c := dialTest(t, primary.URL, secondary.URL)
+	for i := 1; i < MaxRateLimited; i++ {
+		if _, err := callCount(c); err == nil {
+			t.Fatalf("call %d succeeded, want the 429 surfaced before failover", i)
+		}
+	}
+	if got, err := callCount(c); err != nil || got != 3 {
+		t.Fatalf("Call = %d, %v; want failover to the secondary", got, err)
+	}
+	if secondaryHits != 1 {
+		t.Errorf("secondary hits = %d, want 1", secondaryHits)
+	}
+}
+
+func TestCallWrapsAroundToPrimary(t *testing.T) {
+	var primaryHits, secondaryHits int32
+	primary := rpcServer(t, http.StatusOK, 1, &primaryHits)
+	secondary := rpcServer(t, http.StatusServiceUnavailable, 0, &secondaryHits)
+
+	c := dialTest(t, primary.URL, secondary.URL)
+	c.mu.Lock()
+	c.connect(1)
+	c.mu.Unlock()
+
+	if got, err := callCount(c); err != nil || got != 1 {
+		t.Fatalf("Call = %d, %v; want round-robin back to the primary", got, err)
+	}
+}
+
+func TestCallReturnsErrorWhenAllEndpointsFail(t *testing.T) {
+	var hits int32
+	a := rpcServer(t, http.StatusBadGateway, 0, &hits)
+	b := rpcServer(t, http.StatusBadGateway, 0, &hits)
+
+	c := dialTest(t, a.URL, b.URL)
+	if _, err := callCount(c); err == nil {
+		t.Fatal("Call succeeded with every endpoint down")
+	}
+	if hits != 2 {
+		t.Errorf("hits = %d, want each endpoint tried once", hits)
+	}
+}
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"log"
+	"net/netip"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type Config struct {
@@ -10,18 +14,43 @@ type Config struct {
 	APIBaseURL       string
 	ClientAgent      string
 	DefaultAPIKey    string
+	// Check AIPG_API_KEY against the Grid once at startup and report it in /health/ready
+	ValidateAPIKey   bool
+	// Optional Grid route overrides for self-hosted or differently versioned deployments
+	APIModelsPath    string
+	APIGeneratePath  string
+	APIStatusPath    string
+	APIFindUserPath  string
+	// Grid reads that fail with a 5xx or network error are retried with
+	// exponential backoff; job submission only when APIRetryCreateJob is set
+	APIRetries        int
+	APIRetryDelay     time.Duration
+	APIRetryCreateJob bool
 	ModelPresetPath  string
+	// MODEL_OVERRIDE_<ID>_<FIELD> variables by name, applied to preset
+	// defaults after the preset file loads
+	ModelOverrides   map[string]string
 	AllowedOrigins   []string
+	// How long browsers may cache a CORS preflight response
+	CORSMaxAge       time.Duration
+	// Reverse proxies whose X-Forwarded-For / X-Real-IP headers are believed
+	// when working out a client's IP; empty trusts none
+	TrustedProxies   []netip.Prefix
 	GalleryStorePath string
+	// Operator-confirmed model aliases, added through the admin API
+	ModelAliasesPath string
 
 	// ModelVault blockchain configuration
 	ModelVaultEnabled         bool
-	ModelVaultRPCURL          string
+	// Comma-separated in the env; the clients fail over between them in order
+	ModelVaultRPCURLs         []string
 	ModelVaultContractAddress string
+	// Multicall3 contract model loads are batched through; "off" reads one model per call
+	ModelVaultMulticall       string
 
 	// RecipeVault blockchain configuration
 	RecipeVaultEnabled         bool
-	RecipeVaultRPCURL          string
+	RecipeVaultRPCURLs         []string
 	RecipeVaultContractAddress string
 
 	// R2 storage configuration for direct media access
@@ -34,30 +63,123 @@ type Config struct {
 	R2AccessKeySecret    string
 	R2SharedAccessKeyID  string
 	R2SharedAccessKey    string
+	R2KeyPrefix          string
+	// CDN base public media URLs are rewritten to (e.g. https://cdn.example.com);
+	// presigned URLs are never rewritten. Empty keeps images.aipg.art.
+	MediaCDNBase         string
 
 	// PostgreSQL configuration
 	PostgresEnabled bool
 	PostgresConnStr string
+	// Per-query timeout, and the duration above which gallery queries are logged as slow
+	PostgresQueryTimeout time.Duration
+	PostgresSlowQuery    time.Duration
+
+	// Hard ceilings on job size regardless of preset limits: width*height for
+	// images, width*height*length for video (0 disables)
+	MaxImagePixels int
+	MaxVideoPixels int
+	// Most frames (params.length) one video job may ask for, whatever the
+	// preset allows; fps only sets playback speed, so it isn't counted (0 disables)
+	MaxVideoFrames int
+	// Most images one job may ask the Grid for (params.n); 0 disables the cap
+	MaxImagesPerJob int
+	// Larger img2img source images are downscaled and re-encoded before
+	// submission: longest side in pixels, and decoded size in bytes (0 disables)
+	SourceImageMaxDimension int
+	SourceImageMaxBytes     int
+
+	// Preset offered when a requested model has no workers and isn't active on
+	// chain: "append" adds it to the job's models, "reject" returns 409 naming it
+	FallbackModelID   string
+	FallbackModelMode string
+
+	// Requests per minute each client IP may make to the explore feed; 0 disables the limit
+	ExploreRateLimit int
+
+	// Grid shared anonymous key, used for jobs sent without a key when there's no
+	// DefaultAPIKey. Anonymous jobs are always shared, limited to AnonRateLimit
+	// per client IP per hour and to AnonMaxImagesPerJob images.
+	AnonAPIKey          string
+	AnonRateLimit       int
+	AnonMaxImagesPerJob int
+
+	// Job submissions per second sent to the Grid; bursts above it wait in a
+	// queue of at most SubmitQueueSize. A job still waiting after
+	// SubmitQueueWait is answered with its queue position. 0 disables the queue.
+	SubmitRate      int
+	SubmitQueueSize int
+	SubmitQueueWait time.Duration
+
+	// Bearer token for /api/admin endpoints; the admin API is off when empty
+	AdminToken string
+
+	// Shared settings for background pollers that call the Grid
+	PollInterval       time.Duration
+	PollMaxConcurrency int
+	PollJitter         time.Duration
+	PollTimeout        time.Duration
+	// How often the background watcher checks whether awaited models came online
+	ModelNotifyInterval time.Duration
+	// How long Grid model stats are reused across requests
+	ModelStatsCacheTTL time.Duration
+	// Model status thresholds: "busy" from this queue length, "degraded" with
+	// at most ModelDegradedWorkers workers and an ETA of ModelDegradedETA or more
+	ModelBusyQueue       int
+	ModelDegradedWorkers int
+	ModelDegradedETA     time.Duration
+	// Readiness warns when fewer than ModelSyncMinPercent of presets resolve to
+	// a Grid model with workers, checked every ModelSyncInterval (0 disables)
+	ModelSyncInterval   time.Duration
+	ModelSyncMinPercent int
+	// Private, unfavorited, uncollected items older than RetentionMaxAge are
+	// deleted every RetentionInterval; 0 keeps everything
+	RetentionMaxAge   time.Duration
+	RetentionInterval time.Duration
+
+	// Worker IDs or names whose generations are caught: every blocklisted
+	// worker, and with an allowlist every worker not on it. WorkerFilterMode
+	// "flag" marks caught generations, "hide" drops them; WorkerFilterRetry
+	// resubmits a finished job that had nothing left.
+	WorkerAllowlist   []string
+	WorkerBlocklist   []string
+	WorkerFilterMode  string
+	WorkerFilterRetry bool
 }
 
 func Load() Config {
+	pollInterval := getDuration("POLL_INTERVAL", time.Minute)
+
 	return Config{
 		Address:          getEnv("GALLERY_SERVER_ADDR", ":4000"),
 		APIBaseURL:       getEnv("AIPG_API_URL", "https://api.aipowergrid.io/api/v2"),
 		ClientAgent:      getEnv("AIPG_CLIENT_AGENT", "AIPG-Art-Gallery:v2"),
 		DefaultAPIKey:    os.Getenv("AIPG_API_KEY"),
+		ValidateAPIKey:   getEnv("AIPG_VALIDATE_API_KEY", "true") == "true",
+		APIModelsPath:    os.Getenv("AIPG_API_MODELS_PATH"),
+		APIGeneratePath:  os.Getenv("AIPG_API_GENERATE_PATH"),
+		APIStatusPath:    os.Getenv("AIPG_API_STATUS_PATH"),
+		APIFindUserPath:  os.Getenv("AIPG_API_FIND_USER_PATH"),
+		APIRetries:        getInt("AIPG_API_RETRIES", 3),
+		APIRetryDelay:     getDuration("AIPG_API_RETRY_DELAY", 500*time.Millisecond),
+		APIRetryCreateJob: getEnv("AIPG_API_RETRY_CREATE_JOB", "false") == "true",
 		ModelPresetPath:  getEnv("MODEL_PRESETS_PATH", "./server/config/model_presets.json"),
+		ModelOverrides:   getPrefixed("MODEL_OVERRIDE_"),
 		AllowedOrigins:   splitAndClean(os.Getenv("GALLERY_ALLOWED_ORIGINS")),
+		CORSMaxAge:       getDuration("CORS_MAX_AGE", 10*time.Minute),
+		TrustedProxies:   getPrefixes("TRUSTED_PROXIES"),
 		GalleryStorePath: getEnv("GALLERY_STORE_PATH", "./data/gallery.json"),
+		ModelAliasesPath: getEnv("MODEL_ALIASES_PATH", "./data/model_aliases.json"),
 
 		// ModelVault blockchain configuration (enabled by default)
 		ModelVaultEnabled:         getEnv("MODELVAULT_ENABLED", "true") == "true",
-		ModelVaultRPCURL:          getEnv("MODELVAULT_RPC_URL", "https://mainnet.base.org"),
+		ModelVaultRPCURLs:         splitAndClean(getEnv("MODELVAULT_RPC_URL", "https://mainnet.base.org")),
 		ModelVaultContractAddress: getEnv("MODELVAULT_CONTRACT", "0x79F39f2a0eA476f53994812e6a8f3C8CFe08c609"),
+		ModelVaultMulticall:       getEnv("MODELVAULT_MULTICALL", "0xcA11bde05977b3631167028862bE2a173976CA11"),
 
 		// RecipeVault blockchain configuration (enabled by default, uses same contract as ModelVault - diamond proxy)
 		RecipeVaultEnabled:         getEnv("RECIPESVAULT_ENABLED", "true") == "true",
-		RecipeVaultRPCURL:          getEnv("RECIPESVAULT_RPC_URL", getEnv("MODELVAULT_RPC_URL", "https://mainnet.base.org")),
+		RecipeVaultRPCURLs:         splitAndClean(getEnv("RECIPESVAULT_RPC_URL", getEnv("MODELVAULT_RPC_URL", "https://mainnet.base.org"))),
 		RecipeVaultContractAddress: getEnv("RECIPESVAULT_CONTRACT", getEnv("MODELVAULT_CONTRACT", "0x79F39f2a0eA476f53994812e6a8f3C8CFe08c609")),
 
 		// R2 storage configuration (uses same env vars as system-core)
@@ -69,10 +191,60 @@ func Load() Config {
 		R2AccessKeySecret:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
 		R2SharedAccessKeyID:  os.Getenv("SHARED_AWS_ACCESS_ID"),
 		R2SharedAccessKey:    os.Getenv("SHARED_AWS_ACCESS_KEY"),
+		R2KeyPrefix:          os.Getenv("R2_KEY_PREFIX"),
+		MediaCDNBase:         os.Getenv("MEDIA_CDN_BASE"),
 
 		// PostgreSQL configuration
 		PostgresEnabled: getEnv("POSTGRES_ENABLED", "true") == "true",
 		PostgresConnStr: getEnv("POSTGRES_CONN_STR", "host=localhost port=5432 user=aipg_user password=aipg_gallery_2024 dbname=aipg_gallery sslmode=disable"),
+		PostgresQueryTimeout: getDuration("POSTGRES_QUERY_TIMEOUT", 10*time.Second),
+		PostgresSlowQuery:    getDuration("POSTGRES_SLOW_QUERY", 500*time.Millisecond),
+
+		MaxImagePixels:  getInt("MAX_IMAGE_PIXELS", 2048*2048),
+		MaxVideoPixels:  getInt("MAX_VIDEO_PIXELS", 1920*1080*144),
+		MaxVideoFrames:  getInt("MAX_VIDEO_FRAMES", 0),
+		MaxImagesPerJob: getInt("MAX_IMAGES_PER_JOB", 4),
+
+		SourceImageMaxDimension: getInt("SOURCE_IMAGE_MAX_DIMENSION", 2048),
+		SourceImageMaxBytes:     getInt("SOURCE_IMAGE_MAX_BYTES", 4<<20),
+
+		FallbackModelID:   os.Getenv("FALLBACK_MODEL_ID"),
+		FallbackModelMode: getEnv("FALLBACK_MODEL_MODE", "append"),
+
+		ExploreRateLimit: getInt("EXPLORE_RATE_LIMIT", 60),
+
+		AnonAPIKey:          os.Getenv("ANON_API_KEY"),
+		AnonRateLimit:       getInt("ANON_RATE_LIMIT", 10),
+		AnonMaxImagesPerJob: getInt("ANON_MAX_IMAGES_PER_JOB", 1),
+
+		SubmitRate:      getInt("SUBMIT_RATE", 10),
+		SubmitQueueSize: getInt("SUBMIT_QUEUE_SIZE", 200),
+		SubmitQueueWait: getDuration("SUBMIT_QUEUE_WAIT", 5*time.Second),
+
+		AdminToken: os.Getenv("GALLERY_ADMIN_TOKEN"),
+
+		PollInterval:       pollInterval,
+		PollMaxConcurrency: getInt("POLL_MAX_CONCURRENCY", 2),
+		PollJitter:         getDuration("POLL_JITTER", 5*time.Second),
+		PollTimeout:        getDuration("POLL_TIMEOUT", 30*time.Second),
+
+		ModelNotifyInterval: getDuration("MODEL_NOTIFY_INTERVAL", pollInterval),
+		ModelStatsCacheTTL:  getDuration("MODEL_STATS_CACHE_TTL", 10*time.Second),
+
+		ModelBusyQueue:       getInt("MODEL_STATUS_BUSY_QUEUE", 20),
+		ModelDegradedWorkers: getInt("MODEL_STATUS_DEGRADED_WORKERS", 1),
+		ModelDegradedETA:     getDuration("MODEL_STATUS_DEGRADED_ETA", 5*time.Minute),
+
+		ModelSyncInterval:   getDuration("MODEL_SYNC_INTERVAL", pollInterval),
+		ModelSyncMinPercent: getInt("MODEL_SYNC_MIN_PERCENT", 50),
+
+		RetentionMaxAge:   getDuration("RETENTION_MAX_AGE", 0),
+		RetentionInterval: getDuration("RETENTION_INTERVAL", time.Hour),
+
+		WorkerAllowlist:   splitAndClean(os.Getenv("WORKER_ALLOWLIST")),
+		WorkerBlocklist:   splitAndClean(os.Getenv("WORKER_BLOCKLIST")),
+		WorkerFilterMode:  getEnv("WORKER_FILTER_MODE", "flag"),
+		WorkerFilterRetry: getEnv("WORKER_FILTER_RETRY", "false") == "true",
 	}
 }
 
@@ -83,6 +255,64 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+// getDuration parses a Go duration string (e.g. "30s"), falling back on empty or invalid input
+func getDuration(key string, fallback time.Duration) time.Duration {
+	raw := getEnv(key, "")
+	if raw == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using %v", key, raw, fallback)
+		return fallback
+	}
+	return d
+}
+
+// getInt parses an integer, falling back on empty or invalid input
+func getInt(key string, fallback int) int {
+	raw := getEnv(key, "")
+	if raw == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using %d", key, raw, fallback)
+		return fallback
+	}
+	return n
+}
+
+// getPrefixes parses a comma-separated list of CIDRs or bare IPs, skipping invalid entries
+func getPrefixes(key string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, raw := range splitAndClean(os.Getenv(key)) {
+		if addr, err := netip.ParseAddr(raw); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(raw)
+		if err != nil {
+			log.Printf("Warning: ignoring invalid %s entry %q", key, raw)
+			continue
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes
+}
+
+// getPrefixed collects every non-empty variable whose name starts with prefix, keyed by full name
+func getPrefixed(prefix string) map[string]string {
+	vars := make(map[string]string)
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if value = strings.TrimSpace(value); strings.HasPrefix(name, prefix) && value != "" {
+			vars[name] = value
+		}
+	}
+	return vars
+}
+
 func splitAndClean(raw string) []string {
 	if raw == "" {
 		return nil
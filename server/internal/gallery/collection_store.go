@@ -0,0 +1,244 @@
+package gallery
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrCollectionNotFound is returned when a collection doesn't exist
+	ErrCollectionNotFound = errors.New("collection not found")
+	// ErrCollectionExists is returned when a wallet already has a collection with that name
+	ErrCollectionExists = errors.New("a collection with that name already exists")
+	// ErrSlugTaken is returned when publishing with a slug another collection already uses
+	ErrSlugTaken = errors.New("collection slug already taken")
+)
+
+// Collection is a named, wallet-owned group of gallery items
+type Collection struct {
+	ID            int64     `json:"id"`
+	WalletAddress string    `json:"walletAddress"`
+	Name          string    `json:"name"`
+	IsPublic      bool      `json:"isPublic"`
+	Slug          string    `json:"slug,omitempty"` // shareable URL key, assigned on first publish and kept afterwards
+	ItemCount     int       `json:"itemCount"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// CollectionStore persists collections and their membership.
+// An item can belong to any number of collections; within a collection
+// items keep the order they were added in.
+type CollectionStore interface {
+	Create(ctx context.Context, wallet, name string) (*Collection, error)
+	Get(ctx context.Context, id int64) (*Collection, error)
+	ListByWallet(ctx context.Context, wallet string) ([]Collection, error)
+	AddItem(ctx context.Context, collectionID int64, jobID string) error
+	RemoveItem(ctx context.Context, collectionID int64, jobID string) error
+	ItemJobIDs(ctx context.Context, collectionID int64) ([]string, error)
+	GetBySlug(ctx context.Context, slug string) (*Collection, error)
+	Publish(ctx context.Context, id int64, slug string) error
+	Unpublish(ctx context.Context, id int64) error
+}
+
+// collectionColumns are read by scanCollection, in order
+const collectionColumns = `c.id, c.wallet_address, c.name, c.is_public, COALESCE(c.slug, ''), c.created_at,
+			   (SELECT COUNT(*) FROM collection_items ci WHERE ci.collection_id = c.id)`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanCollection(row rowScanner) (*Collection, error) {
+	var c Collection
+	err := row.Scan(&c.ID, &c.WalletAddress, &c.Name, &c.IsPublic, &c.Slug, &c.CreatedAt, &c.ItemCount)
+	if err == sql.ErrNoRows {
+		return nil, ErrCollectionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// PostgresCollectionStore implements CollectionStore using PostgreSQL,
+// sharing the gallery store's connection and query timeout
+type PostgresCollectionStore struct {
+	pg *PostgresStore
+}
+
+func NewPostgresCollectionStore(pg *PostgresStore) *PostgresCollectionStore {
+	return &PostgresCollectionStore{pg: pg}
+}
+
+// Create adds an empty collection for a wallet
+func (s *PostgresCollectionStore) Create(ctx context.Context, wallet, name string) (*Collection, error) {
+	ctx, cancel := s.pg.queryContext(ctx)
+	defer cancel()
+	c := &Collection{WalletAddress: strings.ToLower(wallet), Name: name}
+	err := s.pg.db.QueryRowContext(ctx, `
+		INSERT INTO collections (wallet_address, name)
+		VALUES ($1, $2)
+		RETURNING id, created_at
+	`, c.WalletAddress, name).Scan(&c.ID, &c.CreatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" { // unique_violation
+		return nil, ErrCollectionExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Get returns a collection with its item count
+func (s *PostgresCollectionStore) Get(ctx context.Context, id int64) (*Collection, error) {
+	ctx, cancel := s.pg.queryContext(ctx)
+	defer cancel()
+	return scanCollection(s.pg.db.QueryRowContext(ctx, `
+		SELECT `+collectionColumns+`
+		FROM collections c
+		WHERE c.id = $1
+	`, id))
+}
+
+// GetBySlug returns the collection published under a slug
+func (s *PostgresCollectionStore) GetBySlug(ctx context.Context, slug string) (*Collection, error) {
+	ctx, cancel := s.pg.queryContext(ctx)
+	defer cancel()
+	return scanCollection(s.pg.db.QueryRowContext(ctx, `
+		SELECT `+collectionColumns+`
+		FROM collections c
+		WHERE c.slug = $1
+	`, slug))
+}
+
+// ListByWallet returns a wallet's collections, newest first
+func (s *PostgresCollectionStore) ListByWallet(ctx context.Context, wallet string) ([]Collection, error) {
+	ctx, cancel := s.pg.queryContext(ctx)
+	defer cancel()
+	rows, err := s.pg.db.QueryContext(ctx, `
+		SELECT `+collectionColumns+`
+		FROM collections c
+		WHERE c.wallet_address = LOWER($1)
+		ORDER BY c.created_at DESC, c.id DESC
+	`, wallet)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := make([]Collection, 0)
+	for rows.Next() {
+		c, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, *c)
+	}
+	return collections, rows.Err()
+}
+
+// AddItem appends a gallery item to the end of a collection; adding it again is a no-op.
+// The collection's row is locked while the next position is worked out, so
+// concurrent adds can't both take the same one.
+func (s *PostgresCollectionStore) AddItem(ctx context.Context, collectionID int64, jobID string) error {
+	ctx, cancel := s.pg.queryContext(ctx)
+	defer cancel()
+
+	tx, err := s.pg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, `SELECT id FROM collections WHERE id = $1 FOR UPDATE`, collectionID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return ErrCollectionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO collection_items (collection_id, job_id, position)
+		SELECT $1, $2, COALESCE(MAX(position), 0) + 1
+		FROM collection_items
+		WHERE collection_id = $1
+		ON CONFLICT (collection_id, job_id) DO NOTHING
+	`, collectionID, jobID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveItem takes a gallery item out of a collection
+func (s *PostgresCollectionStore) RemoveItem(ctx context.Context, collectionID int64, jobID string) error {
+	ctx, cancel := s.pg.queryContext(ctx)
+	defer cancel()
+	_, err := s.pg.db.ExecContext(ctx, `DELETE FROM collection_items WHERE collection_id = $1 AND job_id = $2`, collectionID, jobID)
+	return err
+}
+
+// ItemJobIDs returns a collection's job IDs in the order they were added.
+// AddItem keeps positions unique; the tie-breakers only make the order total
+// for rows written before it locked the collection.
+func (s *PostgresCollectionStore) ItemJobIDs(ctx context.Context, collectionID int64) ([]string, error) {
+	ctx, cancel := s.pg.queryContext(ctx)
+	defer cancel()
+	rows, err := s.pg.db.QueryContext(ctx, `
+		SELECT job_id FROM collection_items
+		WHERE collection_id = $1
+		ORDER BY position ASC, added_at ASC, job_id ASC
+	`, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobIDs := make([]string, 0)
+	for rows.Next() {
+		var jobID string
+		if err := rows.Scan(&jobID); err != nil {
+			return nil, err
+		}
+		jobIDs = append(jobIDs, jobID)
+	}
+	return jobIDs, rows.Err()
+}
+
+// Publish makes a collection public under the given slug
+func (s *PostgresCollectionStore) Publish(ctx context.Context, id int64, slug string) error {
+	ctx, cancel := s.pg.queryContext(ctx)
+	defer cancel()
+	result, err := s.pg.db.ExecContext(ctx, `UPDATE collections SET is_public = true, slug = $2 WHERE id = $1`, id, slug)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" { // unique_violation
+		return ErrSlugTaken
+	}
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrCollectionNotFound
+	}
+	return nil
+}
+
+// Unpublish hides a collection again; its slug is kept so re-publishing restores the same link
+func (s *PostgresCollectionStore) Unpublish(ctx context.Context, id int64) error {
+	ctx, cancel := s.pg.queryContext(ctx)
+	defer cancel()
+	result, err := s.pg.db.ExecContext(ctx, `UPDATE collections SET is_public = false WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrCollectionNotFound
+	}
+	return nil
+}
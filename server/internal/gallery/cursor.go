@@ -0,0 +1,58 @@
+package gallery
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor can't be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// WalletCursor marks a position in a wallet's newest-first listing. Paging by
+// (created_at, job_id) instead of an offset stays stable while new items are
+// being added to the front of the list.
+type WalletCursor struct {
+	CreatedAt time.Time
+	JobID     string
+}
+
+// String encodes the cursor as an opaque URL-safe token
+func (c WalletCursor) String() string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixMicro(), 10) + "|" + c.JobID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseWalletCursor decodes a token produced by WalletCursor.String
+func ParseWalletCursor(token string) (*WalletCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	micros, jobID, ok := strings.Cut(string(raw), "|")
+	if !ok || jobID == "" {
+		return nil, ErrInvalidCursor
+	}
+	us, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &WalletCursor{CreatedAt: time.UnixMicro(us), JobID: jobID}, nil
+}
+
+// after reports whether an item at (createdAt, jobID) sorts after the cursor
+// in newest-first order, i.e. belongs on a later page
+func (c WalletCursor) after(createdAt time.Time, jobID string) bool {
+	if !createdAt.Equal(c.CreatedAt) {
+		return createdAt.Before(c.CreatedAt)
+	}
+	return jobID < c.JobID
+}
+
+// WalletPage is one page of a wallet's items, newest first
+type WalletPage struct {
+	Items      []GalleryItem `json:"items"`
+	NextCursor string        `json:"nextCursor,omitempty"`
+}
@@ -74,7 +74,7 @@ func (s *FavoritesStore) GetFavoritedItems(wallet string, limit int) []GalleryIt
 		SELECT g.job_id, g.model, g.prompt, g.negative_prompt,
 			   g.media_url, g.is_public, g.wallet_address,
 			   g.width, g.height, g.steps, g.cfg_scale, g.sampler, g.scheduler, g.seed,
-			   g.created_at
+			   g.created_at, g.params_json, g.type, g.is_nsfw, g.model_id
 		FROM gallery_items g
 		INNER JOIN favorites f ON g.job_id = f.job_id
 		WHERE LOWER(f.wallet_address) = LOWER($1)
@@ -93,11 +93,13 @@ func (s *FavoritesStore) GetFavoritedItems(wallet string, limit int) []GalleryIt
 	for rows.Next() {
 		var item GalleryItem
 		var mediaURL string
-		var walletAddr, model, prompt, negPrompt sql.NullString
+		var walletAddr, model, modelID, prompt, negPrompt sql.NullString
 		var width, height, steps sql.NullInt64
 		var cfgScale sql.NullFloat64
 		var sampler, scheduler, seed sql.NullString
 		var createdAt time.Time
+		var paramsJSON []byte
+		var itemType string
 
 		err := rows.Scan(
 			&item.JobID,
@@ -108,16 +110,14 @@ func (s *FavoritesStore) GetFavoritedItems(wallet string, limit int) []GalleryIt
 			&item.IsPublic,
 			&walletAddr,
 			&width, &height, &steps, &cfgScale, &sampler, &scheduler, &seed,
-			&createdAt,
+			&createdAt, &paramsJSON, &itemType, &item.IsNSFW, &modelID,
 		)
 
 		if err != nil {
 			continue
 		}
 
-		if model.Valid {
-			item.ModelName = model.String
-		}
+		item.ModelName, item.ModelID = modelNames(model, modelID)
 		if prompt.Valid {
 			item.Prompt = prompt.String
 		}
@@ -163,9 +163,10 @@ func (s *FavoritesStore) GetFavoritedItems(wallet string, limit int) []GalleryIt
 			sd := seed.String
 			item.Params.Seed = &sd
 		}
+		item.GridParams = paramsJSON
 
 		item.CreatedAt = createdAt.UnixMilli()
-		item.Type = "image"
+		item.Type = itemType
 
 		items = append(items, item)
 	}
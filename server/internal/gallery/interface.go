@@ -1,14 +1,39 @@
 package gallery
 
-// GalleryStore defines the interface for gallery storage operations
+import (
+	"context"
+	"time"
+)
+
+// GalleryStore defines the interface for gallery storage operations.
+// ctx is the caller's context, usually the request's; the Postgres store
+// stops a query when it's done, or at its own query timeout if that's sooner.
 type GalleryStore interface {
-	Add(item GalleryItem) error
-	Get(jobID string) *GalleryItem
-	List(typeFilter string, limit, offset int, searchQuery string) ListResult
-	ListByWallet(wallet string, limit int) []GalleryItem
-	Delete(jobID string) error
-	SetPublic(jobID string, isPublic bool) error
-	Count() int
+	Add(ctx context.Context, item GalleryItem) error
+	Get(ctx context.Context, jobID string) *GalleryItem
+	List(ctx context.Context, opts ListOptions) ListResult
+	ListByWallet(ctx context.Context, wallet string, limit int) []GalleryItem
+	ListByWalletPage(ctx context.Context, wallet string, limit int, before *WalletCursor) (WalletPage, error)
+	Delete(ctx context.Context, jobID string) error
+	SetPublic(ctx context.Context, jobID string, isPublic bool) error
+	SetFeatured(ctx context.Context, jobID string, featured bool) error
+	Update(ctx context.Context, jobID string, update ItemUpdate) error
+	Count(ctx context.Context) int
+	ModelCounts(ctx context.Context, includeNSFW bool) ([]ModelCount, error)
+}
+
+// ListStreamer is implemented by stores that can hand out List results one
+// item at a time, so large pages don't have to be built in memory.
+// fn gets the full match count with every item; an error from fn stops the scan.
+type ListStreamer interface {
+	StreamList(ctx context.Context, opts ListOptions, fn func(total int, item GalleryItem) error) (total int, err error)
+}
+
+// RetentionStore is implemented by stores the retention reaper can prune.
+// PrunableItems returns up to limit private items created before cutoff,
+// oldest first, that nobody has favorited or added to a collection.
+type RetentionStore interface {
+	PrunableItems(ctx context.Context, cutoff time.Time, limit int) ([]GalleryItem, error)
 }
 
 // FileStoreAdapter wraps the file-based Store to implement GalleryStore interface
@@ -16,32 +41,62 @@ type FileStoreAdapter struct {
 	Store *Store
 }
 
-func (a *FileStoreAdapter) Add(item GalleryItem) error {
-	a.Store.Add(item)
-	return nil
+func (a *FileStoreAdapter) Add(ctx context.Context, item GalleryItem) error {
+	return a.Store.Add(item)
 }
 
-func (a *FileStoreAdapter) Get(jobID string) *GalleryItem {
+func (a *FileStoreAdapter) Get(ctx context.Context, jobID string) *GalleryItem {
 	return a.Store.Get(jobID)
 }
 
-func (a *FileStoreAdapter) List(typeFilter string, limit, offset int, searchQuery string) ListResult {
-	return a.Store.List(typeFilter, limit, offset, searchQuery)
+func (a *FileStoreAdapter) List(ctx context.Context, opts ListOptions) ListResult {
+	return a.Store.List(opts)
+}
+
+func (a *FileStoreAdapter) StreamList(ctx context.Context, opts ListOptions, fn func(total int, item GalleryItem) error) (int, error) {
+	// The file store already holds everything in memory
+	result := a.Store.List(opts)
+	for _, item := range result.Items {
+		if err := fn(result.Total, item); err != nil {
+			return result.Total, err
+		}
+	}
+	return result.Total, nil
+}
+
+func (a *FileStoreAdapter) PrunableItems(ctx context.Context, cutoff time.Time, limit int) ([]GalleryItem, error) {
+	return a.Store.PrunableItems(cutoff, limit), nil
 }
 
-func (a *FileStoreAdapter) ListByWallet(wallet string, limit int) []GalleryItem {
+func (a *FileStoreAdapter) ListByWallet(ctx context.Context, wallet string, limit int) []GalleryItem {
 	return a.Store.ListByWallet(wallet, limit)
 }
 
-func (a *FileStoreAdapter) Delete(jobID string) error {
+func (a *FileStoreAdapter) ListByWalletPage(ctx context.Context, wallet string, limit int, before *WalletCursor) (WalletPage, error) {
+	return a.Store.ListByWalletPage(wallet, limit, before)
+}
+
+func (a *FileStoreAdapter) Delete(ctx context.Context, jobID string) error {
 	return a.Store.Delete(jobID)
 }
 
-func (a *FileStoreAdapter) SetPublic(jobID string, isPublic bool) error {
+func (a *FileStoreAdapter) SetPublic(ctx context.Context, jobID string, isPublic bool) error {
 	// File store doesn't support this operation
 	return nil
 }
 
-func (a *FileStoreAdapter) Count() int {
-	return a.Store.List("", 1, 0, "").Total
+func (a *FileStoreAdapter) SetFeatured(ctx context.Context, jobID string, featured bool) error {
+	return a.Store.SetFeatured(jobID, featured)
+}
+
+func (a *FileStoreAdapter) Update(ctx context.Context, jobID string, update ItemUpdate) error {
+	return a.Store.Update(jobID, update)
+}
+
+func (a *FileStoreAdapter) ModelCounts(ctx context.Context, includeNSFW bool) ([]ModelCount, error) {
+	return a.Store.ModelCounts(includeNSFW), nil
+}
+
+func (a *FileStoreAdapter) Count(ctx context.Context) int {
+	return a.Store.Count()
 }
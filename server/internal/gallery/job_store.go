@@ -6,6 +6,38 @@ import (
 	"time"
 )
 
+// JobRequestStore remembers the request behind each submitted job so it can
+// be resubmitted later. API keys are never stored, only a hash to check that
+// a retry comes from the same key.
+type JobRequestStore interface {
+	// RecordJobRequest stores rec; RetriedAs is ignored
+	RecordJobRequest(rec RecordedJobRequest) error
+	GetJobRequest(jobID string) (*RecordedJobRequest, error)
+	// SetRetriedAs links a job to the job it was resubmitted as
+	SetRetriedAs(jobID, newJobID string) error
+}
+
+// RecordedJobRequest is the stored request for a job
+type RecordedJobRequest struct {
+	JobID         string
+	WalletAddress string
+	APIKeyHash    string
+	RequestID     string // X-Request-ID the job was submitted under, sent on to the Grid
+	Request       []byte // JSON-encoded job request, without the API key
+	GridParams    []byte // JSON-encoded params map as sent to the Grid
+	RetryOf       string // Job this one resubmitted, if any
+	RetriedAs     string // Job this one was resubmitted as, if any
+}
+
+// JobResultStore keeps the outcome of completed jobs after the Grid has
+// expired them, so a job's permalink keeps working
+type JobResultStore interface {
+	// SaveJobResult stores result unless the job already has one
+	SaveJobResult(jobID string, result []byte) error
+	// GetJobResult returns the stored result, or nil if there is none
+	GetJobResult(jobID string) ([]byte, error)
+}
+
 // GenerationJob represents a generation job in the database
 type GenerationJob struct {
 	ID            int64     `json:"id"`
@@ -167,3 +199,94 @@ func (s *JobStore) GetJob(jobID string) (*GenerationJob, error) {
 
 	return &job, nil
 }
+
+// RecordJobRequest stores the request behind a job, creating the job record if needed
+func (s *JobStore) RecordJobRequest(rec RecordedJobRequest) error {
+	retryOf := sql.NullString{String: rec.RetryOf, Valid: rec.RetryOf != ""}
+	result, err := s.db.Exec(`
+		UPDATE generation_jobs
+		SET request = $2, api_key_hash = $3, request_id = $4, grid_params = $5, retry_of = $6, updated_at = NOW()
+		WHERE job_id = $1
+	`, rec.JobID, rec.Request, rec.APIKeyHash, rec.RequestID, rec.GridParams, retryOf)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		return nil
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO generation_jobs (job_id, wallet_address, status, created_at, updated_at, request, api_key_hash, request_id, grid_params, retry_of)
+		VALUES ($1, $2, 'queued', NOW(), NOW(), $3, $4, $5, $6, $7)
+	`, rec.JobID, strings.ToLower(rec.WalletAddress), rec.Request, rec.APIKeyHash, rec.RequestID, rec.GridParams, retryOf)
+	return err
+}
+
+// SetRetriedAs links a job to the job it was resubmitted as
+func (s *JobStore) SetRetriedAs(jobID, newJobID string) error {
+	_, err := s.db.Exec(`
+		UPDATE generation_jobs
+		SET retried_as = $2, updated_at = NOW()
+		WHERE job_id = $1
+	`, jobID, newJobID)
+	return err
+}
+
+// GetJobRequest returns the stored request for a job, or nil if none was recorded
+func (s *JobStore) GetJobRequest(jobID string) (*RecordedJobRequest, error) {
+	query := `
+		SELECT job_id, wallet_address, COALESCE(api_key_hash, ''), COALESCE(request_id, ''), request, grid_params,
+			   COALESCE(retry_of, ''), COALESCE(retried_as, '')
+		FROM generation_jobs
+		WHERE job_id = $1 AND request IS NOT NULL
+	`
+
+	var rec RecordedJobRequest
+	err := s.db.QueryRow(query, jobID).Scan(&rec.JobID, &rec.WalletAddress, &rec.APIKeyHash, &rec.RequestID, &rec.Request, &rec.GridParams,
+		&rec.RetryOf, &rec.RetriedAs)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// SaveJobResult stores a completed job's result the first time it is seen,
+// creating the job record if needed
+func (s *JobStore) SaveJobResult(jobID string, result []byte) error {
+	res, err := s.db.Exec(`
+		UPDATE generation_jobs
+		SET result = $2, status = 'completed', updated_at = NOW()
+		WHERE job_id = $1 AND result IS NULL
+	`, jobID, result)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO generation_jobs (job_id, wallet_address, status, created_at, updated_at, result)
+		SELECT $1, '', 'completed', NOW(), NOW(), $2
+		WHERE NOT EXISTS (SELECT 1 FROM generation_jobs WHERE job_id = $1)
+	`, jobID, result)
+	return err
+}
+
+// GetJobResult returns the stored result for a job, or nil if none was saved
+func (s *JobStore) GetJobResult(jobID string) ([]byte, error) {
+	var result []byte
+	err := s.db.QueryRow(`
+		SELECT result FROM generation_jobs WHERE job_id = $1 AND result IS NOT NULL
+	`, jobID).Scan(&result)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,86 @@
+package gallery
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// migrations are idempotent schema changes applied in order on startup.
+// The base tables are provisioned outside this service; these only extend them,
+// so every statement must be safe to run against an already-migrated database.
+var migrations = []string{
+	// Per-wallet preferences (NSFW visibility, default model)
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS settings JSONB NOT NULL DEFAULT '{}'::jsonb`,
+	// NSFW flag, set on add and editable by the owner; older rows count as safe
+	`ALTER TABLE gallery_items ADD COLUMN IF NOT EXISTS is_nsfw BOOLEAN NOT NULL DEFAULT false`,
+	// Owner edits of prompt / NSFW flag
+	`ALTER TABLE gallery_items ADD COLUMN IF NOT EXISTS edited_at TIMESTAMPTZ`,
+	// Collections / albums; position keeps items in the order they were added
+	`CREATE TABLE IF NOT EXISTS collections (
+		id BIGSERIAL PRIMARY KEY,
+		wallet_address TEXT NOT NULL,
+		name TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		UNIQUE (wallet_address, name)
+	)`,
+	`CREATE TABLE IF NOT EXISTS collection_items (
+		collection_id BIGINT NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+		job_id TEXT NOT NULL,
+		position INTEGER NOT NULL,
+		added_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (collection_id, job_id)
+	)`,
+	// Shareable public collections
+	`ALTER TABLE collections ADD COLUMN IF NOT EXISTS is_public BOOLEAN NOT NULL DEFAULT false`,
+	`ALTER TABLE collections ADD COLUMN IF NOT EXISTS slug TEXT UNIQUE`,
+	// Original request behind a job, for retries (the API key itself is never stored)
+	`ALTER TABLE generation_jobs ADD COLUMN IF NOT EXISTS request JSONB`,
+	`ALTER TABLE generation_jobs ADD COLUMN IF NOT EXISTS api_key_hash TEXT`,
+	// Request ID a job was submitted under, to find it from Grid worker logs
+	`ALTER TABLE generation_jobs ADD COLUMN IF NOT EXISTS request_id TEXT`,
+	`CREATE INDEX IF NOT EXISTS idx_generation_jobs_request_id ON generation_jobs (request_id)`,
+	// Params map sent to the Grid; the width/steps/... columns stay for filtering
+	`ALTER TABLE generation_jobs ADD COLUMN IF NOT EXISTS grid_params JSONB`,
+	`ALTER TABLE gallery_items ADD COLUMN IF NOT EXISTS params_json JSONB`,
+	// Completed job views, served once the Grid has expired the job
+	`ALTER TABLE generation_jobs ADD COLUMN IF NOT EXISTS result JSONB`,
+	// Image or video; rows from before the column existed were all saved as images
+	`ALTER TABLE gallery_items ADD COLUMN IF NOT EXISTS type TEXT NOT NULL DEFAULT 'image'`,
+	`CREATE INDEX IF NOT EXISTS idx_gallery_items_type ON gallery_items (type)`,
+	// Preset ID, separate from the model name the item was saved under; older
+	// rows are filled in on startup where the name resolves to a preset
+	`ALTER TABLE gallery_items ADD COLUMN IF NOT EXISTS model_id TEXT`,
+	`CREATE INDEX IF NOT EXISTS idx_gallery_items_model_id ON gallery_items (LOWER(model_id))`,
+	// Full-text search over prompts, kept current by a trigger; rows from
+	// before the column existed are backfilled here
+	`ALTER TABLE gallery_items ADD COLUMN IF NOT EXISTS prompt_tsv TSVECTOR`,
+	`CREATE OR REPLACE FUNCTION gallery_items_prompt_tsv() RETURNS trigger AS $$
+	BEGIN
+		NEW.prompt_tsv := to_tsvector('english', COALESCE(NEW.prompt, ''));
+		RETURN NEW;
+	END
+	$$ LANGUAGE plpgsql`,
+	`DROP TRIGGER IF EXISTS gallery_items_prompt_tsv ON gallery_items`,
+	`CREATE TRIGGER gallery_items_prompt_tsv BEFORE INSERT OR UPDATE OF prompt ON gallery_items
+		FOR EACH ROW EXECUTE FUNCTION gallery_items_prompt_tsv()`,
+	`UPDATE gallery_items SET prompt_tsv = to_tsvector('english', COALESCE(prompt, '')) WHERE prompt_tsv IS NULL`,
+	`CREATE INDEX IF NOT EXISTS idx_gallery_items_prompt_tsv ON gallery_items USING GIN (prompt_tsv)`,
+	// Admin-picked homepage showcase, listed most recently featured first
+	`ALTER TABLE gallery_items ADD COLUMN IF NOT EXISTS featured BOOLEAN NOT NULL DEFAULT false`,
+	`ALTER TABLE gallery_items ADD COLUMN IF NOT EXISTS featured_at TIMESTAMPTZ`,
+	`CREATE INDEX IF NOT EXISTS idx_gallery_items_featured_at ON gallery_items (featured_at DESC) WHERE featured`,
+	// Links between a job resubmitted by the worker filter and its replacement,
+	// so a restart doesn't resubmit it again and chains of retries stay short
+	`ALTER TABLE generation_jobs ADD COLUMN IF NOT EXISTS retry_of TEXT`,
+	`ALTER TABLE generation_jobs ADD COLUMN IF NOT EXISTS retried_as TEXT`,
+}
+
+// migrate applies all schema migrations
+func migrate(db *sql.DB) error {
+	for i, stmt := range migrations {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("migration %d failed: %w", i+1, err)
+		}
+	}
+	return nil
+}
@@ -1,13 +1,14 @@
 package gallery
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"strings"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // PostgresStore implements GalleryStore using PostgreSQL
@@ -15,8 +16,17 @@ type PostgresStore struct {
 	db        *sql.DB
 	UserStore *UserStore
 	JobStore  *JobStore
+
+	queryTimeout       time.Duration
+	slowQueryThreshold time.Duration
 }
 
+// Defaults for SetQueryLimits
+const (
+	defaultQueryTimeout       = 10 * time.Second
+	defaultSlowQueryThreshold = 500 * time.Millisecond
+)
+
 // DB returns the underlying database connection
 func (s *PostgresStore) DB() *sql.DB {
 	return s.db
@@ -39,17 +49,47 @@ func NewPostgresStore(connStr string) (*PostgresStore, error) {
 	db.SetMaxIdleConns(5)
 	db.SetConnMaxLifetime(5 * time.Minute)
 
+	if err := migrate(db); err != nil {
+		return nil, err
+	}
+
 	store := &PostgresStore{
-		db:        db,
-		UserStore: &UserStore{db: db},
-		JobStore:  &JobStore{db: db},
+		db:                 db,
+		UserStore:          &UserStore{db: db},
+		JobStore:           &JobStore{db: db},
+		queryTimeout:       defaultQueryTimeout,
+		slowQueryThreshold: defaultSlowQueryThreshold,
 	}
 
 	return store, nil
 }
 
+// SetQueryLimits sets the per-query timeout and the duration above which a
+// query is logged as slow. Zero disables the respective limit.
+func (s *PostgresStore) SetQueryLimits(timeout, slowThreshold time.Duration) {
+	s.queryTimeout = timeout
+	s.slowQueryThreshold = slowThreshold
+}
+
+// queryContext returns ctx bounded by the store's query timeout, so a query
+// stops when the caller gives up or the timeout passes, whichever is first
+func (s *PostgresStore) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.queryTimeout)
+}
+
+// logSlowQuery logs an operation that took longer than the slow-query threshold
+func (s *PostgresStore) logSlowQuery(op string, start time.Time, filter string) {
+	elapsed := time.Since(start)
+	if s.slowQueryThreshold > 0 && elapsed >= s.slowQueryThreshold {
+		log.Printf("⚠️  Slow gallery query: %s took %s (%s)", op, elapsed.Round(time.Millisecond), filter)
+	}
+}
+
 // Add inserts a new gallery item
-func (s *PostgresStore) Add(item GalleryItem) error {
+func (s *PostgresStore) Add(ctx context.Context, item GalleryItem) error {
 	// Convert media URLs array to single URL
 	mediaURL := ""
 	if len(item.MediaURLs) > 0 {
@@ -60,6 +100,10 @@ func (s *PostgresStore) Add(item GalleryItem) error {
 	var width, height, steps *int
 	var cfgScale *float64
 	var sampler, scheduler, seed *string
+	var paramsJSON []byte
+	if len(item.GridParams) > 0 {
+		paramsJSON = item.GridParams
+	}
 
 	if item.Params != nil {
 		width = item.Params.Width
@@ -76,11 +120,13 @@ func (s *PostgresStore) Add(item GalleryItem) error {
 			job_id, model, prompt, negative_prompt,
 			media_url, is_public, wallet_address,
 			width, height, steps, cfg_scale, sampler, scheduler, seed,
-			created_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+			created_at, params_json, type, is_nsfw, model_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
 		ON CONFLICT (job_id) DO UPDATE SET
-			media_url = EXCLUDED.media_url,
-			is_public = EXCLUDED.is_public
+			media_url = COALESCE(NULLIF(EXCLUDED.media_url, ''), gallery_items.media_url),
+			is_public = EXCLUDED.is_public,
+			is_nsfw = EXCLUDED.is_nsfw
+		WHERE LOWER(COALESCE(gallery_items.wallet_address, '')) = LOWER(COALESCE(EXCLUDED.wallet_address, ''))
 	`
 
 	createdAt := time.UnixMilli(item.CreatedAt)
@@ -88,41 +134,58 @@ func (s *PostgresStore) Add(item GalleryItem) error {
 		createdAt = time.Now()
 	}
 
-	_, err := s.db.Exec(query,
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	defer s.logSlowQuery("Add", time.Now(), "job_id="+item.JobID)
+
+	result, err := s.db.ExecContext(ctx, query,
 		item.JobID,
-		item.ModelName, // Use ModelName as 'model'
+		item.ModelName, // model keeps the name the item was saved with; model_id the preset
 		item.Prompt,
 		item.NegativePrompt,
 		mediaURL,
 		item.IsPublic,
 		strings.ToLower(item.WalletAddress),
 		width, height, steps, cfgScale, sampler, scheduler, seed,
-		createdAt,
+		createdAt, paramsJSON, ItemType(item), item.IsNSFW, nullIfEmpty(item.ModelID),
 	)
-
-	return err
+	if err != nil {
+		return err
+	}
+	// The upsert's WHERE skips items saved by another wallet
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotOwner
+	}
+	return nil
 }
 
 // Get retrieves a single gallery item by job ID
-func (s *PostgresStore) Get(jobID string) *GalleryItem {
+func (s *PostgresStore) Get(ctx context.Context, jobID string) *GalleryItem {
 	query := `
 		SELECT job_id, model, prompt, negative_prompt,
 			   media_url, is_public, wallet_address,
 			   width, height, steps, cfg_scale, sampler, scheduler, seed,
-			   created_at
+			   created_at, is_nsfw, edited_at, params_json, type, model_id
 		FROM gallery_items
 		WHERE job_id = $1
 	`
 
 	var item GalleryItem
 	var mediaURL string
-	var walletAddr, model, prompt, negPrompt sql.NullString
+	var walletAddr, model, modelID, prompt, negPrompt sql.NullString
 	var createdAt time.Time
+	var editedAt sql.NullTime
 	var width, height, steps sql.NullInt64
 	var cfgScale sql.NullFloat64
 	var sampler, scheduler, seed sql.NullString
+	var paramsJSON []byte
+	var itemType string
 
-	err := s.db.QueryRow(query, jobID).Scan(
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	defer s.logSlowQuery("Get", time.Now(), "job_id="+jobID)
+
+	err := s.db.QueryRowContext(ctx, query, jobID).Scan(
 		&item.JobID,
 		&model,
 		&prompt,
@@ -131,17 +194,14 @@ func (s *PostgresStore) Get(jobID string) *GalleryItem {
 		&item.IsPublic,
 		&walletAddr,
 		&width, &height, &steps, &cfgScale, &sampler, &scheduler, &seed,
-		&createdAt,
+		&createdAt, &item.IsNSFW, &editedAt, &paramsJSON, &itemType, &modelID,
 	)
 
 	if err != nil {
 		return nil
 	}
 
-	if model.Valid {
-		item.ModelName = model.String
-		item.ModelID = model.String
-	}
+	item.ModelName, item.ModelID = modelNames(model, modelID)
 	if prompt.Valid {
 		item.Prompt = prompt.String
 	}
@@ -150,7 +210,10 @@ func (s *PostgresStore) Get(jobID string) *GalleryItem {
 	}
 	item.MediaURLs = []string{mediaURL}
 	item.CreatedAt = createdAt.UnixMilli()
-	item.Type = "image" // Default to image
+	if editedAt.Valid {
+		item.EditedAt = editedAt.Time.UnixMilli()
+	}
+	item.Type = itemType
 
 	if walletAddr.Valid {
 		item.WalletAddress = walletAddr.String
@@ -182,63 +245,128 @@ func (s *PostgresStore) Get(jobID string) *GalleryItem {
 	if seed.Valid {
 		item.Params.Seed = &seed.String
 	}
+	item.GridParams = paramsJSON
 
 	return &item
 }
 
 // List returns paginated gallery items with optional filtering
-func (s *PostgresStore) List(typeFilter string, limit, offset int, searchQuery string) ListResult {
+func (s *PostgresStore) List(ctx context.Context, opts ListOptions) ListResult {
 	items := make([]GalleryItem, 0) // Initialize to empty array, not nil
+	total, err := s.StreamList(ctx, opts, func(_ int, item GalleryItem) error {
+		items = append(items, item)
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error querying gallery items: %v", err)
+	}
+
+	return ListResult{
+		Items:      items,
+		Total:      total,
+		HasMore:    opts.Offset+len(items) < total,
+		NextOffset: opts.Offset + len(items),
+		Seed:       opts.Seed,
+	}
+}
+
+// StreamList runs List's query and hands each item to fn as it's scanned
+// instead of collecting the page
+func (s *PostgresStore) StreamList(ctx context.Context, opts ListOptions, fn func(total int, item GalleryItem) error) (int, error) {
+	limit, offset, searchQuery := opts.Limit, opts.Offset, opts.Search
 	var args []interface{}
 	argNum := 1
 
 	// Build WHERE clause
 	whereClauses := []string{"is_public = true"}
+	if !opts.IncludeNSFW {
+		whereClauses = append(whereClauses, "is_nsfw = false")
+	}
 
+	var rank string
 	if searchQuery != "" {
-		// Use word boundary regex for better matching
-		whereClauses = append(whereClauses, fmt.Sprintf("prompt ~* $%d", argNum))
-		pattern := fmt.Sprintf("\\m%s", strings.ToLower(searchQuery))
-		args = append(args, pattern)
+		clause, searchArgs, searchRank := searchClause(searchQuery, opts.SearchFields, argNum)
+		whereClauses = append(whereClauses, clause)
+		args = append(args, searchArgs...)
+		argNum += len(searchArgs)
+		rank = searchRank
+	}
+
+	if opts.Type == TypeImage || opts.Type == TypeVideo {
+		whereClauses = append(whereClauses, fmt.Sprintf("type = $%d", argNum))
+		args = append(args, opts.Type)
 		argNum++
 	}
 
+	if len(opts.Models) > 0 {
+		models := make([]string, len(opts.Models))
+		for i, m := range opts.Models {
+			models[i] = strings.ToLower(m)
+		}
+		whereClauses = append(whereClauses, fmt.Sprintf("(LOWER(model) = ANY($%d) OR LOWER(model_id) = ANY($%d))", argNum, argNum))
+		args = append(args, pq.Array(models))
+		argNum++
+	}
+
+	if opts.Featured {
+		whereClauses = append(whereClauses, "featured = true")
+	}
+
 	whereClause := strings.Join(whereClauses, " AND ")
 
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	defer s.logSlowQuery("List", time.Now(), fmt.Sprintf("search=%q fields=%v models=%v limit=%d offset=%d", searchQuery, opts.SearchFields, opts.Models, limit, offset))
+
 	// Get total count
 	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM gallery_items WHERE %s", whereClause)
 	var total int
-	s.db.QueryRow(countQuery, args...).Scan(&total)
-
-	// Get items with random ordering
+	s.db.QueryRowContext(ctx, countQuery, args...).Scan(&total)
+
+	// Newest first, most relevant first for a prompt search, most recently
+	// featured first for the featured list, or a seeded shuffle. Every order is
+	// total, so offset pages neither repeat nor skip items.
+	order := "created_at DESC, job_id DESC"
+	if opts.Seed != "" {
+		order = fmt.Sprintf("md5(job_id || $%d), job_id", argNum)
+		args = append(args, opts.Seed)
+		argNum++
+	} else if opts.Featured {
+		order = "featured_at DESC, job_id"
+	} else if rank != "" {
+		order = rank + " DESC, created_at DESC, job_id"
+	}
 	query := fmt.Sprintf(`
 		SELECT job_id, model, prompt, negative_prompt,
 			   media_url, is_public, wallet_address,
 			   width, height, steps, cfg_scale, sampler, scheduler, seed,
-			   created_at
+			   created_at, params_json, type, is_nsfw, model_id,
+			   featured, featured_at
 		FROM gallery_items
 		WHERE %s
-		ORDER BY RANDOM()
+		ORDER BY %s
 		LIMIT $%d OFFSET $%d
-	`, whereClause, argNum, argNum+1)
+	`, whereClause, order, argNum, argNum+1)
 
 	args = append(args, limit, offset)
 
-	rows, err := s.db.Query(query, args...)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		log.Printf("Error querying gallery items: %v", err)
-		return ListResult{Items: items, Total: total}
+		return total, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var item GalleryItem
 		var mediaURL string
-		var walletAddr, prompt, negPrompt, model sql.NullString
+		var walletAddr, prompt, negPrompt, model, modelID sql.NullString
 		var createdAt time.Time
 		var width, height, steps sql.NullInt64
 		var cfgScale sql.NullFloat64
 		var sampler, scheduler, seed sql.NullString
+		var paramsJSON []byte
+		var itemType string
+		var featuredAt sql.NullTime
 
 		err := rows.Scan(
 			&item.JobID,
@@ -249,7 +377,8 @@ func (s *PostgresStore) List(typeFilter string, limit, offset int, searchQuery s
 			&item.IsPublic,
 			&walletAddr,
 			&width, &height, &steps, &cfgScale, &sampler, &scheduler, &seed,
-			&createdAt,
+			&createdAt, &paramsJSON, &itemType, &item.IsNSFW, &modelID,
+			&item.Featured, &featuredAt,
 		)
 
 		if err != nil {
@@ -257,9 +386,9 @@ func (s *PostgresStore) List(typeFilter string, limit, offset int, searchQuery s
 			continue
 		}
 
-		if model.Valid {
-			item.ModelName = model.String
-			item.ModelID = model.String
+		item.ModelName, item.ModelID = modelNames(model, modelID)
+		if featuredAt.Valid {
+			item.FeaturedAt = featuredAt.Time.UnixMilli()
 		}
 		if prompt.Valid {
 			item.Prompt = prompt.String
@@ -269,7 +398,7 @@ func (s *PostgresStore) List(typeFilter string, limit, offset int, searchQuery s
 		}
 		item.MediaURLs = []string{mediaURL}
 		item.CreatedAt = createdAt.UnixMilli()
-		item.Type = "image"
+		item.Type = itemType
 
 		if walletAddr.Valid {
 			item.WalletAddress = walletAddr.String
@@ -301,48 +430,88 @@ func (s *PostgresStore) List(typeFilter string, limit, offset int, searchQuery s
 		if seed.Valid {
 			item.Params.Seed = &seed.String
 		}
+		item.GridParams = paramsJSON
 
-		items = append(items, item)
+		if err := fn(total, item); err != nil {
+			return total, err
+		}
 	}
 
-	return ListResult{
-		Items:      items,
-		Total:      total,
-		HasMore:    offset+len(items) < total,
-		NextOffset: offset + len(items),
-	}
+	return total, rows.Err()
 }
 
 // ListByWallet returns gallery items for a specific wallet address
-func (s *PostgresStore) ListByWallet(wallet string, limit int) []GalleryItem {
-	items := make([]GalleryItem, 0) // Initialize to empty array, not nil
+func (s *PostgresStore) ListByWallet(ctx context.Context, wallet string, limit int) []GalleryItem {
+	if strings.TrimSpace(wallet) == "" {
+		return make([]GalleryItem, 0)
+	}
+	page, err := s.ListByWalletPage(ctx, wallet, limit, nil)
+	if err != nil {
+		log.Printf("Error querying wallet gallery items: %v", err)
+		return make([]GalleryItem, 0) // Initialize to empty array, not nil
+	}
+	return page.Items
+}
 
-	query := `
+// ListByWalletPage returns up to limit of a wallet's items older than the
+// cursor (nil for the first page), newest first, plus the cursor for the next page
+func (s *PostgresStore) ListByWalletPage(ctx context.Context, wallet string, limit int, before *WalletCursor) (WalletPage, error) {
+	page := WalletPage{Items: make([]GalleryItem, 0)}
+	wallet = strings.TrimSpace(wallet)
+	if wallet == "" {
+		return page, ErrWalletRequired
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+
+	args := []interface{}{wallet}
+	keyset := ""
+	if before != nil {
+		keyset = "AND (created_at, job_id) < ($2, $3)"
+		args = append(args, before.CreatedAt, before.JobID)
+	}
+	// Fetch one extra row to know whether there is a next page
+	args = append(args, limit+1)
+
+	query := fmt.Sprintf(`
 		SELECT job_id, model, prompt, negative_prompt,
 			   media_url, is_public, wallet_address,
 			   width, height, steps, cfg_scale, sampler, scheduler, seed,
-			   created_at
+			   created_at, params_json, type, is_nsfw, model_id
 		FROM gallery_items
-		WHERE LOWER(wallet_address) = LOWER($1)
-		ORDER BY created_at DESC
-		LIMIT $2
-	`
+		WHERE LOWER(wallet_address) = LOWER($1) %s
+		ORDER BY created_at DESC, job_id DESC
+		LIMIT $%d
+	`, keyset, len(args))
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	defer s.logSlowQuery("ListByWalletPage", time.Now(), fmt.Sprintf("wallet=%s limit=%d cursor=%v", wallet, limit, before != nil))
 
-	rows, err := s.db.Query(query, wallet, limit)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		log.Printf("Error querying wallet gallery items: %v", err)
-		return items
+		return page, err
 	}
 	defer rows.Close()
 
+	var lastCreatedAt time.Time
 	for rows.Next() {
+		if len(page.Items) == limit {
+			last := page.Items[len(page.Items)-1]
+			page.NextCursor = WalletCursor{CreatedAt: lastCreatedAt, JobID: last.JobID}.String()
+			break
+		}
+
 		var item GalleryItem
 		var mediaURL string
-		var walletAddr, model, prompt, negPrompt sql.NullString
+		var walletAddr, model, prompt, negPrompt, modelID sql.NullString
 		var createdAt time.Time
 		var width, height, steps sql.NullInt64
 		var cfgScale sql.NullFloat64
 		var sampler, scheduler, seed sql.NullString
+		var paramsJSON []byte
+		var itemType string
 
 		err := rows.Scan(
 			&item.JobID,
@@ -353,17 +522,16 @@ func (s *PostgresStore) ListByWallet(wallet string, limit int) []GalleryItem {
 			&item.IsPublic,
 			&walletAddr,
 			&width, &height, &steps, &cfgScale, &sampler, &scheduler, &seed,
-			&createdAt,
+			&createdAt, &paramsJSON, &itemType, &item.IsNSFW, &modelID,
 		)
 
 		if err != nil {
 			continue
 		}
+		// Keep full precision for the cursor; CreatedAt on the item is only milliseconds
+		lastCreatedAt = createdAt
 
-		if model.Valid {
-			item.ModelName = model.String
-			item.ModelID = model.String
-		}
+		item.ModelName, item.ModelID = modelNames(model, modelID)
 		if prompt.Valid {
 			item.Prompt = prompt.String
 		}
@@ -372,7 +540,7 @@ func (s *PostgresStore) ListByWallet(wallet string, limit int) []GalleryItem {
 		}
 		item.MediaURLs = []string{mediaURL}
 		item.CreatedAt = createdAt.UnixMilli()
-		item.Type = "image"
+		item.Type = itemType
 
 		if walletAddr.Valid {
 			item.WalletAddress = walletAddr.String
@@ -404,32 +572,215 @@ func (s *PostgresStore) ListByWallet(wallet string, limit int) []GalleryItem {
 		if seed.Valid {
 			item.Params.Seed = &seed.String
 		}
+		item.GridParams = paramsJSON
 
-		items = append(items, item)
+		page.Items = append(page.Items, item)
+	}
+
+	return page, rows.Err()
+}
+
+// PrunableItems returns up to limit private items created before cutoff,
+// oldest first, leaving out anything in a favorites list or a collection.
+// Only the fields the reaper needs are filled in.
+func (s *PostgresStore) PrunableItems(ctx context.Context, cutoff time.Time, limit int) ([]GalleryItem, error) {
+	query := `
+		SELECT g.job_id, g.media_url, g.wallet_address, g.created_at
+		FROM gallery_items g
+		WHERE g.is_public = false AND g.created_at < $1
+		  AND NOT EXISTS (SELECT 1 FROM favorites f WHERE f.job_id = g.job_id)
+		  AND NOT EXISTS (SELECT 1 FROM collection_items ci WHERE ci.job_id = g.job_id)
+		ORDER BY g.created_at
+		LIMIT $2
+	`
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	defer s.logSlowQuery("PrunableItems", time.Now(), fmt.Sprintf("cutoff=%s limit=%d", cutoff.Format(time.RFC3339), limit))
+
+	rows, err := s.db.QueryContext(ctx, query, cutoff, limit)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	return items
+	items := make([]GalleryItem, 0)
+	for rows.Next() {
+		var item GalleryItem
+		var mediaURL, wallet sql.NullString
+		var createdAt time.Time
+		if err := rows.Scan(&item.JobID, &mediaURL, &wallet, &createdAt); err != nil {
+			return nil, err
+		}
+		if mediaURL.String != "" {
+			item.MediaURLs = []string{mediaURL.String}
+		}
+		item.WalletAddress = wallet.String
+		item.CreatedAt = createdAt.UnixMilli()
+		items = append(items, item)
+	}
+	return items, rows.Err()
 }
 
 // Delete removes a gallery item
-func (s *PostgresStore) Delete(jobID string) error {
-	_, err := s.db.Exec("DELETE FROM gallery_items WHERE job_id = $1", jobID)
+func (s *PostgresStore) Delete(ctx context.Context, jobID string) error {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	_, err := s.db.ExecContext(ctx, "DELETE FROM gallery_items WHERE job_id = $1", jobID)
 	return err
 }
 
 // SetPublic updates the is_public flag for a gallery item
-func (s *PostgresStore) SetPublic(jobID string, isPublic bool) error {
-	_, err := s.db.Exec("UPDATE gallery_items SET is_public = $1 WHERE job_id = $2", isPublic, jobID)
+func (s *PostgresStore) SetPublic(ctx context.Context, jobID string, isPublic bool) error {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	_, err := s.db.ExecContext(ctx, "UPDATE gallery_items SET is_public = $1 WHERE job_id = $2", isPublic, jobID)
 	return err
 }
 
+// SetFeatured features or unfeatures a gallery item. Featuring stamps
+// featured_at, so featuring an item again moves it to the front of the
+// featured list.
+func (s *PostgresStore) SetFeatured(ctx context.Context, jobID string, featured bool) error {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE gallery_items
+		SET featured = $2,
+			featured_at = CASE WHEN $2 THEN NOW() END
+		WHERE job_id = $1
+	`, jobID, featured)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrItemNotFound
+	}
+	return nil
+}
+
+// Update applies an owner edit to a gallery item and stamps edited_at
+func (s *PostgresStore) Update(ctx context.Context, jobID string, update ItemUpdate) error {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE gallery_items
+		SET prompt = COALESCE($2, prompt),
+			is_nsfw = COALESCE($3, is_nsfw),
+			edited_at = NOW()
+		WHERE job_id = $1
+	`, jobID, update.Prompt, update.IsNSFW)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrItemNotFound
+	}
+	return nil
+}
+
 // Count returns the total number of gallery items
-func (s *PostgresStore) Count() int {
+func (s *PostgresStore) Count(ctx context.Context) int {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	defer s.logSlowQuery("Count", time.Now(), "all items")
+
 	var count int
-	s.db.QueryRow("SELECT COUNT(*) FROM gallery_items").Scan(&count)
+	s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM gallery_items").Scan(&count)
 	return count
 }
 
+// nullIfEmpty stores an empty string as NULL
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// modelNames returns an item's model name and preset ID. Rows saved before
+// model_id existed, and not matched by BackfillModelIDs, use the name for both.
+func modelNames(model, modelID sql.NullString) (name, id string) {
+	name = model.String
+	id = modelID.String
+	if id == "" {
+		id = name
+	}
+	return name, id
+}
+
+// BackfillModelIDs sets model_id on rows saved before the column existed.
+// resolve maps a stored model name to its preset ID; rows it can't resolve
+// keep a NULL model_id and reads fall back to the name. Returns the rows updated.
+func (s *PostgresStore) BackfillModelIDs(ctx context.Context, resolve func(model string) (string, bool)) (int64, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	defer s.logSlowQuery("BackfillModelIDs", time.Now(), "model_id IS NULL")
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT model FROM gallery_items
+		WHERE model_id IS NULL AND model IS NOT NULL AND model <> ''
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list unresolved models: %w", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan model: %w", err)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var updated int64
+	for _, name := range names {
+		id, ok := resolve(name)
+		if !ok {
+			continue
+		}
+		res, err := s.db.ExecContext(ctx, `UPDATE gallery_items SET model_id = $1 WHERE model_id IS NULL AND model = $2`, id, name)
+		if err != nil {
+			return updated, fmt.Errorf("failed to backfill model_id for %q: %w", name, err)
+		}
+		n, _ := res.RowsAffected()
+		updated += n
+	}
+	return updated, nil
+}
+
+// ModelCounts returns the distinct models of public items with their counts,
+// most used first, leaving out NSFW items unless includeNSFW is set
+func (s *PostgresStore) ModelCounts(ctx context.Context, includeNSFW bool) ([]ModelCount, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	defer s.logSlowQuery("ModelCounts", time.Now(), "public items")
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT model, COUNT(*) FROM gallery_items
+		WHERE is_public = true AND model IS NOT NULL AND model <> ''
+			AND ($1 OR is_nsfw = false)
+		GROUP BY model
+		ORDER BY COUNT(*) DESC, model ASC
+	`, includeNSFW)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count models: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make([]ModelCount, 0)
+	for rows.Next() {
+		var mc ModelCount
+		if err := rows.Scan(&mc.Model, &mc.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan model count: %w", err)
+		}
+		counts = append(counts, mc)
+	}
+	return counts, rows.Err()
+}
+
 // Close closes the database connection
 func (s *PostgresStore) Close() error {
 	return s.db.Close()
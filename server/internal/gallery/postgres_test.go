@@ -0,0 +1,557 @@
+package gallery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// openTestPostgres connects to the database named by GALLERY_TEST_POSTGRES,
+// skipping the test when it isn't set. The database must already have the
+// base gallery tables; migrations are applied on connect.
+func openTestPostgres(t *testing.T) *PostgresStore {
+	t.Helper()
+
+	connStr := os.Getenv("GALLERY_TEST_POSTGRES")
+	if connStr == "" {
+		t.Skip("GALLERY_TEST_POSTGRES not set")
+	}
+	store, err := NewPostgresStore(connStr)
+	if err != nil {
+		t.Fatalf("NewPostgresStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestUserSettingsRoundTrip(t *testing.T) {
+	store := openTestPostgres(t)
+	wallet := "0xsettings-roundtrip"
+	t.Cleanup(func() { store.DB().Exec(`DELETE FROM users WHERE wallet_address = $1`, wallet) })
+
+	show := true
+	want := UserSettings{ShowNSFW: &show, DefaultModel: "FLUX.1-dev"}
+	if err := store.UserStore.UpdateSettings(wallet, want); err != nil {
+		t.Fatalf("UpdateSettings: %v", err)
+	}
+
+	got, err := store.UserStore.GetSettings(wallet)
+	if err != nil {
+		t.Fatalf("GetSettings: %v", err)
+	}
+	if got.ShowNSFW == nil || *got.ShowNSFW != show || got.DefaultModel != want.DefaultModel {
+		t.Errorf("GetSettings = %+v, want %+v", got, want)
+	}
+}
+
+func TestUpdateGalleryItemPostgres(t *testing.T) {
+	store := openTestPostgres(t)
+	jobID := "test-update-item"
+	t.Cleanup(func() { store.Delete(context.Background(), jobID) })
+
+	if err := store.Add(context.Background(), GalleryItem{JobID: jobID, Prompt: "typo promt", IsPublic: true}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	prompt, nsfw := "fixed prompt", true
+	if err := store.Update(context.Background(), jobID, ItemUpdate{Prompt: &prompt, IsNSFW: &nsfw}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got := store.Get(context.Background(), jobID)
+	if got == nil || got.Prompt != prompt || !got.IsNSFW || got.EditedAt == 0 {
+		t.Errorf("Get after Update = %+v", got)
+	}
+
+	if err := store.Update(context.Background(), "missing-job", ItemUpdate{Prompt: &prompt}); err != ErrItemNotFound {
+		t.Errorf("Update missing = %v, want ErrItemNotFound", err)
+	}
+}
+
+func TestCollectionMembershipOrder(t *testing.T) {
+	pg := openTestPostgres(t)
+	store := NewPostgresCollectionStore(pg)
+	wallet := "0xcollections-test"
+	t.Cleanup(func() { pg.DB().Exec(`DELETE FROM collections WHERE wallet_address = $1`, wallet) })
+
+	c, err := store.Create(context.Background(), wallet, "landscapes")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := store.Create(context.Background(), wallet, "landscapes"); err != ErrCollectionExists {
+		t.Errorf("duplicate Create = %v, want ErrCollectionExists", err)
+	}
+
+	for _, jobID := range []string{"job-b", "job-a", "job-c", "job-a"} {
+		if err := store.AddItem(context.Background(), c.ID, jobID); err != nil {
+			t.Fatalf("AddItem(%s): %v", jobID, err)
+		}
+	}
+	if err := store.RemoveItem(context.Background(), c.ID, "job-a"); err != nil {
+		t.Fatalf("RemoveItem: %v", err)
+	}
+	if err := store.AddItem(context.Background(), c.ID, "job-a"); err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	got, err := store.ItemJobIDs(context.Background(), c.ID)
+	if err != nil {
+		t.Fatalf("ItemJobIDs: %v", err)
+	}
+	want := []string{"job-b", "job-c", "job-a"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("ItemJobIDs = %v, want %v", got, want)
+	}
+	if fetched, err := store.Get(context.Background(), c.ID); err != nil || fetched.ItemCount != 3 {
+		t.Errorf("Get = %+v, %v; want 3 items", fetched, err)
+	}
+}
+
+func TestCollectionConcurrentAddsTakeDistinctPositions(t *testing.T) {
+	pg := openTestPostgres(t)
+	store := NewPostgresCollectionStore(pg)
+	wallet := "0xcollections-concurrent-test"
+	t.Cleanup(func() { pg.DB().Exec(`DELETE FROM collections WHERE wallet_address = $1`, wallet) })
+
+	c, err := store.Create(context.Background(), wallet, "burst")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := store.AddItem(context.Background(), c.ID, fmt.Sprintf("job-%02d", i)); err != nil {
+				t.Errorf("AddItem: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var positions int
+	pg.DB().QueryRow(`SELECT COUNT(DISTINCT position) FROM collection_items WHERE collection_id = $1`, c.ID).Scan(&positions)
+	if positions != 20 {
+		t.Errorf("20 concurrent adds took %d distinct positions, want 20", positions)
+	}
+}
+
+func TestCollectionSlugUniqueness(t *testing.T) {
+	pg := openTestPostgres(t)
+	store := NewPostgresCollectionStore(pg)
+	wallet := "0xcollections-slug-test"
+	t.Cleanup(func() { pg.DB().Exec(`DELETE FROM collections WHERE wallet_address = $1`, wallet) })
+
+	first, err := store.Create(context.Background(), wallet, "first")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	second, err := store.Create(context.Background(), wallet, "second")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if err := store.Publish(context.Background(), first.ID, "shared-slug-test"); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if err := store.Publish(context.Background(), second.ID, "shared-slug-test"); err != ErrSlugTaken {
+		t.Errorf("Publish duplicate slug = %v, want ErrSlugTaken", err)
+	}
+
+	got, err := store.GetBySlug(context.Background(), "shared-slug-test")
+	if err != nil || got.ID != first.ID || !got.IsPublic {
+		t.Errorf("GetBySlug = %+v, %v", got, err)
+	}
+}
+
+func TestListByWalletPagePostgres(t *testing.T) {
+	store := openTestPostgres(t)
+	wallet := "0xkeyset-test"
+	t.Cleanup(func() { store.DB().Exec(`DELETE FROM gallery_items WHERE wallet_address = $1`, wallet) })
+
+	for i := 0; i < 5; i++ {
+		store.Add(context.Background(), GalleryItem{JobID: fmt.Sprintf("keyset-%d", i), Prompt: "p", WalletAddress: wallet, CreatedAt: int64(1700000000000 + i/2)})
+	}
+
+	seen := make(map[string]int)
+	var before *WalletCursor
+	for {
+		page, err := store.ListByWalletPage(context.Background(), wallet, 2, before)
+		if err != nil {
+			t.Fatalf("ListByWalletPage: %v", err)
+		}
+		for _, item := range page.Items {
+			seen[item.JobID]++
+		}
+		if before == nil {
+			store.Add(context.Background(), GalleryItem{JobID: "keyset-new", Prompt: "p", WalletAddress: wallet, CreatedAt: 1800000000000})
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		before, _ = ParseWalletCursor(page.NextCursor)
+	}
+
+	if len(seen) != 5 {
+		t.Errorf("saw %d distinct items, want 5: %v", len(seen), seen)
+	}
+	for id, n := range seen {
+		if n != 1 {
+			t.Errorf("%s seen %d times", id, n)
+		}
+	}
+}
+
+func TestVideoParamsRoundTripPostgres(t *testing.T) {
+	store := openTestPostgres(t)
+	jobID := "test-video-params"
+	t.Cleanup(func() { store.Delete(context.Background(), jobID) })
+
+	width, steps := 832, 30
+	gridParams := json.RawMessage(`{"width":832,"steps":30,"length":81,"video_length":81,"fps":16,"sampler_name":"euler","loras":[{"name":"wave","model":0.8}]}`)
+	item := GalleryItem{JobID: jobID, Prompt: "a wave", Type: "video", IsPublic: true, Params: &JobParams{Width: &width, Steps: &steps}, GridParams: gridParams}
+	if err := store.Add(context.Background(), item); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	got := store.Get(context.Background(), jobID)
+	if got == nil || got.Params == nil || got.Params.Width == nil || *got.Params.Width != width {
+		t.Fatalf("Get returned %+v, want the indexed columns", got)
+	}
+	var want, have map[string]any
+	json.Unmarshal(gridParams, &want)
+	if err := json.Unmarshal(got.GridParams, &have); err != nil {
+		t.Fatalf("stored params %q: %v", got.GridParams, err)
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("params after round trip = %v, want %v", have, want)
+	}
+}
+
+func TestItemTypeRoundTripPostgres(t *testing.T) {
+	store := openTestPostgres(t)
+	wallet := "0xtype-roundtrip"
+	t.Cleanup(func() { store.DB().Exec(`DELETE FROM gallery_items WHERE wallet_address = $1`, wallet) })
+
+	store.Add(context.Background(), GalleryItem{JobID: "type-video", Prompt: "a wave", Type: "video", IsPublic: true, WalletAddress: wallet})
+	// No type sent: the media decides
+	store.Add(context.Background(), GalleryItem{JobID: "type-inferred", Prompt: "a wave", IsPublic: true, WalletAddress: wallet, MediaURLs: []string{"https://images.aipg.art/x.mp4"}})
+	store.Add(context.Background(), GalleryItem{JobID: "type-image", Prompt: "a wave", Type: "image", IsPublic: true, WalletAddress: wallet})
+
+	want := map[string]string{"type-video": TypeVideo, "type-inferred": TypeVideo, "type-image": TypeImage}
+	for jobID, itemType := range want {
+		if got := store.Get(context.Background(), jobID); got == nil || got.Type != itemType {
+			t.Errorf("Get(%s) = %+v, want type %s", jobID, got, itemType)
+		}
+	}
+
+	page, err := store.ListByWalletPage(context.Background(), wallet, 10, nil)
+	if err != nil {
+		t.Fatalf("ListByWalletPage: %v", err)
+	}
+	for _, item := range page.Items {
+		if item.Type != want[item.JobID] {
+			t.Errorf("ListByWalletPage %s type = %s, want %s", item.JobID, item.Type, want[item.JobID])
+		}
+	}
+
+	videos := store.List(context.Background(), ListOptions{Type: TypeVideo, Limit: 1000, IncludeNSFW: true})
+	found := 0
+	for _, item := range videos.Items {
+		if item.Type != TypeVideo {
+			t.Errorf("video filter returned %s (%s)", item.JobID, item.Type)
+		}
+		if item.WalletAddress == wallet {
+			found++
+		}
+	}
+	if found != 2 {
+		t.Errorf("video filter found %d of this test's videos, want 2", found)
+	}
+}
+
+func TestNSFWFlagPostgres(t *testing.T) {
+	store := openTestPostgres(t)
+	wallet := "0xnsfw-roundtrip"
+	t.Cleanup(func() { store.DB().Exec(`DELETE FROM gallery_items WHERE wallet_address = $1`, wallet) })
+
+	store.Add(context.Background(), GalleryItem{JobID: "nsfw-flagged", Prompt: "nsfw-roundtrip", IsNSFW: true, IsPublic: true, WalletAddress: wallet})
+	store.Add(context.Background(), GalleryItem{JobID: "nsfw-safe", Prompt: "nsfw-roundtrip", IsPublic: true, WalletAddress: wallet})
+
+	if got := store.Get(context.Background(), "nsfw-flagged"); got == nil || !got.IsNSFW {
+		t.Errorf("Get flagged item = %+v, want isNsfw", got)
+	}
+	page, err := store.ListByWalletPage(context.Background(), wallet, 10, nil)
+	if err != nil {
+		t.Fatalf("ListByWalletPage: %v", err)
+	}
+	for _, item := range page.Items {
+		if item.IsNSFW != (item.JobID == "nsfw-flagged") {
+			t.Errorf("ListByWalletPage %s isNsfw = %v", item.JobID, item.IsNSFW)
+		}
+	}
+
+	listed := func(includeNSFW bool) map[string]bool {
+		result := store.List(context.Background(), ListOptions{Search: "nsfw-roundtrip", Limit: 100, IncludeNSFW: includeNSFW})
+		ids := make(map[string]bool)
+		for _, item := range result.Items {
+			ids[item.JobID] = item.IsNSFW
+		}
+		return ids
+	}
+	if ids := listed(false); len(ids) != 1 || ids["nsfw-safe"] {
+		t.Errorf("List without NSFW = %v, want only nsfw-safe", ids)
+	}
+	if ids := listed(true); len(ids) != 2 || !ids["nsfw-flagged"] {
+		t.Errorf("List with NSFW = %v, want both with the flag set", ids)
+	}
+}
+
+func TestModelIDRoundTripPostgres(t *testing.T) {
+	store := openTestPostgres(t)
+	wallet := "0xmodel-id-test"
+	t.Cleanup(func() { store.DB().Exec(`DELETE FROM gallery_items WHERE wallet_address = $1`, wallet) })
+
+	store.Add(context.Background(), GalleryItem{JobID: "model-id-new", ModelID: "FLUX.1-dev", ModelName: "Flux Dev", Prompt: "p", IsPublic: true, WalletAddress: wallet})
+	// Saved before model_id existed
+	store.Add(context.Background(), GalleryItem{JobID: "model-id-legacy", ModelName: "flux1-dev-legacy-test", Prompt: "p", IsPublic: true, WalletAddress: wallet})
+	store.Add(context.Background(), GalleryItem{JobID: "model-id-unknown", ModelName: "mystery-legacy-test", Prompt: "p", IsPublic: true, WalletAddress: wallet})
+
+	if got := store.Get(context.Background(), "model-id-new"); got == nil || got.ModelID != "FLUX.1-dev" || got.ModelName != "Flux Dev" {
+		t.Errorf("Get = %+v, want separate ID and name", got)
+	}
+
+	if _, err := store.BackfillModelIDs(context.Background(), func(model string) (string, bool) {
+		return "FLUX.1-dev", model == "flux1-dev-legacy-test"
+	}); err != nil {
+		t.Fatalf("BackfillModelIDs: %v", err)
+	}
+	if got := store.Get(context.Background(), "model-id-legacy"); got == nil || got.ModelID != "FLUX.1-dev" || got.ModelName != "flux1-dev-legacy-test" {
+		t.Errorf("backfilled Get = %+v", got)
+	}
+	if got := store.Get(context.Background(), "model-id-unknown"); got == nil || got.ModelID != "mystery-legacy-test" {
+		t.Errorf("unresolved Get = %+v, want the name as ID", got)
+	}
+
+	// Filtering by preset ID finds items saved under any name
+	result := store.List(context.Background(), ListOptions{Models: []string{"flux.1-dev"}, Limit: 1000, IncludeNSFW: true})
+	found := 0
+	for _, item := range result.Items {
+		if item.WalletAddress == wallet {
+			found++
+		}
+	}
+	if found != 2 {
+		t.Errorf("model filter found %d of this test's items, want 2", found)
+	}
+}
+
+func TestSeededListPostgres(t *testing.T) {
+	store := openTestPostgres(t)
+	wallet := "0xseeded-list"
+	t.Cleanup(func() { store.DB().Exec(`DELETE FROM gallery_items WHERE wallet_address = $1`, wallet) })
+
+	for i := 0; i < 12; i++ {
+		store.Add(context.Background(), GalleryItem{JobID: fmt.Sprintf("seeded-%02d", i), Prompt: "seeded-list", IsPublic: true, WalletAddress: wallet})
+	}
+
+	list := func(offset, limit int) []string {
+		result := store.List(context.Background(), ListOptions{Search: "seeded-list", Limit: limit, Offset: offset, Seed: "abc"})
+		if result.Seed != "abc" {
+			t.Errorf("Seed = %q, want abc", result.Seed)
+		}
+		var ids []string
+		for _, item := range result.Items {
+			ids = append(ids, item.JobID)
+		}
+		return ids
+	}
+	all := list(0, 12)
+	paged := append(list(0, 5), append(list(5, 5), list(10, 5)...)...)
+	if !reflect.DeepEqual(all, paged) {
+		t.Errorf("paged order %v, want %v", paged, all)
+	}
+	// Same ordering as the file store
+	want := append([]string(nil), all...)
+	sort.Slice(want, func(i, j int) bool { return shuffleKey(want[i], "abc") < shuffleKey(want[j], "abc") })
+	if !reflect.DeepEqual(all, want) {
+		t.Errorf("order %v, want %v", all, want)
+	}
+}
+
+func TestListPagesNewestFirstPostgres(t *testing.T) {
+	store := openTestPostgres(t)
+	wallet := "0xpaged-list"
+	model := "paged-list-model"
+	t.Cleanup(func() { store.DB().Exec(`DELETE FROM gallery_items WHERE wallet_address = $1`, wallet) })
+
+	base := time.Now().Add(-time.Hour).UnixMilli()
+	var want []string
+	for i := 0; i < 12; i++ {
+		// Two items per timestamp, so ties have to be broken by job ID
+		id := fmt.Sprintf("paged-%02d", i)
+		store.Add(context.Background(), GalleryItem{JobID: id, ModelID: model, Prompt: "paged", IsPublic: true, WalletAddress: wallet, CreatedAt: base + int64(i/2)*1000})
+		want = append([]string{id}, want...)
+	}
+
+	list := func(offset, limit int) []string {
+		var ids []string
+		for _, item := range store.List(context.Background(), ListOptions{Models: []string{model}, Limit: limit, Offset: offset}).Items {
+			ids = append(ids, item.JobID)
+		}
+		return ids
+	}
+	paged := append(list(0, 5), append(list(5, 5), list(10, 5)...)...)
+	if !reflect.DeepEqual(paged, want) {
+		t.Errorf("paged order %v, want newest first %v", paged, want)
+	}
+}
+
+func TestPrunableItemsPostgres(t *testing.T) {
+	store := openTestPostgres(t)
+	wallet := "0xretention"
+	collections := NewPostgresCollectionStore(store)
+	t.Cleanup(func() {
+		store.DB().Exec(`DELETE FROM favorites WHERE wallet_address = $1`, wallet)
+		store.DB().Exec(`DELETE FROM collections WHERE wallet_address = $1`, wallet)
+		store.DB().Exec(`DELETE FROM gallery_items WHERE wallet_address = $1`, wallet)
+	})
+
+	old := time.Now().Add(-48 * time.Hour).UnixMilli()
+	for _, id := range []string{"retention-private", "retention-favorited", "retention-collected"} {
+		store.Add(context.Background(), GalleryItem{JobID: id, WalletAddress: wallet, CreatedAt: old})
+	}
+	store.Add(context.Background(), GalleryItem{JobID: "retention-public", WalletAddress: wallet, IsPublic: true, CreatedAt: old})
+	store.Add(context.Background(), GalleryItem{JobID: "retention-recent", WalletAddress: wallet})
+
+	if err := NewFavoritesStore(store.DB()).Add(wallet, "retention-favorited"); err != nil {
+		t.Fatalf("favorite: %v", err)
+	}
+	collection, err := collections.Create(context.Background(), wallet, "Keepers")
+	if err != nil {
+		t.Fatalf("Create collection: %v", err)
+	}
+	if err := collections.AddItem(context.Background(), collection.ID, "retention-collected"); err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	items, err := store.PrunableItems(context.Background(), time.Now().Add(-24*time.Hour), 1000)
+	if err != nil {
+		t.Fatalf("PrunableItems: %v", err)
+	}
+	var ours []string
+	for _, item := range items {
+		if item.WalletAddress == wallet {
+			ours = append(ours, item.JobID)
+		}
+	}
+	if len(ours) != 1 || ours[0] != "retention-private" {
+		t.Errorf("prunable = %v, want only retention-private", ours)
+	}
+}
+
+func TestListSearchFieldsPostgres(t *testing.T) {
+	store := openTestPostgres(t)
+	for _, item := range searchFixtures {
+		item.JobID = "search-fields-" + item.JobID
+		store.Add(context.Background(), item)
+	}
+	t.Cleanup(func() { store.DB().Exec(`DELETE FROM gallery_items WHERE wallet_address = $1`, searchWallet) })
+
+	tests := []struct {
+		fields []string
+		want   string
+	}{
+		{fields: nil, want: "search-fields-in-prompt"},
+		{fields: []string{SearchNegativePrompt}, want: "search-fields-in-negative"},
+		{fields: []string{SearchModel}, want: "search-fields-in-model-id,search-fields-in-model-name"},
+		{fields: []string{SearchPrompt, SearchNegativePrompt}, want: "search-fields-in-negative,search-fields-in-prompt"},
+	}
+	for _, tc := range tests {
+		if got := searchIDs(store, "heron", tc.fields); got != tc.want {
+			t.Errorf("search fields %v = [%s], want [%s]", tc.fields, got, tc.want)
+		}
+	}
+}
+
+func TestFullTextSearchPostgres(t *testing.T) {
+	store := openTestPostgres(t)
+	wallet := "0xfull-text-search"
+	t.Cleanup(func() { store.DB().Exec(`DELETE FROM gallery_items WHERE wallet_address = $1`, wallet) })
+
+	store.Add(context.Background(), GalleryItem{JobID: "fts-once", Prompt: "a kestrel on a fence", IsPublic: true, WalletAddress: wallet, CreatedAt: 3})
+	store.Add(context.Background(), GalleryItem{JobID: "fts-thrice", Prompt: "kestrels hunting, kestrel wings, kestrel eyes", IsPublic: true, WalletAddress: wallet, CreatedAt: 1})
+	store.Add(context.Background(), GalleryItem{JobID: "fts-twice", Prompt: "kestrel and kestrel chicks", IsPublic: true, WalletAddress: wallet, CreatedAt: 2})
+	store.Add(context.Background(), GalleryItem{JobID: "fts-other", Prompt: "a sparrow", NegativePrompt: "kestrel", IsPublic: true, WalletAddress: wallet})
+
+	search := func(q string) []string {
+		t.Helper()
+		var ids []string
+		for _, item := range store.List(context.Background(), ListOptions{Search: q, Limit: 1000}).Items {
+			if item.WalletAddress == wallet {
+				ids = append(ids, item.JobID)
+			}
+		}
+		return ids
+	}
+
+	// Most mentions first, whatever the save order; prefixes and plurals match
+	if got, want := search("Kestrel"), []string{"fts-thrice", "fts-twice", "fts-once"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ranked search = %v, want %v", got, want)
+	}
+	if got, want := search("kest fence"), []string{"fts-once"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("every word must match: got %v, want %v", got, want)
+	}
+
+	// The trigger keeps the index current through owner edits
+	prompt := "a wren"
+	if err := store.Update(context.Background(), "fts-other", ItemUpdate{Prompt: &prompt}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got := search("wren"); !reflect.DeepEqual(got, []string{"fts-other"}) {
+		t.Errorf("search after edit = %v, want the edited item", got)
+	}
+}
+
+func TestFeaturedPostgres(t *testing.T) {
+	store := openTestPostgres(t)
+	wallet := "0xfeatured-test"
+	t.Cleanup(func() { store.DB().Exec(`DELETE FROM gallery_items WHERE wallet_address = $1`, wallet) })
+
+	for _, id := range []string{"featured-a", "featured-b", "featured-c"} {
+		store.Add(context.Background(), GalleryItem{JobID: id, Prompt: "p", IsPublic: true, WalletAddress: wallet})
+	}
+	for _, id := range []string{"featured-a", "featured-b", "featured-c"} {
+		if err := store.SetFeatured(context.Background(), id, true); err != nil {
+			t.Fatalf("SetFeatured(%s): %v", id, err)
+		}
+		// featured_at orders the list; keep each pick distinct
+		time.Sleep(2 * time.Millisecond)
+	}
+	store.SetFeatured(context.Background(), "featured-b", false)
+	store.SetFeatured(context.Background(), "featured-a", true)
+
+	var ids []string
+	for _, item := range store.List(context.Background(), ListOptions{Featured: true, Limit: 1000}).Items {
+		if item.WalletAddress != wallet {
+			continue
+		}
+		if !item.Featured || item.FeaturedAt == 0 {
+			t.Errorf("listed item %+v, want featured with a timestamp", item)
+		}
+		ids = append(ids, item.JobID)
+	}
+	if want := []string{"featured-a", "featured-c"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("featured = %v, want %v", ids, want)
+	}
+
+	if err := store.SetFeatured(context.Background(), "featured-missing", true); err != ErrItemNotFound {
+		t.Errorf("SetFeatured missing = %v, want ErrItemNotFound", err)
+	}
+}
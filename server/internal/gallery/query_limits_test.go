@@ -0,0 +1,87 @@
+package gallery
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+)
+
+// blockingDriver is a database/sql driver whose queries hang until their
+// context is done, standing in for a pathological ORDER BY RANDOM() scan.
+type blockingDriver struct{}
+
+func (blockingDriver) Open(string) (driver.Conn, error) { return blockingConn{}, nil }
+
+type blockingConn struct{}
+
+func (blockingConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (blockingConn) Close() error                        { return nil }
+func (blockingConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (blockingConn) QueryContext(ctx context.Context, _ string, _ []driver.NamedValue) (driver.Rows, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (blockingConn) ExecContext(ctx context.Context, _ string, _ []driver.NamedValue) (driver.Result, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func init() {
+	sql.Register("gallery-blocking", blockingDriver{})
+}
+
+func TestQueryTimeoutReturnsPromptly(t *testing.T) {
+	db, err := sql.Open("gallery-blocking", "")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+	store.SetQueryLimits(50*time.Millisecond, 10*time.Millisecond)
+
+	start := time.Now()
+	result := store.List(context.Background(), ListOptions{Limit: 10, Search: "cat"})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("List took %s, want it cut off by the 50ms query timeout", elapsed)
+	}
+	if len(result.Items) != 0 {
+		t.Errorf("List returned %d items from a timed-out query", len(result.Items))
+	}
+
+	start = time.Now()
+	if err := store.Delete(context.Background(), "job-1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Delete error = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Delete took %s", elapsed)
+	}
+}
+
+func TestCancelledRequestStopsQuery(t *testing.T) {
+	db, err := sql.Open("gallery-blocking", "")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+
+	// The query timeout is far off; the caller giving up is what stops the query
+	store := &PostgresStore{db: db}
+	store.SetQueryLimits(time.Minute, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	if err := store.Delete(ctx, "job-1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Delete error = %v, want canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Delete took %s, want it stopped when the request was cancelled", elapsed)
+	}
+}
@@ -0,0 +1,135 @@
+package gallery
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Fields a gallery search can cover. Searches cover the prompt alone unless
+// asked for more, since every extra field is another column to scan.
+const (
+	SearchPrompt         = "prompt"
+	SearchNegativePrompt = "negativePrompt"
+	SearchModel          = "model"
+)
+
+// searchColumns are the gallery_items columns behind each search field
+var searchColumns = map[string][]string{
+	SearchPrompt:         {"prompt"},
+	SearchNegativePrompt: {"negative_prompt"},
+	SearchModel:          {"model", "model_id"},
+}
+
+// ParseSearchFields reads a comma-separated list of search fields, matched
+// case-insensitively. Empty input means the prompt alone.
+func ParseSearchFields(raw string) ([]string, error) {
+	var fields []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field, ok := lookupSearchField(part)
+		if !ok {
+			return nil, fmt.Errorf("searchFields must be a list of %s, %s or %s; got %q", SearchPrompt, SearchNegativePrompt, SearchModel, part)
+		}
+		if !seen[field] {
+			seen[field] = true
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return []string{SearchPrompt}, nil
+	}
+	return fields, nil
+}
+
+func lookupSearchField(name string) (string, bool) {
+	for field := range searchColumns {
+		if strings.EqualFold(name, field) {
+			return field, true
+		}
+	}
+	return "", false
+}
+
+// searchFieldsOrDefault is fields, or the prompt alone when none are given
+func searchFieldsOrDefault(fields []string) []string {
+	if len(fields) == 0 {
+		return []string{SearchPrompt}
+	}
+	return fields
+}
+
+// matchesSearch reports whether any of the item's searched fields contains
+// searchLower, which must already be lowercase
+func matchesSearch(item GalleryItem, searchLower string, fields []string) bool {
+	for _, field := range searchFieldsOrDefault(fields) {
+		var texts []string
+		switch field {
+		case SearchPrompt:
+			texts = []string{item.Prompt}
+		case SearchNegativePrompt:
+			texts = []string{item.NegativePrompt}
+		case SearchModel:
+			texts = []string{item.ModelName, item.ModelID}
+		}
+		for _, text := range texts {
+			if strings.Contains(strings.ToLower(text), searchLower) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// searchConfig is the text search configuration prompts are indexed with
+const searchConfig = "english"
+
+// promptTSQuery turns a search into a to_tsquery expression that needs every
+// word, each as a prefix, the way the regex search matched from word starts.
+// It is empty when the search has no words to look for.
+func promptTSQuery(search string) string {
+	words := strings.FieldsFunc(strings.ToLower(search), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// searchClause is the SQL condition for a search across fields, with the
+// arguments it binds from $argNum on. The prompt is matched through its
+// full-text index, and rank scores that match for ordering; rank is empty
+// when the prompt isn't searched. Other fields, and prompts searched for
+// nothing but punctuation, fall back to a word-boundary regex.
+func searchClause(search string, fields []string, argNum int) (where string, args []interface{}, rank string) {
+	var clauses []string
+	regexArg := 0
+	for _, field := range searchFieldsOrDefault(fields) {
+		if field == SearchPrompt {
+			if query := promptTSQuery(search); query != "" {
+				tsquery := fmt.Sprintf("to_tsquery('%s', $%d)", searchConfig, argNum+len(args))
+				args = append(args, query)
+				clauses = append(clauses, "prompt_tsv @@ "+tsquery)
+				rank = fmt.Sprintf("ts_rank(prompt_tsv, %s)", tsquery)
+				continue
+			}
+		}
+		if regexArg == 0 {
+			regexArg = argNum + len(args)
+			args = append(args, fmt.Sprintf("\\m%s", strings.ToLower(search)))
+		}
+		for _, column := range searchColumns[field] {
+			clauses = append(clauses, fmt.Sprintf("%s ~* $%d", column, regexArg))
+		}
+	}
+	where = clauses[0]
+	if len(clauses) > 1 {
+		where = "(" + strings.Join(clauses, " OR ") + ")"
+	}
+	return where, args, rank
+}
@@ -0,0 +1,134 @@
+package gallery
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const searchWallet = "0xsearch-fields"
+
+// searchFixtures share a search term ("heron") across different fields
+var searchFixtures = []GalleryItem{
+	{JobID: "in-prompt", Prompt: "a heron at dawn", ModelName: "Flux Dev", ModelID: "FLUX.1-dev", WalletAddress: searchWallet, IsPublic: true},
+	{JobID: "in-negative", Prompt: "a lake", NegativePrompt: "heron, birds", ModelName: "Flux Dev", ModelID: "FLUX.1-dev", WalletAddress: searchWallet, IsPublic: true},
+	{JobID: "in-model-name", Prompt: "a lake", ModelName: "heron-xl", ModelID: "SDXL", WalletAddress: searchWallet, IsPublic: true},
+	{JobID: "in-model-id", Prompt: "a lake", ModelName: "Heron Turbo", ModelID: "heron-turbo", WalletAddress: searchWallet, IsPublic: true},
+	{JobID: "nowhere", Prompt: "a lake", ModelName: "Chroma", ModelID: "Chroma", WalletAddress: searchWallet, IsPublic: true},
+}
+
+func searchIDs(store GalleryStore, search string, fields []string) string {
+	result := store.List(context.Background(), ListOptions{Search: search, SearchFields: fields, Limit: 1000})
+	var ids []string
+	for _, item := range result.Items {
+		if item.WalletAddress == searchWallet {
+			ids = append(ids, item.JobID)
+		}
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ",")
+}
+
+func TestListSearchFields(t *testing.T) {
+	store := &FileStoreAdapter{Store: NewStore("", 100)}
+	for _, item := range searchFixtures {
+		store.Add(context.Background(), item)
+	}
+
+	tests := []struct {
+		fields []string
+		want   string
+	}{
+		{fields: nil, want: "in-prompt"},
+		{fields: []string{SearchPrompt}, want: "in-prompt"},
+		{fields: []string{SearchNegativePrompt}, want: "in-negative"},
+		{fields: []string{SearchModel}, want: "in-model-id,in-model-name"},
+		{fields: []string{SearchPrompt, SearchNegativePrompt, SearchModel}, want: "in-model-id,in-model-name,in-negative,in-prompt"},
+	}
+	for _, tc := range tests {
+		if got := searchIDs(store, "heron", tc.fields); got != tc.want {
+			t.Errorf("search fields %v = [%s], want [%s]", tc.fields, got, tc.want)
+		}
+	}
+}
+
+func TestParseSearchFields(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want []string
+	}{
+		{raw: "", want: []string{SearchPrompt}},
+		{raw: " , ", want: []string{SearchPrompt}},
+		{raw: "model", want: []string{SearchModel}},
+		{raw: "prompt, NegativePrompt,model,prompt", want: []string{SearchPrompt, SearchNegativePrompt, SearchModel}},
+	}
+	for _, tc := range tests {
+		got, err := ParseSearchFields(tc.raw)
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ParseSearchFields(%q) = %v, %v; want %v", tc.raw, got, err, tc.want)
+		}
+	}
+	if _, err := ParseSearchFields("prompt,wallet"); err == nil || !strings.Contains(err.Error(), `"wallet"`) {
+		t.Errorf("unknown field err = %v, want it named", err)
+	}
+}
+
+func TestPromptTSQuery(t *testing.T) {
+	tests := map[string]string{
+		"Red Fox":            "red:* & fox:*",
+		"  neon, city! ":     "neon:* & city:*",
+		"it's 8k":            "it:* & s:* & 8k:*",
+		"café":               "café:*",
+		"') | !(":            "",
+		"a:* | b & !c <-> d": "a:* & b:* & c:* & d:*",
+	}
+	for search, want := range tests {
+		if got := promptTSQuery(search); got != want {
+			t.Errorf("promptTSQuery(%q) = %q, want %q", search, got, want)
+		}
+	}
+}
+
+func TestSearchClause(t *testing.T) {
+	tests := []struct {
+		search    string
+		fields    []string
+		wantWhere string
+		wantArgs  []interface{}
+		wantRank  string
+	}{
+		{
+			search:    "Red Fox",
+			wantWhere: "prompt_tsv @@ to_tsquery('english', $2)",
+			wantArgs:  []interface{}{"red:* & fox:*"},
+			wantRank:  "ts_rank(prompt_tsv, to_tsquery('english', $2))",
+		},
+		{
+			search:    "fox",
+			fields:    []string{SearchNegativePrompt, SearchModel},
+			wantWhere: "(negative_prompt ~* $2 OR model ~* $2 OR model_id ~* $2)",
+			wantArgs:  []interface{}{`\mfox`},
+		},
+		{
+			search:    "fox",
+			fields:    []string{SearchModel, SearchPrompt},
+			wantWhere: "(model ~* $2 OR model_id ~* $2 OR prompt_tsv @@ to_tsquery('english', $3))",
+			wantArgs:  []interface{}{`\mfox`, "fox:*"},
+			wantRank:  "ts_rank(prompt_tsv, to_tsquery('english', $3))",
+		},
+		{
+			// Nothing for full-text search to look for
+			search:    "!!",
+			wantWhere: "prompt ~* $2",
+			wantArgs:  []interface{}{`\m!!`},
+		},
+	}
+	for _, tc := range tests {
+		where, args, rank := searchClause(tc.search, tc.fields, 2)
+		if where != tc.wantWhere || !reflect.DeepEqual(args, tc.wantArgs) || rank != tc.wantRank {
+			t.Errorf("searchClause(%q, %v) = %q %v %q, want %q %v %q", tc.search, tc.fields, where, args, rank, tc.wantWhere, tc.wantArgs, tc.wantRank)
+		}
+	}
+}
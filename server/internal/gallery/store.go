@@ -1,8 +1,12 @@
 package gallery
 
 import (
+	"crypto/md5"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -22,6 +26,9 @@ type JobParams struct {
 	Fps        *int     `json:"fps,omitempty"`
 	Tiling     *bool    `json:"tiling,omitempty"`
 	HiresFix   *bool    `json:"hiresFix,omitempty"`
+	ClipSkip   *int     `json:"clipSkip,omitempty"`
+	// Post-processors applied to the output (e.g. upscalers, face fixers)
+	PostProcessing []string `json:"postProcessing,omitempty"`
 }
 
 // GalleryItem represents a generation (can be public or private)
@@ -36,13 +43,72 @@ type GalleryItem struct {
 	IsPublic       bool     `json:"isPublic"`
 	WalletAddress  string   `json:"walletAddress,omitempty"`
 	CreatedAt      int64    `json:"createdAt"`
-	// GenerationIDs are the R2 object keys for the generated media
-	// Format: {procgen_id}.webp for images, {procgen_id}.mp4 for videos
+	// GenerationIDs name the R2 objects for the generated media; the keys
+	// themselves come from r2.MediaFilename
 	GenerationIDs  []string `json:"generationIds,omitempty"`
 	// MediaURLs are the cached R2 URLs (may be expired)
 	MediaURLs      []string `json:"mediaUrls,omitempty"`
 	// Parameters used to create this generation
 	Params         *JobParams `json:"params,omitempty"`
+	// GridParams is the complete params map the job was submitted to the Grid with
+	GridParams     json.RawMessage `json:"gridParams,omitempty"`
+	// EditedAt is set when the owner last corrected the item's metadata
+	EditedAt       int64    `json:"editedAt,omitempty"`
+	// Featured items are hand-picked by an admin for the homepage showcase;
+	// FeaturedAt is when, in Unix milliseconds
+	Featured       bool     `json:"featured,omitempty"`
+	FeaturedAt     int64    `json:"featuredAt,omitempty"`
+}
+
+// Item types
+const (
+	TypeImage = "image"
+	TypeVideo = "video"
+)
+
+// videoExtensions mark media that makes an item a video when its type is missing
+var videoExtensions = []string{".mp4", ".webm", ".mov"}
+
+// ItemType returns the item's type as image or video. Type comes from the
+// client, so when it's missing or unrecognised the media decides, and items
+// without video media are images.
+func ItemType(item GalleryItem) string {
+	switch strings.ToLower(strings.TrimSpace(item.Type)) {
+	case TypeImage:
+		return TypeImage
+	case TypeVideo:
+		return TypeVideo
+	}
+	for _, ref := range append(append([]string(nil), item.GenerationIDs...), item.MediaURLs...) {
+		ref = strings.ToLower(ref)
+		if i := strings.IndexAny(ref, "?#"); i >= 0 {
+			ref = ref[:i]
+		}
+		for _, ext := range videoExtensions {
+			if strings.HasSuffix(ref, ext) {
+				return TypeVideo
+			}
+		}
+	}
+	return TypeImage
+}
+
+// ErrItemNotFound is returned when a gallery item doesn't exist
+var ErrItemNotFound = errors.New("gallery item not found")
+
+// ErrNotOwner is returned when re-adding an item saved by another wallet
+var ErrNotOwner = errors.New("gallery item belongs to another wallet")
+
+// ErrWalletRequired is returned by wallet listings given a blank wallet.
+// Items from anonymous generations have no wallet, so matching on an empty
+// one would list all of them.
+var ErrWalletRequired = errors.New("wallet address is required")
+
+// ItemUpdate holds the owner-editable fields of a gallery item.
+// Nil fields are left unchanged; media, params and seed are immutable.
+type ItemUpdate struct {
+	Prompt *string
+	IsNSFW *bool
 }
 
 // Store manages the public gallery
@@ -51,14 +117,24 @@ type Store struct {
 	items    []GalleryItem
 	filePath string
 	maxItems int
+	// version counts changes to items, under mu
+	version uint64
+
+	// saveMu serializes file writes, which happen outside mu so adds never
+	// wait on disk; saved is the version last written, under saveMu
+	saveMu sync.Mutex
+	saved  uint64
+	// writeFile is os.WriteFile, swappable in tests
+	writeFile func(name string, data []byte, perm os.FileMode) error
 }
 
 // NewStore creates a new gallery store
 func NewStore(filePath string, maxItems int) *Store {
 	s := &Store{
-		items:    make([]GalleryItem, 0),
-		filePath: filePath,
-		maxItems: maxItems,
+		items:     make([]GalleryItem, 0),
+		filePath:  filePath,
+		maxItems:  maxItems,
+		writeFile: os.WriteFile,
 	}
 	
 	// Load existing data
@@ -68,14 +144,27 @@ func NewStore(filePath string, maxItems int) *Store {
 }
 
 // Add adds a new item to the gallery
-func (s *Store) Add(item GalleryItem) {
+func (s *Store) Add(item GalleryItem) error {
+	// Runs after the unlock below, so the write doesn't hold up other callers
+	defer s.persist()
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	
-	// Check for duplicate
-	for _, existing := range s.items {
-		if existing.JobID == item.JobID {
-			return // Already exists
+	// A re-add by the same wallet updates the fields that can change after
+	// saving, like the postgres upsert; who made it, with what and when stay
+	// as first saved, and media is only replaced with more media
+	for i := range s.items {
+		if s.items[i].JobID == item.JobID {
+			if !strings.EqualFold(s.items[i].WalletAddress, item.WalletAddress) {
+				return ErrNotOwner
+			}
+			s.items[i].IsPublic = item.IsPublic
+			s.items[i].IsNSFW = item.IsNSFW
+			if len(item.MediaURLs) > 0 {
+				s.items[i].MediaURLs = item.MediaURLs
+			}
+			s.version++
+			return nil
 		}
 	}
 	
@@ -83,6 +172,7 @@ func (s *Store) Add(item GalleryItem) {
 	if item.CreatedAt == 0 {
 		item.CreatedAt = time.Now().UnixMilli()
 	}
+	item.Type = ItemType(item)
 	
 	// Prepend (newest first)
 	s.items = append([]GalleryItem{item}, s.items...)
@@ -92,8 +182,8 @@ func (s *Store) Add(item GalleryItem) {
 		s.items = s.items[:s.maxItems]
 	}
 	
-	// Persist
-	s.save()
+	s.version++
+	return nil
 }
 
 // ListResult contains paginated gallery items
@@ -102,21 +192,63 @@ type ListResult struct {
 	Total      int           `json:"total"`
 	HasMore    bool          `json:"hasMore"`
 	NextOffset int           `json:"nextOffset"`
+	// Shuffle seed the page was ordered by, to pass back for the next page
+	Seed       string        `json:"seed,omitempty"`
+}
+
+// ModelCount is how many public items were made with one model
+type ModelCount struct {
+	Model string `json:"model"`
+	Count int    `json:"count"`
+}
+
+// shuffleKey orders items in a seeded shuffle. It's the hex MD5 of job ID
+// plus seed, the same as md5(job_id || seed) in Postgres, so both stores
+// shuffle alike.
+func shuffleKey(jobID, seed string) string {
+	sum := md5.Sum([]byte(jobID + seed))
+	return hex.EncodeToString(sum[:])
+}
+
+// ListOptions filters and paginates a public gallery listing
+type ListOptions struct {
+	Type        string // "image", "video", or "" / "all" for both
+	Limit       int
+	Offset      int
+	Search      string
+	// SearchFields are the fields Search looks in (SearchPrompt,
+	// SearchNegativePrompt, SearchModel); empty for the prompt alone
+	SearchFields []string
+	IncludeNSFW bool
+	// Models keeps only items made with one of these model names (case-insensitive); empty for all
+	Models      []string
+	// Seed orders items by a shuffle that stays the same for the same seed, so
+	// a shuffled feed can be paged; empty keeps the store's default order
+	Seed        string
+	// Featured keeps only featured items, most recently featured first
+	// unless Seed is set
+	Featured    bool
 }
 
 // List returns public gallery items, optionally filtered by type and search, with pagination
-func (s *Store) List(typeFilter string, limit int, offset int, searchQuery string) ListResult {
+func (s *Store) List(opts ListOptions) ListResult {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	
+	limit := opts.Limit
 	if limit <= 0 {
 		limit = 25
 	}
+	offset := opts.Offset
 	if offset < 0 {
 		offset = 0
 	}
 	
-	searchLower := strings.ToLower(searchQuery)
+	searchLower := strings.ToLower(opts.Search)
+	models := make(map[string]bool, len(opts.Models))
+	for _, m := range opts.Models {
+		models[strings.ToLower(m)] = true
+	}
 	
 	// First, collect all matching items to get total count
 	allMatching := make([]GalleryItem, 0)
@@ -126,19 +258,43 @@ func (s *Store) List(typeFilter string, limit int, offset int, searchQuery strin
 			continue
 		}
 		
+		// Hide NSFW items unless explicitly requested
+		if item.IsNSFW && !opts.IncludeNSFW {
+			continue
+		}
+		
 		// Apply type filter
-		if typeFilter != "" && typeFilter != "all" && item.Type != typeFilter {
+		if opts.Type != "" && opts.Type != "all" && ItemType(item) != opts.Type {
 			continue
 		}
 		
 		// Apply search filter
-		if searchQuery != "" && !strings.Contains(strings.ToLower(item.Prompt), searchLower) {
+		if opts.Search != "" && !matchesSearch(item, searchLower, opts.SearchFields) {
+			continue
+		}
+		
+		// Apply model filter against both the ID and the name the item was saved with
+		if len(models) > 0 && !models[strings.ToLower(item.ModelID)] && !models[strings.ToLower(item.ModelName)] {
+			continue
+		}
+		
+		if opts.Featured && !item.Featured {
 			continue
 		}
 		
 		allMatching = append(allMatching, item)
 	}
 	
+	if opts.Seed != "" {
+		sort.SliceStable(allMatching, func(i, j int) bool {
+			return shuffleKey(allMatching[i].JobID, opts.Seed) < shuffleKey(allMatching[j].JobID, opts.Seed)
+		})
+	} else if opts.Featured {
+		sort.SliceStable(allMatching, func(i, j int) bool {
+			return allMatching[i].FeaturedAt > allMatching[j].FeaturedAt
+		})
+	}
+	
 	total := len(allMatching)
 	
 	// Apply offset
@@ -148,6 +304,7 @@ func (s *Store) List(typeFilter string, limit int, offset int, searchQuery strin
 			Total:      total,
 			HasMore:    false,
 			NextOffset: offset,
+			Seed:       opts.Seed,
 		}
 	}
 	
@@ -164,6 +321,7 @@ func (s *Store) List(typeFilter string, limit int, offset int, searchQuery strin
 		Total:      total,
 		HasMore:    end < total,
 		NextOffset: end,
+		Seed:       opts.Seed,
 	}
 }
 
@@ -172,13 +330,12 @@ func (s *Store) ListByWallet(walletAddress string, limit int) []GalleryItem {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	
+	// Normalize wallet address (lowercase)
+	walletAddress = strings.ToLower(strings.TrimSpace(walletAddress))
 	if walletAddress == "" {
 		return []GalleryItem{}
 	}
 	
-	// Normalize wallet address (lowercase)
-	walletAddress = strings.ToLower(walletAddress)
-	
 	if limit <= 0 {
 		limit = len(s.items)
 	}
@@ -196,15 +353,59 @@ func (s *Store) ListByWallet(walletAddress string, limit int) []GalleryItem {
 	return result
 }
 
+// ListByWalletPage returns up to limit of a wallet's items older than the
+// cursor (nil for the first page), newest first, plus the cursor for the next page
+func (s *Store) ListByWalletPage(walletAddress string, limit int, before *WalletCursor) (WalletPage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	page := WalletPage{Items: []GalleryItem{}}
+	walletAddress = strings.ToLower(strings.TrimSpace(walletAddress))
+	if walletAddress == "" {
+		return page, ErrWalletRequired
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+	
+	matching := make([]GalleryItem, 0)
+	for _, item := range s.items {
+		if strings.ToLower(item.WalletAddress) != walletAddress {
+			continue
+		}
+		if before != nil && !before.after(time.UnixMilli(item.CreatedAt), item.JobID) {
+			continue
+		}
+		matching = append(matching, item)
+	}
+	
+	sort.SliceStable(matching, func(i, j int) bool {
+		if matching[i].CreatedAt != matching[j].CreatedAt {
+			return matching[i].CreatedAt > matching[j].CreatedAt
+		}
+		return matching[i].JobID > matching[j].JobID
+	})
+	
+	if len(matching) > limit {
+		last := matching[limit-1]
+		page.NextCursor = WalletCursor{CreatedAt: time.UnixMilli(last.CreatedAt), JobID: last.JobID}.String()
+		matching = matching[:limit]
+	}
+	page.Items = matching
+	return page, nil
+}
+
 // Remove removes an item by job ID (for moderation)
 func (s *Store) Remove(jobID string) bool {
+	// Runs after the unlock below, so the write doesn't hold up other callers
+	defer s.persist()
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	
 	for i, item := range s.items {
 		if item.JobID == jobID {
 			s.items = append(s.items[:i], s.items[i+1:]...)
-			s.save()
+			s.version++
 			return true
 		}
 	}
@@ -212,6 +413,27 @@ func (s *Store) Remove(jobID string) bool {
 	return false
 }
 
+// PrunableItems returns up to limit private items created before cutoff,
+// oldest first. The file store has no favorites or collections to protect.
+func (s *Store) PrunableItems(cutoff time.Time, limit int) []GalleryItem {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	old := make([]GalleryItem, 0)
+	for _, item := range s.items {
+		if !item.IsPublic && item.CreatedAt < cutoff.UnixMilli() {
+			old = append(old, item)
+		}
+	}
+	sort.SliceStable(old, func(i, j int) bool {
+		return old[i].CreatedAt < old[j].CreatedAt
+	})
+	if limit > 0 && len(old) > limit {
+		old = old[:limit]
+	}
+	return old
+}
+
 // Delete removes an item by job ID (implements GalleryStore interface)
 func (s *Store) Delete(jobID string) error {
 	if s.Remove(jobID) {
@@ -220,6 +442,14 @@ func (s *Store) Delete(jobID string) error {
 	return nil // Item not found is not an error
 }
 
+// Count returns how many items the store holds, private ones included, like
+// the Postgres store's Count
+func (s *Store) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.items)
+}
+
 // Get returns a single item by job ID
 func (s *Store) Get(jobID string) *GalleryItem {
 	s.mu.RLock()
@@ -236,6 +466,8 @@ func (s *Store) Get(jobID string) *GalleryItem {
 
 // UpdateGenerations updates the generation IDs and media URLs for an item
 func (s *Store) UpdateGenerations(jobID string, generationIDs []string, mediaURLs []string) bool {
+	// Runs after the unlock below, so the write doesn't hold up other callers
+	defer s.persist()
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	
@@ -243,13 +475,91 @@ func (s *Store) UpdateGenerations(jobID string, generationIDs []string, mediaURL
 		if s.items[i].JobID == jobID {
 			s.items[i].GenerationIDs = generationIDs
 			s.items[i].MediaURLs = mediaURLs
-			s.save()
+			s.version++
 			return true
 		}
 	}
 	return false
 }
 
+// ModelCounts returns the models used by public items, most used first,
+// leaving out NSFW items unless includeNSFW is set
+func (s *Store) ModelCounts(includeNSFW bool) []ModelCount {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	counts := make(map[string]int)
+	for _, item := range s.items {
+		if !item.IsPublic || (item.IsNSFW && !includeNSFW) {
+			continue
+		}
+		model := item.ModelName
+		if model == "" {
+			model = item.ModelID
+		}
+		if model != "" {
+			counts[model]++
+		}
+	}
+	
+	result := make([]ModelCount, 0, len(counts))
+	for model, count := range counts {
+		result = append(result, ModelCount{Model: model, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Model < result[j].Model
+	})
+	return result
+}
+
+// Update applies an owner edit to an item and stamps EditedAt
+func (s *Store) Update(jobID string, update ItemUpdate) error {
+	// Runs after the unlock below, so the write doesn't hold up other callers
+	defer s.persist()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	for i := range s.items {
+		if s.items[i].JobID == jobID {
+			if update.Prompt != nil {
+				s.items[i].Prompt = *update.Prompt
+			}
+			if update.IsNSFW != nil {
+				s.items[i].IsNSFW = *update.IsNSFW
+			}
+			s.items[i].EditedAt = time.Now().UnixMilli()
+			s.version++
+			return nil
+		}
+	}
+	return ErrItemNotFound
+}
+
+// SetFeatured features or unfeatures an item. Featuring stamps FeaturedAt,
+// so featuring an item again moves it to the front of the featured list.
+func (s *Store) SetFeatured(jobID string, featured bool) error {
+	// Runs after the unlock below, so the write doesn't hold up other callers
+	defer s.persist()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	for i := range s.items {
+		if s.items[i].JobID == jobID {
+			s.items[i].Featured = featured
+			s.items[i].FeaturedAt = 0
+			if featured {
+				s.items[i].FeaturedAt = time.Now().UnixMilli()
+			}
+			s.version++
+			return nil
+		}
+	}
+	return ErrItemNotFound
+}
+
 func (s *Store) load() {
 	if s.filePath == "" {
 		return
@@ -268,16 +578,38 @@ func (s *Store) load() {
 	s.items = items
 }
 
-func (s *Store) save() {
+// persist writes the items to disk if they changed since the last write. The
+// items are copied under a read lock and written without it; writers queue on
+// saveMu, and whichever goes first writes everything changed so far, so the
+// rest find nothing left to do and no change is lost.
+func (s *Store) persist() {
 	if s.filePath == "" {
 		return
 	}
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
 	
-	data, err := json.MarshalIndent(s.items, "", "  ")
-	if err != nil {
+	s.mu.RLock()
+	version := s.version
+	if version == s.saved {
+		s.mu.RUnlock()
 		return
 	}
+	snapshot := append([]GalleryItem(nil), s.items...)
+	s.mu.RUnlock()
 	
-	os.WriteFile(s.filePath, data, 0644)
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return
+	}
+	// Write then rename, so a crash mid-write can't leave a truncated file
+	tmp := s.filePath + ".tmp"
+	if err := s.writeFile(tmp, data, 0644); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, s.filePath); err != nil {
+		return
+	}
+	s.saved = version
 }
 
@@ -0,0 +1,306 @@
+package gallery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestListByWalletPageStableUnderInserts(t *testing.T) {
+	store := NewStore("", 100)
+	for i := 1; i <= 7; i++ {
+		store.Add(GalleryItem{
+			JobID:         fmt.Sprintf("job-%02d", i),
+			WalletAddress: "0xOwner",
+			CreatedAt:     int64(1000 + i/2), // pairs share a timestamp to exercise the job_id tiebreak
+		})
+	}
+	store.Add(GalleryItem{JobID: "other", WalletAddress: "0xother", CreatedAt: 1002})
+
+	seen := make(map[string]int)
+	var before *WalletCursor
+	for pageNum := 0; ; pageNum++ {
+		page, err := store.ListByWalletPage("0xowner", 3, before)
+		if err != nil {
+			t.Fatalf("ListByWalletPage: %v", err)
+		}
+		for _, item := range page.Items {
+			seen[item.JobID]++
+		}
+
+		// New items land at the front of the list mid-pagination
+		if pageNum == 0 {
+			store.Add(GalleryItem{JobID: "job-new-1", WalletAddress: "0xowner", CreatedAt: 2000})
+			store.Add(GalleryItem{JobID: "job-new-2", WalletAddress: "0xowner", CreatedAt: 1003})
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+		before, err = ParseWalletCursor(page.NextCursor)
+		if err != nil {
+			t.Fatalf("ParseWalletCursor: %v", err)
+		}
+	}
+
+	for i := 1; i <= 7; i++ {
+		id := fmt.Sprintf("job-%02d", i)
+		if seen[id] != 1 {
+			t.Errorf("%s seen %d times, want exactly once", id, seen[id])
+		}
+	}
+	for _, id := range []string{"job-new-1", "job-new-2", "other"} {
+		if seen[id] != 0 {
+			t.Errorf("%s should not appear in pages that started before it was added", id)
+		}
+	}
+}
+
+func TestParseWalletCursorRejectsGarbage(t *testing.T) {
+	for _, token := range []string{"!!!", "bm9waXBl", "MTIzfA"} {
+		if _, err := ParseWalletCursor(token); err != ErrInvalidCursor {
+			t.Errorf("ParseWalletCursor(%q) = %v, want ErrInvalidCursor", token, err)
+		}
+	}
+}
+
+func TestItemType(t *testing.T) {
+	tests := []struct {
+		item GalleryItem
+		want string
+	}{
+		{GalleryItem{Type: "video"}, TypeVideo},
+		{GalleryItem{Type: " Image "}, TypeImage},
+		{GalleryItem{}, TypeImage},
+		{GalleryItem{MediaURLs: []string{"https://images.aipg.art/a.webp"}}, TypeImage},
+		{GalleryItem{MediaURLs: []string{"https://images.aipg.art/a.MP4?X-Amz-Expires=3600"}}, TypeVideo},
+		{GalleryItem{Type: "clip", GenerationIDs: []string{"gen-1.webm"}}, TypeVideo},
+	}
+	for _, tc := range tests {
+		if got := ItemType(tc.item); got != tc.want {
+			t.Errorf("ItemType(%+v) = %s, want %s", tc.item, got, tc.want)
+		}
+	}
+}
+
+func TestAddDuplicateUpdatesMutableFields(t *testing.T) {
+	store := NewStore("", 100)
+	store.Add(GalleryItem{JobID: "job-1", Prompt: "a cat", WalletAddress: "0xabc", CreatedAt: 1000})
+	err := store.Add(GalleryItem{
+		JobID:         "job-1",
+		Prompt:        "a dog",
+		WalletAddress: "0xABC",
+		CreatedAt:     2000,
+		IsPublic:      true,
+		MediaURLs:     []string{"https://images.aipg.art/gen-1.webp"},
+	})
+	if err != nil {
+		t.Fatalf("owner re-add: %v", err)
+	}
+
+	got := store.Get("job-1")
+	if got == nil || !got.IsPublic || len(got.MediaURLs) != 1 {
+		t.Fatalf("re-added item = %+v, want it public with its media URL", got)
+	}
+	if got.Prompt != "a cat" || got.WalletAddress != "0xabc" || got.CreatedAt != 1000 {
+		t.Errorf("re-add changed immutable fields: %+v", got)
+	}
+	if result := store.List(ListOptions{Limit: 10}); result.Total != 1 {
+		t.Errorf("public items = %d, want the one item", result.Total)
+	}
+
+	// A re-add without media keeps what was saved
+	store.Add(GalleryItem{JobID: "job-1", WalletAddress: "0xabc", IsPublic: true})
+	if got := store.Get("job-1"); len(got.MediaURLs) != 1 {
+		t.Errorf("media after a re-add without any = %v, want it kept", got.MediaURLs)
+	}
+
+	// Another wallet can't change it
+	if err := store.Add(GalleryItem{JobID: "job-1", WalletAddress: "0xother", IsNSFW: true}); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("re-add by another wallet: err = %v, want ErrNotOwner", err)
+	}
+	if got := store.Get("job-1"); got.IsNSFW || !got.IsPublic {
+		t.Errorf("item after another wallet's re-add = %+v, want it unchanged", got)
+	}
+}
+
+func TestBlankWalletRejected(t *testing.T) {
+	file := NewStore("", 100)
+	file.Add(GalleryItem{JobID: "anon-1", IsPublic: true})
+	pg := &PostgresStore{} // rejected before any query, so no database needed
+
+	for _, wallet := range []string{"", "   "} {
+		if _, err := file.ListByWalletPage(wallet, 10, nil); !errors.Is(err, ErrWalletRequired) {
+			t.Errorf("file ListByWalletPage(%q) err = %v, want ErrWalletRequired", wallet, err)
+		}
+		if items := file.ListByWallet(wallet, 10); len(items) != 0 {
+			t.Errorf("file ListByWallet(%q) = %d items, want none", wallet, len(items))
+		}
+		if _, err := pg.ListByWalletPage(context.Background(), wallet, 10, nil); !errors.Is(err, ErrWalletRequired) {
+			t.Errorf("postgres ListByWalletPage(%q) err = %v, want ErrWalletRequired", wallet, err)
+		}
+		if items := pg.ListByWallet(context.Background(), wallet, 10); len(items) != 0 {
+			t.Errorf("postgres ListByWallet(%q) = %d items, want none", wallet, len(items))
+		}
+	}
+}
+
+func TestConcurrentAddsAllPersisted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gallery.json")
+	store := NewStore(path, 1000)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			store.Add(GalleryItem{JobID: fmt.Sprintf("job-%d", i), WalletAddress: "0xabc", IsPublic: true, CreatedAt: int64(i)})
+		}(i)
+	}
+	wg.Wait()
+
+	reloaded := NewStore(path, 1000)
+	if got := reloaded.List(ListOptions{Limit: 100}).Total; got != 50 {
+		t.Errorf("reloaded %d items, want all 50", got)
+	}
+	if _, err := os.Stat(path + ".tmp"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("temp file left behind: %v", err)
+	}
+}
+
+func TestSlowWriteDoesNotBlockStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gallery.json")
+	store := NewStore(path, 1000)
+	writing := make(chan struct{}, 1)
+	release := make(chan struct{})
+	store.writeFile = func(name string, data []byte, perm os.FileMode) error {
+		select {
+		case writing <- struct{}{}:
+			<-release
+		default:
+		}
+		return os.WriteFile(name, data, perm)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		store.Add(GalleryItem{JobID: "job-1", WalletAddress: "0xabc", IsPublic: true})
+	}()
+	<-writing
+
+	// With the first write stuck on disk, reads and further changes still go through
+	added := make(chan struct{})
+	go func() {
+		defer close(added)
+		store.Add(GalleryItem{JobID: "job-2", WalletAddress: "0xabc", IsPublic: true})
+	}()
+	deadline := time.Now().Add(time.Second)
+	for store.Get("job-2") == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("second add waited on the first add's file write")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got := store.List(ListOptions{Limit: 10}).Total; got != 2 {
+		t.Errorf("listed %d items during the write, want 2", got)
+	}
+
+	close(release)
+	<-done
+	<-added
+	if got := NewStore(path, 1000).List(ListOptions{Limit: 10}).Total; got != 2 {
+		t.Errorf("reloaded %d items, want both", got)
+	}
+}
+
+func TestFileStoreAdapterCountAndList(t *testing.T) {
+	ctx := context.Background()
+	var store GalleryStore = &FileStoreAdapter{Store: NewStore("", 100)}
+	store.Add(ctx, GalleryItem{JobID: "public-1", WalletAddress: "0xabc", IsPublic: true, CreatedAt: 1})
+	store.Add(ctx, GalleryItem{JobID: "public-2", WalletAddress: "0xabc", IsPublic: true, IsNSFW: true, CreatedAt: 2})
+	store.Add(ctx, GalleryItem{JobID: "private-1", WalletAddress: "0xabc", CreatedAt: 3})
+
+	// Count covers every item, as the Postgres store's does; List only public ones
+	if got := store.Count(ctx); got != 3 {
+		t.Errorf("Count = %d, want 3 including the private item", got)
+	}
+	if result := store.List(ctx, ListOptions{Limit: 10}); result.Total != 1 || len(result.Items) != 1 {
+		t.Errorf("List = %d of %d, want the one SFW public item", len(result.Items), result.Total)
+	}
+	if result := store.List(ctx, ListOptions{Limit: 10, IncludeNSFW: true}); result.Total != 2 {
+		t.Errorf("List with NSFW total = %d, want 2", result.Total)
+	}
+
+	store.Delete(ctx, "public-1")
+	if got := store.Count(ctx); got != 2 {
+		t.Errorf("Count after delete = %d, want 2", got)
+	}
+}
+
+func TestFileStoreListResultPaging(t *testing.T) {
+	store := NewStore("", 100)
+	for i := 1; i <= 5; i++ {
+		store.Add(GalleryItem{JobID: fmt.Sprintf("job-%d", i), WalletAddress: "0xabc", IsPublic: true, CreatedAt: int64(i)})
+	}
+	store.Add(GalleryItem{JobID: "private", WalletAddress: "0xabc", CreatedAt: 6})
+
+	tests := []struct {
+		offset, limit int
+		wantItems     int
+		wantHasMore   bool
+		wantNext      int
+	}{
+		{offset: 0, limit: 2, wantItems: 2, wantHasMore: true, wantNext: 2},
+		{offset: 2, limit: 2, wantItems: 2, wantHasMore: true, wantNext: 4},
+		{offset: 4, limit: 2, wantItems: 1, wantHasMore: false, wantNext: 5},
+		{offset: 5, limit: 2, wantItems: 0, wantHasMore: false, wantNext: 5},
+		{offset: 9, limit: 2, wantItems: 0, wantHasMore: false, wantNext: 9},
+		{offset: -3, limit: 0, wantItems: 5, wantHasMore: false, wantNext: 5}, // defaults
+	}
+	for _, tc := range tests {
+		result := store.List(ListOptions{Offset: tc.offset, Limit: tc.limit})
+		if result.Total != 5 || len(result.Items) != tc.wantItems || result.HasMore != tc.wantHasMore || result.NextOffset != tc.wantNext {
+			t.Errorf("offset %d limit %d: total=%d items=%d hasMore=%v next=%d, want 5/%d/%v/%d",
+				tc.offset, tc.limit, result.Total, len(result.Items), result.HasMore, result.NextOffset,
+				tc.wantItems, tc.wantHasMore, tc.wantNext)
+		}
+		if result.Items == nil {
+			t.Errorf("offset %d: Items is nil, want an empty page to encode as []", tc.offset)
+		}
+	}
+
+	if result := store.List(ListOptions{Search: "nothing matches", Limit: 10}); result.Total != 0 || result.HasMore || len(result.Items) != 0 {
+		t.Errorf("no matches = %+v", result)
+	}
+}
+
+func TestFileStoreFeatured(t *testing.T) {
+	store := NewStore("", 100)
+	for _, id := range []string{"a", "b", "c"} {
+		store.Add(GalleryItem{JobID: id, WalletAddress: "0xabc", IsPublic: true})
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		if err := store.SetFeatured(id, true); err != nil {
+			t.Fatalf("SetFeatured(%s): %v", id, err)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	store.SetFeatured("b", false)
+
+	result := store.List(ListOptions{Featured: true, Limit: 10})
+	if result.Total != 2 || len(result.Items) != 2 || result.Items[0].JobID != "c" || result.Items[1].JobID != "a" {
+		t.Errorf("featured list = %+v, want c then a", result.Items)
+	}
+	if b := store.Get("b"); b.Featured || b.FeaturedAt != 0 {
+		t.Errorf("unfeatured item = %+v", b)
+	}
+	if err := store.SetFeatured("missing", true); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("SetFeatured missing = %v, want ErrItemNotFound", err)
+	}
+}
@@ -0,0 +1,82 @@
+package gallery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Every timestamp the API returns is an RFC3339 string. Favorites,
+// collections, users and jobs carry time.Time, which already marshals that
+// way; gallery items keep unix milliseconds for sorting and cursors and
+// convert at the JSON boundary.
+
+// millisTime is unix milliseconds that marshals as an RFC3339 string. It
+// unmarshals from either form, since older gallery files and clients that
+// save items send milliseconds.
+type millisTime int64
+
+func (t millisTime) MarshalJSON() ([]byte, error) {
+	if t == 0 {
+		return []byte("null"), nil
+	}
+	return json.Marshal(time.UnixMilli(int64(t)).UTC().Format(time.RFC3339Nano))
+}
+
+func (t *millisTime) UnmarshalJSON(data []byte) error {
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		*t = 0
+	case len(data) > 0 && data[0] == '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		parsed, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return fmt.Errorf("timestamp must be RFC3339 or unix milliseconds: %w", err)
+		}
+		*t = millisTime(parsed.UnixMilli())
+	default:
+		var ms int64
+		if err := json.Unmarshal(data, &ms); err != nil {
+			return fmt.Errorf("timestamp must be RFC3339 or unix milliseconds: %w", err)
+		}
+		*t = millisTime(ms)
+	}
+	return nil
+}
+
+// galleryItemFields is GalleryItem without its JSON methods
+type galleryItemFields GalleryItem
+
+// galleryItemJSON is the wire form of a GalleryItem: the same fields, with
+// the millisecond timestamps swapped for RFC3339 ones
+type galleryItemJSON struct {
+	galleryItemFields
+	CreatedAt  millisTime `json:"createdAt"`
+	EditedAt   millisTime `json:"editedAt,omitempty"`
+	FeaturedAt millisTime `json:"featuredAt,omitempty"`
+}
+
+func (item GalleryItem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(galleryItemJSON{
+		galleryItemFields: galleryItemFields(item),
+		CreatedAt:         millisTime(item.CreatedAt),
+		EditedAt:          millisTime(item.EditedAt),
+		FeaturedAt:        millisTime(item.FeaturedAt),
+	})
+}
+
+func (item *GalleryItem) UnmarshalJSON(data []byte) error {
+	var wire galleryItemJSON
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	*item = GalleryItem(wire.galleryItemFields)
+	item.CreatedAt = int64(wire.CreatedAt)
+	item.EditedAt = int64(wire.EditedAt)
+	item.FeaturedAt = int64(wire.FeaturedAt)
+	return nil
+}
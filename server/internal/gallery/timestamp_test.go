@@ -0,0 +1,85 @@
+package gallery
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// timestampFields marshals v and returns the named fields, failing unless each
+// is an RFC3339 string
+func timestampFields(t *testing.T, v any, fields ...string) map[string]time.Time {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	parsed := make(map[string]time.Time, len(fields))
+	for _, field := range fields {
+		s, ok := raw[field].(string)
+		if !ok {
+			t.Errorf("%T.%s = %v, want an RFC3339 string", v, field, raw[field])
+			continue
+		}
+		ts, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			t.Errorf("%T.%s = %q: %v", v, field, s, err)
+		}
+		parsed[field] = ts
+	}
+	return parsed
+}
+
+func TestTimestampsAreRFC3339(t *testing.T) {
+	when := time.Date(2025, 3, 4, 5, 6, 7, 890_000_000, time.UTC)
+
+	item := GalleryItem{JobID: "job-1", CreatedAt: when.UnixMilli(), EditedAt: when.Add(time.Hour).UnixMilli(), FeaturedAt: when.Add(2 * time.Hour).UnixMilli()}
+	got := timestampFields(t, item, "createdAt", "editedAt", "featuredAt")
+	if !got["createdAt"].Equal(when) || !got["editedAt"].Equal(when.Add(time.Hour)) || !got["featuredAt"].Equal(when.Add(2*time.Hour)) {
+		t.Errorf("gallery item times = %v, want %v, an hour later and two hours later", got, when)
+	}
+	data, _ := json.Marshal(GalleryItem{JobID: "job-2", CreatedAt: when.UnixMilli()})
+	var raw map[string]any
+	json.Unmarshal(data, &raw)
+	if raw["createdAt"] != "2025-03-04T05:06:07.89Z" || raw["jobId"] != "job-2" {
+		t.Errorf("gallery item JSON = %s", data)
+	}
+	if _, ok := raw["editedAt"]; ok {
+		t.Errorf("unedited item has editedAt: %s", data)
+	}
+	if _, ok := raw["featuredAt"]; ok {
+		t.Errorf("unfeatured item has featuredAt: %s", data)
+	}
+
+	timestampFields(t, Favorite{CreatedAt: when}, "createdAt")
+	timestampFields(t, Collection{CreatedAt: when}, "createdAt")
+	timestampFields(t, User{CreatedAt: when, LastSeenAt: when}, "createdAt", "lastSeenAt")
+	timestampFields(t, GenerationJob{CreatedAt: when, UpdatedAt: when}, "createdAt", "updatedAt")
+}
+
+func TestGalleryItemReadsEitherTimestamp(t *testing.T) {
+	want := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC).UnixMilli()
+	for _, input := range []string{
+		`{"jobId":"j","createdAt":1741064767000}`,
+		`{"jobId":"j","createdAt":"2025-03-04T05:06:07Z"}`,
+		`{"jobId":"j","createdAt":"2025-03-04T06:06:07+01:00"}`,
+	} {
+		var item GalleryItem
+		if err := json.Unmarshal([]byte(input), &item); err != nil {
+			t.Errorf("%s: %v", input, err)
+			continue
+		}
+		if item.JobID != "j" || item.CreatedAt != want {
+			t.Errorf("%s: got %+v, want createdAt %d", input, item, want)
+		}
+	}
+
+	var item GalleryItem
+	if err := json.Unmarshal([]byte(`{"createdAt":"yesterday"}`), &item); err == nil {
+		t.Error("unparseable createdAt: want an error")
+	}
+}
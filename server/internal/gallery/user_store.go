@@ -2,6 +2,7 @@ package gallery
 
 import (
 	"database/sql"
+	"encoding/json"
 	"strings"
 	"time"
 )
@@ -14,6 +15,20 @@ type User struct {
 	LastSeenAt    time.Time `json:"lastSeenAt"`
 }
 
+// UserSettings holds persistent per-wallet preferences
+type UserSettings struct {
+	// ShowNSFW is the default NSFW visibility for gallery listings; nil means
+	// the wallet never chose and the server default applies
+	ShowNSFW     *bool  `json:"showNsfw,omitempty"`
+	DefaultModel string `json:"defaultModel,omitempty"`
+}
+
+// SettingsStore reads and writes per-wallet preferences
+type SettingsStore interface {
+	GetSettings(walletAddress string) (UserSettings, error)
+	UpdateSettings(walletAddress string, settings UserSettings) error
+}
+
 // UserStore handles user-related database operations
 type UserStore struct {
 	db *sql.DB
@@ -73,3 +88,46 @@ func (s *UserStore) GetUserByWallet(walletAddress string) (*User, error) {
 
 	return &user, nil
 }
+
+// GetSettings returns the stored preferences for a wallet.
+// Unknown wallets get empty settings rather than an error.
+func (s *UserStore) GetSettings(walletAddress string) (UserSettings, error) {
+	wallet := strings.ToLower(walletAddress)
+
+	var raw []byte
+	err := s.db.QueryRow(`SELECT settings FROM users WHERE wallet_address = $1`, wallet).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return UserSettings{}, nil
+	}
+	if err != nil {
+		return UserSettings{}, err
+	}
+
+	var settings UserSettings
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &settings); err != nil {
+			return UserSettings{}, err
+		}
+	}
+	return settings, nil
+}
+
+// UpdateSettings replaces the stored preferences for a wallet,
+// creating the user if they haven't connected before
+func (s *UserStore) UpdateSettings(walletAddress string, settings UserSettings) error {
+	wallet := strings.ToLower(walletAddress)
+	now := time.Now()
+
+	raw, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO users (wallet_address, created_at, last_seen_at, settings)
+		VALUES ($1, $2, $2, $3)
+		ON CONFLICT (wallet_address) DO UPDATE SET settings = $3
+	`
+	_, err = s.db.Exec(query, wallet, now, raw)
+	return err
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"fmt"
+	"math"
+)
+
+// DimensionPreset is a ready-made width/height pair that satisfies a model's limits
+type DimensionPreset struct {
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	AspectRatio string `json:"aspectRatio"`
+}
+
+// commonAspectRatios are offered in this order at every size
+var commonAspectRatios = [][2]int{
+	{1, 1}, {4, 3}, {3, 4}, {3, 2}, {2, 3}, {16, 9}, {9, 16}, {21, 9}, {9, 21},
+}
+
+// presetSides are the square side lengths whose pixel area each preset size targets
+var presetSides = []int{512, 768, 1024, 1280, 1536}
+
+// DimensionPresets derives resolution presets from the width/height limits by
+// fitting common aspect ratios to a few target areas and snapping each side to
+// the limit's step. Only sizes the model actually accepts are returned.
+func (l ModelLimits) DimensionPresets() []DimensionPreset {
+	if l.Width == nil || l.Height == nil {
+		return nil
+	}
+
+	seen := make(map[[2]int]bool)
+	var presets []DimensionPreset
+	for _, side := range presetSides {
+		area := float64(side * side)
+		for _, ratio := range commonAspectRatios {
+			r := float64(ratio[0]) / float64(ratio[1])
+			w := snapToRange(math.Sqrt(area*r), l.Width)
+			h := snapToRange(math.Sqrt(area/r), l.Height)
+			if w < 0 || h < 0 || seen[[2]int{w, h}] {
+				continue
+			}
+			seen[[2]int{w, h}] = true
+			presets = append(presets, DimensionPreset{
+				Width:       w,
+				Height:      h,
+				AspectRatio: fmt.Sprintf("%d:%d", ratio[0], ratio[1]),
+			})
+		}
+	}
+	return presets
+}
+
+// snapToRange rounds v to the nearest step above the range minimum, returning -1 when out of range
+func snapToRange(v float64, r *RangeInt) int {
+	step := r.Step
+	if step <= 0 {
+		step = 1
+	}
+	snapped := r.Min + int(math.Round((v-float64(r.Min))/float64(step)))*step
+	if snapped < r.Min || snapped > r.Max {
+		return -1
+	}
+	return snapped
+}
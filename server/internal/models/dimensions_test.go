@@ -0,0 +1,51 @@
+package models
+
+import "testing"
+
+func TestDimensionPresets(t *testing.T) {
+	limits := ModelLimits{
+		Width:  &RangeInt{Min: 512, Max: 1536, Step: 64},
+		Height: &RangeInt{Min: 512, Max: 1536, Step: 64},
+	}
+
+	presets := limits.DimensionPresets()
+	if len(presets) == 0 {
+		t.Fatal("expected presets")
+	}
+
+	seen := make(map[DimensionPreset]bool)
+	for _, p := range presets {
+		if p.Width < 512 || p.Width > 1536 || p.Height < 512 || p.Height > 1536 {
+			t.Errorf("%+v outside limits", p)
+		}
+		if p.Width%64 != 0 || p.Height%64 != 0 {
+			t.Errorf("%+v not snapped to step 64", p)
+		}
+		seen[p] = true
+	}
+
+	for _, want := range []DimensionPreset{
+		{Width: 512, Height: 512, AspectRatio: "1:1"},
+		{Width: 1024, Height: 1024, AspectRatio: "1:1"},
+		{Width: 1344, Height: 768, AspectRatio: "16:9"},
+		{Width: 768, Height: 1344, AspectRatio: "9:16"},
+		{Width: 1152, Height: 896, AspectRatio: "4:3"},
+	} {
+		if !seen[want] {
+			t.Errorf("missing preset %+v", want)
+		}
+	}
+
+	// 16:9 at 512x512 area would need a 384px side, below the minimum
+	for _, p := range presets {
+		if p.Height == 384 || p.Width == 384 {
+			t.Errorf("unexpected preset %+v below minimum", p)
+		}
+	}
+}
+
+func TestDimensionPresetsWithoutLimits(t *testing.T) {
+	if got := (ModelLimits{Width: &RangeInt{Min: 512, Max: 1024, Step: 64}}).DimensionPresets(); got != nil {
+		t.Errorf("DimensionPresets without height limit = %v, want nil", got)
+	}
+}
@@ -0,0 +1,59 @@
+package models
+
+// DefaultLimits are the ranges filled in, by model type, for limit fields a
+// preset leaves out, so every model advertises a complete set of ranges.
+// Adjust entries before the catalog is loaded to change them.
+var DefaultLimits = map[string]ModelLimits{
+	"image": {
+		Width:    &RangeInt{Min: 256, Max: 2048, Step: 64},
+		Height:   &RangeInt{Min: 256, Max: 2048, Step: 64},
+		Steps:    &RangeInt{Min: 1, Max: 50, Step: 1},
+		CfgScale: &RangeFloat{Min: 1, Max: 15, Step: 0.5},
+	},
+	"video": {
+		Width:    &RangeInt{Min: 512, Max: 1280, Step: 64},
+		Height:   &RangeInt{Min: 512, Max: 1280, Step: 64},
+		Steps:    &RangeInt{Min: 1, Max: 50, Step: 1},
+		CfgScale: &RangeFloat{Min: 1, Max: 10, Step: 0.25},
+		Length:   &RangeInt{Min: 49, Max: 129, Step: 8},
+		FPS:      &RangeInt{Min: 12, Max: 30, Step: 1},
+	},
+}
+
+// withDefaultLimits fills nil limits from DefaultLimits for the preset's type.
+// Limits the preset sets are never touched, and a filled-in range is widened
+// to include the preset's own default value for that field.
+func withDefaultLimits(p ModelPreset) ModelPreset {
+	defaults, ok := DefaultLimits[p.Type]
+	if !ok {
+		return p
+	}
+
+	fillInt(&p.Limits.Width, defaults.Width, p.Defaults.Width)
+	fillInt(&p.Limits.Height, defaults.Height, p.Defaults.Height)
+	fillInt(&p.Limits.Steps, defaults.Steps, p.Defaults.Steps)
+	fillInt(&p.Limits.Length, defaults.Length, p.Defaults.Length)
+	fillInt(&p.Limits.FPS, defaults.FPS, p.Defaults.FPS)
+
+	if p.Limits.CfgScale == nil && defaults.CfgScale != nil {
+		r := *defaults.CfgScale
+		if v := p.Defaults.CfgScale; v > 0 {
+			r.Min = min(r.Min, v)
+			r.Max = max(r.Max, v)
+		}
+		p.Limits.CfgScale = &r
+	}
+	return p
+}
+
+func fillInt(field **RangeInt, fallback *RangeInt, presetDefault int) {
+	if *field != nil || fallback == nil {
+		return
+	}
+	r := *fallback
+	if presetDefault > 0 {
+		r.Min = min(r.Min, presetDefault)
+		r.Max = max(r.Max, presetDefault)
+	}
+	*field = &r
+}
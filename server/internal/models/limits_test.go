@@ -0,0 +1,46 @@
+package models
+
+import "testing"
+
+func TestCatalogFillsMissingLimits(t *testing.T) {
+	catalog := NewCatalog([]ModelPreset{
+		{
+			ID:       "partial",
+			Type:     "image",
+			Defaults: ModelDefaults{CfgScale: 25},
+			Limits:   ModelLimits{Steps: &RangeInt{Min: 4, Max: 8, Step: 1}},
+		},
+		{ID: "clip", Type: "video"},
+		{ID: "text", Type: "text"},
+	})
+
+	p, _ := catalog.Get("partial")
+	if p.Limits.Steps.Min != 4 || p.Limits.Steps.Max != 8 {
+		t.Errorf("explicit steps limit changed to %+v", p.Limits.Steps)
+	}
+	if p.Limits.CfgScale == nil {
+		t.Fatal("missing cfgScale limit was not filled")
+	}
+	if p.Limits.CfgScale.Min != 1 || p.Limits.CfgScale.Max != 25 {
+		t.Errorf("cfgScale = %+v, want the image default widened to the preset default 25", p.Limits.CfgScale)
+	}
+	if p.Limits.Width == nil || p.Limits.Height == nil || p.Limits.Length != nil {
+		t.Errorf("image limits = %+v, want width/height filled and no video fields", p.Limits)
+	}
+
+	// Filled ranges are copies, not the shared defaults
+	p.Limits.CfgScale.Max = 99
+	if DefaultLimits["image"].CfgScale.Max == 99 {
+		t.Error("filled limit aliases DefaultLimits")
+	}
+
+	v, _ := catalog.Get("clip")
+	if v.Limits.Length == nil || v.Limits.FPS == nil {
+		t.Errorf("video limits = %+v, want length and fps filled", v.Limits)
+	}
+
+	text, _ := catalog.Get("text")
+	if text.Limits != (ModelLimits{}) {
+		t.Errorf("unknown type got limits %+v", text.Limits)
+	}
+}
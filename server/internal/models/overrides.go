@@ -0,0 +1,131 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// OverridePrefix starts every preset override variable:
+// MODEL_OVERRIDE_<ID>_<FIELD>=value, where <ID> is the preset ID uppercased
+// with anything other than letters and digits turned into underscores, e.g.
+// MODEL_OVERRIDE_FLUX_1_DEV_STEPS=30.
+const OverridePrefix = "MODEL_OVERRIDE_"
+
+// overrideFields are the defaults an override can set, by variable suffix
+var overrideFields = map[string]func(p *ModelPreset, raw string) error{
+	"STEPS":     func(p *ModelPreset, raw string) error { return setInt(&p.Defaults.Steps, p.Limits.Steps, raw) },
+	"WIDTH":     func(p *ModelPreset, raw string) error { return setInt(&p.Defaults.Width, p.Limits.Width, raw) },
+	"HEIGHT":    func(p *ModelPreset, raw string) error { return setInt(&p.Defaults.Height, p.Limits.Height, raw) },
+	"LENGTH":    func(p *ModelPreset, raw string) error { return setInt(&p.Defaults.Length, p.Limits.Length, raw) },
+	"FPS":       func(p *ModelPreset, raw string) error { return setInt(&p.Defaults.FPS, p.Limits.FPS, raw) },
+	"CFG_SCALE": func(p *ModelPreset, raw string) error { return setFloat(&p.Defaults.CfgScale, p.Limits.CfgScale, raw) },
+	"DENOISE":   func(p *ModelPreset, raw string) error { return setFloat(&p.Defaults.Denoise, &RangeFloat{Max: 1}, raw) },
+	"SAMPLER":   func(p *ModelPreset, raw string) error { return setChoice(&p.Defaults.Sampler, p.Samplers, raw) },
+	"SCHEDULER": func(p *ModelPreset, raw string) error { return setChoice(&p.Defaults.Scheduler, p.Schedulers, raw) },
+}
+
+// OverrideKey is the form a preset ID takes in override variable names
+func OverrideKey(id string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, id)
+}
+
+// ApplyOverrides sets preset defaults from override variables, keyed by full
+// variable name. The preset file stays the source of truth: only defaults can
+// change, and each value must sit inside the preset's limits (or its sampler
+// and scheduler lists). It returns a line for each override applied and an
+// error for each one rejected; rejected overrides leave the preset as it was.
+func (c Catalog) ApplyOverrides(vars map[string]string) (applied []string, rejected []error) {
+	ids := make(map[string]string, len(c.items))
+	for id := range c.items {
+		ids[OverrideKey(id)] = id
+	}
+
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		raw := strings.TrimSpace(vars[name])
+		key, field, set := splitOverride(strings.TrimPrefix(name, OverridePrefix))
+		if set == nil {
+			rejected = append(rejected, fmt.Errorf("%s: unknown field, want one of %s", name, strings.Join(overrideFieldNames(), ", ")))
+			continue
+		}
+		id, ok := ids[key]
+		if !ok {
+			rejected = append(rejected, fmt.Errorf("%s: no preset matches %s", name, key))
+			continue
+		}
+		preset := c.items[id]
+		if err := set(&preset, raw); err != nil {
+			rejected = append(rejected, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		c.items[id] = preset
+		applied = append(applied, fmt.Sprintf("%s %s = %s", id, strings.ToLower(field), raw))
+	}
+	return applied, rejected
+}
+
+// splitOverride splits "<KEY>_<FIELD>" on the known field suffix
+func splitOverride(rest string) (key, field string, set func(*ModelPreset, string) error) {
+	for _, field := range overrideFieldNames() {
+		if key, ok := strings.CutSuffix(rest, "_"+field); ok && key != "" {
+			return key, field, overrideFields[field]
+		}
+	}
+	return "", "", nil
+}
+
+func overrideFieldNames() []string {
+	names := make([]string, 0, len(overrideFields))
+	for name := range overrideFields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func setInt(field *int, limit *RangeInt, raw string) error {
+	v, err := strconv.Atoi(raw)
+	if err != nil || v <= 0 {
+		return fmt.Errorf("%q is not a positive whole number", raw)
+	}
+	if limit != nil && (v < limit.Min || v > limit.Max) {
+		return fmt.Errorf("%d is outside the preset's limit of %d-%d", v, limit.Min, limit.Max)
+	}
+	*field = v
+	return nil
+}
+
+func setFloat(field *float64, limit *RangeFloat, raw string) error {
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || v < 0 {
+		return fmt.Errorf("%q is not a non-negative number", raw)
+	}
+	if limit != nil && (v < limit.Min || v > limit.Max) {
+		return fmt.Errorf("%g is outside the preset's limit of %g-%g", v, limit.Min, limit.Max)
+	}
+	*field = v
+	return nil
+}
+
+func setChoice(field *string, allowed []string, raw string) error {
+	if len(allowed) > 0 && !slices.Contains(allowed, raw) {
+		return fmt.Errorf("%q is not one of the preset's options: %s", raw, strings.Join(allowed, ", "))
+	}
+	*field = raw
+	return nil
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyOverrides(t *testing.T) {
+	catalog := NewCatalog([]ModelPreset{
+		{
+			ID:       "FLUX.1-dev",
+			Type:     "image",
+			Samplers: []string{"euler", "dpmpp_2m"},
+			Defaults: ModelDefaults{Steps: 20, CfgScale: 3.5, Sampler: "euler"},
+		},
+		{ID: "sdxl", Type: "image", Defaults: ModelDefaults{Steps: 25}},
+	})
+
+	applied, rejected := catalog.ApplyOverrides(map[string]string{
+		"MODEL_OVERRIDE_FLUX_1_DEV_STEPS":     "30",
+		"MODEL_OVERRIDE_FLUX_1_DEV_CFG_SCALE": " 4.5 ",
+		"MODEL_OVERRIDE_FLUX_1_DEV_SAMPLER":   "dpmpp_2m",
+		"MODEL_OVERRIDE_SDXL_STEPS":           "500",    // over the steps limit
+		"MODEL_OVERRIDE_SDXL_WIDTH":           "wide",   // not a number
+		"MODEL_OVERRIDE_SDXL_COLOR":           "blue",   // no such field
+		"MODEL_OVERRIDE_NOPE_STEPS":           "10",     // no such preset
+		"MODEL_OVERRIDE_FLUX_1_DEV_SCHEDULER": "karras", // preset lists no schedulers, so anything goes
+	})
+
+	if len(applied) != 4 || len(rejected) != 4 {
+		t.Fatalf("applied %q, rejected %v; want 4 of each", applied, rejected)
+	}
+	if applied[0] != "FLUX.1-dev cfg_scale = 4.5" {
+		t.Errorf("first applied = %q", applied[0])
+	}
+	for _, err := range rejected {
+		if !strings.HasPrefix(err.Error(), "MODEL_OVERRIDE_") {
+			t.Errorf("rejection %q doesn't name its variable", err)
+		}
+	}
+
+	flux, _ := catalog.Get("FLUX.1-dev")
+	if d := flux.Defaults; d.Steps != 30 || d.CfgScale != 4.5 || d.Sampler != "dpmpp_2m" || d.Scheduler != "karras" {
+		t.Errorf("flux defaults = %+v, want the overrides applied", d)
+	}
+	sdxl, _ := catalog.Get("sdxl")
+	if sdxl.Defaults.Steps != 25 || sdxl.Defaults.Width != 0 {
+		t.Errorf("sdxl defaults = %+v, want the rejected overrides left out", sdxl.Defaults)
+	}
+
+	if _, rejected := catalog.ApplyOverrides(map[string]string{"MODEL_OVERRIDE_FLUX_1_DEV_SAMPLER": "ddim"}); len(rejected) != 1 {
+		t.Errorf("sampler outside the preset's list was accepted")
+	}
+}
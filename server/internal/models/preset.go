@@ -25,6 +25,8 @@ type ModelLimits struct {
 	CfgScale *RangeFloat `json:"cfgScale,omitempty"`
 	Length   *RangeInt   `json:"length,omitempty"`
 	FPS      *RangeInt   `json:"fps,omitempty"`
+	// Approximate prompt token budget; 0 uses the model family's default
+	PromptTokens int `json:"promptTokens,omitempty"`
 }
 
 type ModelDefaults struct {
@@ -69,15 +71,21 @@ func LoadCatalog(path string) (Catalog, error) {
 		return Catalog{}, fmt.Errorf("decode presets: %w", err)
 	}
 
+	return NewCatalog(presets), nil
+}
+
+// NewCatalog builds a catalog from already-decoded presets, skipping any without an ID.
+// Limits a preset leaves out are filled from DefaultLimits for its type.
+func NewCatalog(presets []ModelPreset) Catalog {
 	items := make(map[string]ModelPreset, len(presets))
 	for _, p := range presets {
 		if p.ID == "" {
 			continue
 		}
-		items[p.ID] = p
+		items[p.ID] = withDefaultLimits(p)
 	}
 
-	return Catalog{items: items}, nil
+	return Catalog{items: items}
 }
 
 func (c Catalog) Get(id string) (ModelPreset, bool) {
@@ -2,18 +2,20 @@ package modelvault
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
 	"reflect"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/cache"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/chainrpc"
 )
 
 // ModelType represents the type of AI model
@@ -60,28 +62,43 @@ type OnChainModel struct {
 
 // ModelConstraints represents the per-model generation limits from blockchain
 type ModelConstraints struct {
-	StepsMin          uint16
-	StepsMax          uint16
-	CfgMin            float64 // Already converted from tenths
-	CfgMax            float64
-	ClipSkip          uint8
-	AllowedSamplers   []string
-	AllowedSchedulers []string
+	StepsMin          uint16   `json:"stepsMin"`
+	StepsMax          uint16   `json:"stepsMax"`
+	CfgMin            float64  `json:"cfgMin"` // Already converted from tenths
+	CfgMax            float64  `json:"cfgMax"`
+	ClipSkip          uint8    `json:"clipSkip"`
+	AllowedSamplers   []string `json:"allowedSamplers"`
+	AllowedSchedulers []string `json:"allowedSchedulers"`
 }
 
 // Client for querying the ModelVault contract on Base Mainnet
 type Client struct {
-	rpcURL          string
 	contractAddress common.Address
-	ethClient       *ethclient.Client
-	contract        *bind.BoundContract
+	contract        *chainrpc.Contract
+	abi             abi.ABI
+	rpcURLs         []string
 	enabled         bool
 
-	// Cache
-	mu              sync.RWMutex
-	modelCache      map[string]*OnChainModel
-	cacheExpiry     time.Time
-	cacheTTL        time.Duration
+	// Multicall3 contract model loads are batched through; nil reads one model per call
+	multicall       *chainrpc.Contract
+
+	// Active models keyed by display name, lowercase name and file name
+	models          *cache.TTLCache[map[string]*OnChainModel]
+	// Where an interrupted load stopped; loads are serialized by the models cache
+	progress        fetchProgress
+
+	// Known sampler and scheduler names by keccak256 hash (see SetParamNames)
+	samplerNames    nameTable
+	schedulerNames  nameTable
+}
+
+// fetchProgress remembers how far an interrupted model load got so the next
+// load resumes there instead of re-reading models it already has
+type fetchProgress struct {
+	next    int64 // next model ID to read; 0 when there is nothing to resume
+	models  map[string]*OnChainModel
+	success int
+	failed  int
 }
 
 // Default configuration
@@ -90,6 +107,7 @@ const (
 	DefaultContractAddress = "0x79F39f2a0eA476f53994812e6a8f3C8CFe08c609"
 	DefaultCacheTTL        = 30 * time.Minute // Longer cache to reduce RPC calls
 	RPCRateLimit           = 300 * time.Millisecond // Delay between RPC calls
+	FetchTimeout           = 2 * time.Minute // Budget for one load; an interrupted load resumes on the next fetch
 )
 
 // ABI for the ModelVault contract (Grid proxy)
@@ -165,41 +183,39 @@ const modelVaultABI = `[
 ]`
 
 // NewClient creates a new ModelVault client
-func NewClient(rpcURL, contractAddress string, enabled bool) (*Client, error) {
+// Several RPC URLs may be given; calls fail over between them in order.
+func NewClient(rpcURLs []string, contractAddress string, enabled bool) (*Client, error) {
 	if !enabled {
-		return &Client{enabled: false, modelCache: make(map[string]*OnChainModel)}, nil
+		return &Client{enabled: false}, nil
 	}
 
-	if rpcURL == "" {
-		rpcURL = DefaultRPCURL
+	if len(rpcURLs) == 0 {
+		rpcURLs = []string{DefaultRPCURL}
 	}
 	if contractAddress == "" {
 		contractAddress = DefaultContractAddress
 	}
 
-	ethClient, err := ethclient.Dial(rpcURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Ethereum RPC: %w", err)
-	}
-
 	parsedABI, err := abi.JSON(strings.NewReader(modelVaultABI))
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse ABI: %w", err)
 	}
 
 	addr := common.HexToAddress(contractAddress)
-	boundContract := bind.NewBoundContract(addr, parsedABI, ethClient, ethClient, ethClient)
+	contract, err := chainrpc.Dial("ModelVault", rpcURLs, addr, parsedABI)
+	if err != nil {
+		return nil, err
+	}
 
-	log.Printf("ModelVault client initialized (chain: Base Mainnet, contract: %s)", contractAddress[:12]+"...")
+	log.Printf("ModelVault client initialized (chain: Base Mainnet, contract: %s, RPC endpoints: %d)", contractAddress[:12]+"...", len(rpcURLs))
 
 	return &Client{
-		rpcURL:          rpcURL,
 		contractAddress: addr,
-		ethClient:       ethClient,
-		contract:        boundContract,
+		contract:        contract,
+		abi:             parsedABI,
+		rpcURLs:         rpcURLs,
 		enabled:         true,
-		modelCache:      make(map[string]*OnChainModel),
-		cacheTTL:        DefaultCacheTTL,
+		models:          cache.New[map[string]*OnChainModel](DefaultCacheTTL),
 	}, nil
 }
 
@@ -382,7 +398,9 @@ func (c *Client) GetConstraints(ctx context.Context, modelHash [32]byte) (*Model
 		CfgMin:   float64(constraintData.CfgMinTenths) / 10.0,
 		CfgMax:   float64(constraintData.CfgMaxTenths) / 10.0,
 		ClipSkip: constraintData.ClipSkip,
-		// Note: samplers/schedulers would need keccak256 reverse lookup
+		// Stored as keccak256 hashes of the names; unknown ones come back as hex
+		AllowedSamplers:   c.samplerNames.resolve(constraintData.AllowedSamplers),
+		AllowedSchedulers: c.schedulerNames.resolve(constraintData.AllowedSchedulers),
 	}, nil
 }
 
@@ -392,18 +410,33 @@ func (c *Client) FetchAllModels(ctx context.Context) (map[string]*OnChainModel,
 		return nil, nil
 	}
 
-	// Check cache first - this avoids rate limiting issues
-	c.mu.RLock()
-	if time.Now().Before(c.cacheExpiry) && len(c.modelCache) > 0 {
-		cache := make(map[string]*OnChainModel, len(c.modelCache))
-		for k, v := range c.modelCache {
-			cache[k] = v
-		}
-		c.mu.RUnlock()
-		log.Printf("Using cached blockchain models (%d entries, expires in %v)", len(cache), time.Until(c.cacheExpiry).Round(time.Second))
-		return cache, nil
+	// Cached to avoid rate limiting; concurrent callers share one fetch and a
+	// failed refresh keeps serving the previous models
+	cached, stale, err := c.models.Get(ctx, c.loadAllModels)
+	if errors.Is(err, errNoModelsLoaded) {
+		return map[string]*OnChainModel{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stale {
+		log.Printf("Warning: blockchain model refresh failed, using %d cached entries", len(cached))
 	}
-	c.mu.RUnlock()
+
+	models := make(map[string]*OnChainModel, len(cached))
+	for k, v := range cached {
+		models[k] = v
+	}
+	return models, nil
+}
+
+// errNoModelsLoaded keeps an empty result out of the cache
+var errNoModelsLoaded = errors.New("no active models loaded from blockchain")
+
+// loadAllModels reads every registered model from the contract
+func (c *Client) loadAllModels(ctx context.Context) (map[string]*OnChainModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, FetchTimeout)
+	defer cancel()
 
 	count, err := c.GetModelCount(ctx)
 	if err != nil {
@@ -411,70 +444,131 @@ func (c *Client) FetchAllModels(ctx context.Context) (map[string]*OnChainModel,
 		return nil, err
 	}
 
-	log.Printf("Fetching %d models from blockchain (with rate limiting)...", count)
+	if c.multicall != nil {
+		models, err := c.loadModelBatches(ctx, count, MulticallBatchSize, c.getModels)
+		if err == nil || ctx.Err() != nil || errors.Is(err, errNoModelsLoaded) {
+			return models, err
+		}
+		// The batch pass kept its progress, so the fallback picks up where it stopped
+		if errors.Is(err, bind.ErrNoCode) {
+			log.Printf("Warning: no Multicall3 contract deployed, reading models one call at a time from now on")
+			c.multicall = nil
+		} else {
+			log.Printf("Warning: multicall model fetch failed, finishing one call at a time: %v", err)
+		}
+	}
+	return c.loadModels(ctx, count, c.GetModel)
+}
+
+// batchGetter reads the models with the given IDs. errs[i] is set for each
+// model that couldn't be read; a non-nil err means the whole batch failed.
+type batchGetter func(ctx context.Context, ids []int64) (models []*OnChainModel, errs []error, err error)
+
+// loadModels is loadModelBatches reading one model per call with get
+func (c *Client) loadModels(ctx context.Context, count int64, get func(context.Context, int64) (*OnChainModel, error)) (map[string]*OnChainModel, error) {
+	return c.loadModelBatches(ctx, count, 1, func(ctx context.Context, ids []int64) ([]*OnChainModel, []error, error) {
+		model, err := get(ctx, ids[0])
+		return []*OnChainModel{model}, []error{err}, nil
+	})
+}
+
+// loadModelBatches reads model IDs 1..count, size at a time, picking up where
+// an interrupted previous pass stopped. Progress is kept when ctx ends or a
+// whole batch fails mid-pass, and cleared once a pass completes.
+func (c *Client) loadModelBatches(ctx context.Context, count, size int64, getBatch batchGetter) (map[string]*OnChainModel, error) {
+	p := c.progress
+	if p.next < 1 || p.next > count {
+		p = fetchProgress{next: 1, models: make(map[string]*OnChainModel)}
+		if size > 1 {
+			log.Printf("Fetching %d models from blockchain in batches of %d...", count, size)
+		} else {
+			log.Printf("Fetching %d models from blockchain (with rate limiting)...", count)
+		}
+	} else {
+		log.Printf("Resuming blockchain model fetch at %d of %d (%d models already loaded)", p.next, count, p.success)
+	}
+	models := p.models
 
-	models := make(map[string]*OnChainModel)
-	successCount := 0
-	failCount := 0
+	interrupted := func(i int64) (map[string]*OnChainModel, error) {
+		p.next = i
+		c.progress = p
+		log.Printf("Model fetch interrupted at %d of %d, will resume from there", i, count)
+		return nil, fmt.Errorf("model fetch interrupted at %d of %d: %w", i, count, ctx.Err())
+	}
 
 	// Rate limit: ~3 requests per second to avoid 429 errors from Base RPC
 	ticker := time.NewTicker(RPCRateLimit)
 	defer ticker.Stop()
 
-	for i := int64(1); i <= count; i++ {
+	for i := p.next; i <= count; i += size {
 		// Wait for rate limit ticker (except for first request)
-		if i > 1 {
+		if i > p.next {
 			select {
 			case <-ticker.C:
 				// Continue
 			case <-ctx.Done():
-				log.Printf("Context cancelled after %d models", successCount)
-				break
+				return interrupted(i)
 			}
 		}
 
-		model, err := c.GetModel(ctx, i)
+		ids := make([]int64, 0, size)
+		for id := i; id <= count && id < i+size; id++ {
+			ids = append(ids, id)
+		}
+		batch, errs, err := getBatch(ctx, ids)
 		if err != nil {
-			failCount++
-			// Only log rate limit errors once
-			if strings.Contains(err.Error(), "429") && failCount == 1 {
-				log.Printf("Warning: rate limited by RPC endpoint, some models may be missing")
-			} else if !strings.Contains(err.Error(), "429") {
-				log.Printf("Warning: failed to fetch model %d: %v", i, err)
+			if ctx.Err() != nil {
+				return interrupted(i)
 			}
-			continue
-		}
-		if model == nil || !model.IsActive {
-			continue
+			p.next = i
+			c.progress = p
+			return nil, fmt.Errorf("model fetch failed at %d of %d: %w", i, count, err)
 		}
 
-		successCount++
+		for j, id := range ids {
+			model, err := batch[j], errs[j]
+			if err != nil {
+				if ctx.Err() != nil {
+					return interrupted(id)
+				}
+				p.failed++
+				// Only log rate limit errors once
+				if strings.Contains(err.Error(), "429") && p.failed == 1 {
+					log.Printf("Warning: rate limited by RPC endpoint, some models may be missing")
+				} else if !strings.Contains(err.Error(), "429") {
+					log.Printf("Warning: failed to fetch model %d: %v", id, err)
+				}
+				continue
+			}
+			if model == nil || !model.IsActive {
+				continue
+			}
 
-		// Skip fetching constraints to reduce RPC calls
-		// Constraints can be fetched on-demand if needed
+			p.success++
 
-		models[model.DisplayName] = model
-		// Also index by variations
-		models[strings.ToLower(model.DisplayName)] = model
-		if model.FileName != "" {
-			models[model.FileName] = model
-		}
-	}
+			// Skip fetching constraints to reduce RPC calls
+			// Constraints can be fetched on-demand if needed
 
-	// Update cache even if we got partial results
-	if successCount > 0 {
-		c.mu.Lock()
-		c.modelCache = models
-		c.cacheExpiry = time.Now().Add(c.cacheTTL)
-		c.mu.Unlock()
+			models[model.DisplayName] = model
+			// Also index by variations
+			models[strings.ToLower(model.DisplayName)] = model
+			if model.FileName != "" {
+				models[model.FileName] = model
+			}
+		}
 	}
+	c.progress = fetchProgress{}
 
-	if failCount > 0 {
-		log.Printf("✓ Loaded %d active models from blockchain (%d failed)", successCount, failCount)
+	if p.failed > 0 {
+		log.Printf("✓ Loaded %d active models from blockchain (%d failed)", p.success, p.failed)
 	} else {
-		log.Printf("✓ Loaded %d active models from blockchain", successCount)
+		log.Printf("✓ Loaded %d active models from blockchain", p.success)
 	}
 
+	// Partial results are still cached; only an empty fetch is treated as a failure
+	if p.success == 0 {
+		return models, errNoModelsLoaded
+	}
 	return models, nil
 }
 
@@ -0,0 +1,62 @@
+package modelvault
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestLoadModelsResumesAfterInterruption(t *testing.T) {
+	c := &Client{enabled: true}
+
+	var fetched []int64
+	get := func(cancel context.CancelFunc, stopAfter int64) func(context.Context, int64) (*OnChainModel, error) {
+		return func(ctx context.Context, id int64) (*OnChainModel, error) {
+			fetched = append(fetched, id)
+			if id == stopAfter {
+				cancel()
+			}
+			return &OnChainModel{DisplayName: fmt.Sprintf("Model-%d", id), IsActive: id != 2}, nil
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	models, err := c.loadModels(ctx, 5, get(cancel, 3))
+	if err == nil || models != nil {
+		t.Fatalf("interrupted load = %v, %v; want an error and no models", models, err)
+	}
+	if c.progress.next != 4 || c.progress.success != 2 {
+		t.Fatalf("progress = %+v, want next 4 with 2 models loaded", c.progress)
+	}
+
+	fetched = nil
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	models, err = c.loadModels(ctx, 5, get(cancel, -1))
+	if err != nil {
+		t.Fatalf("resumed load: %v", err)
+	}
+	if want := []int64{4, 5}; !reflect.DeepEqual(fetched, want) {
+		t.Errorf("resumed load fetched %v, want only %v", fetched, want)
+	}
+	for _, name := range []string{"Model-1", "Model-3", "Model-4", "model-5"} {
+		if models[name] == nil {
+			t.Errorf("model %q missing after resume", name)
+		}
+	}
+	if models["Model-2"] != nil {
+		t.Error("inactive model included")
+	}
+	if c.progress.next != 0 || c.progress.models != nil {
+		t.Errorf("progress = %+v, want it reset after a full pass", c.progress)
+	}
+
+	fetched = nil
+	if _, err := c.loadModels(ctx, 5, get(cancel, -1)); err != nil {
+		t.Fatalf("fresh load: %v", err)
+	}
+	if len(fetched) != 5 {
+		t.Errorf("fresh load after a full pass fetched %v, want all 5", fetched)
+	}
+}
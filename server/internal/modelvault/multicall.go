@@ -0,0 +1,130 @@
+package modelvault
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/chainrpc"
+)
+
+const (
+	// Multicall3 is deployed at the same address on Base and most EVM chains
+	DefaultMulticallAddress = "0xcA11bde05977b3631167028862bE2a173976CA11"
+	// getModel calls per eth_call; each model is a few hundred bytes of return data
+	MulticallBatchSize = 50
+)
+
+// Only aggregate3 is needed: it runs every call and reports each one's
+// success, so a single reverting model doesn't sink its batch
+const multicall3ABI = `[
+	{
+		"inputs": [
+			{
+				"components": [
+					{"name": "target", "type": "address"},
+					{"name": "allowFailure", "type": "bool"},
+					{"name": "callData", "type": "bytes"}
+				],
+				"name": "calls",
+				"type": "tuple[]"
+			}
+		],
+		"name": "aggregate3",
+		"outputs": [
+			{
+				"components": [
+					{"name": "success", "type": "bool"},
+					{"name": "returnData", "type": "bytes"}
+				],
+				"name": "returnData",
+				"type": "tuple[]"
+			}
+		],
+		"stateMutability": "payable",
+		"type": "function"
+	}
+]`
+
+type multicallCall struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+type multicallResult struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// EnableMulticall batches model loads through the Multicall3 contract at
+// address, on the client's RPC endpoints. If no contract turns out to be
+// deployed there, the first load goes back to one call per model for good.
+func (c *Client) EnableMulticall(address string) error {
+	if !c.enabled {
+		return nil
+	}
+	if !common.IsHexAddress(address) {
+		return fmt.Errorf("invalid Multicall3 address %q", address)
+	}
+
+	parsedABI, err := abi.JSON(strings.NewReader(multicall3ABI))
+	if err != nil {
+		return fmt.Errorf("failed to parse Multicall3 ABI: %w", err)
+	}
+	contract, err := chainrpc.Dial("Multicall3", c.rpcURLs, common.HexToAddress(address), parsedABI)
+	if err != nil {
+		return err
+	}
+	c.multicall = contract
+
+	log.Printf("ModelVault: batching model loads through Multicall3 at %s (%d per call)", address[:12]+"...", MulticallBatchSize)
+	return nil
+}
+
+// getModels reads the models with the given IDs in one aggregate3 eth_call.
+// It is a batchGetter: a model that reverts or won't decode fails alone.
+func (c *Client) getModels(ctx context.Context, ids []int64) ([]*OnChainModel, []error, error) {
+	calls := make([]multicallCall, len(ids))
+	for i, id := range ids {
+		data, err := c.abi.Pack("getModel", big.NewInt(id))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode getModel(%d): %w", id, err)
+		}
+		calls[i] = multicallCall{Target: c.contractAddress, AllowFailure: true, CallData: data}
+	}
+
+	var result []interface{}
+	if err := c.multicall.Call(&bind.CallOpts{Context: ctx}, &result, "aggregate3", calls); err != nil {
+		return nil, nil, fmt.Errorf("aggregate3 call failed: %w", err)
+	}
+	if len(result) == 0 {
+		return nil, nil, fmt.Errorf("empty result from aggregate3")
+	}
+	results := *abi.ConvertType(result[0], new([]multicallResult)).(*[]multicallResult)
+	if len(results) != len(ids) {
+		return nil, nil, fmt.Errorf("aggregate3 returned %d results for %d calls", len(results), len(ids))
+	}
+
+	models := make([]*OnChainModel, len(ids))
+	errs := make([]error, len(ids))
+	for i, r := range results {
+		if !r.Success {
+			errs[i] = fmt.Errorf("getModel(%d) reverted", ids[i])
+			continue
+		}
+		values, err := c.abi.Unpack("getModel", r.ReturnData)
+		if err != nil || len(values) == 0 {
+			errs[i] = fmt.Errorf("failed to decode getModel(%d): %v", ids[i], err)
+			continue
+		}
+		models[i], errs[i] = parseModelResult(values[0])
+	}
+	return models, errs, nil
+}
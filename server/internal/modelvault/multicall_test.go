@@ -0,0 +1,243 @@
+package modelvault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/chainrpc"
+)
+
+var (
+	testVaultAddress     = common.HexToAddress("0x79F39f2a0eA476f53994812e6a8f3C8CFe08c609")
+	testMulticallAddress = common.HexToAddress(DefaultMulticallAddress)
+)
+
+// vaultNode is a JSON-RPC node holding a ModelVault with count models, where
+// model 3 reverts. With multicall off it has no Multicall3 contract.
+type vaultNode struct {
+	count     int64
+	multicall bool
+	calls     atomic.Int32 // eth_calls to ModelVault
+	batches   atomic.Int32 // eth_calls to Multicall3
+}
+
+func (n *vaultNode) serve(t *testing.T) *httptest.Server {
+	t.Helper()
+	vaultABI, _ := abi.JSON(strings.NewReader(modelVaultABI))
+	multiABI, _ := abi.JSON(strings.NewReader(multicall3ABI))
+	getModel := vaultABI.Methods["getModel"]
+
+	// modelData is getModel's return data for id, or nil where it reverts
+	modelData := func(id int64) []byte {
+		if id == 3 {
+			return nil
+		}
+		model := reflect.New(getModel.Outputs[0].Type.GetType()).Elem()
+		model.FieldByName("ModelHash").Set(reflect.ValueOf([32]byte{byte(id)}))
+		model.FieldByName("Name").SetString(fmt.Sprintf("Model-%d", id))
+		model.FieldByName("IsActive").SetBool(true)
+		model.FieldByName("SizeBytes").Set(reflect.ValueOf(big.NewInt(id)))
+		model.FieldByName("Timestamp").Set(reflect.ValueOf(big.NewInt(0)))
+		data, err := getModel.Outputs.Pack(model.Interface())
+		if err != nil {
+			t.Fatalf("pack getModel: %v", err)
+		}
+		return data
+	}
+	// vaultCall answers one ModelVault call; ok is false when it reverts
+	vaultCall := func(input []byte) ([]byte, bool) {
+		method, err := vaultABI.MethodById(input[:4])
+		if err != nil {
+			t.Fatalf("unknown ModelVault call: %v", err)
+		}
+		if method.Name == "getModelCount" {
+			data, _ := method.Outputs.Pack(big.NewInt(n.count))
+			return data, true
+		}
+		args, _ := method.Inputs.Unpack(input[4:])
+		data := modelData(args[0].(*big.Int).Int64())
+		return data, data != nil
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		reply := func(result string) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":%q}`, req.ID, result)
+		}
+		if req.Method == "eth_getCode" {
+			reply("0x")
+			return
+		}
+
+		var call struct {
+			To    common.Address `json:"to"`
+			Data  hexutil.Bytes  `json:"data"`
+			Input hexutil.Bytes  `json:"input"`
+		}
+		json.Unmarshal(req.Params[0], &call)
+		input := call.Input
+		if len(input) == 0 {
+			input = call.Data
+		}
+
+		if call.To == testMulticallAddress {
+			n.batches.Add(1)
+			if !n.multicall {
+				reply("0x")
+				return
+			}
+			args, err := multiABI.Methods["aggregate3"].Inputs.Unpack(input[4:])
+			if err != nil {
+				t.Fatalf("unpack aggregate3: %v", err)
+			}
+			calls := *abi.ConvertType(args[0], new([]multicallCall)).(*[]multicallCall)
+			results := make([]multicallResult, len(calls))
+			for i, c := range calls {
+				if c.Target != testVaultAddress || !c.AllowFailure {
+					t.Errorf("call %d = %+v, want ModelVault with failures allowed", i, c)
+				}
+				results[i].ReturnData, results[i].Success = vaultCall(c.CallData)
+			}
+			data, err := multiABI.Methods["aggregate3"].Outputs.Pack(results)
+			if err != nil {
+				t.Fatalf("pack aggregate3: %v", err)
+			}
+			reply(hexutil.Encode(data))
+			return
+		}
+
+		n.calls.Add(1)
+		data, ok := vaultCall(input)
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"error":{"code":3,"message":"execution reverted"}}`, req.ID)
+			return
+		}
+		reply(hexutil.Encode(data))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testVaultClient(t *testing.T, url string) *Client {
+	t.Helper()
+	parsed, _ := abi.JSON(strings.NewReader(modelVaultABI))
+	contract, err := chainrpc.Dial("ModelVault", []string{url}, testVaultAddress, parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{contractAddress: testVaultAddress, contract: contract, abi: parsed, rpcURLs: []string{url}, enabled: true}
+	if err := c.EnableMulticall(DefaultMulticallAddress); err != nil {
+		t.Fatalf("EnableMulticall: %v", err)
+	}
+	return c
+}
+
+func checkLoadedModels(t *testing.T, models map[string]*OnChainModel, count int64) {
+	t.Helper()
+	for id := int64(1); id <= count; id++ {
+		name := fmt.Sprintf("Model-%d", id)
+		if got := models[name]; (got != nil) != (id != 3) {
+			t.Errorf("%s = %+v; want every model but the reverting 3", name, got)
+		}
+	}
+	if m := models["Model-2"]; m != nil && (m.ModelHash != [32]byte{2} || m.SizeBytes != 2) {
+		t.Errorf("Model-2 decoded as %+v", m)
+	}
+}
+
+func TestLoadModelsThroughMulticall(t *testing.T) {
+	node := &vaultNode{count: MulticallBatchSize + 5, multicall: true}
+	c := testVaultClient(t, node.serve(t).URL)
+
+	models, err := c.loadAllModels(context.Background())
+	if err != nil {
+		t.Fatalf("loadAllModels: %v", err)
+	}
+	checkLoadedModels(t, models, node.count)
+	// getModelCount, then two batches instead of one call per model
+	if calls, batches := node.calls.Load(), node.batches.Load(); calls != 1 || batches != 2 {
+		t.Errorf("%d ModelVault calls and %d batches, want 1 and 2", calls, batches)
+	}
+}
+
+func TestLoadModelsFallsBackWithoutMulticall(t *testing.T) {
+	node := &vaultNode{count: 4}
+	c := testVaultClient(t, node.serve(t).URL)
+
+	models, err := c.loadAllModels(context.Background())
+	if err != nil {
+		t.Fatalf("loadAllModels: %v", err)
+	}
+	checkLoadedModels(t, models, node.count)
+	if c.multicall != nil {
+		t.Error("multicall still enabled with no contract deployed")
+	}
+	if calls := node.calls.Load(); calls != 1+4 {
+		t.Errorf("ModelVault calls = %d, want the count and one per model", calls)
+	}
+
+	node.batches.Store(0)
+	if _, err := c.loadAllModels(context.Background()); err != nil {
+		t.Fatalf("second load: %v", err)
+	}
+	if n := node.batches.Load(); n != 0 {
+		t.Errorf("second load tried multicall %d times, want it left off", n)
+	}
+}
+
+func TestLoadModelBatchesResumesAfterFailedBatch(t *testing.T) {
+	c := &Client{enabled: true}
+	var failed bool
+	getBatch := func(ctx context.Context, ids []int64) ([]*OnChainModel, []error, error) {
+		if ids[0] == 3 && !failed {
+			failed = true
+			return nil, nil, fmt.Errorf("429 Too Many Requests")
+		}
+		models := make([]*OnChainModel, len(ids))
+		for i, id := range ids {
+			models[i] = &OnChainModel{DisplayName: fmt.Sprintf("Model-%d", id), IsActive: true}
+		}
+		return models, make([]error, len(ids)), nil
+	}
+
+	if _, err := c.loadModelBatches(context.Background(), 5, 2, getBatch); err == nil {
+		t.Fatal("failed batch: want an error")
+	}
+	if c.progress.next != 3 || c.progress.success != 2 {
+		t.Fatalf("progress = %+v, want next 3 with 2 models loaded", c.progress)
+	}
+
+	var fetched []int64
+	models, err := c.loadModels(context.Background(), 5, func(ctx context.Context, id int64) (*OnChainModel, error) {
+		fetched = append(fetched, id)
+		return &OnChainModel{DisplayName: fmt.Sprintf("Model-%d", id), IsActive: true}, nil
+	})
+	if err != nil {
+		t.Fatalf("fallback load: %v", err)
+	}
+	if want := []int64{3, 4, 5}; !reflect.DeepEqual(fetched, want) {
+		t.Errorf("fallback fetched %v, want %v", fetched, want)
+	}
+	if len(models) != 2*5 {
+		t.Errorf("%d model keys, want 5 models under two keys each", len(models))
+	}
+}
@@ -0,0 +1,64 @@
+package modelvault
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// nameTable maps keccak256 hashes back to the names they were made from. The
+// contract stores a model's allowed samplers and schedulers only as hashes,
+// so they can be read back only for names the gallery already knows.
+type nameTable map[[32]byte]string
+
+// newNameTable hashes each name as given and in lower case, since the
+// registering side's casing isn't fixed
+func newNameTable(names []string) nameTable {
+	table := make(nameTable, 2*len(names))
+	for _, name := range names {
+		for _, variant := range []string{name, strings.ToLower(name)} {
+			hash := crypto.Keccak256Hash([]byte(variant))
+			if _, ok := table[hash]; !ok && variant != "" {
+				table[hash] = variant
+			}
+		}
+	}
+	return table
+}
+
+// resolve returns the name behind each hash, or the hash as 0x-prefixed hex
+// when it isn't in the table, so unknown entries still show up
+func (t nameTable) resolve(hashes [][32]byte) []string {
+	if len(hashes) == 0 {
+		return nil
+	}
+	names := make([]string, len(hashes))
+	for i, hash := range hashes {
+		name, ok := t[hash]
+		if !ok {
+			name = common.Hash(hash).Hex()
+		}
+		names[i] = name
+	}
+	return names
+}
+
+// IsUnresolvedName reports whether name is a hash resolve couldn't match,
+// returned as 0x-prefixed hex, rather than a real sampler or scheduler name
+func IsUnresolvedName(name string) bool {
+	if len(name) != 2+2*common.HashLength || !strings.HasPrefix(name, "0x") {
+		return false
+	}
+	_, err := hex.DecodeString(name[2:])
+	return err == nil
+}
+
+// SetParamNames sets the sampler and scheduler names GetConstraints can turn
+// the contract's hashes back into. Call it at startup, before the client is
+// used.
+func (c *Client) SetParamNames(samplers, schedulers []string) {
+	c.samplerNames = newNameTable(samplers)
+	c.schedulerNames = newNameTable(schedulers)
+}
@@ -0,0 +1,46 @@
+package modelvault
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestNameTableResolve(t *testing.T) {
+	table := newNameTable([]string{"k_euler", "DDIM", "dpmpp_2m"})
+
+	unknown := crypto.Keccak256Hash([]byte("mystery_sampler"))
+	hashes := [][32]byte{
+		crypto.Keccak256Hash([]byte("k_euler")),
+		crypto.Keccak256Hash([]byte("ddim")),
+		crypto.Keccak256Hash([]byte("DDIM")),
+		unknown,
+	}
+	want := []string{"k_euler", "ddim", "DDIM", unknown.Hex()}
+	if got := table.resolve(hashes); !reflect.DeepEqual(got, want) {
+		t.Errorf("resolve = %v, want %v", got, want)
+	}
+	if !IsUnresolvedName(unknown.Hex()) || IsUnresolvedName("k_euler") || IsUnresolvedName("0xzz") {
+		t.Error("IsUnresolvedName should match only a hex hash")
+	}
+	if got := table.resolve(nil); got != nil {
+		t.Errorf("resolve(nil) = %v, want nil", got)
+	}
+}
+
+func TestSetParamNames(t *testing.T) {
+	c := &Client{}
+	hash := crypto.Keccak256Hash([]byte("karras"))
+	if got := c.schedulerNames.resolve([][32]byte{hash}); got[0] != hash.Hex() {
+		t.Errorf("before SetParamNames = %v, want the hex hash", got)
+	}
+
+	c.SetParamNames([]string{"k_euler"}, []string{"karras"})
+	if got := c.schedulerNames.resolve([][32]byte{hash}); got[0] != "karras" {
+		t.Errorf("scheduler = %v, want karras", got)
+	}
+	if got := c.samplerNames.resolve([][32]byte{hash}); got[0] != hash.Hex() {
+		t.Errorf("scheduler hash resolved as a sampler: %v", got)
+	}
+}
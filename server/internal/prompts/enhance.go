@@ -4,6 +4,8 @@ import (
 	"strings"
 )
 
+// MaxPromptLength is a character cap applied on top of the token budget, as a
+// safety net for input the token estimate handles badly
 const MaxPromptLength = 512
 
 // ModelCategory represents the type of model for prompt optimization
@@ -52,46 +54,53 @@ func DefaultNegativePrompt(category ModelCategory) string {
 }
 
 // EnhancePrompt rewrites the prompt to be more effective for the specific model
-// while staying within the character limit
+// while staying within the category's token budget and the character limit
 func EnhancePrompt(prompt string, category ModelCategory) string {
+	return enhancePrompt(prompt, category, DefaultTokenBudget(category))
+}
+
+func enhancePrompt(prompt string, category ModelCategory, budget int) string {
 	prompt = strings.TrimSpace(prompt)
 	if prompt == "" {
 		return prompt
 	}
+	fits := func(s string) bool {
+		return len(s) <= MaxPromptLength && EstimateTokens(s) <= budget
+	}
 	
 	// If already at or over limit, truncate intelligently
-	if len(prompt) >= MaxPromptLength {
-		return truncatePrompt(prompt, MaxPromptLength)
+	if !fits(prompt) {
+		return limitPrompt(prompt, budget)
 	}
 	
 	// Get enhancement prefix/suffix based on model
 	prefix, suffix := getEnhancements(category)
 	
-	// Calculate available space
-	prefixLen := len(prefix)
-	suffixLen := len(suffix)
-	available := MaxPromptLength - prefixLen - suffixLen - 2 // -2 for separators
-	
 	// If user prompt fits with enhancements
-	if len(prompt) <= available {
-		enhanced := prompt
-		if prefix != "" {
-			enhanced = prefix + " " + enhanced
-		}
-		if suffix != "" {
-			enhanced = enhanced + ", " + suffix
-		}
-		return truncatePrompt(enhanced, MaxPromptLength)
+	enhanced := prompt
+	if prefix != "" {
+		enhanced = prefix + " " + enhanced
+	}
+	if suffix != "" {
+		enhanced = enhanced + ", " + suffix
+	}
+	if fits(enhanced) {
+		return enhanced
 	}
 	
 	// User prompt is too long for full enhancement - prioritize user content
 	// Add only suffix (quality terms) if possible
-	if suffixLen > 0 && len(prompt)+suffixLen+2 <= MaxPromptLength {
-		return truncatePrompt(prompt+", "+suffix, MaxPromptLength)
+	if suffix != "" && fits(prompt+", "+suffix) {
+		return prompt + ", " + suffix
 	}
 	
-	// Just return truncated user prompt
-	return truncatePrompt(prompt, MaxPromptLength)
+	// Just return the user prompt
+	return prompt
+}
+
+// limitPrompt cuts a prompt to the token budget, then to the character cap
+func limitPrompt(prompt string, budget int) string {
+	return truncatePrompt(TruncateTokens(prompt, budget), MaxPromptLength)
 }
 
 func getEnhancements(category ModelCategory) (prefix, suffix string) {
@@ -139,12 +148,17 @@ func truncatePrompt(prompt string, maxLen int) string {
 	return truncated
 }
 
-// ProcessPrompts handles both positive and negative prompt processing
-func ProcessPrompts(prompt, negativePrompt, modelID string) (string, string) {
+// ProcessPrompts handles both positive and negative prompt processing. Both
+// are held to tokenBudget estimated tokens, or the model category's default
+// budget when it's zero, and to MaxPromptLength characters.
+func ProcessPrompts(prompt, negativePrompt, modelID string, tokenBudget int) (string, string) {
 	category := DetectCategory(modelID)
+	if tokenBudget <= 0 {
+		tokenBudget = DefaultTokenBudget(category)
+	}
 	
 	// Enhance the positive prompt
-	enhancedPrompt := EnhancePrompt(prompt, category)
+	enhancedPrompt := enhancePrompt(prompt, category, tokenBudget)
 	
 	// Provide default negative prompt if empty
 	finalNegative := strings.TrimSpace(negativePrompt)
@@ -153,9 +167,7 @@ func ProcessPrompts(prompt, negativePrompt, modelID string) (string, string) {
 	}
 	
 	// Ensure negative prompt is also within limits
-	if len(finalNegative) > MaxPromptLength {
-		finalNegative = truncatePrompt(finalNegative, MaxPromptLength)
-	}
+	finalNegative = limitPrompt(finalNegative, tokenBudget)
 	
 	return enhancedPrompt, finalNegative
 }
@@ -69,7 +69,7 @@ func TestEnhancePrompt(t *testing.T) {
 
 func TestProcessPrompts(t *testing.T) {
 	// Test with no negative prompt - should get default
-	enhanced, negative := ProcessPrompts("A cat sitting", "", "flux_dev")
+	enhanced, negative := ProcessPrompts("A cat sitting", "", "flux_dev", 0)
 	if negative == "" {
 		t.Error("Expected default negative prompt, got empty")
 	}
@@ -81,7 +81,7 @@ func TestProcessPrompts(t *testing.T) {
 	}
 
 	// Test with provided negative prompt - should keep it
-	_, negative2 := ProcessPrompts("A cat", "blurry", "flux_dev")
+	_, negative2 := ProcessPrompts("A cat", "blurry", "flux_dev", 0)
 	if negative2 != "blurry" {
 		t.Errorf("Expected 'blurry', got %q", negative2)
 	}
@@ -0,0 +1,94 @@
+package prompts
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Token budgets by model family, used when a preset doesn't set its own.
+// SDXL's CLIP encoders read 75-token windows and ComfyUI concatenates up to
+// three; Flux and WAN use T5 encoders with 512 positions; LTX-Video's T5 is
+// cut at 128.
+const (
+	tokenBudgetCLIP   = 225
+	tokenBudgetT5     = 512
+	tokenBudgetLTX    = 128
+	charsPerWordToken = 4
+)
+
+// DefaultTokenBudget returns the approximate prompt token limit for a model category
+func DefaultTokenBudget(category ModelCategory) int {
+	switch category {
+	case CategoryFluxImage, CategoryWANVideo:
+		return tokenBudgetT5
+	case CategoryLTXVideo:
+		return tokenBudgetLTX
+	default:
+		return tokenBudgetCLIP
+	}
+}
+
+// promptTokens splits s the way BPE tokenizers roughly do, returning the end
+// offset of each token: words cost one token per four letters, and each
+// punctuation mark or non-Latin character is a token of its own. It's an
+// estimate, close enough to keep prompts inside a model's budget without
+// shipping every model's vocabulary.
+func promptTokens(s string) []int {
+	var ends []int
+	wordStart, wordLen := -1, 0
+	endWord := func(end int) {
+		if wordStart < 0 {
+			return
+		}
+		// A long word spends several tokens; they all end with it
+		for n := (wordLen + charsPerWordToken - 1) / charsPerWordToken; n > 0; n-- {
+			ends = append(ends, end)
+		}
+		wordStart, wordLen = -1, 0
+	}
+
+	for i, r := range s {
+		switch {
+		case r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)) || r == '\'':
+			if wordStart < 0 {
+				wordStart = i
+			}
+			wordLen++
+		case unicode.IsSpace(r):
+			endWord(i)
+		default:
+			endWord(i)
+			ends = append(ends, i+utf8.RuneLen(r))
+		}
+	}
+	endWord(len(s))
+	return ends
+}
+
+// EstimateTokens approximates how many tokens a text encoder will see in s
+func EstimateTokens(s string) int {
+	return len(promptTokens(s))
+}
+
+// TruncateTokens cuts s to at most budget estimated tokens, dropping any
+// trailing separators. It cuts between tokens and never inside a word, so a
+// word that would straddle the budget goes entirely, unless it's the first
+// word: that one is cut to the letters the budget pays for, so the prompt is
+// never emptied.
+func TruncateTokens(s string, budget int) string {
+	ends := promptTokens(s)
+	if budget <= 0 || len(ends) <= budget {
+		return s
+	}
+	keep := budget
+	for keep > 0 && ends[keep-1] == ends[keep] {
+		keep--
+	}
+	if keep == 0 {
+		// Word letters are single bytes, so this cuts on a character boundary
+		word := strings.TrimLeftFunc(s, unicode.IsSpace)
+		return word[:budget*charsPerWordToken]
+	}
+	return strings.TrimRight(s[:ends[keep-1]], " ,.")
+}
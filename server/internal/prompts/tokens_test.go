@@ -0,0 +1,108 @@
+package prompts
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"a cat", 2},
+		{"a cat, on a mat.", 7},
+		// Long words cost a token per four letters
+		{"photorealistic", 4},
+		{"dog's 4k", 3},
+		// Each non-Latin character is its own token
+		{"富士山", 3},
+	}
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateTokens(t *testing.T) {
+	tests := []struct {
+		text   string
+		budget int
+		want   string
+	}{
+		{"a cat on a mat", 10, "a cat on a mat"},
+		{"a cat on a mat", 3, "a cat on"},
+		{"a cat, on a mat", 3, "a cat"},
+		// "photorealistic" needs 4 tokens and only 2 are left, so it goes whole
+		{"a cat photorealistic", 4, "a cat"},
+		// A first word over budget is cut rather than dropping the whole prompt
+		{"photorealistic", 2, "photorea"},
+		{"  photorealistic cat", 1, "phot"},
+		{"anything", 0, "anything"},
+	}
+	for _, tt := range tests {
+		got := TruncateTokens(tt.text, tt.budget)
+		if got != tt.want {
+			t.Errorf("TruncateTokens(%q, %d) = %q, want %q", tt.text, tt.budget, got, tt.want)
+		}
+		if tt.budget > 0 && EstimateTokens(got) > tt.budget {
+			t.Errorf("TruncateTokens(%q, %d) left %d tokens", tt.text, tt.budget, EstimateTokens(got))
+		}
+	}
+}
+
+func TestTokenVersusCharacterTruncation(t *testing.T) {
+	// Short words pack many tokens into few characters: well under the
+	// character cap but far over a CLIP budget
+	dense := strings.Repeat("a, ", 170)
+	if len(dense) > MaxPromptLength {
+		t.Fatalf("test prompt is %d chars, want it under the %d cap", len(dense), MaxPromptLength)
+	}
+	got := limitPrompt(dense, tokenBudgetCLIP)
+	if tokens := EstimateTokens(got); tokens > tokenBudgetCLIP || tokens < tokenBudgetCLIP-2 {
+		t.Errorf("dense prompt kept %d tokens, want about %d", tokens, tokenBudgetCLIP)
+	}
+	if truncatePrompt(dense, MaxPromptLength) != dense {
+		t.Error("character truncation alone should have left the dense prompt alone")
+	}
+
+	// Long words are few tokens for their length: the token budget allows
+	// them, so the character cap is what stops them
+	sparse := strings.Repeat("abcd ", 600)
+	if EstimateTokens(sparse) > tokenBudgetT5*2 {
+		t.Fatal("test prompt should be within a generous token budget")
+	}
+	got = limitPrompt(sparse, tokenBudgetT5*2)
+	if len(got) > MaxPromptLength || len(got) < MaxPromptLength-10 {
+		t.Errorf("sparse prompt is %d chars, want it cut near the %d cap", len(got), MaxPromptLength)
+	}
+}
+
+func TestProcessPromptsTokenBudget(t *testing.T) {
+	long := strings.Repeat("castle ", 300)
+
+	// SDXL's default budget is tighter than Flux's; a dense prompt shows it
+	// before the character cap cuts both
+	dense := strings.Repeat("a, ", 300)
+	sdxl, _ := ProcessPrompts(dense, "", "sdxl_base", 0)
+	flux, _ := ProcessPrompts(dense, "", "flux_dev", 0)
+	if got := EstimateTokens(sdxl); got > DefaultTokenBudget(CategorySDXLImage) {
+		t.Errorf("SDXL prompt has %d tokens, want at most %d", got, DefaultTokenBudget(CategorySDXLImage))
+	}
+	if len(flux) <= len(sdxl) {
+		t.Errorf("Flux prompt (%d chars) should keep more than SDXL's (%d)", len(flux), len(sdxl))
+	}
+
+	// A preset's own budget wins, and holds the negative prompt too
+	prompt, negative := ProcessPrompts(long, long, "flux_dev", 20)
+	if EstimateTokens(prompt) > 20 || EstimateTokens(negative) > 20 {
+		t.Errorf("with a 20 token budget got %d and %d tokens", EstimateTokens(prompt), EstimateTokens(negative))
+	}
+
+	// Short prompts still get their quality suffix
+	if got, _ := ProcessPrompts("a cat", "", "flux_dev", 0); got != "a cat, high quality, detailed, sharp focus" {
+		t.Errorf("short prompt = %q", got)
+	}
+}
@@ -21,13 +21,17 @@ type Client struct {
 	sharedPresign     *s3.PresignClient
 	transientBucket   string
 	permanentBucket   string
+	// keyPrefix namespaces object keys (e.g. "prod/") so environments can share a bucket
+	keyPrefix         string
 }
 
 // NewClient creates a new R2 client with both transient and shared access
-func NewClient(endpoint, transientBucket, permanentBucket, accessKeyID, accessKeySecret, sharedKeyID, sharedKeySecret string) (*Client, error) {
+// keyPrefix may be empty; otherwise it is applied to every object key the client touches
+func NewClient(endpoint, transientBucket, permanentBucket, accessKeyID, accessKeySecret, sharedKeyID, sharedKeySecret, keyPrefix string) (*Client, error) {
 	client := &Client{
 		transientBucket: transientBucket,
 		permanentBucket: permanentBucket,
+		keyPrefix:       normalizeKeyPrefix(keyPrefix),
 	}
 
 	// Create transient client (for regular media access)
@@ -79,9 +83,49 @@ func NewClient(endpoint, transientBucket, permanentBucket, accessKeyID, accessKe
 	return client, nil
 }
 
+// normalizeKeyPrefix turns "prod", "/prod/" or "prod/" into "prod/"
+func normalizeKeyPrefix(prefix string) string {
+	prefix = strings.Trim(strings.TrimSpace(prefix), "/")
+	if prefix == "" {
+		return ""
+	}
+	return prefix + "/"
+}
+
+// objectKey applies the configured prefix to a bare object key.
+// Keys that already carry the prefix are returned unchanged.
+func (c *Client) objectKey(key string) string {
+	key = strings.TrimPrefix(key, "/")
+	if c.keyPrefix == "" || strings.HasPrefix(key, c.keyPrefix) {
+		return key
+	}
+	return c.keyPrefix + key
+}
+
+// KeyFromURL resolves the (prefixed) object key referenced by a stored media URL
+func (c *Client) KeyFromURL(mediaURL string) string {
+	if mediaURL == "" || strings.HasPrefix(mediaURL, "data:") {
+		return ""
+	}
+	path := mediaURL
+	if u, err := url.Parse(mediaURL); err == nil && u.Path != "" {
+		path = u.Path
+	} else if idx := strings.Index(path, "?"); idx != -1 {
+		path = path[:idx]
+	}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	filename := parts[len(parts)-1]
+	if filename == "" {
+		return ""
+	}
+	return c.objectKey(filename)
+}
+
 // GenerateDownloadURL generates a presigned URL for downloading an object
 // Tries shared bucket first (for permanent/shared content), then transient
 func (c *Client) GenerateDownloadURL(ctx context.Context, objectKey string, expiresIn time.Duration) (string, error) {
+	objectKey = c.objectKey(objectKey)
+
 	// Try shared/permanent bucket first (shared content persists longer)
 	if c.sharedPresign != nil {
 		request, err := c.sharedPresign.PresignGetObject(ctx, &s3.GetObjectInput{
@@ -108,28 +152,53 @@ func (c *Client) GenerateDownloadURL(ctx context.Context, objectKey string, expi
 	return "", fmt.Errorf("no R2 client available")
 }
 
+// imageExtensions name objects for images the Grid returned in a format other
+// than the default WebP
+var imageExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/jpg":  ".jpg",
+}
+
+// MediaFilename is the object name a generation is stored under: .png or
+// .jpg for PNG and JPEG images, .webp for everything else, videos included
+func MediaFilename(procgenID, mimeType string) string {
+	if ext, ok := imageExtensions[strings.ToLower(strings.TrimSpace(mimeType))]; ok {
+		return procgenID + ext
+	}
+	return procgenID + ".webp"
+}
+
 // GenerateMediaURL returns a CDN URL for accessing the media
 // Always returns CDN URL since presigned URLs have permission issues
-func (c *Client) GenerateMediaURL(ctx context.Context, procgenID string, mediaType string) (string, error) {
-	// All media files (images and videos) use .webp extension
-	// Videos are stored as MP4 with .webp extension for CDN compatibility
-	filename := procgenID + ".webp"
-	
+func (c *Client) GenerateMediaURL(ctx context.Context, procgenID string, mimeType string) (string, error) {
+	// Objects are named by MediaFilename; videos are stored as MP4 under a
+	// .webp key for CDN compatibility
 	// Always return CDN URL - presigned URLs have permission issues
 	// The CDN handles Content-Type headers correctly for video playback
-	return "https://images.aipg.art/" + filename, nil
+	return c.CDNURL(MediaFilename(procgenID, mimeType)), nil
+}
+
+// CDNURL is the public CDN URL of an object, with the key prefix applied.
+// A nil client (R2 not configured) has no prefix to apply.
+func (c *Client) CDNURL(filename string) string {
+	key := strings.TrimPrefix(filename, "/")
+	if c != nil {
+		key = c.objectKey(key)
+	}
+	return "https://" + PublicMediaHost + "/" + key
 }
 
 // ConvertToCDNURL converts any R2 URL to the CDN format
-// Extracts the filename from the URL and returns https://images.aipg.art/{filename}
-func ConvertToCDNURL(mediaURL string) string {
+// Extracts the filename from the URL and returns its CDNURL
+func (c *Client) ConvertToCDNURL(mediaURL string) string {
 	// Return empty string if input is empty
 	if mediaURL == "" {
 		return ""
 	}
 	
 	// If already a CDN URL, return as-is
-	if strings.HasPrefix(mediaURL, "https://images.aipg.art/") {
+	if strings.HasPrefix(mediaURL, "https://"+PublicMediaHost+"/") {
 		return mediaURL
 	}
 	
@@ -155,7 +224,7 @@ func ConvertToCDNURL(mediaURL string) string {
 			if !strings.Contains(filename, ".") {
 				filename = filename + ".webp"
 			}
-			return "https://images.aipg.art/" + filename
+			return c.CDNURL(filename)
 		}
 		return mediaURL // Fallback to original URL
 	}
@@ -178,14 +247,44 @@ func ConvertToCDNURL(mediaURL string) string {
 		if !strings.Contains(filename, ".") {
 			filename = filename + ".webp"
 		}
-		return "https://images.aipg.art/" + filename
+		return c.CDNURL(filename)
 	}
 	
 	return mediaURL // Fallback to original URL
 }
 
+// PublicMediaHost serves the public (permanent) bucket. URLs on it are safe to
+// move to another CDN; presigned URLs carry a signature tied to their host.
+const PublicMediaHost = "images.aipg.art"
+
+// RewriteMediaURL moves a public media URL onto base (e.g.
+// "https://cdn.example.com" or "https://example.com/media"), keeping its path
+// and query. Anything not on PublicMediaHost, presigned URLs included, comes
+// back unchanged, as does everything when base is empty or invalid.
+func RewriteMediaURL(mediaURL, base string) string {
+	if base == "" || mediaURL == "" {
+		return mediaURL
+	}
+	u, err := url.Parse(mediaURL)
+	if err != nil || u.Scheme != "https" || !strings.EqualFold(u.Host, PublicMediaHost) {
+		return mediaURL
+	}
+	b, err := url.Parse(base)
+	if err != nil || b.Host == "" || (b.Scheme != "https" && b.Scheme != "http") {
+		return mediaURL
+	}
+	u.Scheme, u.Host = b.Scheme, b.Host
+	if prefix := strings.Trim(b.Path, "/"); prefix != "" {
+		u.Path = "/" + prefix + "/" + strings.TrimPrefix(u.Path, "/")
+		u.RawPath = ""
+	}
+	return u.String()
+}
+
 // ObjectExists checks if an object exists in either bucket
 func (c *Client) ObjectExists(ctx context.Context, objectKey string) (bool, error) {
+	objectKey = c.objectKey(objectKey)
+
 	// Check shared bucket first
 	if c.sharedClient != nil {
 		_, err := c.sharedClient.HeadObject(ctx, &s3.HeadObjectInput{
@@ -216,6 +315,7 @@ func (c *Client) DeleteObject(ctx context.Context, objectKey string) error {
 	if c.transientClient == nil {
 		return fmt.Errorf("no transient R2 client available")
 	}
+	objectKey = c.objectKey(objectKey)
 	_, err := c.transientClient.DeleteObject(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(c.transientBucket),
 		Key:    aws.String(objectKey),
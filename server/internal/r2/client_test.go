@@ -0,0 +1,129 @@
+package r2
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestKeyPrefixAppliedUniformly(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "transient", "permanent", "id", "secret", "", "", "/staging/")
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	ctx := context.Background()
+
+	mediaURL, _ := c.GenerateMediaURL(ctx, "gen-1", "")
+	if mediaURL != "https://images.aipg.art/staging/gen-1.webp" {
+		t.Errorf("GenerateMediaURL = %q", mediaURL)
+	}
+	if pngURL, _ := c.GenerateMediaURL(ctx, "gen-1", "image/png"); pngURL != "https://images.aipg.art/staging/gen-1.png" {
+		t.Errorf("GenerateMediaURL for a PNG = %q", pngURL)
+	}
+
+	downloadURL, err := c.GenerateDownloadURL(ctx, "gen-1.webp", 0)
+	if err != nil || !strings.Contains(downloadURL, "/transient/staging/gen-1.webp") {
+		t.Errorf("GenerateDownloadURL = %q (err %v)", downloadURL, err)
+	}
+
+	if ok, _ := c.ObjectExists(ctx, "gen-1.webp"); !ok {
+		t.Errorf("ObjectExists = false")
+	}
+	if err := c.DeleteObject(ctx, "staging/gen-1.webp"); err != nil {
+		t.Errorf("DeleteObject: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"HEAD /transient/staging/gen-1.webp", "DELETE /transient/staging/gen-1.webp"}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("requests = %v, want %v", paths, want)
+	}
+}
+
+func TestKeyFromURL(t *testing.T) {
+	c := &Client{keyPrefix: normalizeKeyPrefix("prod")}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://images.aipg.art/gen-1.webp", "prod/gen-1.webp"},
+		{"https://acct.r2.cloudflarestorage.com/bucket/prod/gen-1.webp?X-Amz-Signature=abc", "prod/gen-1.webp"},
+		{"data:image/webp;base64,AAAA", ""},
+		{"", ""},
+	}
+	for _, tc := range tests {
+		if got := c.KeyFromURL(tc.url); got != tc.want {
+			t.Errorf("KeyFromURL(%q) = %q, want %q", tc.url, got, tc.want)
+		}
+	}
+
+	unprefixed := &Client{}
+	if got := unprefixed.KeyFromURL("https://images.aipg.art/gen-1.webp"); got != "gen-1.webp" {
+		t.Errorf("KeyFromURL without prefix = %q", got)
+	}
+}
+
+func TestRewriteMediaURL(t *testing.T) {
+	presigned := "https://acct.r2.cloudflarestorage.com/horde-permanent/gen-1.webp?X-Amz-Algorithm=AWS4-HMAC-SHA256&X-Amz-Signature=abc"
+
+	tests := []struct {
+		url  string
+		base string
+		want string
+	}{
+		{"https://images.aipg.art/gen-1.webp", "https://cdn.example.com", "https://cdn.example.com/gen-1.webp"},
+		{"https://images.aipg.art/prod/gen-1.webp?v=2", "https://cdn.example.com/", "https://cdn.example.com/prod/gen-1.webp?v=2"},
+		{"https://images.aipg.art/gen-1.webp", "https://example.com/media/", "https://example.com/media/gen-1.webp"},
+		{"https://images.aipg.art/gen-1.webp", "", "https://images.aipg.art/gen-1.webp"},
+		{"https://images.aipg.art/gen-1.webp", "cdn.example.com", "https://images.aipg.art/gen-1.webp"},
+		{presigned, "https://cdn.example.com", presigned},
+		{"data:image/webp;base64,AAAA", "https://cdn.example.com", "data:image/webp;base64,AAAA"},
+	}
+	for _, tt := range tests {
+		if got := RewriteMediaURL(tt.url, tt.base); got != tt.want {
+			t.Errorf("RewriteMediaURL(%q, %q) = %q, want %q", tt.url, tt.base, got, tt.want)
+		}
+	}
+}
+
+func TestCDNURLAppliesKeyPrefix(t *testing.T) {
+	c := &Client{keyPrefix: normalizeKeyPrefix("prod")}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://acct.r2.cloudflarestorage.com/bucket/prod/gen-1.webp?X-Amz-Signature=abc", "https://images.aipg.art/prod/gen-1.webp"},
+		{"gen-2", "https://images.aipg.art/prod/gen-2.webp"},
+		{"https://images.aipg.art/gen-3.webp", "https://images.aipg.art/gen-3.webp"},
+		{"data:image/webp;base64,AAAA", "data:image/webp;base64,AAAA"},
+	}
+	for _, tc := range tests {
+		if got := c.ConvertToCDNURL(tc.url); got != tc.want {
+			t.Errorf("ConvertToCDNURL(%q) = %q, want %q", tc.url, got, tc.want)
+		}
+	}
+
+	if got := c.CDNURL("gen-1.png"); got != "https://images.aipg.art/prod/gen-1.png" {
+		t.Errorf("CDNURL = %q", got)
+	}
+	var unconfigured *Client
+	if got := unconfigured.CDNURL("gen-1.png"); got != "https://images.aipg.art/gen-1.png" {
+		t.Errorf("CDNURL without a client = %q", got)
+	}
+}
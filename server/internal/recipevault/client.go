@@ -4,19 +4,22 @@ import (
 	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"math/big"
 	"reflect"
+	"sort"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
+
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/cache"
+	"github.com/aipowergrid/aipg-art-gallery/server/internal/chainrpc"
 )
 
 // Compression enum matching the SDK
@@ -37,23 +40,21 @@ type OnChainRecipeInfo struct {
 	CreatedAt     int64
 	Name          string
 	Description   string
+	WorkflowData  []byte                 // Raw on-chain bytes, still compressed
 	Workflow      map[string]interface{} // Decompressed workflow JSON
-	WorkflowError string                 // Error message if decompression failed
+	WorkflowError string                 // Error message if decompression or verification failed
+	// WorkflowVerified is true when the decompressed workflow hashes to RecipeRoot (see WorkflowRoot)
+	WorkflowVerified bool
 }
 
 // Client for querying the RecipeVault facet through the diamond proxy contract
 type Client struct {
-	rpcURL          string
 	contractAddress common.Address
-	ethClient       *ethclient.Client
-	contract        *bind.BoundContract
+	contract        *chainrpc.Contract
 	enabled         bool
 
-	// Cache
-	mu              sync.RWMutex
-	recipeCache     map[string]*OnChainRecipeInfo
-	cacheExpiry     time.Time
-	cacheTTL        time.Duration
+	// Every registered recipe, public or not; callers filter by visibility
+	recipes         *cache.TTLCache[[]*OnChainRecipeInfo]
 }
 
 // Default configuration
@@ -99,41 +100,37 @@ const recipeVaultABI = `[
 ]`
 
 // NewClient creates a new RecipeVault client
-func NewClient(rpcURL, contractAddress string, enabled bool) (*Client, error) {
+// Several RPC URLs may be given; calls fail over between them in order.
+func NewClient(rpcURLs []string, contractAddress string, enabled bool) (*Client, error) {
 	if !enabled {
-		return &Client{enabled: false, recipeCache: make(map[string]*OnChainRecipeInfo)}, nil
+		return &Client{enabled: false}, nil
 	}
 
-	if rpcURL == "" {
-		rpcURL = DefaultRecipeVaultRPCURL
+	if len(rpcURLs) == 0 {
+		rpcURLs = []string{DefaultRecipeVaultRPCURL}
 	}
 	if contractAddress == "" {
 		contractAddress = DefaultRecipeVaultContractAddress
 	}
 
-	ethClient, err := ethclient.Dial(rpcURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Ethereum RPC: %w", err)
-	}
-
 	parsedABI, err := abi.JSON(strings.NewReader(recipeVaultABI))
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse ABI: %w", err)
 	}
 
 	addr := common.HexToAddress(contractAddress)
-	boundContract := bind.NewBoundContract(addr, parsedABI, ethClient, ethClient, ethClient)
+	contract, err := chainrpc.Dial("RecipeVault", rpcURLs, addr, parsedABI)
+	if err != nil {
+		return nil, err
+	}
 
-	log.Printf("RecipeVault client initialized (chain: Base Mainnet, contract: %s)", contractAddress[:12]+"...")
+	log.Printf("RecipeVault client initialized (chain: Base Mainnet, contract: %s, RPC endpoints: %d)", contractAddress[:12]+"...", len(rpcURLs))
 
 	return &Client{
-		rpcURL:          rpcURL,
 		contractAddress: addr,
-		ethClient:       ethClient,
-		contract:        boundContract,
+		contract:        contract,
 		enabled:         true,
-		recipeCache:     make(map[string]*OnChainRecipeInfo),
-		cacheTTL:        DefaultRecipeVaultCacheTTL,
+		recipes:         cache.New[[]*OnChainRecipeInfo](DefaultRecipeVaultCacheTTL),
 	}, nil
 }
 
@@ -283,27 +280,34 @@ func parseRecipeResult(data interface{}) (*OnChainRecipeInfo, error) {
 	}
 
 	// Decompress workflow data
-	workflow, workflowError := decompressWorkflow(workflowData, compression)
+	workflow, workflowJSON, workflowError := decompressWorkflow(workflowData, compression)
+	verified := false
+	if workflowError == "" {
+		verified, workflowError = verifyWorkflowRoot(recipeRoot, workflowJSON)
+	}
 
 	return &OnChainRecipeInfo{
-		RecipeID:      recipeID,
-		RecipeRoot:    recipeRoot,
-		Creator:       creator,
-		CanCreateNFTs: canCreateNFTs,
-		IsPublic:      isPublic,
-		Compression:   compression,
-		CreatedAt:     createdAt,
-		Name:          name,
-		Description:   description,
-		Workflow:      workflow,
-		WorkflowError: workflowError,
+		RecipeID:         recipeID,
+		RecipeRoot:       recipeRoot,
+		Creator:          creator,
+		CanCreateNFTs:    canCreateNFTs,
+		IsPublic:         isPublic,
+		Compression:      compression,
+		CreatedAt:        createdAt,
+		Name:             name,
+		Description:      description,
+		WorkflowData:     workflowData,
+		Workflow:         workflow,
+		WorkflowError:    workflowError,
+		WorkflowVerified: verified,
 	}, nil
 }
 
-// decompressWorkflow decompresses workflow data based on compression type
-func decompressWorkflow(data []byte, compression int) (map[string]interface{}, string) {
+// decompressWorkflow decompresses workflow data based on compression type,
+// returning the parsed workflow and the decompressed JSON bytes
+func decompressWorkflow(data []byte, compression int) (map[string]interface{}, []byte, string) {
 	if len(data) == 0 {
-		return nil, "empty workflow data"
+		return nil, nil, "empty workflow data"
 	}
 
 	var workflowJSON []byte
@@ -312,7 +316,7 @@ func decompressWorkflow(data []byte, compression int) (map[string]interface{}, s
 	case CompressionGzip:
 		reader, err := gzip.NewReader(strings.NewReader(string(data)))
 		if err != nil {
-			return nil, fmt.Sprintf("failed to create gzip reader: %v", err)
+			return nil, nil, fmt.Sprintf("failed to create gzip reader: %v", err)
 		}
 		defer reader.Close()
 		
@@ -320,42 +324,98 @@ func decompressWorkflow(data []byte, compression int) (map[string]interface{}, s
 		var buf strings.Builder
 		_, err = io.Copy(&buf, reader)
 		if err != nil {
-			return nil, fmt.Sprintf("failed to decompress gzip: %v", err)
+			return nil, nil, fmt.Sprintf("failed to decompress gzip: %v", err)
 		}
 		workflowJSON = []byte(buf.String())
 	case CompressionNone:
 		workflowJSON = data
 	default:
-		return nil, fmt.Sprintf("unsupported compression type: %d", compression)
+		return nil, nil, fmt.Sprintf("unsupported compression type: %d", compression)
 	}
 
 	var workflow map[string]interface{}
 	if err := json.Unmarshal(workflowJSON, &workflow); err != nil {
-		return nil, fmt.Sprintf("failed to parse workflow JSON: %v", err)
+		return nil, nil, fmt.Sprintf("failed to parse workflow JSON: %v", err)
 	}
 
-	return workflow, ""
+	return workflow, workflowJSON, ""
 }
 
-// FetchAllRecipes fetches all registered recipes from the blockchain
+// FetchAllRecipes returns the public recipes, keyed by name and normalized name
 func (c *Client) FetchAllRecipes(ctx context.Context) (map[string]*OnChainRecipeInfo, error) {
 	if !c.enabled {
 		return nil, nil
 	}
 
-	// Check cache first
-	c.mu.RLock()
-	if time.Now().Before(c.cacheExpiry) && len(c.recipeCache) > 0 {
-		cache := make(map[string]*OnChainRecipeInfo, len(c.recipeCache))
-		for k, v := range c.recipeCache {
-			cache[k] = v
+	all, err := c.loadCachedRecipes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	recipes := make(map[string]*OnChainRecipeInfo, len(all)*2)
+	for _, recipe := range all {
+		if !recipe.IsPublic {
+			continue
 		}
-		c.mu.RUnlock()
-		log.Printf("Using cached RecipeVault recipes (%d entries, expires in %v)", len(cache), time.Until(c.cacheExpiry).Round(time.Second))
-		return cache, nil
+		recipes[recipe.Name] = recipe
+		// Also index by normalized name
+		normalized := strings.ToLower(strings.ReplaceAll(strings.ReplaceAll(recipe.Name, ".", "_"), "-", "_"))
+		recipes[normalized] = recipe
 	}
-	c.mu.RUnlock()
+	return recipes, nil
+}
 
+// FetchRecipesByCreator returns the recipes created by creator that requester may see:
+// private ones are only included when the requester is the creator
+func (c *Client) FetchRecipesByCreator(ctx context.Context, creator, requester string) ([]*OnChainRecipeInfo, error) {
+	if !c.enabled {
+		return nil, nil
+	}
+
+	all, err := c.loadCachedRecipes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return FilterByCreator(all, creator, requester), nil
+}
+
+// VisibleTo reports whether requester (a wallet address, empty when anonymous) may see the recipe
+func (r *OnChainRecipeInfo) VisibleTo(requester string) bool {
+	return r.IsPublic || (requester != "" && strings.EqualFold(r.Creator, requester))
+}
+
+// FilterByCreator keeps the recipes created by creator that are visible to requester
+func FilterByCreator(recipes []*OnChainRecipeInfo, creator, requester string) []*OnChainRecipeInfo {
+	out := make([]*OnChainRecipeInfo, 0)
+	for _, recipe := range recipes {
+		if strings.EqualFold(recipe.Creator, creator) && recipe.VisibleTo(requester) {
+			out = append(out, recipe)
+		}
+	}
+	return out
+}
+
+// loadCachedRecipes returns every recipe via the cache. Concurrent callers
+// share one fetch; a failed refresh keeps serving the previous recipes.
+func (c *Client) loadCachedRecipes(ctx context.Context) ([]*OnChainRecipeInfo, error) {
+	all, stale, err := c.recipes.Get(ctx, c.loadAllRecipes)
+	if errors.Is(err, errNoRecipesLoaded) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stale {
+		log.Printf("Warning: RecipeVault refresh failed, using %d cached recipes", len(all))
+	}
+	return all, nil
+}
+
+// errNoRecipesLoaded keeps an empty result out of the cache
+var errNoRecipesLoaded = errors.New("no recipes loaded from RecipeVault")
+
+// loadAllRecipes reads every registered recipe from the contract
+func (c *Client) loadAllRecipes(ctx context.Context) ([]*OnChainRecipeInfo, error) {
 	count, err := c.GetTotalRecipes(ctx)
 	if err != nil {
 		log.Printf("Warning: failed to get recipe count from blockchain: %v", err)
@@ -364,8 +424,8 @@ func (c *Client) FetchAllRecipes(ctx context.Context) (map[string]*OnChainRecipe
 
 	log.Printf("Fetching %d recipes from RecipeVault (with rate limiting)...", count)
 
-	recipes := make(map[string]*OnChainRecipeInfo)
-	successCount := 0
+	recipes := make([]*OnChainRecipeInfo, 0, count)
+	publicCount := 0
 	failCount := 0
 
 	// Rate limit: ~3 requests per second
@@ -378,7 +438,7 @@ func (c *Client) FetchAllRecipes(ctx context.Context) (map[string]*OnChainRecipe
 			case <-ticker.C:
 				// Continue
 			case <-ctx.Done():
-				log.Printf("Context cancelled after %d recipes", successCount)
+				log.Printf("Context cancelled after %d recipes", len(recipes))
 				break
 			}
 		}
@@ -391,31 +451,25 @@ func (c *Client) FetchAllRecipes(ctx context.Context) (map[string]*OnChainRecipe
 			}
 			continue
 		}
-		if recipe == nil || !recipe.IsPublic {
+		if recipe == nil {
 			continue
 		}
 
-		successCount++
-		recipes[recipe.Name] = recipe
-		// Also index by normalized name
-		normalized := strings.ToLower(strings.ReplaceAll(strings.ReplaceAll(recipe.Name, ".", "_"), "-", "_"))
-		recipes[normalized] = recipe
-	}
-
-	// Update cache
-	if successCount > 0 {
-		c.mu.Lock()
-		c.recipeCache = recipes
-		c.cacheExpiry = time.Now().Add(c.cacheTTL)
-		c.mu.Unlock()
+		recipes = append(recipes, recipe)
+		if recipe.IsPublic {
+			publicCount++
+		}
 	}
 
 	if failCount > 0 {
-		log.Printf("✓ Loaded %d public recipes from RecipeVault (%d failed)", successCount, failCount)
+		log.Printf("✓ Loaded %d recipes (%d public) from RecipeVault (%d failed)", len(recipes), publicCount, failCount)
 	} else {
-		log.Printf("✓ Loaded %d public recipes from RecipeVault", successCount)
+		log.Printf("✓ Loaded %d recipes (%d public) from RecipeVault", len(recipes), publicCount)
 	}
 
+	if len(recipes) == 0 {
+		return recipes, errNoRecipesLoaded
+	}
 	return recipes, nil
 }
 
@@ -452,6 +506,13 @@ func (c *Client) ExtractModelsFromRecipes(ctx context.Context) ([]string, error)
 	return models, nil
 }
 
+// ExtractWorkflowModels returns the model files a ComfyUI workflow loads, sorted
+func ExtractWorkflowModels(workflow map[string]interface{}) []string {
+	models := extractModelsFromWorkflow(workflow)
+	sort.Strings(models)
+	return models
+}
+
 // extractModelsFromWorkflow extracts model names from a ComfyUI workflow
 // Handles both ComfyUI native format (nodes array) and simple format (dict of nodes)
 func extractModelsFromWorkflow(workflow map[string]interface{}) []string {
@@ -0,0 +1,66 @@
+package recipevault
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// WorkflowRoot computes the recipeRoot for decompressed workflow JSON.
+//
+// The root is keccak256 (Ethereum's Keccak-256, not NIST SHA3-256) over the
+// canonical encoding of the decompressed workflow, never over the compressed
+// on-chain bytes. The canonical encoding is UTF-8 JSON with:
+//   - no whitespace between tokens and no trailing newline
+//   - object keys sorted bytewise at every level
+//   - strings escaped as encoding/json does, except that <, > and & are
+//     written literally
+//   - numbers written exactly as they appear in the source (no float
+//     round-tripping, so 64-bit seeds stay exact)
+//
+// Canonicalizing first means formatting differences introduced by an
+// uploader's JSON library don't change the root. The result is lowercase hex
+// without a 0x prefix, matching OnChainRecipeInfo.RecipeRoot.
+func WorkflowRoot(workflowJSON []byte) (string, error) {
+	canonical, err := canonicalJSON(workflowJSON)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(crypto.Keccak256(canonical)), nil
+}
+
+// canonicalJSON re-encodes JSON in the form hashed by WorkflowRoot
+func canonicalJSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber() // keep seeds and other large integers exact
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	// encoding/json writes map keys in sorted order
+	if err := enc.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// verifyWorkflowRoot reports whether workflowJSON matches recipeRoot, with an error message when it doesn't
+func verifyWorkflowRoot(recipeRoot string, workflowJSON []byte) (bool, string) {
+	got, err := WorkflowRoot(workflowJSON)
+	if err != nil {
+		return false, fmt.Sprintf("failed to canonicalize workflow: %v", err)
+	}
+	want := strings.ToLower(strings.TrimPrefix(recipeRoot, "0x"))
+	if got != want {
+		return false, fmt.Sprintf("workflow hash 0x%s does not match recipeRoot 0x%s", got, want)
+	}
+	return true, ""
+}
@@ -0,0 +1,60 @@
+package recipevault
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestWorkflowRootIgnoresFormatting(t *testing.T) {
+	compact := []byte(`{"a":{"seed":12345678901234567890,"text":"<b>"},"b":[1,2]}`)
+	pretty := []byte("{\n  \"b\": [1, 2],\n  \"a\": {\"text\": \"<b>\", \"seed\": 12345678901234567890}\n}")
+
+	want, err := WorkflowRoot(compact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := WorkflowRoot(pretty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("root of reformatted workflow = %s, want %s", got, want)
+	}
+	if len(want) != 64 {
+		t.Errorf("root %q is not 32 bytes of hex", want)
+	}
+}
+
+func TestWorkflowRootScheme(t *testing.T) {
+	workflow := []byte("{\n  \"b\": [1, 2.50],\n  \"a\": {\"text\": \"<b> & \\u00e9\", \"seed\": 12345678901234567890}\n}\n")
+	want := `{"a":{"seed":12345678901234567890,"text":"<b> & é"},"b":[1,2.50]}`
+
+	canonical, err := canonicalJSON(workflow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(canonical) != want {
+		t.Errorf("canonical form = %s, want %s", canonical, want)
+	}
+	root, _ := WorkflowRoot(workflow)
+	if wantRoot := hex.EncodeToString(crypto.Keccak256([]byte(want))); root != wantRoot {
+		t.Errorf("root = %s, want keccak256 of the canonical form %s", root, wantRoot)
+	}
+}
+
+func TestVerifyWorkflowRoot(t *testing.T) {
+	workflow := []byte(`{"3":{"class_type":"KSampler"}}`)
+	root, _ := WorkflowRoot(workflow)
+
+	if ok, msg := verifyWorkflowRoot("0x"+strings.ToUpper(root), workflow); !ok {
+		t.Errorf("matching root rejected: %s", msg)
+	}
+
+	ok, msg := verifyWorkflowRoot(root, []byte(`{"3":{"class_type":"KSamplerAdvanced"}}`))
+	if ok || !strings.Contains(msg, "does not match recipeRoot") {
+		t.Errorf("mismatching root = %v, %q; want rejection", ok, msg)
+	}
+}
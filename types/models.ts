@@ -7,6 +7,8 @@ export interface ModelLimits {
   cfgScale?: RangeFieldFloat;
   length?: RangeField;
   fps?: RangeField;
+  /** Approximate prompt token budget, when the preset sets one */
+  promptTokens?: number;
 }
 
 export interface RangeField {
@@ -45,6 +47,19 @@ export interface ChainConstraints {
   cfgMin?: number;
   cfgMax?: number;
   clipSkip?: number;
+  /** Allowed on chain but under a hash no known name matches; not selectable */
+  unknownSamplers?: string[];
+  unknownSchedulers?: string[];
+}
+
+/** A sampler or scheduler choice with display metadata */
+export interface ParamOption {
+  value: string;
+  label: string;
+  default: boolean;
+  recommended: boolean;
+  /** Listed in the model's on-chain allowed set */
+  onChain?: boolean;
 }
 
 export interface GalleryModel {
@@ -56,7 +71,9 @@ export interface GalleryModel {
   capabilities: ModelCapability[];
   samplers: string[];
   schedulers: string[];
-  status: "online" | "offline";
+  samplerOptions: ParamOption[];
+  schedulerOptions: ParamOption[];
+  status: "online" | "busy" | "degraded" | "offline";
   onlineWorkers: number;
   queueLength: number;
   estimatedWaitSeconds: number;
@@ -97,6 +114,8 @@ export interface CreateJobRequest {
     fps?: number;
     tiling?: boolean;
     hiresFix?: boolean;
+    /** Images to generate in one job (defaults to 1) */
+    count?: number;
   };
   sourceImage?: string;
   sourceMask?: string;
@@ -106,7 +125,7 @@ export interface CreateJobRequest {
 
 export interface JobStatus {
   jobId: string;
-  status: "queued" | "processing" | "completed" | "faulted";
+  status: "queued" | "processing" | "completed" | "completed_empty" | "faulted";
   faulted: boolean;
   waitTime: number;
   queuePosition: number;
@@ -117,6 +136,17 @@ export interface JobStatus {
   /** Number of generations still waiting */
   waiting: number;
   generations: GenerationView[];
+  /** Why the job faulted; only present when faulted */
+  faultReason?: FaultReason;
+  /** Why a completed_empty job has no output */
+  message?: string;
+}
+
+export interface FaultReason {
+  message: string;
+  /** Whether resubmitting the same request is worth trying */
+  class: "retryable" | "permanent";
+  faultedGenerations?: number;
 }
 
 export interface GenerationView {
@@ -128,5 +158,7 @@ export interface GenerationView {
   base64?: string;
   workerId?: string;
   workerName?: string;
+  /** Grid model that produced it; differs from the request when a fallback ran the job */
+  model?: string;
 }
 